@@ -0,0 +1,67 @@
+package arbitrage
+
+import (
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+// walks the side of the book until quantity (in base units) is filled
+// returns the quote spent/received, the base actually filled and the worst price touched
+func walkBookByQuantity(side []types.OrderBookEntry, quantity decimal.Decimal) (decimal.Decimal, decimal.Decimal, decimal.Decimal) {
+    notional := decimal.Zero
+    filled := decimal.Zero
+    worstPrice := decimal.Zero
+
+    for _, entry := range side {
+        if !filled.LessThan(quantity) {
+            break
+        }
+        fill := decimal.Min(entry.Quantity, quantity.Sub(filled))
+        notional = notional.Add(fill.Mul(entry.Price))
+        filled = filled.Add(fill)
+        worstPrice = entry.Price
+    }
+
+    return notional, filled, worstPrice
+}
+
+// walks the side of the book until notional (in quote units) is spent
+// returns the base received, the quote actually spent and the worst price touched
+func walkBookByNotional(side []types.OrderBookEntry, notional decimal.Decimal) (decimal.Decimal, decimal.Decimal, decimal.Decimal) {
+    quantity := decimal.Zero
+    spent := decimal.Zero
+    worstPrice := decimal.Zero
+
+    for _, entry := range side {
+        if !spent.LessThan(notional) {
+            break
+        }
+        levelNotional := entry.Quantity.Mul(entry.Price)
+        if remaining := notional.Sub(spent); remaining.LessThan(levelNotional) {
+            quantity = quantity.Add(remaining.Div(entry.Price))
+            spent = notional
+        } else {
+            quantity = quantity.Add(entry.Quantity)
+            spent = spent.Add(levelNotional)
+        }
+        worstPrice = entry.Price
+    }
+
+    return quantity, spent, worstPrice
+}
+
+func totalQuantity(side []types.OrderBookEntry) decimal.Decimal {
+    total := decimal.Zero
+    for _, entry := range side {
+        total = total.Add(entry.Quantity)
+    }
+    return total
+}
+
+func totalNotional(side []types.OrderBookEntry) decimal.Decimal {
+    total := decimal.Zero
+    for _, entry := range side {
+        total = total.Add(entry.Quantity.Mul(entry.Price))
+    }
+    return total
+}
@@ -0,0 +1,137 @@
+package arbitrage
+
+import (
+    "fmt"
+
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+// returned when a side of the book cannot fill the requested size, rather than pricing a partial fill
+type DepthError struct {
+    // "asks" of the book bought on or "bids" of the book sold on
+    Side      string
+    Requested decimal.Decimal
+    Available decimal.Decimal
+}
+
+func (e *DepthError) Error() string {
+    return fmt.Sprintf("%v can fill %v of %v", e.Side, e.Available, e.Requested)
+}
+
+// buying Size on one exchange's asks and selling it on another's bids with taker fees;
+// prices and amounts are in the quote asset
+type CrossEdge struct {
+    Size         decimal.Decimal
+    // volume weighted fill prices before fees
+    BuyVWAP      decimal.Decimal
+    SellVWAP     decimal.Decimal
+    // worst levels touched, the limit prices that fill Size
+    BuyLimit     decimal.Decimal
+    SellLimit    decimal.Decimal
+    // spent on the buy and received from the sell, net of fees
+    Cost         decimal.Decimal
+    Proceeds     decimal.Decimal
+    // Proceeds - Cost
+    Profit       decimal.Decimal
+    // Profit as a fraction of Cost
+    Return       decimal.Decimal
+    // size with the largest profit, zero when no size is profitable
+    OptimalSize  decimal.Decimal
+    // largest size whose profit is still positive, zero when no size is profitable
+    MaxSize      decimal.Decimal
+    // MaxSize is bounded by the visible depth rather than by the edge running out
+    DepthLimited bool
+}
+
+type level struct {
+    side  []types.OrderBookEntry
+    index int
+    left  decimal.Decimal
+}
+
+// moves to the next level with quantity, false at the end of the side
+func (l *level) next() bool {
+    for l.index++; l.index < len(l.side); l.index++ {
+        if l.side[l.index].Quantity.IsPositive() {
+            l.left = l.side[l.index].Quantity
+            return true
+        }
+    }
+    return false
+}
+
+// walks both books level by level; the marginal profit of each unit only falls as the
+// walk goes deeper, so profit rises to OptimalSize and then falls back to zero at MaxSize
+func sizeCrossEdge(asks, bids []types.OrderBookEntry, buyFee, sellFee decimal.Decimal) (decimal.Decimal, decimal.Decimal, bool) {
+    one := decimal.NewFromInt(1)
+    ask := &level{side: asks, index: -1}
+    bid := &level{side: bids, index: -1}
+    if !ask.next() || !bid.next() {
+        return decimal.Zero, decimal.Zero, false
+    }
+
+    size := decimal.Zero
+    profit := decimal.Zero
+    optimal := decimal.Zero
+    for {
+        length := decimal.Min(ask.left, bid.left)
+        marginal := bids[bid.index].Price.Mul(one.Sub(sellFee)).Sub(asks[ask.index].Price.Mul(one.Add(buyFee)))
+        if marginal.IsPositive() {
+            size = size.Add(length)
+            profit = profit.Add(marginal.Mul(length))
+            optimal = size
+        } else {
+            if !profit.IsPositive() {
+                return optimal, size, false
+            }
+            // profit falls to zero within this level
+            if loss := marginal.Neg().Mul(length); !loss.LessThan(profit) {
+                return optimal, size.Add(profit.Div(marginal.Neg())), false
+            }
+            size = size.Add(length)
+            profit = profit.Add(marginal.Mul(length))
+        }
+
+        ask.left = ask.left.Sub(length)
+        bid.left = bid.left.Sub(length)
+        if (!ask.left.IsPositive() && !ask.next()) || (!bid.left.IsPositive() && !bid.next()) {
+            return optimal, size, true
+        }
+    }
+}
+
+// prices size (in base units) against buyBook's asks and sellBook's bids; fees are fractions,
+// e.g. 0.001 for 0.1%; a *DepthError is returned when either side cannot fill size
+func ComputeCrossEdge(buyBook, sellBook *types.OrderBook, buyFee, sellFee, size decimal.Decimal) (CrossEdge, error) {
+    if !size.IsPositive() {
+        return CrossEdge{}, fmt.Errorf("size must be positive, got %v", size)
+    }
+    if available := totalQuantity(buyBook.Asks); available.LessThan(size) {
+        return CrossEdge{}, &DepthError{Side: "asks", Requested: size, Available: available}
+    }
+    if available := totalQuantity(sellBook.Bids); available.LessThan(size) {
+        return CrossEdge{}, &DepthError{Side: "bids", Requested: size, Available: available}
+    }
+
+    one := decimal.NewFromInt(1)
+    buyNotional, _, buyLimit := walkBookByQuantity(buyBook.Asks, size)
+    sellNotional, _, sellLimit := walkBookByQuantity(sellBook.Bids, size)
+    cost := buyNotional.Mul(one.Add(buyFee))
+    proceeds := sellNotional.Mul(one.Sub(sellFee))
+    optimal, max, depthLimited := sizeCrossEdge(buyBook.Asks, sellBook.Bids, buyFee, sellFee)
+    return CrossEdge{
+        Size: size,
+        BuyVWAP: buyNotional.Div(size),
+        SellVWAP: sellNotional.Div(size),
+        BuyLimit: buyLimit,
+        SellLimit: sellLimit,
+        Cost: cost,
+        Proceeds: proceeds,
+        Profit: proceeds.Sub(cost),
+        Return: proceeds.Sub(cost).Div(cost),
+        OptimalSize: optimal,
+        MaxSize: max,
+        DepthLimited: depthLimited,
+    }, nil
+}
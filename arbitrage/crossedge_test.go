@@ -0,0 +1,65 @@
+package arbitrage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+func TestCrossEdge(t *testing.T) {
+	buyBook := &types.OrderBook{Asks: []types.OrderBookEntry{entry(100, 1), entry(102, 1), entry(110, 5)}}
+	sellBook := &types.OrderBook{Bids: []types.OrderBookEntry{entry(105, 1), entry(103, 2), entry(90, 5)}}
+
+	edge, err := ComputeCrossEdge(buyBook, sellBook, decimal.Zero, decimal.Zero, decimal.NewFromInt(2))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if !edge.BuyVWAP.Equal(decimal.NewFromInt(101)) || !edge.SellVWAP.Equal(decimal.NewFromInt(104)) || !edge.Profit.Equal(decimal.NewFromInt(6)) {
+		t.Fatalf("expected to buy at 101 and sell at 104 for 6, got %+v\n", edge)
+	}
+	if !edge.BuyLimit.Equal(decimal.NewFromInt(102)) || !edge.SellLimit.Equal(decimal.NewFromInt(103)) {
+		t.Fatalf("expected limits of 102 and 103, got %v %v\n", edge.BuyLimit, edge.SellLimit)
+	}
+	// the third unit loses 7, so profit reaches zero 6/7 of the way into it
+	if !edge.OptimalSize.Equal(decimal.NewFromInt(2)) || edge.MaxSize.StringFixed(4) != "2.8571" || edge.DepthLimited {
+		t.Fatalf("expected an optimal size of 2 and a max size of 2.8571, got %v %v %v\n", edge.OptimalSize, edge.MaxSize, edge.DepthLimited)
+	}
+
+	// a 1% fee on each side costs 4.1 of the 6
+	edge, _ = ComputeCrossEdge(buyBook, sellBook, decimal.NewFromFloat(0.01), decimal.NewFromFloat(0.01), decimal.NewFromInt(2))
+	if !edge.Profit.Equal(decimal.NewFromFloat(1.9)) || !edge.OptimalSize.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("expected a profit of 1.9 and an optimal size of 1, got %v %v\n", edge.Profit, edge.OptimalSize)
+	}
+
+	_, err = ComputeCrossEdge(buyBook, sellBook, decimal.Zero, decimal.Zero, decimal.NewFromInt(20))
+	var depthError *DepthError
+	if !errors.As(err, &depthError) || depthError.Side != "asks" || !depthError.Available.Equal(decimal.NewFromInt(7)) {
+		t.Fatalf("expected the asks to be too thin, got %v\n", err)
+	}
+}
+
+func TestCrossEdgeSizing(t *testing.T) {
+	for _, test := range []struct {
+		asks         []types.OrderBookEntry
+		bids         []types.OrderBookEntry
+		maxSize      int64
+		depthLimited bool
+	}{
+		// still profitable when the asks run out
+		{[]types.OrderBookEntry{entry(100, 1)}, []types.OrderBookEntry{entry(110, 3)}, 1, true},
+		// never profitable
+		{[]types.OrderBookEntry{entry(100, 1)}, []types.OrderBookEntry{entry(99, 1)}, 0, false},
+		// empty levels are skipped
+		{[]types.OrderBookEntry{entry(90, 0), entry(100, 2)}, []types.OrderBookEntry{entry(101, 1), entry(100, 1)}, 2, true},
+	} {
+		edge, err := ComputeCrossEdge(&types.OrderBook{Asks: test.asks}, &types.OrderBook{Bids: test.bids}, decimal.Zero, decimal.Zero, decimal.NewFromInt(1))
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if !edge.MaxSize.Equal(decimal.NewFromInt(test.maxSize)) || edge.DepthLimited != test.depthLimited {
+			t.Fatalf("expected a max size of %v, depth limited %v, got %v %v\n", test.maxSize, test.depthLimited, edge.MaxSize, edge.DepthLimited)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package arbitrage
+
+import (
+    "math"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/denali-capital/grizzly/conversion"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+// below this a cycle is considered floating point noise
+const epsilon float64 = 1e-12
+// number of times the size is halved when the maximum size is not profitable
+const sizeReductions int = 8
+
+type EdgeKind uint
+
+const (
+    // places an order against a book
+    Trade EdgeKind = iota
+    // moves inventory of the same asset between exchanges
+    Transfer
+    // swaps between equivalent assets (e.g. USDT and USD) at the converter's rate
+    Conversion
+)
+
+type Node struct {
+    Exchange string
+    Asset    types.Asset
+}
+
+type Edge struct {
+    From      Node
+    To        Node
+    AssetPair types.AssetPair
+    OrderType types.OrderType
+    Kind      EdgeKind
+    // units of To received per unit of From, fee-adjusted and depth-walked at the reference quantity
+    Rate      float64
+    // maximum amount of From executable against the visible depth
+    Capacity  decimal.Decimal
+
+    side      []types.OrderBookEntry
+    fee       decimal.Decimal
+    // exact rate for transfers and conversions
+    rate      decimal.Decimal
+}
+
+func (e *Edge) weight() float64 {
+    return -math.Log(e.Rate)
+}
+
+// returns the amount of To received, the base quantity traded, the worst price touched and whether depth sufficed
+func (e *Edge) execute(amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, decimal.Decimal, bool) {
+    if e.Kind != Trade {
+        return amount.Mul(e.rate), decimal.Zero, decimal.Zero, true
+    }
+    if e.OrderType == types.Buy {
+        quantity, spent, worstPrice := walkBookByNotional(e.side, amount)
+        return quantity.Mul(decimal.NewFromInt(1).Sub(e.fee)), quantity, worstPrice, spent.Equal(amount)
+    }
+    notional, filled, worstPrice := walkBookByQuantity(e.side, amount)
+    return notional.Mul(decimal.NewFromInt(1).Sub(e.fee)), filled, worstPrice, filled.Equal(amount)
+}
+
+type Graph struct {
+    transferCost decimal.Decimal
+    nodes        map[Node]int
+    nodeList     []Node
+    edges        []*Edge
+}
+
+// transferCost is the fraction lost moving an asset between exchanges; zero assumes inventory is pre-positioned
+func NewGraph(transferCost decimal.Decimal) *Graph {
+    return &Graph{
+        transferCost: transferCost,
+        nodes: make(map[Node]int),
+    }
+}
+
+func (g *Graph) node(node Node) int {
+    if i, ok := g.nodes[node]; ok {
+        return i
+    }
+    i := len(g.nodeList)
+    g.nodes[node] = i
+    g.nodeList = append(g.nodeList, node)
+
+    rate := decimal.NewFromInt(1).Sub(g.transferCost)
+    for _, other := range g.nodeList[:i] {
+        if other.Asset == node.Asset {
+            g.addEdge(node, other, Transfer, rate)
+            g.addEdge(other, node, Transfer, rate)
+        }
+    }
+    return i
+}
+
+func (g *Graph) addEdge(from Node, to Node, kind EdgeKind, rate decimal.Decimal) {
+    floatRate, _ := rate.Float64()
+    g.edges = append(g.edges, &Edge{
+        From: from,
+        To: to,
+        Kind: kind,
+        Rate: floatRate,
+        rate: rate,
+    })
+}
+
+// links every pair of nodes holding distinct equivalent assets, charging the converter's rate
+// call after all order books are added
+func (g *Graph) AddConversions(converter *conversion.Converter) {
+    rates := make(map[[2]types.Asset]decimal.Decimal)
+    for _, from := range g.nodeList {
+        for _, to := range g.nodeList {
+            if from.Asset == to.Asset || !converter.Equivalent(from.Asset, to.Asset) {
+                continue
+            }
+            key := [2]types.Asset{from.Asset, to.Asset}
+            rate, ok := rates[key]
+            if !ok {
+                rate, _ = converter.Rate(from.Asset, to.Asset)
+                rates[key] = rate
+            }
+            if rate.IsPositive() {
+                g.addEdge(from, to, Conversion, rate)
+            }
+        }
+    }
+}
+
+func (g *Graph) Edges() []*Edge {
+    return g.edges
+}
+
+// adds a buy edge (quote -> base) walking the asks and a sell edge (base -> quote) walking the bids
+// fee is a fraction, referenceQuantity is in base units; zero uses the top of the book
+func (g *Graph) AddOrderBook(exchange string, assetPair types.AssetPair, orderBook *types.OrderBook, fee decimal.Decimal, referenceQuantity decimal.Decimal) {
+    base := Node{exchange, assetPair.Base}
+    quote := Node{exchange, assetPair.Quote}
+    g.node(base)
+    g.node(quote)
+
+    one := decimal.NewFromInt(1)
+    if len(orderBook.Asks) > 0 {
+        notional, filled := referencePrice(orderBook.Asks, referenceQuantity)
+        rate, _ := one.Sub(fee).Mul(filled).Div(notional).Float64()
+        g.edges = append(g.edges, &Edge{
+            From: quote,
+            To: base,
+            AssetPair: assetPair,
+            OrderType: types.Buy,
+            Rate: rate,
+            Capacity: totalNotional(orderBook.Asks),
+            side: orderBook.Asks,
+            fee: fee,
+        })
+    }
+    if len(orderBook.Bids) > 0 {
+        notional, filled := referencePrice(orderBook.Bids, referenceQuantity)
+        rate, _ := one.Sub(fee).Mul(notional).Div(filled).Float64()
+        g.edges = append(g.edges, &Edge{
+            From: base,
+            To: quote,
+            AssetPair: assetPair,
+            OrderType: types.Sell,
+            Rate: rate,
+            Capacity: totalQuantity(orderBook.Bids),
+            side: orderBook.Bids,
+            fee: fee,
+        })
+    }
+}
+
+func referencePrice(side []types.OrderBookEntry, referenceQuantity decimal.Decimal) (decimal.Decimal, decimal.Decimal) {
+    if referenceQuantity.IsZero() {
+        return side[0].Price, decimal.NewFromInt(1)
+    }
+    notional, filled, _ := walkBookByQuantity(side, referenceQuantity)
+    return notional, filled
+}
+
+// Bellman-Ford on -log(rate) from a virtual source connected to every node
+func (g *Graph) FindCycles() [][]*Edge {
+    n := len(g.nodeList)
+    distances := make([]float64, n)
+    predecessors := make([]*Edge, n)
+
+    for i := 0; i < n - 1; i++ {
+        relaxed := false
+        for _, edge := range g.edges {
+            from, to := g.nodes[edge.From], g.nodes[edge.To]
+            if d := distances[from] + edge.weight(); d < distances[to] - epsilon {
+                distances[to] = d
+                predecessors[to] = edge
+                relaxed = true
+            }
+        }
+        if !relaxed {
+            return [][]*Edge{}
+        }
+    }
+
+    cycles := make([][]*Edge, 0)
+    seen := make(map[string]struct{})
+    for _, edge := range g.edges {
+        from, to := g.nodes[edge.From], g.nodes[edge.To]
+        if distances[from] + edge.weight() >= distances[to] - epsilon {
+            continue
+        }
+        predecessors[to] = edge
+
+        // walk back far enough to be guaranteed to be inside the cycle
+        current := to
+        for i := 0; i < n && predecessors[current] != nil; i++ {
+            current = g.nodes[predecessors[current].From]
+        }
+        if predecessors[current] == nil {
+            continue
+        }
+
+        cycle := make([]*Edge, 0)
+        for node := current; ; {
+            predecessor := predecessors[node]
+            cycle = append(cycle, predecessor)
+            node = g.nodes[predecessor.From]
+            if node == current {
+                break
+            }
+        }
+        // reverse into execution order
+        for i, j := 0, len(cycle) - 1; i < j; i, j = i + 1, j - 1 {
+            cycle[i], cycle[j] = cycle[j], cycle[i]
+        }
+
+        key := g.cycleKey(cycle)
+        if _, ok := seen[key]; ok {
+            continue
+        }
+        seen[key] = struct{}{}
+        cycles = append(cycles, cycle)
+    }
+    return cycles
+}
+
+func (g *Graph) cycleKey(cycle []*Edge) string {
+    indices := make([]int, len(cycle))
+    for i, edge := range cycle {
+        indices[i] = g.nodes[edge.From]
+    }
+    sort.Ints(indices)
+    parts := make([]string, len(indices))
+    for i, index := range indices {
+        parts[i] = strconv.Itoa(index)
+    }
+    return strings.Join(parts, ",")
+}
+
+// rotate so the cycle starts by spending a quote asset where possible
+func rotate(cycle []*Edge) []*Edge {
+    for i, edge := range cycle {
+        if edge.Kind == Trade && edge.OrderType == types.Buy {
+            return append(append([]*Edge{}, cycle[i:]...), cycle[:i]...)
+        }
+    }
+    return cycle
+}
+
+// false if depth or, unless balances is nil, the balance an order spends from does not suffice
+func simulate(cycle []*Edge, balances map[Node]decimal.Decimal, size decimal.Decimal) (decimal.Decimal, []types.Leg, bool) {
+    amount := size
+    legs := make([]types.Leg, 0, len(cycle))
+    for _, edge := range cycle {
+        if edge.Kind == Trade && balances != nil && amount.GreaterThan(balances[edge.From]) {
+            return decimal.Zero, nil, false
+        }
+        out, quantity, price, ok := edge.execute(amount)
+        if !ok {
+            return decimal.Zero, nil, false
+        }
+        if edge.Kind == Trade {
+            legs = append(legs, types.Leg{
+                Exchange: edge.From.Exchange,
+                Order: types.Order{
+                    OrderType: edge.OrderType,
+                    AssetPair: edge.AssetPair,
+                    Price: price,
+                    Quantity: quantity,
+                },
+            })
+        }
+        amount = out
+    }
+    return amount.Sub(size), legs, true
+}
+
+// sizes the cycle against the visible depth and the balance of every node an order spends from,
+// exchange and asset, and prices it by walking every book at that size; nil balances leave the
+// size unbounded by them, nodes missing from them hold nothing
+// maxSize is in units of the starting asset; zero leaves the size bounded only by depth and balances
+func Evaluate(cycle []*Edge, balances map[Node]decimal.Decimal, maxSize decimal.Decimal) (types.Opportunity, bool) {
+    cycle = rotate(cycle)
+
+    size := decimal.Zero
+    scale := decimal.NewFromInt(1)
+    first := true
+    for _, edge := range cycle {
+        if edge.Kind == Trade {
+            if limit := edge.Capacity.Div(scale); first || limit.LessThan(size) {
+                size = limit
+                first = false
+            }
+            if limit := balances[edge.From].Div(scale); balances != nil && limit.LessThan(size) {
+                size = limit
+            }
+        }
+        scale = scale.Mul(decimal.NewFromFloat(edge.Rate))
+    }
+    if first {
+        // only transfers and conversions
+        return types.Opportunity{}, false
+    }
+    if !maxSize.IsZero() && maxSize.LessThan(size) {
+        size = maxSize
+    }
+
+    two := decimal.NewFromInt(2)
+    for i := 0; i <= sizeReductions && size.IsPositive(); i++ {
+        profit, legs, ok := simulate(cycle, balances, size)
+        if ok && profit.IsPositive() {
+            return types.Opportunity{
+                Legs: legs,
+                Asset: cycle[0].From.Asset,
+                Size: size,
+                ExpectedProfit: profit,
+                Timestamp: time.Now(),
+            }, true
+        }
+        size = size.Div(two)
+    }
+    return types.Opportunity{}, false
+}
+
+// balances bound every cycle as in Evaluate, maxSizes caps the size per starting asset; assets
+// without an entry are bounded only by depth and balances
+func (g *Graph) Opportunities(balances map[Node]decimal.Decimal, maxSizes map[types.Asset]decimal.Decimal) []types.Opportunity {
+    opportunities := make([]types.Opportunity, 0)
+    for _, cycle := range g.FindCycles() {
+        rotated := rotate(cycle)
+        if opportunity, ok := Evaluate(rotated, balances, maxSizes[rotated[0].From.Asset]); ok {
+            opportunities = append(opportunities, opportunity)
+        }
+    }
+    sort.Slice(opportunities, func(i, j int) bool {
+        return opportunities[i].ExpectedProfit.GreaterThan(opportunities[j].ExpectedProfit)
+    })
+    return opportunities
+}
+
+type Venue struct {
+    Exchange   types.Exchange
+    AssetPairs []types.AssetPair
+    // fraction, e.g. 0.001 for 0.1%
+    Fee        decimal.Decimal
+}
+
+// converter may be nil, in which case only identical assets are linked across exchanges
+func BuildGraph(venues []Venue, converter *conversion.Converter, referenceQuantities map[types.AssetPair]decimal.Decimal, transferCost decimal.Decimal) *Graph {
+    graph := NewGraph(transferCost)
+    for _, venue := range venues {
+        exchange := venue.Exchange.String()
+        for assetPair, orderBook := range venue.Exchange.GetOrderBooks(venue.AssetPairs) {
+            graph.AddOrderBook(exchange, assetPair, orderBook, venue.Fee, referenceQuantities[assetPair])
+        }
+    }
+    if converter != nil {
+        graph.AddConversions(converter)
+    }
+    return graph
+}
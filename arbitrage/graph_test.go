@@ -0,0 +1,214 @@
+package arbitrage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/conversion"
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+var BTCUSD types.AssetPair = types.NewAssetPair("BTC", "USD")
+var BTCUSDT types.AssetPair = types.NewAssetPair("BTC", "USDT")
+var USDTUSD types.AssetPair = types.NewAssetPair("USDT", "USD")
+
+func entry(price, quantity int64) types.OrderBookEntry {
+	return types.OrderBookEntry{
+		Price:    decimal.NewFromInt(price),
+		Quantity: decimal.NewFromInt(quantity),
+	}
+}
+
+func TestGraph(t *testing.T) {
+	t.Run("PairwiseCycle", testPairwiseCycle)
+	t.Run("NoCycle", testNoCycle)
+	t.Run("FeesRemoveCycle", testFeesRemoveCycle)
+	t.Run("DepthBoundsSize", testDepthBoundsSize)
+	t.Run("BalancesBoundSize", testBalancesBoundSize)
+	t.Run("StablecoinConversion", testStablecoinConversion)
+}
+
+func testPairwiseCycle(t *testing.T) {
+	graph := NewGraph(decimal.Zero)
+	graph.AddOrderBook("A", BTCUSD, &types.OrderBook{
+		Bids: []types.OrderBookEntry{entry(99, 1)},
+		Asks: []types.OrderBookEntry{entry(100, 1)},
+	}, decimal.Zero, decimal.Zero)
+	graph.AddOrderBook("B", BTCUSD, &types.OrderBook{
+		Bids: []types.OrderBookEntry{entry(110, 1)},
+		Asks: []types.OrderBookEntry{entry(111, 1)},
+	}, decimal.Zero, decimal.Zero)
+
+	opportunities := graph.Opportunities(nil, map[types.Asset]decimal.Decimal{})
+	if len(opportunities) != 1 {
+		t.Fatalf("expected 1 opportunity, got %v\n", len(opportunities))
+	}
+	opportunity := opportunities[0]
+	if opportunity.Asset != "USD" {
+		t.Fatalf("expected opportunity to start in USD, got %v\n", opportunity.Asset)
+	}
+	if !opportunity.Size.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("expected size 100, got %v\n", opportunity.Size)
+	}
+	if !opportunity.ExpectedProfit.Equal(decimal.NewFromInt(10)) {
+		t.Fatalf("expected profit 10, got %v\n", opportunity.ExpectedProfit)
+	}
+	if len(opportunity.Legs) != 2 {
+		t.Fatalf("expected 2 legs, got %v\n", len(opportunity.Legs))
+	}
+	if leg := opportunity.Legs[0]; leg.Exchange != "A" || leg.Order.OrderType != types.Buy {
+		t.Fatalf("expected to buy on A first, got %v\n", leg)
+	}
+	if leg := opportunity.Legs[1]; leg.Exchange != "B" || leg.Order.OrderType != types.Sell {
+		t.Fatalf("expected to sell on B second, got %v\n", leg)
+	}
+}
+
+func testNoCycle(t *testing.T) {
+	graph := NewGraph(decimal.Zero)
+	graph.AddOrderBook("A", BTCUSD, &types.OrderBook{
+		Bids: []types.OrderBookEntry{entry(99, 1)},
+		Asks: []types.OrderBookEntry{entry(100, 1)},
+	}, decimal.Zero, decimal.Zero)
+	graph.AddOrderBook("B", BTCUSD, &types.OrderBook{
+		Bids: []types.OrderBookEntry{entry(99, 1)},
+		Asks: []types.OrderBookEntry{entry(101, 1)},
+	}, decimal.Zero, decimal.Zero)
+
+	if cycles := graph.FindCycles(); len(cycles) != 0 {
+		t.Fatalf("expected no cycles, got %v\n", cycles)
+	}
+}
+
+func testFeesRemoveCycle(t *testing.T) {
+	graph := NewGraph(decimal.Zero)
+	fee := decimal.NewFromFloat(0.01)
+	graph.AddOrderBook("A", BTCUSD, &types.OrderBook{
+		Bids: []types.OrderBookEntry{entry(99, 1)},
+		Asks: []types.OrderBookEntry{entry(100, 1)},
+	}, fee, decimal.Zero)
+	graph.AddOrderBook("B", BTCUSD, &types.OrderBook{
+		Bids: []types.OrderBookEntry{entry(101, 1)},
+		Asks: []types.OrderBookEntry{entry(102, 1)},
+	}, fee, decimal.Zero)
+
+	if cycles := graph.FindCycles(); len(cycles) != 0 {
+		t.Fatalf("expected fees to remove the cycle, got %v\n", cycles)
+	}
+}
+
+func testDepthBoundsSize(t *testing.T) {
+	graph := NewGraph(decimal.Zero)
+	graph.AddOrderBook("A", BTCUSD, &types.OrderBook{
+		Bids: []types.OrderBookEntry{entry(99, 5)},
+		Asks: []types.OrderBookEntry{entry(100, 5)},
+	}, decimal.Zero, decimal.Zero)
+	graph.AddOrderBook("B", BTCUSD, &types.OrderBook{
+		Bids: []types.OrderBookEntry{entry(110, 1), entry(105, 1)},
+		Asks: []types.OrderBookEntry{entry(111, 5)},
+	}, decimal.Zero, decimal.Zero)
+
+	opportunities := graph.Opportunities(nil, map[types.Asset]decimal.Decimal{})
+	if len(opportunities) != 1 {
+		t.Fatalf("expected 1 opportunity, got %v\n", len(opportunities))
+	}
+	// two BTC of bids on B bound the size to 200 USD on A
+	if opportunity := opportunities[0]; !opportunity.Size.Equal(decimal.NewFromInt(200)) || !opportunity.ExpectedProfit.Equal(decimal.NewFromInt(15)) {
+		t.Fatalf("expected size 200 and profit 15, got %v and %v\n", opportunity.Size, opportunity.ExpectedProfit)
+	}
+
+	capped := graph.Opportunities(nil, map[types.Asset]decimal.Decimal{"USD": decimal.NewFromInt(100)})
+	if len(capped) != 1 || !capped[0].Size.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("expected size to be capped at 100, got %v\n", capped)
+	}
+}
+
+func testBalancesBoundSize(t *testing.T) {
+	graph := NewGraph(decimal.Zero)
+	graph.AddOrderBook("A", BTCUSD, &types.OrderBook{
+		Bids: []types.OrderBookEntry{entry(99, 5)},
+		Asks: []types.OrderBookEntry{entry(100, 5)},
+	}, decimal.Zero, decimal.Zero)
+	graph.AddOrderBook("B", BTCUSD, &types.OrderBook{
+		Bids: []types.OrderBookEntry{entry(110, 5)},
+		Asks: []types.OrderBookEntry{entry(111, 5)},
+	}, decimal.Zero, decimal.Zero)
+
+	// the sell on B spends B's BTC, a quarter BTC bounds the buy on A to 25 USD
+	opportunities := graph.Opportunities(map[Node]decimal.Decimal{
+		{Exchange: "A", Asset: "USD"}: decimal.NewFromInt(1000),
+		{Exchange: "B", Asset: "BTC"}: decimal.NewFromFloat(0.25),
+	}, map[types.Asset]decimal.Decimal{})
+	if len(opportunities) != 1 || !opportunities[0].Size.Equal(decimal.NewFromInt(25)) {
+		t.Fatalf("expected size 25, got %v\n", opportunities)
+	}
+
+	// USD held on B cannot fund the buy on A
+	unfunded := graph.Opportunities(map[Node]decimal.Decimal{
+		{Exchange: "B", Asset: "USD"}: decimal.NewFromInt(1000),
+		{Exchange: "B", Asset: "BTC"}: decimal.NewFromInt(1),
+	}, map[types.Asset]decimal.Decimal{})
+	if len(unfunded) != 0 {
+		t.Fatalf("expected no opportunity without USD on A, got %v\n", unfunded)
+	}
+}
+
+// only GetCurrentSpread is used by the converter
+type spreadExchange struct {
+	types.Exchange
+	spreads map[types.AssetPair]types.Spread
+}
+
+func (s *spreadExchange) GetCurrentSpread(assetPair types.AssetPair) types.Spread {
+	return s.spreads[assetPair]
+}
+
+func testStablecoinConversion(t *testing.T) {
+	exchange := &spreadExchange{
+		spreads: map[types.AssetPair]types.Spread{
+			USDTUSD: {
+				Bid:       decimal.NewFromFloat(0.999),
+				Ask:       decimal.NewFromFloat(1.001),
+				Timestamp: time.Now(),
+			},
+		},
+	}
+	converter := conversion.NewConverter("USD")
+	converter.AddEquivalent("USDT", exchange, USDTUSD, decimal.NewFromFloat(0.001))
+
+	books := func(graph *Graph) {
+		graph.AddOrderBook("A", BTCUSD, &types.OrderBook{
+			Bids: []types.OrderBookEntry{entry(99, 1)},
+			Asks: []types.OrderBookEntry{entry(100, 1)},
+		}, decimal.Zero, decimal.Zero)
+		graph.AddOrderBook("B", BTCUSDT, &types.OrderBook{
+			Bids: []types.OrderBookEntry{entry(110, 1)},
+			Asks: []types.OrderBookEntry{entry(111, 1)},
+		}, decimal.Zero, decimal.Zero)
+	}
+
+	unconverted := NewGraph(decimal.Zero)
+	books(unconverted)
+	if cycles := unconverted.FindCycles(); len(cycles) != 0 {
+		t.Fatalf("expected no cycles without conversions, got %v\n", cycles)
+	}
+
+	converted := NewGraph(decimal.Zero)
+	books(converted)
+	converted.AddConversions(converter)
+	opportunities := converted.Opportunities(nil, map[types.Asset]decimal.Decimal{})
+	if len(opportunities) == 0 {
+		t.Fatalf("expected BTCUSD and BTCUSDT to be compared through the converter\n")
+	}
+	// 110 USDT is worth 110 * 0.999 * 0.999 USD after the spread and conversion cost
+	expected := decimal.NewFromInt(110).Mul(decimal.NewFromFloat(0.999)).Mul(decimal.NewFromFloat(0.999)).Sub(decimal.NewFromInt(100))
+	if opportunity := opportunities[0]; opportunity.Asset != "USD" || !opportunity.ExpectedProfit.Equal(expected) {
+		t.Fatalf("expected profit of %v USD, got %v %v\n", expected, opportunity.ExpectedProfit, opportunity.Asset)
+	}
+
+	cost, ok := converter.Cost("USDT", "USD")
+	if !ok || !cost.IsPositive() {
+		t.Fatalf("expected a positive conversion cost, got %v\n", cost)
+	}
+}
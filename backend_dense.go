@@ -0,0 +1,21 @@
+//go:build !tensorflow
+
+package main
+
+import (
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/model"
+    "github.com/denali-capital/grizzly/model/dense"
+)
+
+// KillerInstinct's exported weights, evaluated in Go; build with -tags tensorflow to run the
+// SavedModel through the TensorFlow C library instead
+var loadModel model.Loader = dense.Load
+
+func newModel() model.Learner {
+    learner, err := dense.Load("KillerInstinct")
+    if err != nil {
+        logger.Fatal("loading model failed", logging.Err(err))
+    }
+    return learner
+}
@@ -0,0 +1,15 @@
+//go:build tensorflow
+
+package main
+
+import (
+    "github.com/denali-capital/grizzly/model"
+    "github.com/denali-capital/grizzly/model/nn"
+)
+
+// the KillerInstinct SavedModel, run through the TensorFlow C library
+var loadModel model.Loader = nn.Load
+
+func newModel() model.Learner {
+    return nn.NewKillerInstinct()
+}
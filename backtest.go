@@ -0,0 +1,116 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "time"
+
+    "github.com/denali-capital/grizzly/arbitrage"
+    "github.com/denali-capital/grizzly/config"
+    "github.com/denali-capital/grizzly/control"
+    "github.com/denali-capital/grizzly/execution"
+    "github.com/denali-capital/grizzly/paper"
+    "github.com/denali-capital/grizzly/recording"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+// replays recorded market data through the routing strategy and paper execution
+func backtestCommand(ctx context.Context, cfg *config.Config, args []string) error {
+    flags := flag.NewFlagSet("backtest", flag.ExitOnError)
+    in := flags.String("in", "", "recording written by grizzly record")
+    step := flags.Duration("step", cfg.Trading.SleepDuration.Duration, "recorded time between strategy evaluations")
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: grizzly backtest -in recording [flags]\n")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+    if *in == "" {
+        flags.Usage()
+        return errors.New("-in is required")
+    }
+
+    reader, err := recording.NewReader(*in)
+    if err != nil {
+        return err
+    }
+    defer reader.Close()
+
+    replays := make(map[string]*recording.ReplayExchange)
+    exchanges := make([]types.Exchange, 0)
+    for _, name := range cfg.EnabledExchanges() {
+        replays[name] = recording.NewReplayExchange(name, cfg.Exchanges[name].SpreadCapacity)
+        exchanges = append(exchanges, paper.NewExchange(replays[name], cfg.Fee(name), cfg.PaperBalances(name)))
+    }
+    venues := newVenues(cfg, exchanges)
+    converter := newConverter(cfg, exchanges)
+    limits := riskLimits(cfg)
+    coordinator := execution.NewCoordinator(exchanges, control.NewState(cfg.Trading.Threshold), converter, limits, cfg.Trading.OpportunityQueueCapacity, cfg.Trading.OpportunityMaxAge.Duration)
+
+    initialBalances := make(map[string]map[types.Asset]decimal.Decimal)
+    for _, exchange := range exchanges {
+        initialBalances[exchange.String()] = exchange.GetBalances()
+    }
+
+    var start, end, next time.Time
+    snapshots, evaluations, executed := 0, 0, 0
+    // an interrupt ends the replay early, results so far are still reported
+    for ctx.Err() == nil {
+        snapshot, err := reader.Next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return err
+        }
+        replay, ok := replays[snapshot.Exchange]
+        if !ok {
+            continue
+        }
+        replay.Apply(snapshot)
+        snapshots++
+        if start.IsZero() {
+            start = snapshot.Timestamp
+            next = start.Add(*step)
+        }
+        end = snapshot.Timestamp
+
+        if snapshot.Timestamp.Before(next) {
+            continue
+        }
+        next = snapshot.Timestamp.Add(*step)
+        evaluations++
+        // days are replayed, so the loss limit trips as it would have live
+        coordinator.CheckLoss(snapshot.Timestamp)
+        graph := arbitrage.BuildGraph(venues, converter, map[types.AssetPair]decimal.Decimal{}, cfg.TransferCost())
+        balances := make(map[string]map[types.Asset]decimal.Decimal, len(exchanges))
+        for _, exchange := range exchanges {
+            balances[exchange.String()] = exchange.GetBalances()
+        }
+        for _, opportunity := range fundedOpportunities(graph, balances, converter, limits.MaxOrderNotional) {
+            if coordinator.Place(opportunity) {
+                executed++
+            }
+        }
+    }
+
+    fmt.Printf("replayed %v snapshots from %v to %v\n", snapshots, start.Format(time.RFC3339), end.Format(time.RFC3339))
+    fmt.Printf("%v evaluations, %v opportunities executed\n\n", evaluations, executed)
+    fmt.Printf("%-10v %-6v %20v %20v %20v\n", "exchange", "asset", "start", "end", "change")
+    for _, exchange := range exchanges {
+        balances := exchange.GetBalances()
+        for asset := range initialBalances[exchange.String()] {
+            if _, ok := balances[asset]; !ok {
+                balances[asset] = decimal.Zero
+            }
+        }
+        for _, asset := range sortedAssets(balances) {
+            initial := initialBalances[exchange.String()][asset]
+            fmt.Printf("%-10v %-6v %20v %20v %20v\n", exchange, asset, initial, balances[asset], balances[asset].Sub(initial))
+        }
+    }
+    return nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+
+    "github.com/denali-capital/grizzly/config"
+)
+
+func balancesCommand(ctx context.Context, cfg *config.Config, args []string) error {
+    flags := flag.NewFlagSet("balances", flag.ExitOnError)
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: grizzly balances [exchange...]\n")
+    }
+    flags.Parse(args)
+
+    exchanges, err := newExchanges(cfg, flags.Args())
+    if err != nil {
+        return err
+    }
+    for _, exchange := range exchanges {
+        printBalances(exchange.String(), exchange.GetBalances())
+    }
+    return nil
+}
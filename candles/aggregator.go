@@ -0,0 +1,237 @@
+package candles
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/denali-capital/grizzly/util"
+    "github.com/shopspring/decimal"
+)
+
+var logger *logging.Logger = logging.New("candles")
+
+// resolutions every asset pair is aggregated at
+var Resolutions []time.Duration = []time.Duration{time.Second, time.Minute, 5 * time.Minute, time.Hour}
+
+type key struct {
+    exchange  string
+    assetPair types.AssetPair
+}
+
+// the last trade folded in, and how many trades before it share its timestamp, so that
+// trades returned again by GetTradesSince are not counted twice
+type cursor struct {
+    timestamp time.Time
+    count     int
+}
+
+// builds candles at every resolution per exchange and asset pair from the recorded
+// spreads and trades of exchanges
+type Aggregator struct {
+    mutex      sync.RWMutex
+    capacity   uint
+    // quotes are folded in at the order book microprice instead of the spread midpoint
+    microprice bool
+    exchanges  []types.Exchange
+    // exchange -> asset pairs aggregated
+    assetPairs map[string][]types.AssetPair
+    // the asset pairs of an exchange still polled, nil polls them all
+    registered func(exchange string, assetPairs []types.AssetPair) []types.AssetPair
+    series     map[key][]*Series
+    cursors    map[key]cursor
+    // timestamp of the last spread folded in
+    quotes     map[key]time.Time
+}
+
+// capacity is the number of candles kept per exchange, asset pair and resolution
+func NewAggregator(exchanges []types.Exchange, assetPairs map[string][]types.AssetPair, capacity uint, microprice bool) *Aggregator {
+    return &Aggregator{
+        capacity: capacity,
+        microprice: microprice,
+        exchanges: exchanges,
+        assetPairs: assetPairs,
+        series: make(map[key][]*Series),
+        cursors: make(map[key]cursor),
+        quotes: make(map[key]time.Time),
+    }
+}
+
+// callers hold the write lock
+func (a *Aggregator) get(k key) []*Series {
+    series, ok := a.series[k]
+    if !ok {
+        series = make([]*Series, len(Resolutions))
+        for i, resolution := range Resolutions {
+            series[i] = NewSeries(resolution, a.capacity)
+        }
+        a.series[k] = series
+    }
+    return series
+}
+
+func (a *Aggregator) AddTrade(exchange string, assetPair types.AssetPair, trade types.Trade) {
+    a.mutex.Lock()
+    defer a.mutex.Unlock()
+    for _, series := range a.get(key{exchange, assetPair}) {
+        series.AddTrade(trade)
+    }
+}
+
+func (a *Aggregator) AddMid(exchange string, assetPair types.AssetPair, mid decimal.Decimal, timestamp time.Time) {
+    a.mutex.Lock()
+    defer a.mutex.Unlock()
+    for _, series := range a.get(key{exchange, assetPair}) {
+        series.AddMid(mid, timestamp)
+    }
+}
+
+// oldest first, the last candle may still be forming; false when nothing was aggregated
+// for the asset pair or resolution is not one of Resolutions
+func (a *Aggregator) GetCandles(exchange string, assetPair types.AssetPair, resolution time.Duration) ([]types.Candle, bool) {
+    a.mutex.RLock()
+    defer a.mutex.RUnlock()
+    for _, series := range a.series[key{exchange, assetPair}] {
+        if series.Resolution() == resolution {
+            return series.Candles(), true
+        }
+    }
+    return make([]types.Candle, 0), false
+}
+
+// estimate over the closed candles at options.Interval, zero without enough of them
+func (a *Aggregator) Volatility(exchange string, assetPair types.AssetPair, options util.VolatilityOptions) float64 {
+    history, _ := a.GetCandles(exchange, assetPair, options.Interval)
+    return util.ComputeVolatility(Closed(history, options.Interval, time.Now()), options)
+}
+
+// loads the history of every resolution the exchanges offer over REST, concurrently
+// across exchanges; run it before Run so that live candles are not overwritten
+func (a *Aggregator) Backfill() {
+    var wg sync.WaitGroup
+    for _, exchange := range a.exchanges {
+        provider, ok := exchange.(types.CandleProvider)
+        if !ok {
+            continue
+        }
+        wg.Add(1)
+        go func(name string, provider types.CandleProvider) {
+            defer wg.Done()
+            for _, assetPair := range a.assetPairs[name] {
+                for _, resolution := range Resolutions {
+                    since := time.Now().Add(-time.Duration(a.capacity) * resolution)
+                    candles, ok := provider.GetHistoricalCandles(assetPair, resolution, since)
+                    if !ok {
+                        continue
+                    }
+                    a.backfill(key{name, assetPair}, resolution, candles)
+                    logger.Debug("backfilled candles", logging.Exchange(name), logging.AssetPair(assetPair), "resolution", resolution, "candles", len(candles))
+                }
+            }
+        }(exchange.String(), provider)
+    }
+    wg.Wait()
+}
+
+func (a *Aggregator) backfill(k key, resolution time.Duration, candles []types.Candle) {
+    a.mutex.Lock()
+    defer a.mutex.Unlock()
+    for _, series := range a.get(k) {
+        if series.Resolution() == resolution {
+            series.Backfill(candles)
+        }
+    }
+}
+
+func (a *Aggregator) pollTrades(name string, recorder types.TradeRecorder, assetPair types.AssetPair) {
+    k := key{name, assetPair}
+    a.mutex.RLock()
+    c := a.cursors[k]
+    a.mutex.RUnlock()
+
+    trades, _ := recorder.GetTradesSince(assetPair, c.timestamp)
+    if len(trades) == 0 {
+        return
+    }
+    skipped := 0
+    for _, trade := range trades {
+        if trade.Timestamp.Equal(c.timestamp) && skipped < c.count {
+            skipped++
+            continue
+        }
+        a.AddTrade(name, assetPair, trade)
+    }
+
+    last := trades[len(trades) - 1].Timestamp
+    count := 0
+    for i := len(trades) - 1; i >= 0 && trades[i].Timestamp.Equal(last); i-- {
+        count++
+    }
+    a.mutex.Lock()
+    a.cursors[k] = cursor{last, count}
+    a.mutex.Unlock()
+}
+
+func (a *Aggregator) pollQuote(exchange types.Exchange, assetPair types.AssetPair) {
+    k := key{exchange.String(), assetPair}
+    spread := exchange.GetCurrentSpread(assetPair)
+    if !spread.Bid.IsPositive() || !spread.Ask.IsPositive() {
+        return
+    }
+    a.mutex.RLock()
+    seen := a.quotes[k].Equal(spread.Timestamp)
+    a.mutex.RUnlock()
+    if seen {
+        return
+    }
+    mid := util.Midpoint(spread)
+    if a.microprice {
+        // falls back to the midpoint until the book is recorded
+        if microprice, ok := util.ComputeMicroprice(exchange.GetOrderBooks([]types.AssetPair{assetPair})[assetPair]); ok {
+            mid = microprice
+        }
+    }
+    a.AddMid(k.exchange, assetPair, mid, spread.Timestamp)
+    a.mutex.Lock()
+    a.quotes[k] = spread.Timestamp
+    a.mutex.Unlock()
+}
+
+// asset pairs registered leaves out are no longer polled, so that polling does not register
+// them with the exchange again; call before Run
+func (a *Aggregator) Filter(registered func(exchange string, assetPairs []types.AssetPair) []types.AssetPair) {
+    a.registered = registered
+}
+
+// folds in new trades and the current spread of every asset pair once
+func (a *Aggregator) Poll() {
+    for _, exchange := range a.exchanges {
+        name := exchange.String()
+        recorder, records := exchange.(types.TradeRecorder)
+        assetPairs := a.assetPairs[name]
+        if a.registered != nil {
+            assetPairs = a.registered(name, assetPairs)
+        }
+        for _, assetPair := range assetPairs {
+            if records {
+                a.pollTrades(name, recorder, assetPair)
+            }
+            a.pollQuote(exchange, assetPair)
+        }
+    }
+}
+
+// polls every interval until ctx is canceled
+func (a *Aggregator) Run(ctx context.Context, interval time.Duration) {
+    for {
+        a.Poll()
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-time.After(interval):
+        }
+    }
+}
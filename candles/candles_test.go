@@ -0,0 +1,137 @@
+package candles
+
+import (
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+var BTCUSD types.AssetPair = types.NewAssetPair("BTC", "USD")
+
+var start time.Time = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func trade(price, quantity int64, offset time.Duration) types.Trade {
+	return types.Trade{Price: decimal.NewFromInt(price), Quantity: decimal.NewFromInt(quantity), Timestamp: start.Add(offset)}
+}
+
+func quote(bid, ask int64, offset time.Duration) types.Spread {
+	return types.Spread{Bid: decimal.NewFromInt(bid), Ask: decimal.NewFromInt(ask), Timestamp: start.Add(offset)}
+}
+
+func expectCandle(t *testing.T, candle types.Candle, open, high, low, close, volume int64) {
+	for _, pair := range [][2]decimal.Decimal{
+		{candle.Open, decimal.NewFromInt(open)},
+		{candle.High, decimal.NewFromInt(high)},
+		{candle.Low, decimal.NewFromInt(low)},
+		{candle.Close, decimal.NewFromInt(close)},
+		{candle.Volume, decimal.NewFromInt(volume)},
+	} {
+		if !pair[0].Equal(pair[1]) {
+			t.Fatalf("expected %v %v %v %v %v, got %+v\n", open, high, low, close, volume, candle)
+		}
+	}
+}
+
+func TestSeries(t *testing.T) {
+	series := NewSeries(time.Minute, 3)
+	series.AddQuote(quote(99, 101, 0))
+	series.AddQuote(quote(103, 105, 10 * time.Second))
+	// quotes alone build the first candle
+	expectCandle(t, series.Candles()[0], 100, 104, 100, 104, 0)
+
+	series.AddTrade(trade(102, 1, 20 * time.Second))
+	series.AddTrade(trade(98, 2, 30 * time.Second))
+	// quotes are ignored once the candle has traded
+	series.AddQuote(quote(120, 122, 40 * time.Second))
+	expectCandle(t, series.Candles()[0], 102, 102, 98, 98, 3)
+
+	// the two minutes without updates are filled flat, pushing the first candle out
+	series.AddTrade(trade(97, 1, 3 * time.Minute))
+	candles := series.Candles()
+	if len(candles) != 3 || !candles[0].Start.Equal(start.Add(time.Minute)) {
+		t.Fatalf("expected 3 candles from 12:01, got %+v\n", candles)
+	}
+	expectCandle(t, candles[1], 98, 98, 98, 98, 0)
+	expectCandle(t, candles[2], 97, 97, 97, 97, 1)
+
+	// too late for the forming candle
+	series.AddTrade(trade(50, 1, 90 * time.Second))
+	if candles := series.Candles(); len(candles) != 3 || !candles[2].Low.Equal(decimal.NewFromInt(97)) {
+		t.Fatalf("expected the late trade to be ignored, got %+v\n", candles)
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	series := NewSeries(time.Minute, 3)
+	series.AddTrade(trade(100, 1, 2 * time.Minute))
+	series.Backfill([]types.Candle{
+		{Start: start, Close: decimal.NewFromInt(90)},
+		{Start: start.Add(time.Minute), Close: decimal.NewFromInt(95)},
+		// overlaps the recorded candle, which wins
+		{Start: start.Add(2 * time.Minute), Close: decimal.NewFromInt(80)},
+	})
+	candles := series.Candles()
+	if len(candles) != 3 || !candles[0].Close.Equal(decimal.NewFromInt(90)) || !candles[2].Close.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("unexpected candles %+v\n", candles)
+	}
+}
+
+type fakeExchange struct {
+	types.Exchange
+	types.TradeRecorder
+	trades []types.Trade
+	spread types.Spread
+}
+
+func (f *fakeExchange) String() string {
+	return "Fake"
+}
+
+func (f *fakeExchange) GetTradesSince(assetPair types.AssetPair, since time.Time) ([]types.Trade, bool) {
+	trades := make([]types.Trade, 0)
+	for _, trade := range f.trades {
+		if !trade.Timestamp.Before(since) {
+			trades = append(trades, trade)
+		}
+	}
+	return trades, true
+}
+
+func (f *fakeExchange) GetCurrentSpread(assetPair types.AssetPair) types.Spread {
+	return f.spread
+}
+
+func TestAggregator(t *testing.T) {
+	fake := &fakeExchange{
+		trades: []types.Trade{trade(100, 1, 0), trade(101, 1, time.Second)},
+		spread: quote(99, 101, 0),
+	}
+	aggregator := NewAggregator([]types.Exchange{fake}, map[string][]types.AssetPair{"Fake": {BTCUSD}}, 10, false)
+	aggregator.Poll()
+	// a second trade at the same timestamp arrives after the first poll
+	fake.trades = append(fake.trades, trade(102, 1, time.Second))
+	aggregator.Poll()
+
+	candles, ok := aggregator.GetCandles("Fake", BTCUSD, time.Minute)
+	if !ok || len(candles) != 1 {
+		t.Fatalf("expected one minute candle, got %+v\n", candles)
+	}
+	// each trade is counted once and the quote is ignored
+	expectCandle(t, candles[0], 100, 102, 100, 102, 3)
+
+	if _, ok := aggregator.GetCandles("Fake", BTCUSD, 2 * time.Minute); ok {
+		t.Fatalf("expected an unknown resolution to be missing\n")
+	}
+
+	// asset pairs filtered out are no longer polled
+	aggregator.Filter(func(exchange string, assetPairs []types.AssetPair) []types.AssetPair {
+		return nil
+	})
+	fake.trades = append(fake.trades, trade(103, 1, 2 * time.Second))
+	aggregator.Poll()
+	if candles, _ := aggregator.GetCandles("Fake", BTCUSD, time.Minute); candles[0].Trades != 3 {
+		t.Fatalf("expected the filtered out asset pair not to be polled, got %+v\n", candles)
+	}
+}
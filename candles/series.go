@@ -0,0 +1,131 @@
+package candles
+
+import (
+    "time"
+
+    "github.com/denali-capital/grizzly/types"
+    "github.com/denali-capital/grizzly/util"
+    "github.com/shopspring/decimal"
+)
+
+// rolling candles of one exchange, asset pair and resolution, oldest first;
+// the last candle is still forming until its resolution has passed
+//
+// a candle's prices come from trades once it has volume and from mids until then,
+// so quiet markets still produce candles without quotes distorting traded ones;
+// periods without any update are filled with flat candles at the previous close
+type Series struct {
+    resolution time.Duration
+    capacity   int
+    candles    []types.Candle
+}
+
+func NewSeries(resolution time.Duration, capacity uint) *Series {
+    return &Series{
+        resolution: resolution,
+        capacity: int(capacity),
+        candles: make([]types.Candle, 0, capacity),
+    }
+}
+
+func (s *Series) Resolution() time.Duration {
+    return s.resolution
+}
+
+// the candle covering timestamp, nil for timestamps before the forming candle
+func (s *Series) candle(timestamp time.Time) *types.Candle {
+    start := timestamp.Truncate(s.resolution)
+    if len(s.candles) > 0 {
+        last := &s.candles[len(s.candles) - 1]
+        if start.Before(last.Start) {
+            return nil
+        }
+        if start.Equal(last.Start) {
+            return last
+        }
+        // gaps longer than the history are not worth filling
+        fill := last.Start.Add(s.resolution)
+        if earliest := start.Add(-time.Duration(s.capacity) * s.resolution); fill.Before(earliest) {
+            fill = earliest
+        }
+        for ; fill.Before(start); fill = fill.Add(s.resolution) {
+            s.push(types.Candle{Start: fill, Open: last.Close, High: last.Close, Low: last.Close, Close: last.Close})
+            last = &s.candles[len(s.candles) - 1]
+        }
+    }
+    s.push(types.Candle{Start: start})
+    return &s.candles[len(s.candles) - 1]
+}
+
+func (s *Series) push(candle types.Candle) {
+    if len(s.candles) == s.capacity {
+        copy(s.candles, s.candles[1:])
+        s.candles = s.candles[:len(s.candles) - 1]
+    }
+    s.candles = append(s.candles, candle)
+}
+
+func (s *Series) AddTrade(trade types.Trade) {
+    candle := s.candle(trade.Timestamp)
+    if candle == nil {
+        return
+    }
+    if candle.Volume.IsZero() {
+        // the first trade replaces whatever quotes built the candle so far
+        candle.Open, candle.High, candle.Low = trade.Price, trade.Price, trade.Price
+    }
+    candle.High = decimal.Max(candle.High, trade.Price)
+    candle.Low = decimal.Min(candle.Low, trade.Price)
+    candle.Close = trade.Price
+    candle.Volume = candle.Volume.Add(trade.Quantity)
+    candle.Trades++
+}
+
+func (s *Series) AddQuote(spread types.Spread) {
+    s.AddMid(util.Midpoint(spread), spread.Timestamp)
+}
+
+// folds in a mid price such as a midpoint or microprice, ignored once the candle has traded
+func (s *Series) AddMid(mid decimal.Decimal, timestamp time.Time) {
+    candle := s.candle(timestamp)
+    if candle == nil || !candle.Volume.IsZero() {
+        return
+    }
+    if candle.Open.IsZero() {
+        candle.Open, candle.High, candle.Low = mid, mid, mid
+    }
+    candle.High = decimal.Max(candle.High, mid)
+    candle.Low = decimal.Min(candle.Low, mid)
+    candle.Close = mid
+}
+
+// prepends candles (oldest first) that end before the recorded history starts
+func (s *Series) Backfill(candles []types.Candle) {
+    older := candles
+    if len(s.candles) > 0 {
+        first := s.candles[0].Start
+        older = make([]types.Candle, 0, len(candles))
+        for _, candle := range candles {
+            if candle.Start.Before(first) {
+                older = append(older, candle)
+            }
+        }
+    }
+    merged := append(older, s.candles...)
+    if len(merged) > s.capacity {
+        merged = merged[len(merged) - s.capacity:]
+    }
+    s.candles = append(make([]types.Candle, 0, s.capacity), merged...)
+}
+
+func (s *Series) Candles() []types.Candle {
+    return append([]types.Candle{}, s.candles...)
+}
+
+// candles without the last one if it is still forming at now
+func Closed(candles []types.Candle, resolution time.Duration, now time.Time) []types.Candle {
+    if len(candles) > 0 && now.Before(candles[len(candles) - 1].Start.Add(resolution)) {
+        return candles[:len(candles) - 1]
+    }
+    return candles
+}
@@ -0,0 +1,838 @@
+package config
+
+import (
+    "sort"
+    "time"
+
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+const DefaultPath string = "config/grizzly.toml"
+const DefaultFiltersPath string = "config/filters.toml"
+
+type Config struct {
+    Exchanges  map[string]ExchangeConfig  `toml:"exchanges"`
+    AssetPairs map[string]AssetPairConfig `toml:"asset_pairs"`
+    Conversion ConversionConfig           `toml:"conversion"`
+    Trading    TradingConfig              `toml:"trading"`
+    Risk       RiskConfig                 `toml:"risk"`
+    Secrets    SecretsConfig              `toml:"secrets"`
+    Paper      PaperConfig                `toml:"paper"`
+    Metrics    MetricsConfig              `toml:"metrics"`
+    Control    ControlConfig              `toml:"control"`
+    Candles    CandlesConfig              `toml:"candles"`
+    Volatility VolatilityConfig           `toml:"volatility"`
+    Features   FeaturesConfig             `toml:"features"`
+    Labeling   LabelingConfig             `toml:"labeling"`
+    Training   TrainingConfig             `toml:"training"`
+    Models     ModelsConfig               `toml:"models"`
+    Decision   DecisionConfig             `toml:"decision"`
+    Inference  InferenceConfig            `toml:"inference"`
+    Drift      DriftConfig                `toml:"drift"`
+    Shadow     ShadowConfig               `toml:"shadow"`
+    Shutdown   ShutdownConfig             `toml:"shutdown"`
+    Logging    LoggingConfig              `toml:"logging"`
+
+    Filters    Filters                    `toml:"-"`
+}
+
+type ExchangeConfig struct {
+    // percent, e.g. 0.1 for 0.1%
+    Fee              float64           `toml:"fee"`
+    SpreadCapacity   uint              `toml:"spread_capacity"`
+    // trades kept per asset pair, 0 keeps the default of 1000
+    TradeCapacity    uint              `toml:"trade_capacity"`
+    OrderBookDepth   uint              `toml:"order_book_depth"`
+    // canonical asset pair -> exchange symbol
+    Symbols          map[string]string `toml:"symbols"`
+    // canonical asset pair -> WebSocket symbol, for exchanges where they differ from REST (Kraken)
+    WebSocketSymbols map[string]string `toml:"websocket_symbols"`
+}
+
+type AssetPairConfig struct {
+    Base  string `toml:"base"`
+    Quote string `toml:"quote"`
+}
+
+// stablecoins and other equivalents of the numeraire, see conversion.Converter
+type ConversionConfig struct {
+    Numeraire   string             `toml:"numeraire"`
+    Equivalents []EquivalentConfig `toml:"equivalents"`
+}
+
+type EquivalentConfig struct {
+    Asset     string  `toml:"asset"`
+    Exchange  string  `toml:"exchange"`
+    AssetPair string  `toml:"asset_pair"`
+    // percent
+    Cost      float64 `toml:"cost"`
+}
+
+type TradingConfig struct {
+    // minimum model probability to act on a pairwise opportunity
+    Threshold                float64  `toml:"threshold"`
+    SleepDuration            Duration `toml:"sleep_duration"`
+    OpportunityQueueCapacity uint     `toml:"opportunity_queue_capacity"`
+    OpportunityMaxAge        Duration `toml:"opportunity_max_age"`
+    // percent lost moving inventory between exchanges
+    TransferCost             float64  `toml:"transfer_cost"`
+}
+
+// notional limits are in the conversion numeraire
+type RiskConfig struct {
+    MaxOrderNotional float64 `toml:"max_order_notional"`
+    MaxOpenOrders    uint    `toml:"max_open_orders"`
+    MaxDailyLoss     float64 `toml:"max_daily_loss"`
+}
+
+// key material never lives here, only where to find it
+type SecretsConfig struct {
+    // env, file or vault
+    Provider     string `toml:"provider"`
+    // encrypted secrets file for the file provider
+    Path         string `toml:"path"`
+    VaultAddress string `toml:"vault_address"`
+}
+
+// simulated starting inventory for paper trading and backtests
+type PaperConfig struct {
+    // exchange -> asset -> balance
+    Balances map[string]map[string]float64 `toml:"balances"`
+}
+
+// /metrics is only served when Address is set
+type MetricsConfig struct {
+    Address         string   `toml:"address"`
+    // how often balances are polled for routing and the balance and PnL gauges, defaults to 1m
+    BalanceInterval Duration `toml:"balance_interval"`
+}
+
+// the status and control API is only served when Address is set
+type ControlConfig struct {
+    Address string `toml:"address"`
+}
+
+// empty values keep the defaults, 1000 candles per resolution polled every 250ms
+type CandlesConfig struct {
+    // candles kept per exchange, asset pair and resolution
+    Capacity     uint     `toml:"capacity"`
+    // how often recorded spreads and trades are folded into candles
+    PollInterval Duration `toml:"poll_interval"`
+    // load history from the exchanges' REST APIs at startup
+    Backfill     bool     `toml:"backfill"`
+    // price quotes are folded in at, midpoint or microprice
+    Mid          string   `toml:"mid"`
+}
+
+// how the volatility_1 and volatility_2 features are estimated from candles,
+// empty values keep the defaults, realized volatility over the last 60 one second candles
+type VolatilityConfig struct {
+    // realized, ewma, parkinson or garman_klass
+    Estimator string   `toml:"estimator"`
+    // candle resolution, one of 1s, 1m, 5m or 1h
+    Interval  Duration `toml:"interval"`
+    // candles per estimate
+    Window    uint     `toml:"window"`
+    // estimates are scaled to this horizon, defaults to interval
+    Horizon   Duration `toml:"horizon"`
+    // decay for ewma, defaults to 0.94
+    Lambda    float64  `toml:"lambda"`
+}
+
+// what Observations are made of, empty values keep the defaults, grizzly/1 measured at 100 of the quote asset
+type FeaturesConfig struct {
+    // registered feature schema as name/version
+    Schema   string  `toml:"schema"`
+    // quote amount liquidity and edge features are measured at
+    Notional float64 `toml:"notional"`
+}
+
+// how opportunities replayed from recordings are labeled, empty values keep the defaults,
+// a 5 second horizon and any positive return
+type LabelingConfig struct {
+    // how long after a leg reaches its exchange a book to execute it against is waited for
+    Horizon   Duration `toml:"horizon"`
+    // minimum return after fees and slippage, as a fraction, for an opportunity to be labeled profitable
+    Threshold float64  `toml:"threshold"`
+}
+
+// online retraining of the model on opportunities labeled while trading, off unless enabled;
+// empty values keep the defaults, hourly rounds of one epoch over batches of 32, validated on
+// the last hour
+type TrainingConfig struct {
+    Enabled         bool     `toml:"enabled"`
+    // time between training rounds
+    Interval        Duration `toml:"interval"`
+    // the newest labeled opportunities within window are held out to validate on
+    Window          Duration `toml:"window"`
+    // new labeled opportunities needed for a round, defaults to 1000
+    MinObservations uint     `toml:"min_observations"`
+    // labeled opportunities kept in memory, defaults to 100000
+    Capacity        uint     `toml:"capacity"`
+    BatchSize       uint     `toml:"batch_size"`
+    Epochs          uint     `toml:"epochs"`
+}
+
+// where model versions are stored, empty values keep the defaults, models checked every 10 seconds
+type ModelsConfig struct {
+    // one directory per version, promoted models are saved here too
+    Directory     string   `toml:"directory"`
+    // how often the directory is checked for new versions
+    WatchInterval Duration `toml:"watch_interval"`
+}
+
+// how model probabilities become trades, empty values keep the defaults, isotonic calibration
+// and any positive expected value
+type DecisionConfig struct {
+    // none, platt or isotonic, fitted to each promoted model on its validation observations
+    Calibration string  `toml:"calibration"`
+    // expected value required per unit of quote spent, as a fraction
+    Margin      float64 `toml:"margin"`
+}
+
+// how exchange pairs' predictions are batched, empty values keep the defaults, 2ms windows of up
+// to 256 observations
+type InferenceConfig struct {
+    // how long a prediction waits for other exchange pairs' to join its batch
+    BatchWindow Duration `toml:"batch_window"`
+    // observations at which a batch is predicted without waiting out the window
+    MaxBatch    uint     `toml:"max_batch"`
+}
+
+// how live observations are checked for drift from the model version's training window, empty
+// values keep the defaults, the last 1000 observations checked every minute
+type DriftConfig struct {
+    Interval           Duration `toml:"interval"`
+    // live observations compared, newest first
+    Window             uint     `toml:"window"`
+    // live observations needed before they are compared, defaults to 200
+    MinObservations    uint     `toml:"min_observations"`
+    // population stability index at which a feature or the model's output drifts, defaults to 0.25
+    PSIThreshold       float64  `toml:"psi_threshold"`
+    // Kolmogorov-Smirnov statistic at which a feature or the model's output drifts, defaults to 0.2
+    KSThreshold        float64  `toml:"ks_threshold"`
+    // whether to require conservative_margin rather than decision.margin while drifting
+    Conservative       bool     `toml:"conservative"`
+    ConservativeMargin float64  `toml:"conservative_margin"`
+}
+
+// challenger models evaluated alongside the live one, empty values keep the defaults, no
+// challengers and records appended to shadow.jsonl
+type ShadowConfig struct {
+    // model version directories, e.g. models/20240101T000000Z
+    Challengers []string `toml:"challengers"`
+    // JSON lines of every model's predictions and decisions next to the labeled outcome
+    Log         string   `toml:"log"`
+}
+
+// empty values keep the defaults, canceling open orders within 30 seconds
+type ShutdownConfig struct {
+    // keep, cancel or flatten orders placed by this process
+    OpenOrders string   `toml:"open_orders"`
+    // how long closing out orders and stopping servers may take
+    Timeout    Duration `toml:"timeout"`
+}
+
+// empty values keep the defaults, text output at info
+type LoggingConfig struct {
+    // text or json
+    Format string            `toml:"format"`
+    Level  string            `toml:"level"`
+    // component (e.g. exchanges/kraken) -> level
+    Levels map[string]string `toml:"levels"`
+}
+
+// hard filters on what is used out of everything configured
+type Filters struct {
+    Exchanges  []string `toml:"exchanges"`
+    // empty enables every configured asset pair
+    AssetPairs []string `toml:"asset_pairs"`
+}
+
+func Load(path, filtersPath string) (*Config, error) {
+    config := &Config{}
+    s, err := decodeFile(path, config)
+    if err != nil {
+        return nil, err
+    }
+    fs, err := decodeFile(filtersPath, &config.Filters)
+    if err != nil {
+        return nil, err
+    }
+    if errors := config.validate(s, fs); len(errors) > 0 {
+        return nil, errors
+    }
+    return config, nil
+}
+
+func sortedKeys(m interface{}) []string {
+    keys := make([]string, 0)
+    switch typed := m.(type) {
+    case map[string]ExchangeConfig:
+        for k := range typed {
+            keys = append(keys, k)
+        }
+    case map[string]AssetPairConfig:
+        for k := range typed {
+            keys = append(keys, k)
+        }
+    case map[string]map[string]float64:
+        for k := range typed {
+            keys = append(keys, k)
+        }
+    case map[string]string:
+        for k := range typed {
+            keys = append(keys, k)
+        }
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func validPercent(percent float64) bool {
+    return percent >= 0 && percent < 100
+}
+
+func (c *Config) validate(s *source, fs *source) Errors {
+    errors := make(Errors, 0)
+
+    if len(c.AssetPairs) == 0 {
+        errors = append(errors, s.errorf("asset_pairs", "at least one asset pair is required"))
+    }
+    for _, canonical := range sortedKeys(c.AssetPairs) {
+        assetPair := c.AssetPairs[canonical]
+        key := "asset_pairs." + canonical
+        if assetPair.Base == "" || assetPair.Quote == "" {
+            errors = append(errors, s.errorf(key, "base and quote are required"))
+        } else if assetPair.Base + assetPair.Quote != canonical {
+            errors = append(errors, s.errorf(key, "canonical name must be base %v followed by quote %v", assetPair.Base, assetPair.Quote))
+        }
+    }
+
+    if len(c.Exchanges) == 0 {
+        errors = append(errors, s.errorf("exchanges", "at least one exchange is required"))
+    }
+    for _, name := range sortedKeys(c.Exchanges) {
+        exchange := c.Exchanges[name]
+        key := "exchanges." + name
+        if !validPercent(exchange.Fee) {
+            errors = append(errors, s.errorf(key + ".fee", "must be a percent in [0, 100), got %v", exchange.Fee))
+        }
+        if exchange.SpreadCapacity == 0 {
+            errors = append(errors, s.errorf(key + ".spread_capacity", "must be positive"))
+        }
+        if exchange.OrderBookDepth == 0 {
+            errors = append(errors, s.errorf(key + ".order_book_depth", "must be positive"))
+        }
+        for _, canonical := range sortedKeys(exchange.Symbols) {
+            if _, ok := c.AssetPairs[canonical]; !ok {
+                errors = append(errors, s.errorf(key + ".symbols." + canonical, "unknown asset pair"))
+            } else if exchange.Symbols[canonical] == "" {
+                errors = append(errors, s.errorf(key + ".symbols." + canonical, "symbol must not be empty"))
+            }
+        }
+        for _, canonical := range sortedKeys(exchange.WebSocketSymbols) {
+            if _, ok := exchange.Symbols[canonical]; !ok {
+                errors = append(errors, s.errorf(key + ".websocket_symbols." + canonical, "no REST symbol configured for this asset pair"))
+            }
+        }
+    }
+
+    if c.Conversion.Numeraire == "" {
+        errors = append(errors, s.errorf("conversion.numeraire", "is required"))
+    }
+    for i, equivalent := range c.Conversion.Equivalents {
+        // array of tables entries share a key, so point at the first one
+        key := "conversion.equivalents"
+        if _, ok := c.Exchanges[equivalent.Exchange]; !ok {
+            errors = append(errors, s.errorf(key, "entry %v: unknown exchange %v", i + 1, equivalent.Exchange))
+        }
+        assetPair, ok := c.AssetPairs[equivalent.AssetPair]
+        if !ok {
+            errors = append(errors, s.errorf(key, "entry %v: unknown asset pair %v", i + 1, equivalent.AssetPair))
+        } else if !(assetPair.Base == equivalent.Asset && assetPair.Quote == c.Conversion.Numeraire) && !(assetPair.Base == c.Conversion.Numeraire && assetPair.Quote == equivalent.Asset) {
+            errors = append(errors, s.errorf(key, "entry %v: %v does not price %v against %v", i + 1, equivalent.AssetPair, equivalent.Asset, c.Conversion.Numeraire))
+        }
+        if !validPercent(equivalent.Cost) {
+            errors = append(errors, s.errorf(key, "entry %v: cost must be a percent in [0, 100), got %v", i + 1, equivalent.Cost))
+        }
+    }
+
+    if c.Trading.Threshold < 0 || c.Trading.Threshold > 1 {
+        errors = append(errors, s.errorf("trading.threshold", "must be a probability in [0, 1], got %v", c.Trading.Threshold))
+    }
+    if c.Trading.SleepDuration.Duration <= 0 {
+        errors = append(errors, s.errorf("trading.sleep_duration", "must be positive"))
+    }
+    if c.Trading.OpportunityQueueCapacity == 0 {
+        errors = append(errors, s.errorf("trading.opportunity_queue_capacity", "must be positive"))
+    }
+    if c.Trading.OpportunityMaxAge.Duration <= 0 {
+        errors = append(errors, s.errorf("trading.opportunity_max_age", "must be positive"))
+    }
+    if !validPercent(c.Trading.TransferCost) {
+        errors = append(errors, s.errorf("trading.transfer_cost", "must be a percent in [0, 100), got %v", c.Trading.TransferCost))
+    }
+
+    if c.Risk.MaxOrderNotional <= 0 {
+        errors = append(errors, s.errorf("risk.max_order_notional", "must be positive"))
+    }
+    if c.Risk.MaxOpenOrders == 0 {
+        errors = append(errors, s.errorf("risk.max_open_orders", "must be positive"))
+    }
+    if c.Risk.MaxDailyLoss <= 0 {
+        errors = append(errors, s.errorf("risk.max_daily_loss", "must be positive"))
+    }
+
+    switch c.Secrets.Provider {
+    case "env":
+    case "file":
+        if c.Secrets.Path == "" {
+            errors = append(errors, s.errorf("secrets.path", "is required for the file provider"))
+        }
+    case "vault":
+        if c.Secrets.VaultAddress == "" {
+            errors = append(errors, s.errorf("secrets.vault_address", "is required for the vault provider"))
+        }
+    default:
+        errors = append(errors, s.errorf("secrets.provider", "must be one of env, file or vault, got %q", c.Secrets.Provider))
+    }
+
+    for _, name := range sortedKeys(c.Paper.Balances) {
+        if _, ok := c.Exchanges[name]; !ok {
+            errors = append(errors, s.errorf("paper.balances." + name, "unknown exchange"))
+        }
+        for asset, balance := range c.Paper.Balances[name] {
+            if balance < 0 {
+                errors = append(errors, s.errorf("paper.balances." + name + "." + asset, "must not be negative"))
+            }
+        }
+    }
+
+    if c.Metrics.Address != "" && c.Metrics.BalanceInterval.Duration <= 0 {
+        errors = append(errors, s.errorf("metrics.balance_interval", "must be positive when metrics.address is set"))
+    }
+    if c.Control.Address != "" && c.Control.Address == c.Metrics.Address {
+        errors = append(errors, s.errorf("control.address", "must differ from metrics.address"))
+    }
+
+    if c.Candles.PollInterval.Duration < 0 {
+        errors = append(errors, s.errorf("candles.poll_interval", "must not be negative"))
+    }
+    switch c.Candles.Mid {
+    case "", "midpoint", "microprice":
+    default:
+        errors = append(errors, s.errorf("candles.mid", "must be midpoint or microprice, got %q", c.Candles.Mid))
+    }
+
+    switch c.Volatility.Estimator {
+    case "", "realized", "ewma", "parkinson", "garman_klass":
+    default:
+        errors = append(errors, s.errorf("volatility.estimator", "must be one of realized, ewma, parkinson or garman_klass, got %q", c.Volatility.Estimator))
+    }
+    // the resolutions candles are aggregated at
+    switch c.Volatility.Interval.Duration {
+    case 0, time.Second, time.Minute, 5 * time.Minute, time.Hour:
+    default:
+        errors = append(errors, s.errorf("volatility.interval", "must be one of 1s, 1m, 5m or 1h, got %v", c.Volatility.Interval.Duration))
+    }
+    if c.Volatility.Horizon.Duration < 0 {
+        errors = append(errors, s.errorf("volatility.horizon", "must not be negative"))
+    }
+    if c.Volatility.Lambda < 0 || c.Volatility.Lambda >= 1 {
+        errors = append(errors, s.errorf("volatility.lambda", "must be in [0, 1)"))
+    }
+    if c.Features.Notional < 0 {
+        errors = append(errors, s.errorf("features.notional", "must not be negative"))
+    }
+    if c.Labeling.Horizon.Duration < 0 {
+        errors = append(errors, s.errorf("labeling.horizon", "must not be negative"))
+    }
+    if c.Training.Interval.Duration < 0 {
+        errors = append(errors, s.errorf("training.interval", "must not be negative"))
+    }
+    if c.Training.Window.Duration < 0 {
+        errors = append(errors, s.errorf("training.window", "must not be negative"))
+    }
+    if c.Training.MinObservations > c.TrainingCapacity() {
+        errors = append(errors, s.errorf("training.min_observations", "must not exceed training.capacity"))
+    }
+    if c.Models.WatchInterval.Duration < 0 {
+        errors = append(errors, s.errorf("models.watch_interval", "must not be negative"))
+    }
+    switch c.Decision.Calibration {
+    case "", "none", "platt", "isotonic":
+    default:
+        errors = append(errors, s.errorf("decision.calibration", "must be one of none, platt or isotonic, got %q", c.Decision.Calibration))
+    }
+    if c.Decision.Margin < 0 {
+        errors = append(errors, s.errorf("decision.margin", "must not be negative"))
+    }
+    if c.Inference.BatchWindow.Duration < 0 {
+        errors = append(errors, s.errorf("inference.batch_window", "must not be negative"))
+    }
+    if c.Drift.Interval.Duration < 0 {
+        errors = append(errors, s.errorf("drift.interval", "must not be negative"))
+    }
+    if c.Drift.MinObservations > uint(c.DriftWindow()) {
+        errors = append(errors, s.errorf("drift.min_observations", "must not exceed drift.window"))
+    }
+    if c.Drift.PSIThreshold < 0 {
+        errors = append(errors, s.errorf("drift.psi_threshold", "must not be negative"))
+    }
+    if c.Drift.KSThreshold < 0 || c.Drift.KSThreshold > 1 {
+        errors = append(errors, s.errorf("drift.ks_threshold", "must be between 0 and 1"))
+    }
+    if c.Drift.ConservativeMargin < 0 {
+        errors = append(errors, s.errorf("drift.conservative_margin", "must not be negative"))
+    }
+    challengers := make(map[string]bool)
+    for _, challenger := range c.Shadow.Challengers {
+        if challengers[challenger] {
+            errors = append(errors, s.errorf("shadow.challengers", "%v is listed more than once", challenger))
+        }
+        challengers[challenger] = true
+    }
+
+    switch c.Shutdown.OpenOrders {
+    case "", "keep", "cancel", "flatten":
+    default:
+        errors = append(errors, s.errorf("shutdown.open_orders", "must be one of keep, cancel or flatten, got %q", c.Shutdown.OpenOrders))
+    }
+    if c.Shutdown.Timeout.Duration < 0 {
+        errors = append(errors, s.errorf("shutdown.timeout", "must not be negative"))
+    }
+
+    if c.Logging.Format != "" {
+        if _, err := logging.ParseFormat(c.Logging.Format); err != nil {
+            errors = append(errors, s.errorf("logging.format", "%v", err))
+        }
+    }
+    if c.Logging.Level != "" {
+        if _, err := logging.ParseLevel(c.Logging.Level); err != nil {
+            errors = append(errors, s.errorf("logging.level", "%v", err))
+        }
+    }
+    for _, component := range sortedKeys(c.Logging.Levels) {
+        if _, err := logging.ParseLevel(c.Logging.Levels[component]); err != nil {
+            errors = append(errors, s.errorf("logging.levels." + component, "%v", err))
+        }
+    }
+
+    if len(c.Filters.Exchanges) == 0 {
+        errors = append(errors, fs.errorf("exchanges", "at least one exchange must be enabled"))
+    }
+    for _, name := range c.Filters.Exchanges {
+        if _, ok := c.Exchanges[name]; !ok {
+            errors = append(errors, fs.errorf("exchanges", "%v is not configured in %v", name, s.file))
+        }
+    }
+    for _, canonical := range c.Filters.AssetPairs {
+        if _, ok := c.AssetPairs[canonical]; !ok {
+            errors = append(errors, fs.errorf("asset_pairs", "%v is not configured in %v", canonical, s.file))
+        }
+    }
+
+    return errors
+}
+
+func (c *Config) EnabledExchanges() []string {
+    return c.Filters.Exchanges
+}
+
+func (c *Config) assetPairEnabled(canonical string) bool {
+    if len(c.Filters.AssetPairs) == 0 {
+        return true
+    }
+    for _, enabled := range c.Filters.AssetPairs {
+        if enabled == canonical {
+            return true
+        }
+    }
+    return false
+}
+
+func (c *Config) AssetPair(canonical string) (types.AssetPair, bool) {
+    assetPair, ok := c.AssetPairs[canonical]
+    if !ok {
+        return types.AssetPair{}, false
+    }
+    return types.NewAssetPair(types.Asset(assetPair.Base), types.Asset(assetPair.Quote)), true
+}
+
+func (c *Config) translator(symbols map[string]string) types.AssetPairTranslator {
+    translator := make(types.AssetPairTranslator)
+    for canonical, symbol := range symbols {
+        if !c.assetPairEnabled(canonical) {
+            continue
+        }
+        assetPair, _ := c.AssetPair(canonical)
+        translator[assetPair] = symbol
+    }
+    return translator
+}
+
+// REST symbols of every enabled asset pair the exchange lists
+func (c *Config) AssetPairTranslator(exchange string) types.AssetPairTranslator {
+    return c.translator(c.Exchanges[exchange].Symbols)
+}
+
+func (c *Config) WebSocketAssetPairTranslator(exchange string) types.AssetPairTranslator {
+    return c.translator(c.Exchanges[exchange].WebSocketSymbols)
+}
+
+func percentToFraction(percent float64) decimal.Decimal {
+    return decimal.NewFromFloat(percent).Div(decimal.NewFromInt(100))
+}
+
+// as a fraction, e.g. 0.001 for 0.1%
+func (c *Config) Fee(exchange string) decimal.Decimal {
+    return percentToFraction(c.Exchanges[exchange].Fee)
+}
+
+func (c *Config) TradeCapacity(exchange string) uint {
+    if c.Exchanges[exchange].TradeCapacity == 0 {
+        return 1000
+    }
+    return c.Exchanges[exchange].TradeCapacity
+}
+
+func (c *Config) TransferCost() decimal.Decimal {
+    return percentToFraction(c.Trading.TransferCost)
+}
+
+func (c *Config) PaperBalances(exchange string) map[types.Asset]decimal.Decimal {
+    balances := make(map[types.Asset]decimal.Decimal)
+    for asset, balance := range c.Paper.Balances[exchange] {
+        balances[types.Asset(asset)] = decimal.NewFromFloat(balance)
+    }
+    return balances
+}
+
+func (c *Config) CandleCapacity() uint {
+    if c.Candles.Capacity == 0 {
+        return 1000
+    }
+    return c.Candles.Capacity
+}
+
+func (c *Config) CandlePollInterval() time.Duration {
+    if c.Candles.PollInterval.Duration == 0 {
+        return 250 * time.Millisecond
+    }
+    return c.Candles.PollInterval.Duration
+}
+
+func (c *Config) VolatilityEstimator() string {
+    if c.Volatility.Estimator == "" {
+        return "realized"
+    }
+    return c.Volatility.Estimator
+}
+
+func (c *Config) VolatilityInterval() time.Duration {
+    if c.Volatility.Interval.Duration == 0 {
+        return time.Second
+    }
+    return c.Volatility.Interval.Duration
+}
+
+func (c *Config) VolatilityWindow() uint {
+    if c.Volatility.Window == 0 {
+        return 60
+    }
+    return c.Volatility.Window
+}
+
+func (c *Config) VolatilityHorizon() time.Duration {
+    if c.Volatility.Horizon.Duration == 0 {
+        return c.VolatilityInterval()
+    }
+    return c.Volatility.Horizon.Duration
+}
+
+func (c *Config) VolatilityLambda() float64 {
+    if c.Volatility.Lambda == 0 {
+        return 0.94
+    }
+    return c.Volatility.Lambda
+}
+
+func (c *Config) FeatureSchema() string {
+    if c.Features.Schema == "" {
+        return "grizzly/1"
+    }
+    return c.Features.Schema
+}
+
+func (c *Config) FeatureNotional() decimal.Decimal {
+    if c.Features.Notional == 0 {
+        return decimal.NewFromInt(100)
+    }
+    return decimal.NewFromFloat(c.Features.Notional)
+}
+
+func (c *Config) LabelHorizon() time.Duration {
+    if c.Labeling.Horizon.Duration == 0 {
+        return 5 * time.Second
+    }
+    return c.Labeling.Horizon.Duration
+}
+
+func (c *Config) LabelThreshold() decimal.Decimal {
+    return decimal.NewFromFloat(c.Labeling.Threshold)
+}
+
+func (c *Config) TrainingInterval() time.Duration {
+    if c.Training.Interval.Duration == 0 {
+        return time.Hour
+    }
+    return c.Training.Interval.Duration
+}
+
+func (c *Config) TrainingWindow() time.Duration {
+    if c.Training.Window.Duration == 0 {
+        return time.Hour
+    }
+    return c.Training.Window.Duration
+}
+
+func (c *Config) TrainingMinObservations() uint {
+    if c.Training.MinObservations == 0 {
+        return 1000
+    }
+    return c.Training.MinObservations
+}
+
+func (c *Config) TrainingCapacity() uint {
+    if c.Training.Capacity == 0 {
+        return 100000
+    }
+    return c.Training.Capacity
+}
+
+func (c *Config) TrainingBatchSize() uint {
+    if c.Training.BatchSize == 0 {
+        return 32
+    }
+    return c.Training.BatchSize
+}
+
+func (c *Config) TrainingEpochs() uint {
+    if c.Training.Epochs == 0 {
+        return 1
+    }
+    return c.Training.Epochs
+}
+
+func (c *Config) ModelDirectory() string {
+    if c.Models.Directory == "" {
+        return "models"
+    }
+    return c.Models.Directory
+}
+
+func (c *Config) ModelWatchInterval() time.Duration {
+    if c.Models.WatchInterval.Duration == 0 {
+        return 10 * time.Second
+    }
+    return c.Models.WatchInterval.Duration
+}
+
+func (c *Config) DecisionCalibration() string {
+    if c.Decision.Calibration == "" {
+        return "isotonic"
+    }
+    return c.Decision.Calibration
+}
+
+func (c *Config) DecisionMargin() decimal.Decimal {
+    return decimal.NewFromFloat(c.Decision.Margin)
+}
+
+func (c *Config) InferenceBatchWindow() time.Duration {
+    if c.Inference.BatchWindow.Duration == 0 {
+        return 2 * time.Millisecond
+    }
+    return c.Inference.BatchWindow.Duration
+}
+
+func (c *Config) InferenceMaxBatch() int {
+    if c.Inference.MaxBatch == 0 {
+        return 256
+    }
+    return int(c.Inference.MaxBatch)
+}
+
+func (c *Config) DriftInterval() time.Duration {
+    if c.Drift.Interval.Duration == 0 {
+        return time.Minute
+    }
+    return c.Drift.Interval.Duration
+}
+
+func (c *Config) DriftWindow() int {
+    if c.Drift.Window == 0 {
+        return 1000
+    }
+    return int(c.Drift.Window)
+}
+
+func (c *Config) DriftMinObservations() int {
+    if c.Drift.MinObservations == 0 {
+        return 200
+    }
+    return int(c.Drift.MinObservations)
+}
+
+func (c *Config) DriftPSIThreshold() float64 {
+    if c.Drift.PSIThreshold == 0 {
+        return 0.25
+    }
+    return c.Drift.PSIThreshold
+}
+
+func (c *Config) DriftKSThreshold() float64 {
+    if c.Drift.KSThreshold == 0 {
+        return 0.2
+    }
+    return c.Drift.KSThreshold
+}
+
+func (c *Config) DriftConservativeMargin() decimal.Decimal {
+    return decimal.NewFromFloat(c.Drift.ConservativeMargin)
+}
+
+func (c *Config) ShadowLog() string {
+    if c.Shadow.Log == "" {
+        return "shadow.jsonl"
+    }
+    return c.Shadow.Log
+}
+
+func (c *Config) ShutdownOpenOrders() string {
+    if c.Shutdown.OpenOrders == "" {
+        return "cancel"
+    }
+    return c.Shutdown.OpenOrders
+}
+
+func (c *Config) BalanceInterval() time.Duration {
+    if c.Metrics.BalanceInterval.Duration == 0 {
+        return time.Minute
+    }
+    return c.Metrics.BalanceInterval.Duration
+}
+
+func (c *Config) ShutdownTimeout() time.Duration {
+    if c.Shutdown.Timeout.Duration == 0 {
+        return 30 * time.Second
+    }
+    return c.Shutdown.Timeout.Duration
+}
+
+func (e EquivalentConfig) CostFraction() decimal.Decimal {
+    return percentToFraction(e.Cost)
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+var BTCUSD types.AssetPair = types.NewAssetPair("BTC", "USD")
+
+const minimalConfig string = `[trading]
+threshold = 0.5
+sleep_duration = "100ms"
+opportunity_queue_capacity = 16
+opportunity_max_age = "500ms"
+transfer_cost = 0.0
+
+[risk]
+max_order_notional = 1000.0
+max_open_orders = 10
+max_daily_loss = 100.0
+
+[secrets]
+provider = "env"
+
+[conversion]
+numeraire = "USD"
+
+[asset_pairs]
+BTCUSD = { base = "BTC", quote = "USD" }
+
+[exchanges.Kraken]
+fee = 0.26
+spread_capacity = 200
+order_book_depth = 1000
+
+[exchanges.Kraken.symbols]
+BTCUSD = "XXBTZUSD"
+`
+
+const minimalFilters string = `exchanges = ["Kraken"]
+`
+
+func load(config, filters string) (*Config, error) {
+	c := &Config{}
+	s, err := decode("grizzly.toml", []byte(config), c)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := decode("filters.toml", []byte(filters), &c.Filters)
+	if err != nil {
+		return nil, err
+	}
+	if errors := c.validate(s, fs); len(errors) > 0 {
+		return nil, errors
+	}
+	return c, nil
+}
+
+func expectError(t *testing.T, err error, expected string) {
+	if err == nil {
+		t.Fatalf("expected error %q, got none\n", expected)
+	}
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected error %q, got %q\n", expected, err.Error())
+	}
+}
+
+func TestLoadShipped(t *testing.T) {
+	c, err := Load("grizzly.toml", "filters.toml")
+	if err != nil {
+		t.Fatalf("shipped config does not load:\n%v\n", err)
+	}
+	if len(c.EnabledExchanges()) == 0 {
+		t.Fatalf("expected enabled exchanges\n")
+	}
+	for _, exchange := range c.EnabledExchanges() {
+		if len(c.AssetPairTranslator(exchange)) == 0 {
+			t.Fatalf("expected asset pairs for %v\n", exchange)
+		}
+	}
+	if translator := c.WebSocketAssetPairTranslator("Kraken"); translator[BTCUSD] != "XBT/USD" {
+		t.Fatalf("expected Kraken WebSocket symbol XBT/USD, got %v\n", translator[BTCUSD])
+	}
+}
+
+func TestLoad(t *testing.T) {
+	c, err := load(minimalConfig, minimalFilters)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if !c.Fee("Kraken").Equal(decimal.NewFromFloat(0.0026)) {
+		t.Fatalf("expected fee 0.0026, got %v\n", c.Fee("Kraken"))
+	}
+	if c.Trading.SleepDuration.Duration != 100 * time.Millisecond {
+		t.Fatalf("expected sleep duration 100ms, got %v\n", c.Trading.SleepDuration)
+	}
+	if translator := c.AssetPairTranslator("Kraken"); translator[BTCUSD] != "XXBTZUSD" {
+		t.Fatalf("expected BTCUSD to translate to XXBTZUSD, got %v\n", translator)
+	}
+	if c.TradeCapacity("Kraken") != 1000 {
+		t.Fatalf("expected trade capacity to default to 1000, got %v\n", c.TradeCapacity("Kraken"))
+	}
+	if c.CandleCapacity() != 1000 || c.CandlePollInterval() != 250 * time.Millisecond || c.Candles.Backfill {
+		t.Fatalf("expected candles to default to 1000 every 250ms without backfill, got %v %v %v\n", c.CandleCapacity(), c.CandlePollInterval(), c.Candles.Backfill)
+	}
+	if c.VolatilityEstimator() != "realized" || c.VolatilityInterval() != time.Second || c.VolatilityWindow() != 60 || c.VolatilityHorizon() != time.Second || c.VolatilityLambda() != 0.94 {
+		t.Fatalf("expected realized volatility over 60 one second candles by default, got %v %v %v %v %v\n", c.VolatilityEstimator(), c.VolatilityInterval(), c.VolatilityWindow(), c.VolatilityHorizon(), c.VolatilityLambda())
+	}
+	if c.FeatureSchema() != "grizzly/1" || !c.FeatureNotional().Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("expected grizzly/1 features at a notional of 100 by default, got %v %v\n", c.FeatureSchema(), c.FeatureNotional())
+	}
+	if c.LabelHorizon() != 5 * time.Second || !c.LabelThreshold().IsZero() {
+		t.Fatalf("expected labels over 5s for any positive return by default, got %v %v\n", c.LabelHorizon(), c.LabelThreshold())
+	}
+	if c.Training.Enabled || c.TrainingInterval() != time.Hour || c.TrainingWindow() != time.Hour || c.TrainingMinObservations() != 1000 || c.TrainingCapacity() != 100000 || c.TrainingBatchSize() != 32 || c.TrainingEpochs() != 1 {
+		t.Fatalf("expected hourly training on 1000 observations disabled by default, got %+v\n", c.Training)
+	}
+	if c.ModelDirectory() != "models" || c.ModelWatchInterval() != 10 * time.Second {
+		t.Fatalf("expected models checked every 10s by default, got %v %v\n", c.ModelDirectory(), c.ModelWatchInterval())
+	}
+	if c.DecisionCalibration() != "isotonic" || !c.DecisionMargin().IsZero() {
+		t.Fatalf("expected isotonic calibration and any positive expected value by default, got %v %v\n", c.DecisionCalibration(), c.DecisionMargin())
+	}
+	if c.InferenceBatchWindow() != 2 * time.Millisecond || c.InferenceMaxBatch() != 256 {
+		t.Fatalf("expected 2ms batches of up to 256 observations by default, got %v %v\n", c.InferenceBatchWindow(), c.InferenceMaxBatch())
+	}
+	if c.DriftInterval() != time.Minute || c.DriftWindow() != 1000 || c.DriftMinObservations() != 200 || c.DriftPSIThreshold() != 0.25 || c.DriftKSThreshold() != 0.2 || c.Drift.Conservative {
+		t.Fatalf("expected the last 1000 observations checked every minute by default, got %+v\n", c.Drift)
+	}
+	if len(c.Shadow.Challengers) != 0 || c.ShadowLog() != "shadow.jsonl" {
+		t.Fatalf("expected no challengers by default, got %+v\n", c.Shadow)
+	}
+	if c.ShutdownOpenOrders() != "cancel" || c.ShutdownTimeout() != 30 * time.Second {
+		t.Fatalf("expected shutdown to default to canceling within 30s, got %v %v\n", c.ShutdownOpenOrders(), c.ShutdownTimeout())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, err := load(minimalConfig + "BTCUSDT = \"XBTUSDT\"\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:29: exchanges.Kraken.symbols.BTCUSDT: unknown asset pair")
+
+	_, err = load(strings.Replace(minimalConfig, "fee = 0.26", "fee = 260.0", 1), minimalFilters)
+	expectError(t, err, "grizzly.toml:23: exchanges.Kraken.fee: must be a percent")
+
+	_, err = load(strings.Replace(minimalConfig, "fee = 0.26", "fee = 1", 1), minimalFilters)
+	expectError(t, err, "grizzly.toml:23: exchanges.Kraken.fee: expected float, found integer")
+
+	_, err = load(strings.Replace(minimalConfig, "max_open_orders = 10", "max_open_orders = 10\nmax_leverage = 2", 1), minimalFilters)
+	expectError(t, err, "grizzly.toml:11: risk.max_leverage: unknown key")
+
+	_, err = load(strings.Replace(minimalConfig, "\"100ms\"", "\"100\"", 1), minimalFilters)
+	expectError(t, err, "grizzly.toml:3: trading.sleep_duration: time: missing unit")
+
+	_, err = load(strings.Replace(minimalConfig, "threshold = 0.5", "threshold = 0.5 0.6", 1), minimalFilters)
+	expectError(t, err, "grizzly.toml:2:")
+
+	_, err = load(strings.Replace(minimalConfig, "fee = 0.26", "api_key = \"key\"\nfee = 0.26", 1), minimalFilters)
+	expectError(t, err, "grizzly.toml:23: exchanges.Kraken.api_key: unknown key")
+
+	_, err = load(strings.Replace(minimalConfig, "provider = \"env\"", "provider = \"plaintext\"", 1), minimalFilters)
+	expectError(t, err, "grizzly.toml:14: secrets.provider: must be one of env, file or vault")
+
+	_, err = load(minimalConfig + "\n[metrics]\naddress = \"127.0.0.1:9100\"\n", minimalFilters)
+	expectError(t, err, "metrics.balance_interval: must be positive when metrics.address is set")
+
+	_, err = load(minimalConfig + "\n[metrics]\naddress = \"127.0.0.1:9100\"\nbalance_interval = \"1m\"\n\n[control]\naddress = \"127.0.0.1:9100\"\n", minimalFilters)
+	expectError(t, err, "control.address: must differ from metrics.address")
+
+	_, err = load(minimalConfig + "\n[shutdown]\nopen_orders = \"liquidate\"\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:31: shutdown.open_orders: must be one of keep, cancel or flatten")
+
+	_, err = load(minimalConfig + "\n[candles]\npoll_interval = \"-1s\"\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:31: candles.poll_interval: must not be negative")
+
+	_, err = load(minimalConfig + "\n[volatility]\nestimator = \"rogers_satchell\"\ninterval = \"2s\"\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:31: volatility.estimator: must be one of realized, ewma, parkinson or garman_klass")
+	expectError(t, err, "grizzly.toml:32: volatility.interval: must be one of 1s, 1m, 5m or 1h, got 2s")
+
+	_, err = load(minimalConfig + "\n[features]\nnotional = -1.0\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:31: features.notional: must not be negative")
+
+	_, err = load(minimalConfig + "\n[labeling]\nhorizon = \"-5s\"\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:31: labeling.horizon: must not be negative")
+
+	_, err = load(minimalConfig + "\n[training]\nwindow = \"-1h\"\nmin_observations = 200\ncapacity = 100\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:31: training.window: must not be negative")
+	expectError(t, err, "grizzly.toml:32: training.min_observations: must not exceed training.capacity")
+
+	_, err = load(minimalConfig + "\n[models]\nwatch_interval = \"-10s\"\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:31: models.watch_interval: must not be negative")
+
+	_, err = load(minimalConfig + "\n[decision]\ncalibration = \"beta\"\nmargin = -0.1\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:31: decision.calibration: must be one of none, platt or isotonic, got \"beta\"")
+	expectError(t, err, "grizzly.toml:32: decision.margin: must not be negative")
+
+	_, err = load(minimalConfig + "\n[inference]\nbatch_window = \"-2ms\"\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:31: inference.batch_window: must not be negative")
+
+	_, err = load(minimalConfig + "\n[drift]\nwindow = 100\nmin_observations = 200\nks_threshold = 1.5\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:32: drift.min_observations: must not exceed drift.window")
+	expectError(t, err, "grizzly.toml:33: drift.ks_threshold: must be between 0 and 1")
+
+	_, err = load(minimalConfig + "\n[shadow]\nchallengers = [\"models/a\", \"models/a\"]\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:31: shadow.challengers: models/a is listed more than once")
+
+	_, err = load(minimalConfig + "\n[logging.levels]\n\"exchanges/kraken\" = \"verbose\"\n", minimalFilters)
+	expectError(t, err, "grizzly.toml:31: logging.levels.exchanges/kraken: slog: level string \"verbose\": unknown name")
+
+	_, err = load(minimalConfig, "exchanges = [\"Kraken\"]\nasset_pairs = [\"ETHUSD\"]\n")
+	expectError(t, err, "filters.toml:2: asset_pairs: ETHUSD is not configured in grizzly.toml")
+}
@@ -0,0 +1,298 @@
+package config
+
+import (
+    "bufio"
+    "bytes"
+    "encoding"
+    "fmt"
+    "io/ioutil"
+    "reflect"
+    "strings"
+    "time"
+
+    "github.com/BurntSushi/toml"
+)
+
+type Error struct {
+    File    string
+    // 0 when the line could not be determined
+    Line    int
+    Key     string
+    Message string
+}
+
+func (e *Error) Error() string {
+    location := e.File
+    if e.Line > 0 {
+        location = fmt.Sprintf("%v:%v", e.File, e.Line)
+    }
+    if e.Key == "" {
+        return fmt.Sprintf("%v: %v", location, e.Message)
+    }
+    return fmt.Sprintf("%v: %v: %v", location, e.Key, e.Message)
+}
+
+type Errors []*Error
+
+func (e Errors) Error() string {
+    messages := make([]string, len(e))
+    for i, err := range e {
+        messages[i] = err.Error()
+    }
+    return strings.Join(messages, "\n")
+}
+
+// time.Duration written as a string, e.g. "100ms"
+type Duration struct {
+    time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+    duration, err := time.ParseDuration(string(text))
+    if err != nil {
+        return err
+    }
+    d.Duration = duration
+    return nil
+}
+
+// tracks where keys are defined so validation errors can point at a line
+type source struct {
+    file  string
+    lines map[string]int
+}
+
+func newSource(file string, data []byte) *source {
+    s := &source{
+        file: file,
+        lines: make(map[string]int),
+    }
+
+    table := ""
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    for line := 1; scanner.Scan(); line++ {
+        text := strings.TrimSpace(stripComment(scanner.Text()))
+        if text == "" {
+            continue
+        }
+        if strings.HasPrefix(text, "[") {
+            table = normalizeKey(strings.Trim(text, "[]"))
+            if _, ok := s.lines[table]; !ok {
+                s.lines[table] = line
+            }
+            continue
+        }
+        if i := strings.Index(text, "="); i > 0 {
+            key := normalizeKey(text[:i])
+            if table != "" {
+                key = table + "." + key
+            }
+            if _, ok := s.lines[key]; !ok {
+                s.lines[key] = line
+            }
+        }
+    }
+    return s
+}
+
+func stripComment(line string) string {
+    inString := false
+    for i, c := range line {
+        switch c {
+        case '"', '\'':
+            inString = !inString
+        case '#':
+            if !inString {
+                return line[:i]
+            }
+        }
+    }
+    return line
+}
+
+func normalizeKey(key string) string {
+    parts := strings.Split(key, ".")
+    for i, part := range parts {
+        parts[i] = strings.Trim(strings.TrimSpace(part), "\"'")
+    }
+    return strings.Join(parts, ".")
+}
+
+// line of the key, falling back to the closest enclosing table
+func (s *source) line(key string) int {
+    for key != "" {
+        if line, ok := s.lines[key]; ok {
+            return line
+        }
+        i := strings.LastIndex(key, ".")
+        if i < 0 {
+            break
+        }
+        key = key[:i]
+    }
+    return 0
+}
+
+func (s *source) errorf(key string, format string, args ...interface{}) *Error {
+    return &Error{
+        File: s.file,
+        Line: s.line(key),
+        Key: key,
+        Message: fmt.Sprintf(format, args...),
+    }
+}
+
+var textUnmarshalerType reflect.Type = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+func fieldByKey(t reflect.Type, name string) (reflect.StructField, bool) {
+    for i := 0; i < t.NumField(); i++ {
+        field := t.Field(i)
+        tag := strings.Split(field.Tag.Get("toml"), ",")[0]
+        if tag == "-" {
+            continue
+        }
+        if tag == name || (tag == "" && strings.EqualFold(field.Name, name)) {
+            return field, true
+        }
+    }
+    return reflect.StructField{}, false
+}
+
+// resolves the Go type a TOML key decodes into
+func typeOfKey(root reflect.Type, key toml.Key) (reflect.Type, bool) {
+    t := root
+    for _, part := range key {
+        for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+            t = t.Elem()
+        }
+        switch t.Kind() {
+        case reflect.Struct:
+            if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+                return nil, false
+            }
+            field, ok := fieldByKey(t, part)
+            if !ok {
+                return nil, false
+            }
+            t = field.Type
+        case reflect.Map:
+            t = t.Elem()
+        default:
+            return nil, false
+        }
+    }
+    return t, true
+}
+
+func expectedTomlTypes(t reflect.Type) []string {
+    if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+        return []string{"String"}
+    }
+    switch t.Kind() {
+    case reflect.String:
+        return []string{"String"}
+    case reflect.Bool:
+        return []string{"Bool"}
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return []string{"Integer"}
+    case reflect.Float32, reflect.Float64:
+        return []string{"Float"}
+    case reflect.Struct, reflect.Map:
+        return []string{"Hash"}
+    case reflect.Slice, reflect.Array:
+        if t.Elem().Kind() == reflect.Struct {
+            return []string{"ArrayHash", "Array"}
+        }
+        return []string{"Array"}
+    }
+    return []string{}
+}
+
+// nil when the key is nested inside an array
+func rawValue(raw map[string]interface{}, key toml.Key) interface{} {
+    var value interface{} = raw
+    for _, part := range key {
+        table, ok := value.(map[string]interface{})
+        if !ok {
+            return nil
+        }
+        value = table[part]
+    }
+    return value
+}
+
+// decodes data into v, rejecting unknown keys and mistyped values before decoding
+func decode(file string, data []byte, v interface{}) (*source, error) {
+    s := newSource(file, data)
+
+    var raw map[string]interface{}
+    md, err := toml.Decode(string(data), &raw)
+    if err != nil {
+        if parseError, ok := err.(toml.ParseError); ok {
+            return s, Errors{&Error{
+                File: file,
+                Line: parseError.Line,
+                Key: parseError.LastKey,
+                Message: parseError.Message,
+            }}
+        }
+        return s, Errors{&Error{File: file, Message: err.Error()}}
+    }
+
+    errors := make(Errors, 0)
+    root := reflect.TypeOf(v).Elem()
+    unknown := make(map[string]struct{})
+    for _, key := range md.Keys() {
+        t, ok := typeOfKey(root, key)
+        if !ok {
+            // only report the outermost unknown table
+            reported := false
+            for i := 1; i < len(key); i++ {
+                if _, ok := unknown[key[:i].String()]; ok {
+                    reported = true
+                }
+            }
+            unknown[key.String()] = struct{}{}
+            if !reported {
+                errors = append(errors, s.errorf(key.String(), "unknown key"))
+            }
+            continue
+        }
+        actual := md.Type(key...)
+        expected := expectedTomlTypes(t)
+        matched := false
+        for _, e := range expected {
+            if e == actual {
+                matched = true
+            }
+        }
+        if !matched {
+            errors = append(errors, s.errorf(key.String(), "expected %v, found %v", strings.ToLower(strings.Join(expected, " or ")), strings.ToLower(actual)))
+            continue
+        }
+        if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+            if text, ok := rawValue(raw, key).(string); ok {
+                if err := reflect.New(t).Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+                    errors = append(errors, s.errorf(key.String(), "%v", err))
+                }
+            }
+        }
+    }
+    if len(errors) > 0 {
+        return s, errors
+    }
+
+    if _, err := toml.Decode(string(data), v); err != nil {
+        return s, Errors{&Error{File: file, Message: err.Error()}}
+    }
+    return s, nil
+}
+
+func decodeFile(path string, v interface{}) (*source, error) {
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        return nil, Errors{&Error{File: path, Message: err.Error()}}
+    }
+    return decode(path, data, v)
+}
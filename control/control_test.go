@@ -0,0 +1,228 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+var BTCUSD types.AssetPair = types.NewAssetPair("BTC", "USD")
+
+// only what the server touches is implemented
+type fakeExchange struct {
+	types.Exchange
+	name         string
+	openOrders   map[types.OrderId]types.OrderStatus
+	canceled     []types.OrderId
+	registered   []types.AssetPair
+	unregistered []types.AssetPair
+	balances     map[types.Asset]decimal.Decimal
+}
+
+func (f *fakeExchange) String() string {
+	return f.name
+}
+
+func (f *fakeExchange) GetOpenOrders() map[types.OrderId]types.OrderStatus {
+	return f.openOrders
+}
+
+func (f *fakeExchange) CancelOrders(orderIds []types.OrderId) {
+	f.canceled = append(f.canceled, orderIds...)
+	f.openOrders = make(map[types.OrderId]types.OrderStatus)
+}
+
+func (f *fakeExchange) GetBalances() map[types.Asset]decimal.Decimal {
+	balances := make(map[types.Asset]decimal.Decimal)
+	for asset, balance := range f.balances {
+		balances[asset] = balance
+	}
+	return balances
+}
+
+func (f *fakeExchange) RegisterAssetPair(assetPair types.AssetPair) {
+	f.registered = append(f.registered, assetPair)
+}
+
+func (f *fakeExchange) UnregisterAssetPair(assetPair types.AssetPair) {
+	f.unregistered = append(f.unregistered, assetPair)
+}
+
+func (f *fakeExchange) Close() {}
+
+// one candle per exchange and asset pair, at minute resolution only
+type fakeCandles struct{}
+
+func (f fakeCandles) GetCandles(exchange string, assetPair types.AssetPair, resolution time.Duration) ([]types.Candle, bool) {
+	if resolution != time.Minute {
+		return nil, false
+	}
+	return []types.Candle{{Close: decimal.NewFromInt(100)}}, true
+}
+
+// rolls back once, from 2 to 1
+type fakeModels struct {
+	current string
+}
+
+func (f *fakeModels) Current() string {
+	return f.current
+}
+
+func (f *fakeModels) Versions() ([]string, error) {
+	return []string{"1", "2"}, nil
+}
+
+func (f *fakeModels) Rollback() (string, error) {
+	if f.current != "2" {
+		return "", errors.New("nothing to roll back to")
+	}
+	f.current = "1"
+	return f.current, nil
+}
+
+func opportunity(exchanges ...string) types.Opportunity {
+	legs := make([]types.Leg, len(exchanges))
+	for i, exchange := range exchanges {
+		legs[i] = types.Leg{Exchange: exchange}
+	}
+	return types.Opportunity{Legs: legs}
+}
+
+func TestState(t *testing.T) {
+	state := NewState(0.5)
+	state.Pause("KuCoin", "Kraken")
+	if !state.Paused("Kraken", "KuCoin") {
+		t.Fatalf("exchange pairs should be unordered\n")
+	}
+	if state.Allows(opportunity("BinanceUS", "Kraken", "KuCoin")) || !state.Allows(opportunity("BinanceUS", "Kraken")) {
+		t.Fatalf("only opportunities trading on a paused pair should be blocked\n")
+	}
+	state.Resume("Kraken", "KuCoin")
+	if len(state.PausedPairs()) != 0 {
+		t.Fatalf("expected no paused pairs, got %v\n", state.PausedPairs())
+	}
+
+	if !state.Kill() || state.Kill() {
+		t.Fatalf("only the first Kill should report triggering the switch\n")
+	}
+	if state.Allows(opportunity("BinanceUS", "Kraken")) {
+		t.Fatalf("nothing should be allowed after the kill switch\n")
+	}
+
+	if err := state.SetThreshold(1.5); err == nil || state.Threshold() != 0.5 {
+		t.Fatalf("expected an out of range threshold to be rejected\n")
+	}
+	if _, _, err := ParsePairKey("Kraken/Kraken"); err == nil {
+		t.Fatalf("expected a pair of the same exchange to be rejected\n")
+	}
+}
+
+func TestLedger(t *testing.T) {
+	ledger := NewLedger()
+	ledger.Observe("Kraken", map[types.Asset]decimal.Decimal{"USD": decimal.NewFromInt(100), "BTC": decimal.NewFromInt(1)})
+
+	balances := map[types.Asset]decimal.Decimal{"USD": decimal.NewFromInt(150), "ETH": decimal.NewFromInt(2)}
+	pnl := ledger.Observe("Kraken", balances)
+	for asset, expected := range map[types.Asset]int64{"USD": 50, "BTC": -1, "ETH": 2} {
+		if !pnl[asset].Equal(decimal.NewFromInt(expected)) {
+			t.Fatalf("expected %v PnL of %v, got %v\n", asset, expected, pnl[asset])
+		}
+	}
+	if balance, ok := balances["BTC"]; !ok || !balance.IsZero() {
+		t.Fatalf("expected the sold out asset to be reported as zero, got %v\n", balances)
+	}
+	if latest := ledger.Balances()["Kraken"]; len(latest) != 3 || !latest["USD"].Equal(decimal.NewFromInt(150)) {
+		t.Fatalf("expected the latest balances, got %v\n", latest)
+	}
+}
+
+func TestServer(t *testing.T) {
+	kraken := &fakeExchange{
+		name: "Kraken",
+		openOrders: map[types.OrderId]types.OrderStatus{"1": {Status: types.Unfilled}},
+	}
+	kuCoin := &fakeExchange{name: "KuCoin"}
+	state := NewState(0.5)
+	server := NewServer(state, NewLedger(), RiskLimits{MaxOpenOrders: 10}, []types.Exchange{kraken, kuCoin}, map[string][]types.AssetPair{
+		"Kraken": {BTCUSD},
+	}, fakeCandles{}, &fakeModels{current: "2"})
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+		return recorder
+	}
+
+	recorder := serve("POST", "/pause?pair=KuCoin/Kraken")
+	status := statusResponse{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if len(status.PausedPairs) != 1 || status.PausedPairs[0] != "Kraken/KuCoin" || status.Risk.OpenOrders != 1 || status.Risk.MaxOpenOrders != 10 {
+		t.Fatalf("unexpected status %+v\n", status)
+	}
+	if !state.Paused("Kraken", "KuCoin") {
+		t.Fatalf("pause was not applied\n")
+	}
+
+	if recorder := serve("GET", "/pause?pair=Kraken/KuCoin"); recorder.Code != 405 {
+		t.Fatalf("expected 405 for GET /pause, got %v\n", recorder.Code)
+	}
+	if recorder := serve("POST", "/pause?pair=Kraken/Bitbank"); recorder.Code != 404 {
+		t.Fatalf("expected 404 for an unknown exchange, got %v\n", recorder.Code)
+	}
+
+	if recorder := serve("POST", "/register?exchange=Kraken&asset_pair=BTCUSD"); recorder.Code != 204 || len(kraken.registered) != 1 {
+		t.Fatalf("expected BTCUSD to be registered, got %v %v\n", recorder.Code, kraken.registered)
+	}
+	if recorder := serve("POST", "/unregister?exchange=Kraken&asset_pair=BTCUSD"); recorder.Code != 204 || len(kraken.unregistered) != 1 {
+		t.Fatalf("expected BTCUSD to be unregistered, got %v %v\n", recorder.Code, kraken.unregistered)
+	}
+	// so that no loop polls it and registers it again
+	if registered := state.Registered("Kraken", []types.AssetPair{BTCUSD}); len(registered) != 0 {
+		t.Fatalf("expected BTCUSD to be left out of Kraken's asset pairs, got %v\n", registered)
+	}
+	if registered := state.Registered("KuCoin", []types.AssetPair{BTCUSD}); len(registered) != 1 {
+		t.Fatalf("expected BTCUSD to stay on KuCoin, got %v\n", registered)
+	}
+	serve("POST", "/register?exchange=Kraken&asset_pair=BTCUSD")
+	if registered := state.Registered("Kraken", []types.AssetPair{BTCUSD}); len(registered) != 1 {
+		t.Fatalf("expected BTCUSD to be polled again once registered, got %v\n", registered)
+	}
+	if recorder := serve("POST", "/register?exchange=KuCoin&asset_pair=BTCUSD"); recorder.Code != 404 {
+		t.Fatalf("expected 404 for an asset pair KuCoin does not have, got %v\n", recorder.Code)
+	}
+
+	candles := make(map[string]map[string][]types.Candle)
+	if err := json.Unmarshal(serve("GET", "/candles?asset_pair=BTCUSD").Body.Bytes(), &candles); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if len(candles) != 1 || len(candles["Kraken"]["BTCUSD"]) != 1 || !candles["Kraken"]["BTCUSD"][0].Close.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("expected one Kraken BTCUSD candle, got %v\n", candles)
+	}
+	if recorder := serve("GET", "/candles?resolution=1x"); recorder.Code != 400 {
+		t.Fatalf("expected 400 for an invalid resolution, got %v\n", recorder.Code)
+	}
+
+	models := modelResponse{}
+	if err := json.Unmarshal(serve("POST", "/model/rollback").Body.Bytes(), &models); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if models.Current != "1" || len(models.Versions) != 2 {
+		t.Fatalf("expected a rollback to version 1, got %+v\n", models)
+	}
+	if recorder := serve("POST", "/model/rollback"); recorder.Code != 409 {
+		t.Fatalf("expected 409 with nothing to roll back to, got %v\n", recorder.Code)
+	}
+
+	serve("POST", "/kill")
+	if !state.Killed() || len(kraken.canceled) != 1 || kraken.canceled[0] != "1" {
+		t.Fatalf("kill switch should cancel open orders, canceled %v\n", kraken.canceled)
+	}
+}
@@ -0,0 +1,65 @@
+package control
+
+import (
+    "sync"
+
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+// remembers the first balances seen per exchange, PnL is the change since then, and the latest
+type Ledger struct {
+    mutex   sync.Mutex
+    initial map[string]map[types.Asset]decimal.Decimal
+    latest  map[string]map[types.Asset]decimal.Decimal
+}
+
+func NewLedger() *Ledger {
+    return &Ledger{
+        initial: make(map[string]map[types.Asset]decimal.Decimal),
+        latest: make(map[string]map[types.Asset]decimal.Decimal),
+    }
+}
+
+// exchange -> asset -> the balance last observed, a copy
+func (l *Ledger) Balances() map[string]map[types.Asset]decimal.Decimal {
+    l.mutex.Lock()
+    defer l.mutex.Unlock()
+    balances := make(map[string]map[types.Asset]decimal.Decimal, len(l.latest))
+    for exchange, latest := range l.latest {
+        balances[exchange] = make(map[types.Asset]decimal.Decimal, len(latest))
+        for asset, balance := range latest {
+            balances[exchange][asset] = balance
+        }
+    }
+    return balances
+}
+
+// returns PnL per asset, assets sold out since the first observation are added to balances as zero
+func (l *Ledger) Observe(exchange string, balances map[types.Asset]decimal.Decimal) map[types.Asset]decimal.Decimal {
+    l.mutex.Lock()
+    defer l.mutex.Unlock()
+
+    initial, ok := l.initial[exchange]
+    if !ok {
+        initial = make(map[types.Asset]decimal.Decimal, len(balances))
+        for asset, balance := range balances {
+            initial[asset] = balance
+        }
+        l.initial[exchange] = initial
+    }
+    for asset := range initial {
+        if _, ok := balances[asset]; !ok {
+            balances[asset] = decimal.Zero
+        }
+    }
+
+    latest := make(map[types.Asset]decimal.Decimal, len(balances))
+    pnl := make(map[types.Asset]decimal.Decimal, len(balances))
+    for asset, balance := range balances {
+        latest[asset] = balance
+        pnl[asset] = balance.Sub(initial[asset])
+    }
+    l.latest[exchange] = latest
+    return pnl
+}
@@ -0,0 +1,481 @@
+package control
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "time"
+
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+var logger *logging.Logger = logging.New("control")
+
+// notional limits are in the conversion numeraire
+type RiskLimits struct {
+    MaxOrderNotional float64 `json:"max_order_notional"`
+    MaxOpenOrders    uint    `json:"max_open_orders"`
+    MaxDailyLoss     float64 `json:"max_daily_loss"`
+}
+
+// candles aggregated in the trading process
+type CandleSource interface {
+    GetCandles(exchange string, assetPair types.AssetPair, resolution time.Duration) ([]types.Candle, bool)
+}
+
+// model versions the trading process can switch between
+type ModelStore interface {
+    // "" while the model the process started with is live
+    Current() string
+    // oldest first
+    Versions() ([]string, error)
+    // returns the version rolled back to
+    Rollback() (string, error)
+}
+
+// JSON status and control endpoints for a running trading process
+//
+// GET  /status                                    threshold, kill switch, paused pairs and risk limits
+// GET  /spreads?exchange=Kraken                   current spread of every asset pair, exchange is optional
+// GET  /order_books?exchange=Kraken&asset_pair=   recorded order books, both parameters are optional
+// GET  /candles?exchange=Kraken&asset_pair=&resolution=1m&limit=60 candles oldest first, every parameter is optional
+// GET  /orders                                    open orders
+// GET  /latency                                   round trip estimate per exchange, in seconds
+// GET  /balances, GET /pnl                        balances and their change since the first poll
+// POST /pause?pair=Kraken/KuCoin, /resume?pair=   stop or restart trading between two exchanges
+// POST /kill                                      stop trading and cancel every open order
+// POST /threshold?value=0.6                       change the model threshold
+// POST /register?exchange=Kraken&asset_pair=BTCUSD start recording an asset pair
+// POST /unregister?exchange=Kraken&asset_pair=BTCUSD stop recording an asset pair and trading it
+// GET  /model                                     live model version and every stored version
+// POST /model/rollback                            go back to the newest loadable version older than the live one
+type Server struct {
+    state      *State
+    ledger     *Ledger
+    risk       RiskLimits
+    names      []string
+    exchanges  map[string]types.Exchange
+    // exchange -> asset pairs spreads and order books are served for
+    assetPairs map[string][]types.AssetPair
+    candles    CandleSource
+    models     ModelStore
+    mux        *http.ServeMux
+}
+
+func NewServer(state *State, ledger *Ledger, risk RiskLimits, exchanges []types.Exchange, assetPairs map[string][]types.AssetPair, candles CandleSource, models ModelStore) *Server {
+    s := &Server{
+        state: state,
+        ledger: ledger,
+        risk: risk,
+        candles: candles,
+        models: models,
+        names: make([]string, 0, len(exchanges)),
+        exchanges: make(map[string]types.Exchange, len(exchanges)),
+        assetPairs: make(map[string][]types.AssetPair, len(exchanges)),
+        mux: http.NewServeMux(),
+    }
+    for _, exchange := range exchanges {
+        name := exchange.String()
+        s.names = append(s.names, name)
+        s.exchanges[name] = exchange
+        sorted := append([]types.AssetPair{}, assetPairs[name]...)
+        sort.Slice(sorted, func(i, j int) bool {
+            return sorted[i].String() < sorted[j].String()
+        })
+        s.assetPairs[name] = sorted
+    }
+    sort.Strings(s.names)
+
+    s.mux.HandleFunc("/status", only(http.MethodGet, s.status))
+    s.mux.HandleFunc("/spreads", only(http.MethodGet, s.spreads))
+    s.mux.HandleFunc("/order_books", only(http.MethodGet, s.orderBooks))
+    s.mux.HandleFunc("/candles", only(http.MethodGet, s.candleHistory))
+    s.mux.HandleFunc("/orders", only(http.MethodGet, s.orders))
+    s.mux.HandleFunc("/latency", only(http.MethodGet, s.latency))
+    s.mux.HandleFunc("/balances", only(http.MethodGet, s.balances))
+    s.mux.HandleFunc("/pnl", only(http.MethodGet, s.pnl))
+    s.mux.HandleFunc("/pause", only(http.MethodPost, s.pause))
+    s.mux.HandleFunc("/resume", only(http.MethodPost, s.resume))
+    s.mux.HandleFunc("/kill", only(http.MethodPost, s.kill))
+    s.mux.HandleFunc("/threshold", only(http.MethodPost, s.threshold))
+    s.mux.HandleFunc("/register", only(http.MethodPost, s.register))
+    s.mux.HandleFunc("/unregister", only(http.MethodPost, s.unregister))
+    s.mux.HandleFunc("/model", only(http.MethodGet, s.model))
+    s.mux.HandleFunc("/model/rollback", only(http.MethodPost, s.rollback))
+    return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+    s.mux.ServeHTTP(w, request)
+}
+
+func only(method string, handler http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, request *http.Request) {
+        if request.Method != method {
+            w.Header().Set("Allow", method)
+            http.Error(w, fmt.Sprintf("method %v not allowed", request.Method), http.StatusMethodNotAllowed)
+            return
+        }
+        handler(w, request)
+    }
+}
+
+func writeJson(w http.ResponseWriter, value interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(value); err != nil {
+        logger.Warn("writing response failed", logging.Err(err))
+    }
+}
+
+// every exchange, or only the one named by the exchange parameter
+func (s *Server) selectExchanges(w http.ResponseWriter, request *http.Request) ([]string, bool) {
+    name := request.URL.Query().Get("exchange")
+    if name == "" {
+        return s.names, true
+    }
+    if _, ok := s.exchanges[name]; !ok {
+        http.Error(w, fmt.Sprintf("unknown exchange %q", name), http.StatusNotFound)
+        return nil, false
+    }
+    return []string{name}, true
+}
+
+// canonical "" selects every configured asset pair of exchange
+func (s *Server) selectAssetPair(w http.ResponseWriter, exchange string, canonical string) ([]types.AssetPair, bool) {
+    if canonical == "" {
+        return s.assetPairs[exchange], true
+    }
+    for _, assetPair := range s.assetPairs[exchange] {
+        if assetPair.String() == canonical {
+            return []types.AssetPair{assetPair}, true
+        }
+    }
+    http.Error(w, fmt.Sprintf("asset pair %q is not configured on %v", canonical, exchange), http.StatusNotFound)
+    return nil, false
+}
+
+func (s *Server) exchangesWith(canonical string) []string {
+    names := make([]string, 0)
+    for _, name := range s.names {
+        for _, assetPair := range s.assetPairs[name] {
+            if assetPair.String() == canonical {
+                names = append(names, name)
+                break
+            }
+        }
+    }
+    return names
+}
+
+type riskResponse struct {
+    RiskLimits
+    OpenOrders int `json:"open_orders"`
+}
+
+type statusResponse struct {
+    Threshold   float64      `json:"threshold"`
+    Killed      bool         `json:"killed"`
+    PausedPairs []string     `json:"paused_pairs"`
+    Risk        riskResponse `json:"risk"`
+}
+
+func (s *Server) status(w http.ResponseWriter, request *http.Request) {
+    openOrders := 0
+    for _, name := range s.names {
+        openOrders += len(s.exchanges[name].GetOpenOrders())
+    }
+    writeJson(w, statusResponse{
+        Threshold: s.state.Threshold(),
+        Killed: s.state.Killed(),
+        PausedPairs: s.state.PausedPairs(),
+        Risk: riskResponse{s.risk, openOrders},
+    })
+}
+
+func (s *Server) spreads(w http.ResponseWriter, request *http.Request) {
+    names, ok := s.selectExchanges(w, request)
+    if !ok {
+        return
+    }
+    // exchange -> asset pair -> spread
+    response := make(map[string]map[string]types.Spread)
+    for _, name := range names {
+        response[name] = make(map[string]types.Spread)
+        for _, assetPair := range s.state.Registered(name, s.assetPairs[name]) {
+            response[name][assetPair.String()] = s.exchanges[name].GetCurrentSpread(assetPair)
+        }
+    }
+    writeJson(w, response)
+}
+
+func (s *Server) orderBooks(w http.ResponseWriter, request *http.Request) {
+    names, ok := s.selectExchanges(w, request)
+    if !ok {
+        return
+    }
+    // exchange -> asset pair -> order book
+    canonical := request.URL.Query().Get("asset_pair")
+    if canonical != "" && len(names) > 1 {
+        // without an exchange, only the exchanges that have the asset pair are listed
+        names = s.exchangesWith(canonical)
+    }
+    response := make(map[string]map[string]*types.OrderBook)
+    for _, name := range names {
+        assetPairs, ok := s.selectAssetPair(w, name, canonical)
+        if !ok {
+            return
+        }
+        response[name] = make(map[string]*types.OrderBook)
+        // reading an unregistered asset pair would register it again
+        for assetPair, orderBook := range s.exchanges[name].GetOrderBooks(s.state.Registered(name, assetPairs)) {
+            response[name][assetPair.String()] = orderBook
+        }
+    }
+    writeJson(w, response)
+}
+
+func (s *Server) candleHistory(w http.ResponseWriter, request *http.Request) {
+    names, ok := s.selectExchanges(w, request)
+    if !ok {
+        return
+    }
+    resolution := time.Minute
+    if value := request.URL.Query().Get("resolution"); value != "" {
+        parsed, err := time.ParseDuration(value)
+        if err != nil || parsed <= 0 {
+            http.Error(w, fmt.Sprintf("invalid resolution %q", value), http.StatusBadRequest)
+            return
+        }
+        resolution = parsed
+    }
+    // 0 returns every candle
+    limit := 0
+    if value := request.URL.Query().Get("limit"); value != "" {
+        parsed, err := strconv.Atoi(value)
+        if err != nil || parsed < 0 {
+            http.Error(w, fmt.Sprintf("invalid limit %q", value), http.StatusBadRequest)
+            return
+        }
+        limit = parsed
+    }
+    canonical := request.URL.Query().Get("asset_pair")
+    if canonical != "" && len(names) > 1 {
+        names = s.exchangesWith(canonical)
+    }
+    // exchange -> asset pair -> candles, asset pairs without candles are left out
+    response := make(map[string]map[string][]types.Candle)
+    for _, name := range names {
+        assetPairs, ok := s.selectAssetPair(w, name, canonical)
+        if !ok {
+            return
+        }
+        response[name] = make(map[string][]types.Candle)
+        for _, assetPair := range assetPairs {
+            if candles, ok := s.candles.GetCandles(name, assetPair, resolution); ok {
+                if limit > 0 && len(candles) > limit {
+                    candles = candles[len(candles) - limit:]
+                }
+                response[name][assetPair.String()] = candles
+            }
+        }
+    }
+    writeJson(w, response)
+}
+
+func (s *Server) orders(w http.ResponseWriter, request *http.Request) {
+    names, ok := s.selectExchanges(w, request)
+    if !ok {
+        return
+    }
+    response := make(map[string]map[types.OrderId]types.OrderStatus)
+    for _, name := range names {
+        response[name] = s.exchanges[name].GetOpenOrders()
+    }
+    writeJson(w, response)
+}
+
+func (s *Server) latency(w http.ResponseWriter, request *http.Request) {
+    names, ok := s.selectExchanges(w, request)
+    if !ok {
+        return
+    }
+    response := make(map[string]float64)
+    for _, name := range names {
+        response[name] = s.exchanges[name].GetLatency().Seconds()
+    }
+    writeJson(w, response)
+}
+
+// balances and PnL per exchange, both keyed by asset
+func (s *Server) observeBalances(names []string) (map[string]map[types.Asset]decimal.Decimal, map[string]map[types.Asset]decimal.Decimal) {
+    balances := make(map[string]map[types.Asset]decimal.Decimal)
+    pnl := make(map[string]map[types.Asset]decimal.Decimal)
+    for _, name := range names {
+        balances[name] = s.exchanges[name].GetBalances()
+        pnl[name] = s.ledger.Observe(name, balances[name])
+    }
+    return balances, pnl
+}
+
+func (s *Server) balances(w http.ResponseWriter, request *http.Request) {
+    names, ok := s.selectExchanges(w, request)
+    if !ok {
+        return
+    }
+    balances, _ := s.observeBalances(names)
+    writeJson(w, balances)
+}
+
+func (s *Server) pnl(w http.ResponseWriter, request *http.Request) {
+    names, ok := s.selectExchanges(w, request)
+    if !ok {
+        return
+    }
+    _, pnl := s.observeBalances(names)
+    writeJson(w, pnl)
+}
+
+func (s *Server) parsePair(w http.ResponseWriter, request *http.Request) (string, string, bool) {
+    exchange1, exchange2, err := ParsePairKey(request.URL.Query().Get("pair"))
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return "", "", false
+    }
+    for _, name := range []string{exchange1, exchange2} {
+        if _, ok := s.exchanges[name]; !ok {
+            http.Error(w, fmt.Sprintf("unknown exchange %q", name), http.StatusNotFound)
+            return "", "", false
+        }
+    }
+    return exchange1, exchange2, true
+}
+
+func (s *Server) pause(w http.ResponseWriter, request *http.Request) {
+    exchange1, exchange2, ok := s.parsePair(w, request)
+    if !ok {
+        return
+    }
+    s.state.Pause(exchange1, exchange2)
+    logger.Warn("trading paused", "pair", PairKey(exchange1, exchange2))
+    s.status(w, request)
+}
+
+func (s *Server) resume(w http.ResponseWriter, request *http.Request) {
+    exchange1, exchange2, ok := s.parsePair(w, request)
+    if !ok {
+        return
+    }
+    s.state.Resume(exchange1, exchange2)
+    logger.Warn("trading resumed", "pair", PairKey(exchange1, exchange2))
+    s.status(w, request)
+}
+
+// cancels every open order, including ones that were open before the switch was triggered
+func (s *Server) kill(w http.ResponseWriter, request *http.Request) {
+    if s.state.Kill() {
+        logger.Warn("kill switch triggered")
+    }
+    for _, name := range s.names {
+        openOrders := s.exchanges[name].GetOpenOrders()
+        if len(openOrders) == 0 {
+            continue
+        }
+        orderIds := make([]types.OrderId, 0, len(openOrders))
+        for orderId := range openOrders {
+            orderIds = append(orderIds, orderId)
+        }
+        s.exchanges[name].CancelOrders(orderIds)
+        logger.Warn("open orders canceled", logging.Exchange(name), "orders", len(orderIds))
+    }
+    s.status(w, request)
+}
+
+func (s *Server) threshold(w http.ResponseWriter, request *http.Request) {
+    threshold, err := strconv.ParseFloat(request.URL.Query().Get("value"), 64)
+    if err == nil {
+        err = s.state.SetThreshold(threshold)
+    }
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    logger.Warn("threshold changed", "threshold", threshold)
+    s.status(w, request)
+}
+
+// resolves the exchange and asset_pair query parameters, writing the error response when false
+func (s *Server) recorderAssetPair(w http.ResponseWriter, request *http.Request) (types.AssetPairRecorder, types.AssetPair, bool) {
+    name := request.URL.Query().Get("exchange")
+    exchange, ok := s.exchanges[name]
+    if !ok {
+        http.Error(w, fmt.Sprintf("unknown exchange %q", name), http.StatusNotFound)
+        return nil, types.AssetPair{}, false
+    }
+    recorder, ok := exchange.(types.AssetPairRecorder)
+    if !ok {
+        http.Error(w, fmt.Sprintf("%v does not record market data", name), http.StatusBadRequest)
+        return nil, types.AssetPair{}, false
+    }
+    canonical := request.URL.Query().Get("asset_pair")
+    if canonical == "" {
+        http.Error(w, "asset_pair is required", http.StatusBadRequest)
+        return nil, types.AssetPair{}, false
+    }
+    assetPairs, ok := s.selectAssetPair(w, name, canonical)
+    if !ok {
+        return nil, types.AssetPair{}, false
+    }
+    return recorder, assetPairs[0], true
+}
+
+func (s *Server) register(w http.ResponseWriter, request *http.Request) {
+    recorder, assetPair, ok := s.recorderAssetPair(w, request)
+    if !ok {
+        return
+    }
+    recorder.RegisterAssetPair(assetPair)
+    s.state.Register(request.URL.Query().Get("exchange"), assetPair)
+    logger.Info("asset pair registered", logging.Exchange(request.URL.Query().Get("exchange")), logging.AssetPair(assetPair))
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) unregister(w http.ResponseWriter, request *http.Request) {
+    recorder, assetPair, ok := s.recorderAssetPair(w, request)
+    if !ok {
+        return
+    }
+    // before unsubscribing, so that no loop registers it again in between
+    s.state.Unregister(request.URL.Query().Get("exchange"), assetPair)
+    recorder.UnregisterAssetPair(assetPair)
+    logger.Info("asset pair unregistered", logging.Exchange(request.URL.Query().Get("exchange")), logging.AssetPair(assetPair))
+    w.WriteHeader(http.StatusNoContent)
+}
+
+type modelResponse struct {
+    Current  string   `json:"current"`
+    Versions []string `json:"versions"`
+}
+
+func (s *Server) model(w http.ResponseWriter, request *http.Request) {
+    versions, err := s.models.Versions()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    writeJson(w, modelResponse{Current: s.models.Current(), Versions: versions})
+}
+
+func (s *Server) rollback(w http.ResponseWriter, request *http.Request) {
+    from := s.models.Current()
+    version, err := s.models.Rollback()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusConflict)
+        return
+    }
+    logger.Warn("model rolled back", "from", from, "to", version)
+    s.model(w, request)
+}
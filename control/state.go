@@ -0,0 +1,165 @@
+package control
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+
+    "github.com/denali-capital/grizzly/types"
+)
+
+// runtime switches flipped through the API, read by the strategies and the coordinator
+type State struct {
+    mutex     sync.RWMutex
+    // pair key -> struct{}
+    paused    map[string]struct{}
+    killed    bool
+    // minimum model probability to act on a pairwise opportunity
+    threshold float64
+    // exchange -> asset pairs unregistered through the API, left out of every loop polling
+    // market data so that polling does not register them again
+    unregistered map[string]map[types.AssetPair]struct{}
+}
+
+func NewState(threshold float64) *State {
+    return &State{
+        paused: make(map[string]struct{}),
+        threshold: threshold,
+        unregistered: make(map[string]map[types.AssetPair]struct{}),
+    }
+}
+
+// exchange pairs are unordered, Kraken/KuCoin and KuCoin/Kraken are the same pair
+func PairKey(exchange1, exchange2 string) string {
+    if exchange2 < exchange1 {
+        exchange1, exchange2 = exchange2, exchange1
+    }
+    return exchange1 + "/" + exchange2
+}
+
+func ParsePairKey(key string) (string, string, error) {
+    exchanges := strings.Split(key, "/")
+    if len(exchanges) != 2 || exchanges[0] == "" || exchanges[1] == "" || exchanges[0] == exchanges[1] {
+        return "", "", fmt.Errorf("expected two different exchanges as Exchange1/Exchange2, got %q", key)
+    }
+    return exchanges[0], exchanges[1], nil
+}
+
+func (s *State) Pause(exchange1, exchange2 string) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    s.paused[PairKey(exchange1, exchange2)] = struct{}{}
+}
+
+func (s *State) Resume(exchange1, exchange2 string) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    delete(s.paused, PairKey(exchange1, exchange2))
+}
+
+func (s *State) Paused(exchange1, exchange2 string) bool {
+    s.mutex.RLock()
+    defer s.mutex.RUnlock()
+    _, ok := s.paused[PairKey(exchange1, exchange2)]
+    return ok
+}
+
+// sorted pair keys
+func (s *State) PausedPairs() []string {
+    s.mutex.RLock()
+    defer s.mutex.RUnlock()
+    keys := make([]string, 0, len(s.paused))
+    for key := range s.paused {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func (s *State) Register(exchange string, assetPair types.AssetPair) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    delete(s.unregistered[exchange], assetPair)
+}
+
+func (s *State) Unregister(exchange string, assetPair types.AssetPair) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    if _, ok := s.unregistered[exchange]; !ok {
+        s.unregistered[exchange] = make(map[types.AssetPair]struct{})
+    }
+    s.unregistered[exchange][assetPair] = struct{}{}
+}
+
+// assetPairs without those unregistered on exchange
+func (s *State) Registered(exchange string, assetPairs []types.AssetPair) []types.AssetPair {
+    s.mutex.RLock()
+    defer s.mutex.RUnlock()
+    registered := make([]types.AssetPair, 0, len(assetPairs))
+    for _, assetPair := range assetPairs {
+        if _, ok := s.unregistered[exchange][assetPair]; !ok {
+            registered = append(registered, assetPair)
+        }
+    }
+    return registered
+}
+
+// exchange -> asset pairs, without those unregistered
+func (s *State) RegisteredAssetPairs(assetPairs map[string][]types.AssetPair) map[string][]types.AssetPair {
+    registered := make(map[string][]types.AssetPair, len(assetPairs))
+    for exchange, pairs := range assetPairs {
+        registered[exchange] = s.Registered(exchange, pairs)
+    }
+    return registered
+}
+
+// stops all trading until the process is restarted, returns false if it was already triggered
+func (s *State) Kill() bool {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    if s.killed {
+        return false
+    }
+    s.killed = true
+    return true
+}
+
+func (s *State) Killed() bool {
+    s.mutex.RLock()
+    defer s.mutex.RUnlock()
+    return s.killed
+}
+
+func (s *State) Threshold() float64 {
+    s.mutex.RLock()
+    defer s.mutex.RUnlock()
+    return s.threshold
+}
+
+func (s *State) SetThreshold(threshold float64) error {
+    if threshold < 0 || threshold > 1 {
+        return fmt.Errorf("threshold must be a probability in [0, 1], got %v", threshold)
+    }
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    s.threshold = threshold
+    return nil
+}
+
+// false once killed, or if any two exchanges the opportunity trades on are paused
+func (s *State) Allows(opportunity types.Opportunity) bool {
+    s.mutex.RLock()
+    defer s.mutex.RUnlock()
+    if s.killed {
+        return false
+    }
+    for i := 0; i < len(opportunity.Legs); i++ {
+        for j := i + 1; j < len(opportunity.Legs); j++ {
+            if _, ok := s.paused[PairKey(opportunity.Legs[i].Exchange, opportunity.Legs[j].Exchange)]; ok {
+                return false
+            }
+        }
+    }
+    return true
+}
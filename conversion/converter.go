@@ -0,0 +1,158 @@
+package conversion
+
+import (
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+var logger *logging.Logger = logging.New("conversion")
+
+// treats a set of assets (e.g. USD, USDT, USDC) as interchangeable at live market rates
+type Converter struct {
+    numeraire types.Asset
+    sources   map[types.Asset]source
+}
+
+type source struct {
+    exchange  types.Exchange
+    assetPair types.AssetPair
+    // fraction charged on top of the book price for every conversion into or out of the numeraire
+    cost      decimal.Decimal
+}
+
+func NewConverter(numeraire types.Asset) *Converter {
+    return &Converter{
+        numeraire: numeraire,
+        sources: make(map[types.Asset]source),
+    }
+}
+
+func (c *Converter) Numeraire() types.Asset {
+    return c.numeraire
+}
+
+// asset is priced against the numeraire from the live spread of assetPair on exchange
+// assetPair must be asset/numeraire or numeraire/asset
+func (c *Converter) AddEquivalent(asset types.Asset, exchange types.Exchange, assetPair types.AssetPair, cost decimal.Decimal) {
+    if !(assetPair.Base == asset && assetPair.Quote == c.numeraire) && !(assetPair.Base == c.numeraire && assetPair.Quote == asset) {
+        logger.Fatal("asset pair cannot price asset against the numeraire", logging.Exchange(exchange), logging.AssetPair(assetPair), "asset", asset, "numeraire", c.numeraire)
+    }
+    c.sources[asset] = source{
+        exchange: exchange,
+        assetPair: assetPair,
+        cost: cost,
+    }
+}
+
+func (c *Converter) Assets() []types.Asset {
+    assets := make([]types.Asset, 0, len(c.sources) + 1)
+    assets = append(assets, c.numeraire)
+    for asset := range c.sources {
+        assets = append(assets, asset)
+    }
+    return assets
+}
+
+func (c *Converter) known(asset types.Asset) bool {
+    if asset == c.numeraire {
+        return true
+    }
+    _, ok := c.sources[asset]
+    return ok
+}
+
+func (c *Converter) Equivalent(a, b types.Asset) bool {
+    return c.known(a) && c.known(b)
+}
+
+// same base and equivalent quotes, e.g. BTCUSD and BTCUSDT
+func (c *Converter) EquivalentAssetPairs(a, b types.AssetPair) bool {
+    return a.Base == b.Base && c.Equivalent(a.Quote, b.Quote)
+}
+
+// units of numeraire received for one unit of asset, crossing the spread
+func (c *Converter) toNumeraire(asset types.Asset) decimal.Decimal {
+    if asset == c.numeraire {
+        return decimal.NewFromInt(1)
+    }
+    s := c.sources[asset]
+    spread := s.exchange.GetCurrentSpread(s.assetPair)
+    if spread.Ask.IsZero() {
+        // nothing recorded yet
+        return decimal.Zero
+    }
+    if s.assetPair.Base == asset {
+        // sell asset at the bid
+        return spread.Bid
+    }
+    // buy numeraire at the ask
+    return decimal.NewFromInt(1).Div(spread.Ask)
+}
+
+// units of asset received for one unit of numeraire, crossing the spread
+func (c *Converter) fromNumeraire(asset types.Asset) decimal.Decimal {
+    if asset == c.numeraire {
+        return decimal.NewFromInt(1)
+    }
+    s := c.sources[asset]
+    spread := s.exchange.GetCurrentSpread(s.assetPair)
+    if spread.Ask.IsZero() {
+        return decimal.Zero
+    }
+    if s.assetPair.Base == asset {
+        // buy asset at the ask
+        return decimal.NewFromInt(1).Div(spread.Ask)
+    }
+    // sell numeraire at the bid
+    return spread.Bid
+}
+
+func (c *Converter) mid(asset types.Asset) decimal.Decimal {
+    if asset == c.numeraire {
+        return decimal.NewFromInt(1)
+    }
+    s := c.sources[asset]
+    spread := s.exchange.GetCurrentSpread(s.assetPair)
+    mid := spread.Bid.Add(spread.Ask).Div(decimal.NewFromInt(2))
+    if mid.IsZero() {
+        return decimal.Zero
+    }
+    if s.assetPair.Base == asset {
+        return mid
+    }
+    return decimal.NewFromInt(1).Div(mid)
+}
+
+// units of to received for one unit of from, net of the spread and the configured cost
+// zero until the spreads involved have been recorded
+func (c *Converter) Rate(from, to types.Asset) (decimal.Decimal, bool) {
+    if !c.Equivalent(from, to) {
+        return decimal.Zero, false
+    }
+    if from == to {
+        return decimal.NewFromInt(1), true
+    }
+    rate := c.toNumeraire(from).Mul(c.fromNumeraire(to))
+    one := decimal.NewFromInt(1)
+    if s, ok := c.sources[from]; ok {
+        rate = rate.Mul(one.Sub(s.cost))
+    }
+    if s, ok := c.sources[to]; ok {
+        rate = rate.Mul(one.Sub(s.cost))
+    }
+    return rate, true
+}
+
+// fraction lost converting from into to relative to the mid price
+func (c *Converter) Cost(from, to types.Asset) (decimal.Decimal, bool) {
+    rate, ok := c.Rate(from, to)
+    if !ok {
+        return decimal.Zero, false
+    }
+    if c.mid(from).IsZero() || c.mid(to).IsZero() {
+        return decimal.Zero, false
+    }
+    mid := c.mid(from).Div(c.mid(to))
+    return decimal.NewFromInt(1).Sub(rate.Div(mid)), true
+}
@@ -0,0 +1,199 @@
+package dashboard
+
+import (
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+    "text/tabwriter"
+    "time"
+
+    "github.com/denali-capital/grizzly/types"
+    "github.com/denali-capital/grizzly/util"
+    "github.com/shopspring/decimal"
+)
+
+// clears the terminal and moves the cursor to the top left
+const ClearScreen string = "\x1b[H\x1b[2J"
+
+// everything one frame is drawn from, keyed by exchange
+type Snapshot struct {
+    Spreads    map[string]map[types.AssetPair]types.Spread
+    OrderBooks map[string]map[types.AssetPair]*types.OrderBook
+    // one minute candles, oldest first
+    Candles    map[string]map[types.AssetPair][]types.Candle
+    Latencies  map[string]time.Duration
+}
+
+// minute candles the trailing change and volume are computed over
+const HourCandles int = 60
+
+type Options struct {
+    // exchange -> fraction of the notional charged per fill
+    Fees  map[string]decimal.Decimal
+    // base quantities slippage is shown at
+    Sizes []decimal.Decimal
+}
+
+// buying at Buy's ask and selling at Sell's bid, Edge is the fee-adjusted return as a fraction
+type Edge struct {
+    Buy  string
+    Sell string
+    Edge decimal.Decimal
+}
+
+var one decimal.Decimal = decimal.NewFromInt(1)
+var hundred decimal.Decimal = decimal.NewFromInt(100)
+
+// best edge across every ordered pair of exchanges quoting the asset pair, false with fewer than two quotes
+func BestEdge(spreads map[string]types.Spread, fees map[string]decimal.Decimal) (Edge, bool) {
+    exchanges := make([]string, 0, len(spreads))
+    for exchange, spread := range spreads {
+        if spread.Ask.IsPositive() && spread.Bid.IsPositive() {
+            exchanges = append(exchanges, exchange)
+        }
+    }
+    // deterministic choice between equal edges
+    sort.Strings(exchanges)
+
+    best := Edge{}
+    found := false
+    for _, buy := range exchanges {
+        for _, sell := range exchanges {
+            if buy == sell {
+                continue
+            }
+            cost := spreads[buy].Ask.Mul(one.Add(fees[buy]))
+            proceeds := spreads[sell].Bid.Mul(one.Sub(fees[sell]))
+            edge := proceeds.Sub(cost).Div(spreads[buy].Ask)
+            if !found || edge.GreaterThan(best.Edge) {
+                best = Edge{buy, sell, edge}
+                found = true
+            }
+        }
+    }
+    return best, found
+}
+
+// asset pairs quoted on at least two exchanges, sorted by name
+func CommonAssetPairs(spreads map[string]map[types.AssetPair]types.Spread) []types.AssetPair {
+    counts := make(map[types.AssetPair]int)
+    for _, assetPairs := range spreads {
+        for assetPair := range assetPairs {
+            counts[assetPair]++
+        }
+    }
+    common := make([]types.AssetPair, 0)
+    for assetPair, count := range counts {
+        if count > 1 {
+            common = append(common, assetPair)
+        }
+    }
+    sort.Slice(common, func(i, j int) bool {
+        return common[i].String() < common[j].String()
+    })
+    return common
+}
+
+func sortedExchanges(m map[string]map[types.AssetPair]types.Spread) []string {
+    exchanges := make([]string, 0, len(m))
+    for exchange := range m {
+        exchanges = append(exchanges, exchange)
+    }
+    sort.Strings(exchanges)
+    return exchanges
+}
+
+func percent(fraction decimal.Decimal) string {
+    return fraction.Mul(hundred).StringFixed(3) + "%"
+}
+
+func age(spread types.Spread, now time.Time) string {
+    if spread.Timestamp.IsZero() {
+        return "-"
+    }
+    return now.Sub(spread.Timestamp).Round(time.Millisecond).String()
+}
+
+func totalQuantity(side []types.OrderBookEntry) decimal.Decimal {
+    total := decimal.Zero
+    for _, entry := range side {
+        total = total.Add(entry.Quantity)
+    }
+    return total
+}
+
+// "thin" when either side of the book cannot fill size
+func slippage(orderBook *types.OrderBook, size decimal.Decimal) string {
+    if orderBook == nil || len(orderBook.Bids) == 0 || len(orderBook.Asks) == 0 {
+        return "-"
+    }
+    if totalQuantity(orderBook.Bids).LessThan(size) || totalQuantity(orderBook.Asks).LessThan(size) {
+        return "thin"
+    }
+    return percent(util.ComputeSlippage(orderBook, size))
+}
+
+// change of the close and traded base volume over the trailing hour of minute candles
+func hourly(candles []types.Candle) (string, string) {
+    if len(candles) > HourCandles {
+        candles = candles[len(candles) - HourCandles:]
+    }
+    if len(candles) == 0 || !candles[0].Open.IsPositive() {
+        return "-", "-"
+    }
+    volume := decimal.Zero
+    for _, candle := range candles {
+        volume = volume.Add(candle.Volume)
+    }
+    change := candles[len(candles) - 1].Close.Sub(candles[0].Open).Div(candles[0].Open)
+    return percent(change), volume.String()
+}
+
+// draws one frame, callers clear the screen first
+func Render(w io.Writer, snapshot Snapshot, options Options, now time.Time) error {
+    latencies := make([]string, 0, len(snapshot.Latencies))
+    for _, exchange := range sortedExchanges(snapshot.Spreads) {
+        if latency, ok := snapshot.Latencies[exchange]; ok {
+            latencies = append(latencies, fmt.Sprintf("%v %v", exchange, latency.Round(time.Millisecond)))
+        }
+    }
+    fmt.Fprintf(w, "grizzly top  %v  latency: %v\n\n", now.Format("15:04:05.000"), strings.Join(latencies, "  "))
+
+    table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+    header := "exchange\tbid\task\tage\tchg 1h\tvol 1h\t"
+    for _, size := range options.Sizes {
+        header += "slip@" + size.String() + "\t"
+    }
+
+    for _, assetPair := range CommonAssetPairs(snapshot.Spreads) {
+        quotes := make(map[string]types.Spread)
+        for exchange, spreads := range snapshot.Spreads {
+            if spread, ok := spreads[assetPair]; ok {
+                quotes[exchange] = spread
+            }
+        }
+
+        edgeLine := "no quotes"
+        if edge, ok := BestEdge(quotes, options.Fees); ok {
+            edgeLine = fmt.Sprintf("edge %v buy %v sell %v", percent(edge.Edge), edge.Buy, edge.Sell)
+        }
+        fmt.Fprintf(table, "%v  %v\n", assetPair, edgeLine)
+        fmt.Fprintln(table, header)
+
+        for _, exchange := range sortedExchanges(snapshot.Spreads) {
+            spread, ok := quotes[exchange]
+            if !ok {
+                continue
+            }
+            change, volume := hourly(snapshot.Candles[exchange][assetPair])
+            row := fmt.Sprintf("%v\t%v\t%v\t%v\t%v\t%v\t", exchange, spread.Bid, spread.Ask, age(spread, now), change, volume)
+            for _, size := range options.Sizes {
+                row += slippage(snapshot.OrderBooks[exchange][assetPair], size) + "\t"
+            }
+            fmt.Fprintln(table, row)
+        }
+        fmt.Fprintln(table)
+    }
+    return table.Flush()
+}
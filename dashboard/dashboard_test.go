@@ -0,0 +1,100 @@
+package dashboard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+var BTCUSD types.AssetPair = types.NewAssetPair("BTC", "USD")
+var ETHUSD types.AssetPair = types.NewAssetPair("ETH", "USD")
+
+func spread(bid, ask int64, timestamp time.Time) types.Spread {
+	return types.Spread{
+		Bid:       decimal.NewFromInt(bid),
+		Ask:       decimal.NewFromInt(ask),
+		Timestamp: timestamp,
+	}
+}
+
+func TestBestEdge(t *testing.T) {
+	spreads := map[string]types.Spread{
+		"Kraken": spread(99, 100, time.Time{}),
+		"KuCoin": spread(102, 103, time.Time{}),
+	}
+	fees := map[string]decimal.Decimal{"Kraken": decimal.NewFromFloat(0.01), "KuCoin": decimal.NewFromFloat(0.01)}
+
+	edge, ok := BestEdge(spreads, fees)
+	// buy at 101 all in, sell at 100.98 all in
+	if !ok || edge.Buy != "Kraken" || edge.Sell != "KuCoin" || !edge.Edge.Equal(decimal.NewFromFloat(-0.0002)) {
+		t.Fatalf("unexpected edge %+v\n", edge)
+	}
+
+	if _, ok := BestEdge(map[string]types.Spread{"Kraken": spreads["Kraken"]}, fees); ok {
+		t.Fatalf("expected no edge from a single quote\n")
+	}
+}
+
+func TestCommonAssetPairs(t *testing.T) {
+	common := CommonAssetPairs(map[string]map[types.AssetPair]types.Spread{
+		"Kraken": {BTCUSD: {}, ETHUSD: {}},
+		"KuCoin": {BTCUSD: {}},
+	})
+	if len(common) != 1 || common[0] != BTCUSD {
+		t.Fatalf("expected only BTCUSD, got %v\n", common)
+	}
+}
+
+func TestRender(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	book := &types.OrderBook{
+		Bids: []types.OrderBookEntry{{Price: decimal.NewFromInt(99), Quantity: decimal.NewFromInt(1)}},
+		Asks: []types.OrderBookEntry{{Price: decimal.NewFromInt(101), Quantity: decimal.NewFromInt(1)}},
+	}
+	snapshot := Snapshot{
+		Spreads: map[string]map[types.AssetPair]types.Spread{
+			"Kraken": {BTCUSD: spread(99, 101, now.Add(-150 * time.Millisecond))},
+			"KuCoin": {BTCUSD: spread(102, 103, now.Add(-2 * time.Second))},
+		},
+		OrderBooks: map[string]map[types.AssetPair]*types.OrderBook{
+			"Kraken": {BTCUSD: book},
+		},
+		Candles: map[string]map[types.AssetPair][]types.Candle{
+			"Kraken": {BTCUSD: {
+				{Open: decimal.NewFromInt(80), Close: decimal.NewFromInt(98), Volume: decimal.NewFromInt(5)},
+				{Open: decimal.NewFromInt(98), Close: decimal.NewFromInt(100), Volume: decimal.NewFromFloat(2.5)},
+			}},
+		},
+		Latencies: map[string]time.Duration{"Kraken": 120 * time.Millisecond},
+	}
+	options := Options{
+		Sizes: []decimal.Decimal{decimal.NewFromFloat(0.5), decimal.NewFromInt(2)},
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := Render(buffer, snapshot, options, now); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	output := buffer.String()
+	for _, expected := range []string{
+		"latency: Kraken 120ms",
+		"BTCUSD  edge 0.990% buy Kraken sell KuCoin",
+		"slip@0.5",
+		"150ms",
+		"2s",
+		// from the first open to the last close of the trailing hour
+		"25.000%",
+		"7.5",
+		// half the quoted spread at 0.5, the book cannot fill 2
+		"1.000%",
+		"thin",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in:\n%v\n", expected, output)
+		}
+	}
+}
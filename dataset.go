@@ -0,0 +1,141 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/denali-capital/grizzly/config"
+    "github.com/denali-capital/grizzly/dataset"
+    "github.com/denali-capital/grizzly/features"
+    "github.com/denali-capital/grizzly/types"
+)
+
+const datasetDirectory string = "data/datasets"
+
+// labeled observations written by grizzly label -out
+func readObservations(path string) ([]types.Observation, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+    observations := make([]types.Observation, 0)
+    decoder := json.NewDecoder(file)
+    for {
+        observation := types.Observation{}
+        err := decoder.Decode(&observation)
+        if err == io.EOF {
+            return observations, nil
+        }
+        if err != nil {
+            return nil, fmt.Errorf("%v: %v", path, err)
+        }
+        observations = append(observations, observation)
+    }
+}
+
+// turns recordings, or labeled observation logs, into time split feature/label datasets
+func datasetCommand(ctx context.Context, cfg *config.Config, args []string) error {
+    flags := flag.NewFlagSet("dataset", flag.ExitOnError)
+    out := flags.String("out", "", "directory to write train, validation and test files to (default " + datasetDirectory + "/<unix time>)")
+    formats := flags.String("format", "csv,parquet", "comma separated formats to write, csv and/or parquet")
+    validation := flags.Float64("validation", 0.15, "fraction of observations, the newest before test, to validate on")
+    test := flags.Float64("test", 0.15, "fraction of observations, the newest, to test on")
+    embargo := flags.Duration("embargo", cfg.LabelHorizon(), "observations this close before a split's start are dropped, since their labels look into it")
+    step := flags.Duration("step", cfg.Trading.SleepDuration.Duration, "recorded time between opportunity detections")
+    logs := flags.Bool("observations", false, "inputs are labeled observations written by grizzly label -out rather than recordings")
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: grizzly dataset [flags] input...\n")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+    if flags.NArg() == 0 {
+        flags.Usage()
+        return errors.New("at least one input is required")
+    }
+    if *validation < 0 || *test < 0 || *validation + *test >= 1 {
+        return errors.New("-validation and -test must not be negative and must leave observations to train on")
+    }
+    for _, format := range strings.Split(*formats, ",") {
+        if format != "csv" && format != "parquet" {
+            return fmt.Errorf("unknown format %q, expected csv or parquet", format)
+        }
+    }
+
+    observations := make([]types.Observation, 0)
+    for _, path := range flags.Args() {
+        if *logs {
+            logged, err := readObservations(path)
+            if err != nil {
+                return err
+            }
+            observations = append(observations, logged...)
+            continue
+        }
+        summary, err := labelRecording(ctx, cfg, path, *step, func(observation types.Observation) error {
+            observations = append(observations, observation)
+            return nil
+        })
+        if err != nil {
+            return err
+        }
+        printLabelSummary(path, summary)
+    }
+    if len(observations) == 0 {
+        return errors.New("no labeled observations in the inputs")
+    }
+    // features are only comparable within one schema
+    schema, err := features.LookupSchema(observations[0].Schema)
+    if err != nil {
+        return err
+    }
+    for _, observation := range observations {
+        if observation.Schema != schema.String() {
+            return fmt.Errorf("inputs mix %v and %v observations", schema, observation.Schema)
+        }
+    }
+
+    directory := *out
+    if directory == "" {
+        directory = filepath.Join(datasetDirectory, fmt.Sprintf("%v", time.Now().Unix()))
+    }
+    if err := os.MkdirAll(directory, 0755); err != nil {
+        return err
+    }
+    splits := dataset.Split(observations, *validation, *test, *embargo)
+    fmt.Printf("\n%-10v %10v %10v %25v %25v\n", "split", "rows", "profitable", "start", "end")
+    for _, split := range []struct {
+        name         string
+        observations []types.Observation
+    }{
+        {"train", splits.Train},
+        {"validation", splits.Validation},
+        {"test", splits.Test},
+    } {
+        metadata := dataset.NewMetadata(schema.String(), schema.Features, split.name, split.observations)
+        for _, format := range strings.Split(*formats, ",") {
+            write := dataset.WriteCSV
+            if format == "parquet" {
+                write = dataset.WriteParquet
+            }
+            if err := write(filepath.Join(directory, split.name + "." + format), metadata, split.observations); err != nil {
+                return err
+            }
+        }
+        profitable := 0
+        for _, observation := range split.observations {
+            profitable += int(observation.Label)
+        }
+        fmt.Printf("%-10v %10v %10v %25v %25v\n", split.name, metadata.Rows, profitable, metadata.Start.Format(time.RFC3339), metadata.End.Format(time.RFC3339))
+    }
+    fmt.Printf("\nwrote %v datasets to %v\n", schema, directory)
+    return nil
+}
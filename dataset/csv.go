@@ -0,0 +1,49 @@
+package dataset
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "os"
+    "strconv"
+    "time"
+
+    "github.com/denali-capital/grizzly/types"
+)
+
+// CSV with a header row, preceded by a # comment line holding the JSON encoded metadata,
+// e.g. for pandas.read_csv(path, comment="#")
+func WriteCSV(path string, metadata Metadata, observations []types.Observation) error {
+    if err := check(metadata, observations); err != nil {
+        return err
+    }
+    encoded, err := json.Marshal(metadata)
+    if err != nil {
+        return err
+    }
+    file, err := os.OpenFile(path, os.O_CREATE | os.O_EXCL | os.O_WRONLY, 0644)
+    if err != nil {
+        return err
+    }
+    if _, err := fmt.Fprintf(file, "# %s\n", encoded); err != nil {
+        file.Close()
+        return err
+    }
+
+    writer := csv.NewWriter(file)
+    writer.Write(Columns(metadata.Features))
+    record := make([]string, 0, len(metadata.Features) + 6)
+    for _, observation := range observations {
+        record = append(record[:0], observation.Timestamp.UTC().Format(time.RFC3339Nano), observation.Exchange1, observation.Exchange2, observation.AssetPair.String())
+        for _, feature := range observation.Features {
+            record = append(record, strconv.FormatFloat(float64(feature), 'g', -1, 32))
+        }
+        writer.Write(append(record, strconv.Itoa(int(observation.Label)), strconv.FormatFloat(observation.Profit, 'g', -1, 64)))
+    }
+    writer.Flush()
+    if err := writer.Error(); err != nil {
+        file.Close()
+        return err
+    }
+    return file.Close()
+}
@@ -0,0 +1,102 @@
+package dataset
+
+import (
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/denali-capital/grizzly/types"
+)
+
+// key of the JSON encoded Metadata in Parquet footers
+const MetadataKey string = "grizzly.dataset"
+
+// describes one split of a dataset, embedded in every file written so that features are
+// never read against the wrong layout
+type Metadata struct {
+    // feature schema as name/version, e.g. grizzly/1
+    Schema   string    `json:"schema"`
+    // in column order
+    Features []string  `json:"features"`
+    // train, validation or test
+    Split    string    `json:"split"`
+    Rows     int       `json:"rows"`
+    // first and last observation timestamps
+    Start    time.Time `json:"start"`
+    End      time.Time `json:"end"`
+}
+
+// every column written, in order
+func Columns(features []string) []string {
+    columns := []string{"timestamp", "exchange_1", "exchange_2", "asset_pair"}
+    columns = append(columns, features...)
+    return append(columns, "label", "profit")
+}
+
+func NewMetadata(schema string, features []string, split string, observations []types.Observation) Metadata {
+    metadata := Metadata{
+        Schema: schema,
+        Features: features,
+        Split: split,
+        Rows: len(observations),
+    }
+    if len(observations) > 0 {
+        metadata.Start = observations[0].Timestamp
+        metadata.End = observations[len(observations) - 1].Timestamp
+    }
+    return metadata
+}
+
+// errors unless every observation has metadata's schema and number of features
+func check(metadata Metadata, observations []types.Observation) error {
+    for _, observation := range observations {
+        if observation.Schema != metadata.Schema || len(observation.Features) != len(metadata.Features) {
+            return fmt.Errorf("expected %v observations with %v features, got %v with %v", metadata.Schema, len(metadata.Features), observation.Schema, len(observation.Features))
+        }
+    }
+    return nil
+}
+
+type Splits struct {
+    Train      []types.Observation
+    Validation []types.Observation
+    Test       []types.Observation
+}
+
+// the observations strictly before cutoff - embargo, and those at or after cutoff
+func cut(observations []types.Observation, cutoff time.Time, embargo time.Duration) ([]types.Observation, []types.Observation) {
+    before := sort.Search(len(observations), func(i int) bool {
+        return !observations[i].Timestamp.Before(cutoff.Add(-embargo))
+    })
+    after := sort.Search(len(observations), func(i int) bool {
+        return !observations[i].Timestamp.Before(cutoff)
+    })
+    return observations[:before], observations[after:]
+}
+
+// splits observations by time, oldest into train and newest into test, with roughly the given
+// fractions of rows in validation and test; labels look forward, so observations within
+// embargo before a split's start are dropped rather than leak what the next split sees
+//
+// observations sharing a timestamp always land in the same split
+func Split(observations []types.Observation, validation, test float64, embargo time.Duration) Splits {
+    sorted := append([]types.Observation{}, observations...)
+    sort.SliceStable(sorted, func(i, j int) bool {
+        return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+    })
+    splits := Splits{Train: sorted, Validation: []types.Observation{}, Test: []types.Observation{}}
+    if len(sorted) == 0 {
+        return splits
+    }
+
+    testIndex := int(float64(len(sorted)) * (1 - test))
+    validationIndex := int(float64(len(sorted)) * (1 - test - validation))
+    if testIndex < len(sorted) && test > 0 {
+        splits.Train, splits.Test = cut(sorted, sorted[testIndex].Timestamp, embargo)
+    }
+    if validationIndex < len(splits.Train) && validation > 0 {
+        rest := splits.Train
+        splits.Train, splits.Validation = cut(rest, rest[validationIndex].Timestamp, embargo)
+    }
+    return splits
+}
@@ -0,0 +1,122 @@
+package dataset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/types"
+)
+
+var start time.Time = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var features []string = []string{"price_delta", "latency_1"}
+
+func observations(count int) []types.Observation {
+	result := make([]types.Observation, count)
+	// newest first, Split sorts them
+	for i := range result {
+		result[i] = types.Observation{
+			Exchange1: "Kraken",
+			Exchange2: "BinanceUS",
+			AssetPair: types.NewAssetPair("BTC", "USD"),
+			Timestamp: start.Add(time.Duration(count - 1 - i) * time.Second),
+			Schema: "grizzly/1",
+			Features: []float32{float32(count - 1 - i), 0.25},
+			Label: int32(i % 2),
+			Profit: float64(i % 2) - 0.5,
+		}
+	}
+	return result
+}
+
+func TestSplit(t *testing.T) {
+	splits := Split(observations(20), 0.2, 0.2, 0)
+	if len(splits.Train) != 12 || len(splits.Validation) != 4 || len(splits.Test) != 4 {
+		t.Fatalf("expected 12, 4 and 4 observations, got %v %v %v\n", len(splits.Train), len(splits.Validation), len(splits.Test))
+	}
+	if !splits.Train[11].Timestamp.Before(splits.Validation[0].Timestamp) || !splits.Validation[3].Timestamp.Before(splits.Test[0].Timestamp) {
+		t.Fatalf("expected splits ordered by time\n")
+	}
+
+	// the 2 seconds before validation and test starts are dropped
+	splits = Split(observations(20), 0.2, 0.2, 2 * time.Second)
+	if len(splits.Train) != 10 || len(splits.Validation) != 2 || len(splits.Test) != 4 {
+		t.Fatalf("expected 10, 2 and 4 observations, got %v %v %v\n", len(splits.Train), len(splits.Validation), len(splits.Test))
+	}
+
+	splits = Split(observations(20), 0, 0, time.Second)
+	if len(splits.Train) != 20 || len(splits.Validation) != 0 || len(splits.Test) != 0 {
+		t.Fatalf("expected every observation in train, got %v %v %v\n", len(splits.Train), len(splits.Validation), len(splits.Test))
+	}
+}
+
+func TestWrite(t *testing.T) {
+	directory, err := ioutil.TempDir("", "dataset")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(directory)
+	train := Split(observations(5), 0, 0, 0).Train
+	metadata := NewMetadata("grizzly/1", features, "train", train)
+
+	path := filepath.Join(directory, "train.csv")
+	if err := WriteCSV(path, metadata, train); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if len(records) != 6 || strings.Join(records[0], ",") != "timestamp,exchange_1,exchange_2,asset_pair,price_delta,latency_1,label,profit" {
+		t.Fatalf("expected a header and 5 rows, got %v\n", records)
+	}
+	if strings.Join(records[2], ",") != "2022-01-01T00:00:01Z,Kraken,BinanceUS,BTCUSD,1,0.25,1,0.5" {
+		t.Fatalf("unexpected row %v\n", records[2])
+	}
+	contents, _ := ioutil.ReadFile(path)
+	decoded := Metadata{}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(strings.SplitN(string(contents), "\n", 2)[0], "# ")), &decoded); err != nil || decoded.Schema != "grizzly/1" || decoded.Rows != 5 || !decoded.End.Equal(start.Add(4 * time.Second)) {
+		t.Fatalf("expected the metadata comment, got %+v %v\n", decoded, err)
+	}
+
+	path = filepath.Join(directory, "train.parquet")
+	if err := WriteParquet(path, metadata, train); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	contents, _ = ioutil.ReadFile(path)
+	if !bytes.HasPrefix(contents, []byte("PAR1")) || !bytes.HasSuffix(contents, []byte("PAR1")) {
+		t.Fatalf("expected Parquet magic\n")
+	}
+	footerLength := int(binary.LittleEndian.Uint32(contents[len(contents) - 8:]))
+	footer := contents[len(contents) - 8 - footerLength:len(contents) - 8]
+	encoded, _ := json.Marshal(metadata)
+	if !bytes.Contains(footer, []byte(MetadataKey)) || !bytes.Contains(footer, encoded) {
+		t.Fatalf("expected the metadata in the footer\n")
+	}
+	// the timestamp column's page header ends with its RLE repetition level encoding and two
+	// struct stops, followed by its PLAIN encoded values
+	page := contents[4:]
+	offset := bytes.Index(page, []byte{0x15, 0x06, 0, 0}) + 4
+	if value := int64(binary.LittleEndian.Uint64(page[offset:])); value != start.UnixNano() / 1000 {
+		t.Fatalf("expected the first timestamp to be %v, got %v\n", start.UnixNano() / 1000, value)
+	}
+
+	if err := WriteCSV(filepath.Join(directory, "mismatched.csv"), NewMetadata("grizzly/2", features, "train", train), train); err == nil {
+		t.Fatalf("expected an error for observations of another schema\n")
+	}
+}
@@ -0,0 +1,272 @@
+package dataset
+
+import (
+    "bytes"
+    "encoding/binary"
+    "encoding/json"
+    "math"
+    "os"
+
+    "github.com/denali-capital/grizzly/types"
+)
+
+// the subset of the Parquet format written here: one row group of required columns, each a
+// single PLAIN encoded, uncompressed data page, described by a Thrift compact protocol footer
+
+// parquet.thrift Type
+const (
+    parquetInt32     int32 = 1
+    parquetInt64     int32 = 2
+    parquetFloat     int32 = 4
+    parquetDouble    int32 = 5
+    parquetByteArray int32 = 6
+)
+
+// parquet.thrift ConvertedType
+const (
+    convertedUTF8            int32 = 0
+    convertedTimestampMicros int32 = 10
+)
+
+// Thrift compact protocol types
+const (
+    thriftI32    byte = 5
+    thriftI64    byte = 6
+    thriftBinary byte = 8
+    thriftList   byte = 9
+    thriftStruct byte = 12
+)
+
+const (
+    encodingPlain int32 = 0
+    encodingRLE   int32 = 3
+)
+
+// writes Thrift compact protocol structs
+type thrift struct {
+    buffer bytes.Buffer
+    // last field id of every struct being written, innermost last
+    fields []int16
+}
+
+func (t *thrift) varint(v uint64) {
+    var b [binary.MaxVarintLen64]byte
+    t.buffer.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (t *thrift) zigzag(v int64) {
+    t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thrift) field(id int16, kind byte) {
+    last := &t.fields[len(t.fields) - 1]
+    if delta := id - *last; delta > 0 && delta <= 15 {
+        t.buffer.WriteByte(byte(delta) << 4 | kind)
+    } else {
+        t.buffer.WriteByte(kind)
+        t.zigzag(int64(id))
+    }
+    *last = id
+}
+
+func (t *thrift) i32(id int16, v int32) {
+    t.field(id, thriftI32)
+    t.zigzag(int64(v))
+}
+
+func (t *thrift) i64(id int16, v int64) {
+    t.field(id, thriftI64)
+    t.zigzag(v)
+}
+
+func (t *thrift) rawString(s string) {
+    t.varint(uint64(len(s)))
+    t.buffer.WriteString(s)
+}
+
+func (t *thrift) string(id int16, s string) {
+    t.field(id, thriftBinary)
+    t.rawString(s)
+}
+
+func (t *thrift) list(id int16, kind byte, size int) {
+    t.field(id, thriftList)
+    if size < 15 {
+        t.buffer.WriteByte(byte(size) << 4 | kind)
+    } else {
+        t.buffer.WriteByte(0xf0 | kind)
+        t.varint(uint64(size))
+    }
+}
+
+// a struct field, or a list element when id is 0
+func (t *thrift) begin(id int16) {
+    if id != 0 {
+        t.field(id, thriftStruct)
+    }
+    t.fields = append(t.fields, 0)
+}
+
+func (t *thrift) end() {
+    t.buffer.WriteByte(0)
+    t.fields = t.fields[:len(t.fields) - 1]
+}
+
+type column struct {
+    name      string
+    kind      int32
+    // -1 when the column has none
+    converted int32
+    values    bytes.Buffer
+}
+
+func (c *column) int32(v int32) {
+    binary.Write(&c.values, binary.LittleEndian, v)
+}
+
+func (c *column) int64(v int64) {
+    binary.Write(&c.values, binary.LittleEndian, v)
+}
+
+func (c *column) float(v float32) {
+    binary.Write(&c.values, binary.LittleEndian, math.Float32bits(v))
+}
+
+func (c *column) double(v float64) {
+    binary.Write(&c.values, binary.LittleEndian, math.Float64bits(v))
+}
+
+func (c *column) string(s string) {
+    binary.Write(&c.values, binary.LittleEndian, uint32(len(s)))
+    c.values.WriteString(s)
+}
+
+// Parquet with the JSON encoded metadata under MetadataKey in the footer
+func WriteParquet(path string, metadata Metadata, observations []types.Observation) error {
+    if err := check(metadata, observations); err != nil {
+        return err
+    }
+    encoded, err := json.Marshal(metadata)
+    if err != nil {
+        return err
+    }
+
+    names := Columns(metadata.Features)
+    columns := make([]*column, len(names))
+    for i, name := range names {
+        columns[i] = &column{name: name, kind: parquetFloat, converted: -1}
+    }
+    columns[0].kind, columns[0].converted = parquetInt64, convertedTimestampMicros
+    for _, c := range columns[1:4] {
+        c.kind, c.converted = parquetByteArray, convertedUTF8
+    }
+    label, profit := columns[len(columns) - 2], columns[len(columns) - 1]
+    label.kind, profit.kind = parquetInt32, parquetDouble
+    for _, observation := range observations {
+        columns[0].int64(observation.Timestamp.UnixNano() / 1000)
+        columns[1].string(observation.Exchange1)
+        columns[2].string(observation.Exchange2)
+        columns[3].string(observation.AssetPair.String())
+        for i, feature := range observation.Features {
+            columns[4 + i].float(feature)
+        }
+        label.int32(observation.Label)
+        profit.double(observation.Profit)
+    }
+
+    file := bytes.NewBufferString("PAR1")
+    // column chunk offsets and sizes, page header included
+    offsets := make([]int64, len(columns))
+    sizes := make([]int64, len(columns))
+    for i, c := range columns {
+        header := &thrift{fields: []int16{0}}
+        // PageHeader of a DATA_PAGE
+        header.i32(1, 0)
+        header.i32(2, int32(c.values.Len()))
+        header.i32(3, int32(c.values.Len()))
+        header.begin(5)
+        header.i32(1, int32(len(observations)))
+        header.i32(2, encodingPlain)
+        header.i32(3, encodingRLE)
+        header.i32(4, encodingRLE)
+        header.end()
+        header.buffer.WriteByte(0)
+
+        offsets[i] = int64(file.Len())
+        sizes[i] = int64(header.buffer.Len() + c.values.Len())
+        file.Write(header.buffer.Bytes())
+        file.Write(c.values.Bytes())
+    }
+
+    footer := &thrift{fields: []int16{0}}
+    // FileMetaData
+    footer.i32(1, 1)
+    footer.list(2, thriftStruct, len(columns) + 1)
+    footer.begin(0)
+    footer.string(4, "schema")
+    footer.i32(5, int32(len(columns)))
+    footer.end()
+    for _, c := range columns {
+        footer.begin(0)
+        footer.i32(1, c.kind)
+        // REQUIRED
+        footer.i32(3, 0)
+        footer.string(4, c.name)
+        if c.converted >= 0 {
+            footer.i32(6, c.converted)
+        }
+        footer.end()
+    }
+    footer.i64(3, int64(len(observations)))
+    footer.list(4, thriftStruct, 1)
+    footer.begin(0)
+    footer.list(1, thriftStruct, len(columns))
+    total := int64(0)
+    for i, c := range columns {
+        total += sizes[i]
+        footer.begin(0)
+        footer.i64(2, offsets[i])
+        footer.begin(3)
+        footer.i32(1, c.kind)
+        footer.list(2, thriftI32, 1)
+        footer.zigzag(int64(encodingPlain))
+        footer.list(3, thriftBinary, 1)
+        footer.rawString(c.name)
+        // UNCOMPRESSED
+        footer.i32(4, 0)
+        footer.i64(5, int64(len(observations)))
+        footer.i64(6, sizes[i])
+        footer.i64(7, sizes[i])
+        footer.i64(9, offsets[i])
+        footer.end()
+        footer.end()
+    }
+    footer.i64(2, total)
+    footer.i64(3, int64(len(observations)))
+    footer.end()
+    footer.list(5, thriftStruct, 1)
+    footer.begin(0)
+    footer.string(1, MetadataKey)
+    footer.string(2, string(encoded))
+    footer.end()
+    footer.string(6, "grizzly")
+    footer.buffer.WriteByte(0)
+
+    file.Write(footer.buffer.Bytes())
+    binary.Write(file, binary.LittleEndian, uint32(footer.buffer.Len()))
+    file.WriteString("PAR1")
+    return writeFile(path, file.Bytes())
+}
+
+// fails rather than overwrite path
+func writeFile(path string, data []byte) error {
+    file, err := os.OpenFile(path, os.O_CREATE | os.O_EXCL | os.O_WRONLY, 0644)
+    if err != nil {
+        return err
+    }
+    if _, err := file.Write(data); err != nil {
+        file.Close()
+        return err
+    }
+    return file.Close()
+}
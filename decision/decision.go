@@ -0,0 +1,150 @@
+package decision
+
+import (
+    "math"
+    "time"
+
+    "github.com/denali-capital/grizzly/arbitrage"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/denali-capital/grizzly/util"
+    "github.com/shopspring/decimal"
+)
+
+type Options struct {
+    // exchange -> taker fee fraction
+    Fees               map[string]decimal.Decimal
+    // quote amount traded, converted to base at the buy side's mid
+    Notional           decimal.Decimal
+    // expected value required per unit of quote spent, e.g. 0.0002 trades only when the
+    // expected value beats 0.02% of the buy cost; 0 trades any positive expected value
+    Margin             decimal.Decimal
+    // while it returns true, e.g. while features or the model's output drift from what it was
+    // trained on, ConservativeMargin is required instead of Margin; nil never does
+    Conservative       func() bool
+    ConservativeMargin decimal.Decimal
+}
+
+// whether to buy Edge.Size of AssetPair on Buy and sell it on Sell
+type Decision struct {
+    Buy           string
+    Sell          string
+    AssetPair     types.AssetPair
+    // calibrated probability that the opportunity is still there when the orders arrive
+    Probability   float64
+    // at the current books, net of fees
+    Edge          arbitrage.CrossEdge
+    // quote lost when it is not: one leg fills and is unwound through its own book, fees included
+    Downside      decimal.Decimal
+    // Probability * Edge.Profit - (1 - Probability) * Downside
+    ExpectedValue decimal.Decimal
+    // Margin * Edge.Cost, or ConservativeMargin * Edge.Cost
+    Required      decimal.Decimal
+    Conservative  bool
+    Trade         bool
+}
+
+// what filling size at book's best prices and trading straight back out costs, fees included
+func roundTrip(book *types.OrderBook, fee, size decimal.Decimal) (decimal.Decimal, error) {
+    edge, err := arbitrage.ComputeCrossEdge(book, book, fee, fee, size)
+    if err != nil {
+        return decimal.Zero, err
+    }
+    return edge.Profit.Neg(), nil
+}
+
+// the worse of the two legs filling alone and being unwound on its exchange: bought on
+// buyBook's asks and sold back into its bids, or sold into sellBook's bids and bought back
+// from its asks
+func Downside(buyBook, sellBook *types.OrderBook, buyFee, sellFee, size decimal.Decimal) (decimal.Decimal, error) {
+    buy, err := roundTrip(buyBook, buyFee, size)
+    if err != nil {
+        return decimal.Zero, err
+    }
+    sell, err := roundTrip(sellBook, sellFee, size)
+    if err != nil {
+        return decimal.Zero, err
+    }
+    return decimal.Max(buy, sell), nil
+}
+
+func ExpectedValue(probability float64, profit, downside decimal.Decimal) decimal.Decimal {
+    p := decimal.NewFromFloat(probability)
+    return p.Mul(profit).Sub(decimal.NewFromInt(1).Sub(p).Mul(downside))
+}
+
+// prices trading Notional both ways between exchange1 and exchange2 at probability, and returns
+// the direction with the higher expected value; false when neither direction can be priced, or
+// probability is NaN because the model could not predict
+func Decide(exchange1, exchange2 types.Exchange, assetPair types.AssetPair, probability float64, options Options) (Decision, bool) {
+    if math.IsNaN(probability) {
+        return Decision{}, false
+    }
+    orderBooks := make(map[string]*types.OrderBook)
+    spreads := make(map[string]types.Spread)
+    for _, exchange := range []types.Exchange{exchange1, exchange2} {
+        orderBooks[exchange.String()] = exchange.GetOrderBooks([]types.AssetPair{assetPair})[assetPair]
+        spreads[exchange.String()] = exchange.GetCurrentSpread(assetPair)
+    }
+
+    margin, conservative := options.Margin, options.Conservative != nil && options.Conservative()
+    if conservative {
+        margin = options.ConservativeMargin
+    }
+
+    var best *Decision
+    for _, exchanges := range [][2]types.Exchange{{exchange1, exchange2}, {exchange2, exchange1}} {
+        buy, sell := exchanges[0].String(), exchanges[1].String()
+        spread := spreads[buy]
+        if !spread.Bid.IsPositive() || !spread.Ask.IsPositive() || orderBooks[buy] == nil || orderBooks[sell] == nil {
+            continue
+        }
+        size := options.Notional.Div(util.Midpoint(spread))
+        edge, err := arbitrage.ComputeCrossEdge(orderBooks[buy], orderBooks[sell], options.Fees[buy], options.Fees[sell], size)
+        if err != nil {
+            continue
+        }
+        downside, err := Downside(orderBooks[buy], orderBooks[sell], options.Fees[buy], options.Fees[sell], size)
+        if err != nil {
+            continue
+        }
+        decision := Decision{
+            Buy: buy,
+            Sell: sell,
+            AssetPair: assetPair,
+            Probability: probability,
+            Edge: edge,
+            Downside: downside,
+            ExpectedValue: ExpectedValue(probability, edge.Profit, downside),
+            Required: margin.Mul(edge.Cost),
+            Conservative: conservative,
+        }
+        decision.Trade = decision.ExpectedValue.IsPositive() && decision.ExpectedValue.GreaterThan(decision.Required)
+        if best == nil || decision.ExpectedValue.GreaterThan(best.ExpectedValue) {
+            best = &decision
+        }
+    }
+    if best == nil {
+        return Decision{}, false
+    }
+    return *best, true
+}
+
+// both legs as limit orders at the worst levels the edge was priced through
+func (d Decision) Opportunity(timestamp time.Time) types.Opportunity {
+    return types.Opportunity{
+        Legs: []types.Leg{
+            {
+                Exchange: d.Buy,
+                Order: types.Order{OrderType: types.Buy, AssetPair: d.AssetPair, Price: d.Edge.BuyLimit, Quantity: d.Edge.Size},
+            },
+            {
+                Exchange: d.Sell,
+                Order: types.Order{OrderType: types.Sell, AssetPair: d.AssetPair, Price: d.Edge.SellLimit, Quantity: d.Edge.Size},
+            },
+        },
+        Asset: d.AssetPair.Quote,
+        Size: d.Edge.Cost,
+        ExpectedProfit: d.ExpectedValue,
+        Timestamp: timestamp,
+    }
+}
@@ -0,0 +1,85 @@
+package decision
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+var BTCUSD types.AssetPair = types.NewAssetPair("BTC", "USD")
+
+type fakeExchange struct {
+	types.Exchange
+	name      string
+	orderBook *types.OrderBook
+}
+
+func (f *fakeExchange) String() string {
+	return f.name
+}
+
+func (f *fakeExchange) GetCurrentSpread(assetPair types.AssetPair) types.Spread {
+	return types.Spread{Bid: f.orderBook.Bids[0].Price, Ask: f.orderBook.Asks[0].Price}
+}
+
+func (f *fakeExchange) GetOrderBooks(assetPairs []types.AssetPair) map[types.AssetPair]*types.OrderBook {
+	return map[types.AssetPair]*types.OrderBook{BTCUSD: f.orderBook}
+}
+
+func book(bid, ask float64) *types.OrderBook {
+	return &types.OrderBook{
+		Bids: []types.OrderBookEntry{{Price: decimal.NewFromFloat(bid), Quantity: decimal.NewFromInt(5)}},
+		Asks: []types.OrderBookEntry{{Price: decimal.NewFromFloat(ask), Quantity: decimal.NewFromInt(5)}},
+	}
+}
+
+func TestDecide(t *testing.T) {
+	kraken := &fakeExchange{name: "Kraken", orderBook: book(99.5, 100.5)}
+	kuCoin := &fakeExchange{name: "KuCoin", orderBook: book(102, 103)}
+	options := Options{Notional: decimal.NewFromInt(100), Margin: decimal.NewFromFloat(0.004)}
+
+	// one unit bought at 100.5 and sold at 102, or unwound for a loss of 1 on either exchange
+	decision, ok := Decide(kuCoin, kraken, BTCUSD, 0.6, options)
+	if !ok || decision.Buy != "Kraken" || decision.Sell != "KuCoin" || !decision.Edge.Profit.Equal(decimal.NewFromFloat(1.5)) || !decision.Downside.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("expected to buy on Kraken for 1.5 with a downside of 1, got %+v %v", decision, ok)
+	}
+	if !decision.ExpectedValue.Equal(decimal.NewFromFloat(0.5)) || !decision.Required.Equal(decimal.NewFromFloat(0.402)) || !decision.Trade {
+		t.Fatalf("expected an expected value of 0.5 to beat 0.402, got %+v", decision)
+	}
+
+	opportunity := decision.Opportunity(time.Unix(0, 0))
+	if len(opportunity.Legs) != 2 || opportunity.Legs[0].Order.OrderType != types.Buy || !opportunity.Legs[0].Order.Price.Equal(decimal.NewFromFloat(100.5)) || opportunity.Legs[1].Exchange != "KuCoin" || !opportunity.Size.Equal(decimal.NewFromFloat(100.5)) {
+		t.Fatalf("unexpected opportunity %+v", opportunity)
+	}
+
+	// a larger margin scales with what is spent
+	options.Margin = decimal.NewFromFloat(0.005)
+	if decision, _ := Decide(kuCoin, kraken, BTCUSD, 0.6, options); decision.Trade {
+		t.Fatalf("expected 0.5 not to beat 0.5025, got %+v", decision)
+	}
+	// drifting, the conservative margin applies instead
+	options.Margin, options.ConservativeMargin = decimal.NewFromFloat(0.004), decimal.NewFromFloat(0.005)
+	options.Conservative = func() bool { return true }
+	if decision, _ := Decide(kuCoin, kraken, BTCUSD, 0.6, options); !decision.Conservative || decision.Trade {
+		t.Fatalf("expected 0.5 not to beat the conservative 0.5025, got %+v", decision)
+	}
+	options.Conservative = nil
+	options.Margin = decimal.Zero
+	if decision, _ := Decide(kuCoin, kraken, BTCUSD, 0.4, options); !decision.ExpectedValue.IsZero() || decision.Trade {
+		t.Fatalf("expected no trade at zero expected value, got %+v", decision)
+	}
+
+	// a model that could not predict
+	if _, ok := Decide(kuCoin, kraken, BTCUSD, math.NaN(), options); ok {
+		t.Fatal("expected no decision at NaN")
+	}
+
+	// too thin to fill 10 units either way
+	options.Notional = decimal.NewFromInt(1000)
+	if _, ok := Decide(kuCoin, kraken, BTCUSD, 0.6, options); ok {
+		t.Fatal("expected no decision without the depth to price it")
+	}
+}
@@ -0,0 +1,162 @@
+package drift
+
+import (
+    "math"
+    "sort"
+
+    "github.com/denali-capital/grizzly/features"
+    "github.com/denali-capital/grizzly/types"
+)
+
+// quantiles kept of every reference sample
+const quantiles int = 100
+
+// PSI bins, about equally likely under the reference
+const bins int = 10
+
+// PSI's floor for empty bins, so that an empty bin weighs heavily rather than infinitely
+const floor float64 = 1e-4
+
+// a reference sample, by its quantiles at 0, 1/n, ..., 1, and by the fraction of it in each of
+// the PSI bins, split at Edges
+type Distribution struct {
+    Quantiles []float64 `json:"quantiles"`
+    // upper bounds of every bin but the last, inclusive; bins repeated by ties are merged
+    Edges     []float64 `json:"edges"`
+    Fractions []float64 `json:"fractions"`
+}
+
+// how many values fall in each bin
+func count(values []float64, edges []float64) []float64 {
+    counts := make([]float64, len(edges) + 1)
+    for _, x := range values {
+        counts[sort.SearchFloat64s(edges, x)]++
+    }
+    return counts
+}
+
+func NewDistribution(sample []float64) Distribution {
+    if len(sample) == 0 {
+        return Distribution{}
+    }
+    sorted := append([]float64{}, sample...)
+    sort.Float64s(sorted)
+    distribution := Distribution{Quantiles: make([]float64, quantiles + 1)}
+    for i := range distribution.Quantiles {
+        position := float64(i) / float64(quantiles) * float64(len(sorted) - 1)
+        lower := int(position)
+        upper := int(math.Min(float64(lower + 1), float64(len(sorted) - 1)))
+        distribution.Quantiles[i] = sorted[lower] + (sorted[upper] - sorted[lower]) * (position - float64(lower))
+    }
+    for i := 1; i < bins; i++ {
+        edge := distribution.Quantiles[i * quantiles / bins]
+        if len(distribution.Edges) == 0 || edge > distribution.Edges[len(distribution.Edges) - 1] {
+            distribution.Edges = append(distribution.Edges, edge)
+        }
+    }
+    distribution.Fractions = count(sorted, distribution.Edges)
+    for i := range distribution.Fractions {
+        distribution.Fractions[i] /= float64(len(sorted))
+    }
+    return distribution
+}
+
+// fraction of the reference at or below x, linear between quantiles
+func (d Distribution) CDF(x float64) float64 {
+    q := d.Quantiles
+    n := len(q) - 1
+    // the last quantile at or below x, so that a run of equal quantiles counts whole
+    k := sort.Search(len(q), func(i int) bool {
+        return q[i] > x
+    }) - 1
+    switch {
+    case k < 0:
+        return 0
+    case k == n:
+        return 1
+    }
+    return (float64(k) + (x - q[k]) / (q[k + 1] - q[k])) / float64(n)
+}
+
+// fraction of the reference strictly below x
+func (d Distribution) below(x float64) float64 {
+    q := d.Quantiles
+    n := len(q) - 1
+    // the first quantile at or above x
+    k := sort.SearchFloat64s(q, x)
+    switch {
+    case k == 0:
+        return 0
+    case k > n:
+        return 1
+    }
+    return (float64(k - 1) + (x - q[k - 1]) / (q[k] - q[k - 1])) / float64(n)
+}
+
+// the largest gap between the reference's and the sample's cumulative distributions; between
+// sample points and quantiles the gap is monotone, so it is measured at and just below each
+func (d Distribution) KS(sample []float64) float64 {
+    if len(d.Quantiles) == 0 || len(sample) == 0 {
+        return 0
+    }
+    sorted := append([]float64{}, sample...)
+    sort.Float64s(sorted)
+    fraction := func(i int) float64 {
+        return float64(i) / float64(len(sorted))
+    }
+
+    statistic := 0.0
+    for _, x := range append(append([]float64{}, sorted...), d.Quantiles...) {
+        at := sort.Search(len(sorted), func(i int) bool {
+            return sorted[i] > x
+        })
+        below := sort.SearchFloat64s(sorted, x)
+        statistic = math.Max(statistic, math.Abs(d.CDF(x) - fraction(at)))
+        statistic = math.Max(statistic, math.Abs(d.below(x) - fraction(below)))
+    }
+    return statistic
+}
+
+// population stability index of the sample over the reference's bins
+func (d Distribution) PSI(sample []float64) float64 {
+    if len(d.Fractions) == 0 || len(sample) == 0 {
+        return 0
+    }
+    psi := 0.0
+    for i, count := range count(sample, d.Edges) {
+        expected := math.Max(d.Fractions[i], floor)
+        actual := math.Max(count / float64(len(sample)), floor)
+        psi += (actual - expected) * math.Log(actual / expected)
+    }
+    return psi
+}
+
+// distributions of every feature and of the model's calibrated output over the observations
+// a model version was trained on, stored with the version
+type Reference struct {
+    Schema   string                  `json:"schema"`
+    Features map[string]Distribution `json:"features"`
+    Output   Distribution            `json:"output"`
+}
+
+// predictions are the model's, calibrated, of observations in schema; observations of any
+// other schema are left out
+func NewReference(schema features.Schema, observations []types.Observation, predictions []float32) *Reference {
+    samples := make([][]float64, len(schema.Features))
+    outputs := make([]float64, 0, len(predictions))
+    for i, observation := range observations {
+        if observation.Schema != schema.String() || len(observation.Features) != len(schema.Features) {
+            continue
+        }
+        for j, value := range observation.Features {
+            samples[j] = append(samples[j], float64(value))
+        }
+        outputs = append(outputs, float64(predictions[i]))
+    }
+    reference := &Reference{Schema: schema.String(), Features: make(map[string]Distribution, len(schema.Features))}
+    for i, name := range schema.Features {
+        reference.Features[name] = NewDistribution(samples[i])
+    }
+    reference.Output = NewDistribution(outputs)
+    return reference
+}
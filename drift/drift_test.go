@@ -0,0 +1,102 @@
+package drift
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/features"
+	"github.com/denali-capital/grizzly/types"
+)
+
+func uniform(n int, offset float64) []float64 {
+	sample := make([]float64, n)
+	for i := range sample {
+		sample[i] = float64(i) + offset
+	}
+	return sample
+}
+
+func TestDistribution(t *testing.T) {
+	reference := NewDistribution(uniform(1000, 0))
+	if cdf := reference.CDF(499.5); math.Abs(cdf - 0.5) > 1e-9 {
+		t.Fatalf("expected half the reference below its median, got %v", cdf)
+	}
+	if reference.CDF(-1) != 0 || reference.CDF(1000) != 1 {
+		t.Fatalf("expected nothing below and everything above the reference, got %v %v", reference.CDF(-1), reference.CDF(1000))
+	}
+
+	if psi, ks := reference.PSI(uniform(1000, 0)), reference.KS(uniform(1000, 0)); psi > 0.01 || ks > 0.01 {
+		t.Fatalf("expected no drift of the reference itself, got %v %v", psi, ks)
+	}
+	// half the shifted sample is above everything in the reference
+	if psi, ks := reference.PSI(uniform(1000, 500)), reference.KS(uniform(1000, 500)); psi < 0.25 || math.Abs(ks - 0.5) > 0.01 {
+		t.Fatalf("expected a shift by half the range to drift, got %v %v", psi, ks)
+	}
+
+	// a feature that is mostly zero, with bins merged over the ties
+	ties := NewDistribution(append(make([]float64, 900), uniform(100, 1)...))
+	if psi := ties.PSI(append(make([]float64, 900), uniform(100, 1)...)); math.IsNaN(psi) || psi > 0.01 {
+		t.Fatalf("expected no drift of a tied reference itself, got %v", psi)
+	}
+	if ks := ties.KS(uniform(100, 1)); ks < 0.85 {
+		t.Fatalf("expected the zeros going missing to drift, got %v", ks)
+	}
+}
+
+// price deltas from offset up, every other feature and the output constant
+func observations(n int, offset float64) ([]types.Observation, []float32) {
+	observations := make([]types.Observation, n)
+	predictions := make([]float32, n)
+	for i := range observations {
+		vector := make([]float32, len(features.Default.Features))
+		vector[0] = float32(float64(i) + offset)
+		observations[i] = types.Observation{Schema: features.Default.String(), Features: vector}
+		predictions[i] = 0.5
+	}
+	return observations, predictions
+}
+
+func TestMonitor(t *testing.T) {
+	var reference *Reference
+	monitor := NewMonitor(func() *Reference { return reference }, Options{Window: 100, MinObservations: 50, PSIThreshold: 0.25, KSThreshold: 0.2})
+	monitor.Observe(observations(100, 0))
+	if _, ok := monitor.Check(); ok {
+		t.Fatal("expected no check without a reference")
+	}
+
+	trained, outputs := observations(100, 0)
+	reference = NewReference(features.Default, trained, outputs)
+	// outputs collected under another reference are dropped, observations are kept
+	report, ok := monitor.Check()
+	if !ok || report.Drifted || len(report.Drifts) != len(features.Default.Features) {
+		t.Fatalf("expected every feature and no output compared without drift, got %+v %v", report, ok)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.Run(ctx, 5 * time.Millisecond)
+	monitor.Observe(observations(100, 50))
+	for deadline := time.Now().Add(time.Second); !monitor.Drifted(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the monitor to find drift")
+		}
+	}
+	report, _ = monitor.Check()
+	for _, d := range report.Drifts {
+		if d.Drifted != (d.Variable == "price_delta") {
+			t.Fatalf("expected only price deltas to drift, got %+v", report.Drifts)
+		}
+	}
+	if last := report.Drifts[len(report.Drifts) - 1]; last.Variable != Output || last.PSI > 0.01 {
+		t.Fatalf("expected the output compared last without drift, got %+v", last)
+	}
+
+	monitor.Observe(observations(100, 0))
+	for deadline := time.Now().Add(time.Second); monitor.Drifted(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the drift to clear")
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package drift
+
+import (
+    "context"
+    "math"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/denali-capital/grizzly/features"
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
+    "github.com/denali-capital/grizzly/types"
+)
+
+var logger *logging.Logger = logging.New("drift")
+
+// the variable model outputs are reported as, next to feature names
+const Output string = "output"
+
+type Options struct {
+    // live observations compared against the reference, oldest dropped first
+    Window          int
+    // live observations needed before they are compared
+    MinObservations int
+    // a variable drifts when its PSI or its KS statistic reaches these
+    PSIThreshold    float64
+    KSThreshold     float64
+}
+
+// one feature's or the output's drift from its reference
+type Drift struct {
+    Variable string
+    PSI      float64
+    KS       float64
+    Drifted  bool
+}
+
+type Report struct {
+    Observations int
+    // features in schema order, then the output
+    Drifts       []Drift
+    Drifted      bool
+}
+
+// compares the live model's observations and calibrated outputs over a rolling window against
+// the reference of the version predicting them
+type Monitor struct {
+    mutex        sync.Mutex
+    options      Options
+    reference    func() *Reference
+    // the reference outputs were collected under, they are dropped when it changes
+    current      *Reference
+    observations []types.Observation
+    outputs      []float64
+    drifted      atomic.Bool
+}
+
+// reference returns the live version's, nil when it has none
+func NewMonitor(reference func() *Reference, options Options) *Monitor {
+    return &Monitor{options: options, reference: reference}
+}
+
+// keeps the newest Window of observations and their predictions, leaving out those the model
+// could not predict
+func (m *Monitor) Observe(observations []types.Observation, predictions []float32) {
+    m.mutex.Lock()
+    defer m.mutex.Unlock()
+    for i, observation := range observations {
+        if i >= len(predictions) {
+            break
+        }
+        if math.IsNaN(float64(predictions[i])) {
+            continue
+        }
+        m.observations = append(m.observations, observation)
+        m.outputs = append(m.outputs, float64(predictions[i]))
+    }
+    // trimmed back to Window once it doubles, so that appending stays amortized constant
+    if len(m.observations) > 2 * m.options.Window {
+        m.observations = append([]types.Observation{}, m.observations[len(m.observations) - m.options.Window:]...)
+    }
+    if len(m.outputs) > 2 * m.options.Window {
+        m.outputs = append([]float64{}, m.outputs[len(m.outputs) - m.options.Window:]...)
+    }
+}
+
+func newest[T any](values []T, n int) []T {
+    if len(values) > n {
+        return values[len(values) - n:]
+    }
+    return values
+}
+
+func (m *Monitor) drift(variable string, reference Distribution, sample []float64) Drift {
+    d := Drift{Variable: variable, PSI: reference.PSI(sample), KS: reference.KS(sample)}
+    d.Drifted = d.PSI >= m.options.PSIThreshold || d.KS >= m.options.KSThreshold
+    metrics.ObserveDrift(variable, d.PSI, d.KS)
+    return d
+}
+
+// compares the window against the reference; false without a reference or enough observations
+func (m *Monitor) Check() (Report, bool) {
+    reference := m.reference()
+    m.mutex.Lock()
+    if reference != m.current {
+        m.current, m.outputs = reference, nil
+    }
+    observations := newest(m.observations, m.options.Window)
+    outputs := append([]float64{}, newest(m.outputs, m.options.Window)...)
+    m.mutex.Unlock()
+    if reference == nil {
+        return Report{}, false
+    }
+    schema, err := features.LookupSchema(reference.Schema)
+    if err != nil {
+        return Report{}, false
+    }
+
+    samples := make([][]float64, len(schema.Features))
+    n := 0
+    for _, observation := range observations {
+        if observation.Schema != reference.Schema || len(observation.Features) != len(schema.Features) {
+            continue
+        }
+        for i, value := range observation.Features {
+            samples[i] = append(samples[i], float64(value))
+        }
+        n++
+    }
+    if n < m.options.MinObservations {
+        return Report{Observations: n}, false
+    }
+
+    report := Report{Observations: n}
+    for i, name := range schema.Features {
+        report.Drifts = append(report.Drifts, m.drift(name, reference.Features[name], samples[i]))
+    }
+    if len(outputs) >= m.options.MinObservations {
+        report.Drifts = append(report.Drifts, m.drift(Output, reference.Output, outputs))
+    }
+    for _, d := range report.Drifts {
+        report.Drifted = report.Drifted || d.Drifted
+    }
+    return report, true
+}
+
+// whether the last check found drift, e.g. for the decision layer to trade conservatively
+func (m *Monitor) Drifted() bool {
+    return m.drifted.Load()
+}
+
+// checks every interval until ctx is canceled, warning when drift starts and noting when it ends
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+        report, ok := m.Check()
+        if !ok {
+            continue
+        }
+        drifted := []string{}
+        for _, d := range report.Drifts {
+            if d.Drifted {
+                drifted = append(drifted, d.Variable)
+            }
+        }
+        metrics.ObserveDrifted(report.Drifted)
+        switch previous := m.drifted.Swap(report.Drifted); {
+        case report.Drifted && !previous:
+            logger.Warn("drift detected", "variables", drifted, "observations", report.Observations)
+        case report.Drifted:
+            logger.Debug("drift persists", "variables", drifted, "observations", report.Observations)
+        case previous:
+            logger.Info("drift cleared", "observations", report.Observations)
+        }
+    }
+}
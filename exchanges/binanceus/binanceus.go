@@ -4,12 +4,14 @@ import (
     "crypto/hmac"
     "crypto/sha256"
     "fmt"
-    "log"
     "net/http"
     "net/url"
     "strconv"
     "time"
 
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
+    "github.com/denali-capital/grizzly/secrets"
     "github.com/denali-capital/grizzly/types"
     "github.com/denali-capital/grizzly/util"
     "github.com/shopspring/decimal"
@@ -18,6 +20,11 @@ import (
 // docs: https://github.com/binance-us/binance-official-api-docs/blob/master/rest-api.md
 const RESTEndpoint string = "https://api.binance.us"
 
+// label on metrics and log lines
+const exchangeName string = "BinanceUS"
+
+var logger *logging.Logger = logging.New("exchanges/binanceus").With(logging.Exchange(exchangeName))
+
 type BinanceUS struct {
     AssetPairTranslator      types.AssetPairTranslator
 
@@ -25,21 +32,27 @@ type BinanceUS struct {
     secretKey                string
     spreadRecorder           types.SpreadRecorder
     orderBookRecorder        types.OrderBookRecorder
+    tradeRecorder            types.TradeRecorder
     latencyEstimator         *util.EwmaEstimator
     orderIdToOrderTranslator *util.ConcurrentOrderIdToOrderPtrMap
     // add timeouts
     httpClient               *http.Client
 }
 
-func NewBinanceUS(apiKey, secretKey string, assetPairTranslator types.AssetPairTranslator) *BinanceUS {
+func NewBinanceUS(provider secrets.Provider, assetPairTranslator types.AssetPairTranslator, spreadCapacity, tradeCapacity, orderBookDepth uint) *BinanceUS {
+    credentials, err := secrets.Load(provider, "BinanceUS")
+    if err != nil {
+        logger.Fatal("loading credentials failed", logging.Err(err))
+    }
     assetPairs := assetPairTranslator.GetAssetPairs()
     httpClient := &http.Client{}
     return &BinanceUS{
         AssetPairTranslator: assetPairTranslator,
-        apiKey: apiKey,
-        secretKey: secretKey,
-        spreadRecorder: NewBinanceUSSpreadRecorder(assetPairs, assetPairTranslator, 200),
-        orderBookRecorder: NewBinanceUSOrderBookRecorder(httpClient, assetPairs, assetPairTranslator, 1000),
+        apiKey: credentials.ApiKey,
+        secretKey: credentials.SecretKey,
+        spreadRecorder: NewBinanceUSSpreadRecorder(assetPairs, assetPairTranslator, spreadCapacity),
+        orderBookRecorder: NewBinanceUSOrderBookRecorder(httpClient, assetPairs, assetPairTranslator, orderBookDepth),
+        tradeRecorder: NewBinanceUSTradeRecorder(assetPairs, assetPairTranslator, tradeCapacity),
         latencyEstimator: util.NewEwmaEstimator(0.125, 0.25, 4),
         orderIdToOrderTranslator: util.NewConcurrentOrderIdToOrderPtrMap(),
         httpClient: httpClient,
@@ -50,9 +63,11 @@ func (b *BinanceUS) String() string {
     return "BinanceUS"
 }
 
-func checkError(bodyJson map[string]interface{}) {
+// endpoint is the requested URL, only its host and path are recorded
+func checkError(requestId, endpoint string, bodyJson map[string]interface{}) {
     if _, ok := bodyJson["code"]; ok {
-        log.Fatalln(bodyJson)
+        metrics.RestErrors.Inc(util.Endpoint(endpoint))
+        logger.Fatal("request failed", logging.RequestId(requestId), "endpoint", util.Endpoint(endpoint), "code", bodyJson["code"], "msg", bodyJson["msg"])
     }
 }
 
@@ -91,18 +106,18 @@ func (b *BinanceUS) GetCurrentSpread(assetPair types.AssetPair) types.Spread {
     spread, ok := b.spreadRecorder.GetCurrentSpread(assetPair)
     if !ok {
         b.spreadRecorder.RegisterAssetPair(assetPair)
-        bodyJson := util.HttpGetAndGetBody(b.httpClient, util.ParseUrlWithQuery(RESTEndpoint + "/api/v3/ticker/bookTicker", url.Values{
+        bodyJson, requestId := util.HttpGetAndGetBody(b.httpClient, util.ParseUrlWithQuery(RESTEndpoint + "/api/v3/ticker/bookTicker", url.Values{
             "symbol": []string{b.AssetPairTranslator[assetPair]},
         }))
-        checkError(bodyJson)
+        checkError(requestId, RESTEndpoint + "/api/v3/ticker/bookTicker", bodyJson)
 
         bid, err := decimal.NewFromString(bodyJson["bidPrice"].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         ask, err := decimal.NewFromString(bodyJson["askPrice"].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
 
         return types.Spread{
@@ -137,17 +152,103 @@ func (b *BinanceUS) GetOrderBooks(assetPairs []types.AssetPair) map[types.AssetP
     return orderBooks
 }
 
+func (b *BinanceUS) GetTradesSince(assetPair types.AssetPair, since time.Time) ([]types.Trade, bool) {
+    trades, ok := b.tradeRecorder.GetTradesSince(assetPair, since)
+    if !ok {
+        b.tradeRecorder.RegisterAssetPair(assetPair)
+    }
+    return trades, ok
+}
+
+func (b *BinanceUS) GetVWAP(assetPair types.AssetPair, window time.Duration) (decimal.Decimal, bool) {
+    return b.tradeRecorder.GetVWAP(assetPair, window)
+}
+
+// resolution -> kline interval
+var klineIntervals map[time.Duration]string = map[time.Duration]string{
+    time.Minute: "1m",
+    5 * time.Minute: "5m",
+    15 * time.Minute: "15m",
+    30 * time.Minute: "30m",
+    time.Hour: "1h",
+    4 * time.Hour: "4h",
+    24 * time.Hour: "1d",
+}
+
+// at most 1000 candles from since are served
+func (b *BinanceUS) GetHistoricalCandles(assetPair types.AssetPair, resolution time.Duration, since time.Time) ([]types.Candle, bool) {
+    interval, ok := klineIntervals[resolution]
+    if !ok {
+        return make([]types.Candle, 0), false
+    }
+    request, err := http.NewRequest("GET", util.ParseUrlWithQuery(RESTEndpoint + "/api/v3/klines", url.Values{
+        "symbol": []string{b.AssetPairTranslator[assetPair]},
+        "interval": []string{interval},
+        "startTime": []string{strconv.FormatInt(since.UnixMilli(), 10)},
+        "limit": []string{"1000"},
+    }), nil)
+    if err != nil {
+        logger.Fatal("building request failed", logging.AssetPair(assetPair), logging.Err(err))
+    }
+
+    // [open time, open, high, low, close, volume, close time, quote volume, trades, ...]
+    data, _ := util.DoHttpAndGetArrayBody(b.httpClient, request)
+    candles := make([]types.Candle, 0, len(data))
+    for _, rawCandle := range data {
+        entry := rawCandle.([]interface{})
+        values := make([]decimal.Decimal, 5)
+        for i := range values {
+            value, err := decimal.NewFromString(entry[i + 1].(string))
+            if err != nil {
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
+            }
+            values[i] = value
+        }
+        candles = append(candles, types.Candle{
+            Start: time.UnixMilli(int64(entry[0].(float64))),
+            Open: values[0],
+            High: values[1],
+            Low: values[2],
+            Close: values[3],
+            Volume: values[4],
+            Trades: uint(entry[8].(float64)),
+        })
+    }
+    return candles, true
+}
+
+// starts recording spreads, order books and trades for assetPair ahead of the first request for them
+func (b *BinanceUS) RegisterAssetPair(assetPair types.AssetPair) {
+    b.spreadRecorder.RegisterAssetPair(assetPair)
+    b.orderBookRecorder.RegisterAssetPair(assetPair)
+    b.tradeRecorder.RegisterAssetPair(assetPair)
+}
+
+func (b *BinanceUS) UnregisterAssetPair(assetPair types.AssetPair) {
+    b.spreadRecorder.UnregisterAssetPair(assetPair)
+    b.orderBookRecorder.UnregisterAssetPair(assetPair)
+    b.tradeRecorder.UnregisterAssetPair(assetPair)
+}
+
+func (b *BinanceUS) Close() {
+    b.spreadRecorder.Close()
+    b.orderBookRecorder.Close()
+    b.tradeRecorder.Close()
+}
+
 func (b *BinanceUS) GetLatency() time.Duration {
     start := time.Now()
 
-    bodyJson := util.HttpGetAndGetBody(b.httpClient, RESTEndpoint + "/api/v3/ping")
-    checkError(bodyJson)
+    bodyJson, requestId := util.HttpGetAndGetBody(b.httpClient, RESTEndpoint + "/api/v3/ping")
+    checkError(requestId, RESTEndpoint + "/api/v3/ping", bodyJson)
 
     duration := time.Since(start)
 
     b.latencyEstimator.Sample(float64(duration.Milliseconds()))
 
-    return time.Duration(b.latencyEstimator.GetEstimate()) * time.Millisecond
+    estimate := time.Duration(b.latencyEstimator.GetEstimate()) * time.Millisecond
+    metrics.ObserveLatency(b.String(), estimate)
+    return estimate
 }
 
 func parseOrderType(ot types.OrderType) string {
@@ -178,17 +279,18 @@ func (b *BinanceUS) executeOrder(order types.Order, channel chan types.OrderIdRe
 
     request, err := http.NewRequest("POST", util.ParseUrlWithQuery(RESTEndpoint + "/api/v3/order", queryParams) + "&signature=" + signature, nil)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.AssetPair(order.AssetPair), logging.Err(err))
     }
     request.Header.Set("X-MBX-APIKEY", b.apiKey)
 
-    bodyJson := util.DoHttpAndGetBody(b.httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(b.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     orderId := types.OrderId(strconv.FormatUint(uint64(bodyJson["orderId"].(float64)), 10))
 
     b.orderIdToOrderTranslator.Store(orderId, &order)
 
+    logger.Info("order placed", logging.OrderId(orderId), logging.AssetPair(order.AssetPair), logging.RequestId(requestId), "type", parseOrderType(order.OrderType), "price", order.Price, "quantity", order.Quantity)
     channel <- types.OrderIdResponse{order, orderId}
 }
 
@@ -203,13 +305,14 @@ func (b *BinanceUS) ExecuteOrders(orders []types.Order) map[types.Order]types.Or
         response := <- channel
         orderIds[response.Order] = response.OrderId
     }
+    metrics.ObserveSubmitted(b.String(), orderIds)
     return orderIds
 }
 
 func (b *BinanceUS) getOrderStatus(orderId types.OrderId, channel chan types.OrderStatusResponse) {
     order, ok := b.orderIdToOrderTranslator.Load(orderId)
     if !ok {
-        log.Fatalf("order with id %v not found\n", orderId)
+        logger.Fatal("order not found", logging.OrderId(orderId))
     }
     queryParams := url.Values{
         "symbol": []string{b.AssetPairTranslator[order.AssetPair]},
@@ -221,12 +324,12 @@ func (b *BinanceUS) getOrderStatus(orderId types.OrderId, channel chan types.Ord
 
     request, err := http.NewRequest("GET", util.ParseUrlWithQuery(RESTEndpoint + "/api/v3/order", queryParams) + "&signature=" + signature, nil)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.OrderId(orderId), logging.Err(err))
     }
     request.Header.Set("X-MBX-APIKEY", b.apiKey)
 
-    bodyJson := util.DoHttpAndGetBody(b.httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(b.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     orderStatus := types.OrderStatus{
         Original: order,
@@ -238,11 +341,11 @@ func (b *BinanceUS) getOrderStatus(orderId types.OrderId, channel chan types.Ord
     case "PARTIALLY_FILLED":
         price, err := decimal.NewFromString(bodyJson["price"].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.OrderId(orderId), logging.Err(err))
         }
         quantity, err := decimal.NewFromString(bodyJson["executedQty"].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.OrderId(orderId), logging.Err(err))
         }
         orderStatus.Status = types.PartiallyFilled
         orderStatus.FilledPrice = &price
@@ -250,11 +353,11 @@ func (b *BinanceUS) getOrderStatus(orderId types.OrderId, channel chan types.Ord
     case "FILLED":
         price, err := decimal.NewFromString(bodyJson["price"].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.OrderId(orderId), logging.Err(err))
         }
         quantity, err := decimal.NewFromString(bodyJson["executedQty"].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.OrderId(orderId), logging.Err(err))
         }
         orderStatus.Status = types.Filled
         orderStatus.FilledPrice = &price
@@ -267,7 +370,7 @@ func (b *BinanceUS) getOrderStatus(orderId types.OrderId, channel chan types.Ord
         orderStatus.Status = types.Expired
         b.orderIdToOrderTranslator.Delete(orderId)
     case "REJECTED":
-        log.Fatalf("order %v was rejected by BinanceUS\n", *order)
+        logger.Fatal("order was rejected", logging.OrderId(orderId), logging.AssetPair(order.AssetPair), logging.RequestId(requestId))
     }
 
     channel <- types.OrderStatusResponse{orderId, orderStatus}
@@ -284,13 +387,14 @@ func (b *BinanceUS) GetOrderStatuses(orderIds []types.OrderId) map[types.OrderId
         response := <- channel
         orderStatuses[response.OrderId] = response.OrderStatus
     }
+    metrics.ObserveOrderStatuses(b.String(), orderStatuses)
     return orderStatuses
 }
 
 func (b *BinanceUS) cancelOrder(orderId types.OrderId) {
     order, ok := b.orderIdToOrderTranslator.Load(orderId)
     if !ok {
-        log.Fatalf("order with id %v not found\n", orderId)
+        logger.Fatal("order not found", logging.OrderId(orderId))
     }
     queryParams := url.Values{
         "symbol": []string{b.AssetPairTranslator[order.AssetPair]},
@@ -302,14 +406,16 @@ func (b *BinanceUS) cancelOrder(orderId types.OrderId) {
 
     request, err := http.NewRequest("DELETE", util.ParseUrlWithQuery(RESTEndpoint + "/api/v3/order", queryParams) + "&signature=" + signature, nil)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.OrderId(orderId), logging.Err(err))
     }
     request.Header.Set("X-MBX-APIKEY", b.apiKey)
 
-    bodyJson := util.DoHttpAndGetBody(b.httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(b.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     b.orderIdToOrderTranslator.Delete(orderId)
+    metrics.ObserveCanceled(b.String(), []types.OrderId{orderId})
+    logger.Info("order canceled", logging.OrderId(orderId), logging.AssetPair(order.AssetPair), logging.RequestId(requestId))
 }
 
 func (b *BinanceUS) CancelOrders(orderIds []types.OrderId) {
@@ -318,6 +424,70 @@ func (b *BinanceUS) CancelOrders(orderIds []types.OrderId) {
     }
 }
 
+func (b *BinanceUS) GetOpenOrders() map[types.OrderId]types.OrderStatus {
+    queryParams := url.Values{
+        "timestamp": []string{strconv.FormatInt(time.Now().UnixMilli(), 10)},
+    }
+
+    signature := b.getBinanceUSSignature(queryParams)
+
+    request, err := http.NewRequest("GET", util.ParseUrlWithQuery(RESTEndpoint + "/api/v3/openOrders", queryParams) + "&signature=" + signature, nil)
+    if err != nil {
+        logger.Fatal("building request failed", logging.Err(err))
+    }
+    request.Header.Set("X-MBX-APIKEY", b.apiKey)
+
+    reverseTranslator := util.ReverseAssetPairTranslator(b.AssetPairTranslator)
+    orderStatuses := make(map[types.OrderId]types.OrderStatus)
+    rawOrders, _ := util.DoHttpAndGetArrayBody(b.httpClient, request)
+    for _, rawOrderData := range rawOrders {
+        orderData := rawOrderData.(map[string]interface{})
+        // cancelling needs the symbol, so only orders on configured asset pairs are returned
+        assetPair, ok := reverseTranslator[orderData["symbol"].(string)]
+        if !ok {
+            continue
+        }
+        orderId := types.OrderId(strconv.FormatUint(uint64(orderData["orderId"].(float64)), 10))
+        original, ok := b.orderIdToOrderTranslator.Load(orderId)
+        if !ok {
+            price, err := decimal.NewFromString(orderData["price"].(string))
+            if err != nil {
+                logger.Fatal("parsing decimal failed", logging.Err(err))
+            }
+            quantity, err := decimal.NewFromString(orderData["origQty"].(string))
+            if err != nil {
+                logger.Fatal("parsing decimal failed", logging.Err(err))
+            }
+            orderType := types.Buy
+            if orderData["side"].(string) == "SELL" {
+                orderType = types.Sell
+            }
+            original = &types.Order{
+                OrderType: orderType,
+                AssetPair: assetPair,
+                Price: price,
+                Quantity: quantity,
+            }
+            b.orderIdToOrderTranslator.Store(orderId, original)
+        }
+
+        orderStatus := types.OrderStatus{
+            Status: types.Unfilled,
+            Original: original,
+        }
+        if orderData["status"].(string) == "PARTIALLY_FILLED" {
+            quantity, err := decimal.NewFromString(orderData["executedQty"].(string))
+            if err != nil {
+                logger.Fatal("parsing decimal failed", logging.Err(err))
+            }
+            orderStatus.Status = types.PartiallyFilled
+            orderStatus.FilledQuantity = &quantity
+        }
+        orderStatuses[orderId] = orderStatus
+    }
+    return orderStatuses
+}
+
 func (b *BinanceUS) GetBalances() map[types.Asset]decimal.Decimal {
     queryParams := url.Values{
         "timestamp": []string{strconv.FormatInt(time.Now().UnixMilli(), 10)},
@@ -327,23 +497,23 @@ func (b *BinanceUS) GetBalances() map[types.Asset]decimal.Decimal {
 
     request, err := http.NewRequest("GET", util.ParseUrlWithQuery(RESTEndpoint + "/api/v3/account", queryParams) + "&signature=" + signature, nil)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.Err(err))
     }
     request.Header.Set("X-MBX-APIKEY", b.apiKey)
 
-    bodyJson := util.DoHttpAndGetBody(b.httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(b.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     balances := make(map[types.Asset]decimal.Decimal)
     for _, rawData := range bodyJson["balances"].([]interface{}) {
         data := rawData.(map[string]string)
         free, err := decimal.NewFromString(data["free"])
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.Err(err))
         }
         locked, err := decimal.NewFromString(data["locked"])
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.Err(err))
         }
         balances[types.Asset(data["asset"])] = free.Add(locked)
     }
@@ -2,23 +2,21 @@ package binanceus
 
 import (
 	"fmt"
-	"os"
 	"testing"
 	"time"
 
+	"github.com/denali-capital/grizzly/secrets"
 	grizzlytesting "github.com/denali-capital/grizzly/testing"
 	"github.com/joho/godotenv"
 )
 
-const apiKey string = "JJqJgQeJhZCgXXZp6SBea3Dje8mmsWW0hWEjuVkYLOuZjxVPf71tNIE5AJU0GsYt"
-
 // TODO: test use data stuff
 func TestBinanceUS(t *testing.T) {
 	err := godotenv.Load("../../.env")
     if err != nil {
         t.Fatalf("Error loading .env file\n%v\n", err)
     }
-	binanceUS := NewBinanceUS(apiKey, os.Getenv("BINANCEUS" + grizzlytesting.SecretKeySuffix), grizzlytesting.BinanceUSAssetPairTranslator)
+	binanceUS := NewBinanceUS(secrets.NewEnvProvider(), grizzlytesting.BinanceUSAssetPairTranslator, 200, 1000, 1000)
 	time.Sleep(grizzlytesting.SleepDuration)
 	t.Run("GetHistoricalSpreads", func(t *testing.T) {
 		testBinanceUSGetHistoricalSpreads(t, binanceUS)
@@ -29,6 +27,9 @@ func TestBinanceUS(t *testing.T) {
 	t.Run("GetOrderBooks", func(t *testing.T) {
 		testGetOrderBooks(t, binanceUS)
 	})
+	t.Run("GetHistoricalCandles", func(t *testing.T) {
+		testGetHistoricalCandles(t, binanceUS)
+	})
 	t.Run("GetLatency", func(t *testing.T) {
 		testGetLatency(t, binanceUS)
 	})
@@ -66,6 +67,22 @@ func testGetOrderBooks(t *testing.T, binanceUS *BinanceUS) {
 	}
 }
 
+func testGetHistoricalCandles(t *testing.T, binanceUS *BinanceUS) {
+	candles, ok := binanceUS.GetHistoricalCandles(grizzlytesting.BTCUSD, time.Minute, time.Now().Add(-time.Hour))
+	if !ok || len(candles) == 0 {
+		t.Fatalf("Candles should not be empty")
+	}
+	for i := 1; i < len(candles); i++ {
+		if !candles[i - 1].Start.Before(candles[i].Start) {
+			t.Fatalf("Candles should be oldest first")
+		}
+	}
+	if _, ok := binanceUS.GetHistoricalCandles(grizzlytesting.BTCUSD, time.Second, time.Now().Add(-time.Minute)); ok {
+		t.Fatalf("One second candles should not be offered")
+	}
+	fmt.Println(candles[len(candles) - 1])
+}
+
 func testGetLatency(t *testing.T, binanceUS *BinanceUS) {
 	latency := binanceUS.GetLatency()
 	fmt.Println(latency)
@@ -1,8 +1,8 @@
 package binanceus
 
 import (
+    "context"
     "encoding/json"
-    "log"
     "net/http"
     "net/url"
     "sort"
@@ -11,6 +11,8 @@ import (
     "sync"
     "time"
 
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
     "github.com/denali-capital/grizzly/types"
     "github.com/denali-capital/grizzly/util"
     "github.com/gorilla/websocket"
@@ -20,6 +22,8 @@ import (
 // docs: https://docs.binanceUS.com/websockets
 const WebSocketEndpoint string = "wss://stream.binance.us:9443"
 const CombinedStreamIndicator string = "/stream?streams="
+// every print, "@aggTrade" carries the same fields aggregated by taker order
+const TradeStream string = "@trade"
 
 type binanceUSSubscriptionMessage struct {
     Method string   `json:"method"`
@@ -34,7 +38,117 @@ type binanceUSWebSocketRecorder struct {
     assetPairTranslator types.AssetPairTranslator
     // map[string]chan map[string]interface{}
     channels            *sync.Map
+    // map[string]context.CancelFunc, stops the goroutine processing the stream
+    cancels             *sync.Map
     id                  uint
+    // canceled by Close, stops record and every per asset pair goroutine
+    ctx                 context.Context
+    cancel              context.CancelFunc
+    closeOnce           sync.Once
+}
+
+// per stream context, canceled by UnregisterAssetPair or along with ctx
+func startStream(ctx context.Context, cancels *sync.Map, streamName string) context.Context {
+    streamCtx, cancel := context.WithCancel(ctx)
+    cancels.Store(streamName, cancel)
+    return streamCtx
+}
+
+func (b *binanceUSWebSocketRecorder) closing() bool {
+    return b.ctx.Err() != nil
+}
+
+// drops resp once the recorder is closing, its goroutine may already be gone
+func (b *binanceUSWebSocketRecorder) forward(channel chan map[string]interface{}, resp map[string]interface{}) {
+    select {
+    case channel <- resp:
+    case <-b.ctx.Done():
+    }
+}
+
+// unsubscribes from every stream, then sends a close frame and closes the connection
+func (b *binanceUSWebSocketRecorder) close() {
+    b.closeOnce.Do(func() {
+        b.cancel()
+        // unblocks the read record is waiting on so that the lock is released
+        b.webSocketConnection.SetReadDeadline(time.Now())
+        b.Lock()
+        defer b.Unlock()
+
+        streams := make([]string, 0)
+        b.channels.Range(func(key, value interface{}) bool {
+            streams = append(streams, key.(string))
+            return true
+        })
+        if len(streams) > 0 {
+            payloadJson, err := json.Marshal(binanceUSSubscriptionMessage{
+                Method: "UNSUBSCRIBE",
+                Params: streams,
+                Id: b.id,
+            })
+            if err != nil {
+                logger.Fatal("encoding message failed", logging.Err(err))
+            }
+            b.webSocketConnection.WriteMessage(websocket.TextMessage, payloadJson)
+            b.id++
+        }
+        b.webSocketConnection.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+        b.webSocketConnection.Close()
+    })
+}
+
+// unsubscribes from one stream and stops its goroutine, false if it was not subscribed
+func (b *binanceUSWebSocketRecorder) unregister(assetPair types.AssetPair, streamName string) bool {
+    b.Lock()
+    defer b.Unlock()
+    if _, ok := b.channels.Load(streamName); !ok || b.closing() {
+        return false
+    }
+
+    payloadJson, err := json.Marshal(binanceUSSubscriptionMessage{
+        Method: "UNSUBSCRIBE",
+        Params: []string{streamName},
+        Id: b.id,
+    })
+    if err != nil {
+        logger.Fatal("encoding message failed", logging.AssetPair(assetPair), logging.Err(err))
+    }
+    b.webSocketConnection.WriteMessage(1, payloadJson)
+    for {
+        var resp map[string]interface{}
+        err := b.webSocketConnection.ReadJSON(&resp)
+        if err != nil {
+            if b.closing() {
+                return false
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
+        }
+        if _, ok := resp["code"]; ok {
+            logger.Fatal("websocket error", logging.AssetPair(assetPair), "response", resp)
+        }
+        if id, ok := resp["id"]; ok {
+            if uint(id.(float64)) != b.id {
+                logger.Fatal("id mismatch", "sent", b.id, "received", id)
+            }
+            b.id++
+            break
+        }
+        messageStreamName := resp["stream"].(string)
+        if messageStreamName == streamName {
+            // still in flight when the unsubscribe was sent
+            continue
+        }
+        channel, ok := b.channels.Load(messageStreamName)
+        if !ok {
+            logger.Fatal("channel not found", "stream", messageStreamName)
+        }
+        b.forward(channel.(chan map[string]interface{}), util.MapCopy(resp))
+    }
+
+    b.channels.Delete(streamName)
+    cancel, _ := b.cancels.LoadAndDelete(streamName)
+    cancel.(context.CancelFunc)()
+    return true
 }
 
 // might be problems with holding a ws connection over 24 hours
@@ -44,17 +158,21 @@ func (b *binanceUSWebSocketRecorder) record() {
         b.Lock()
         err := b.webSocketConnection.ReadJSON(&resp)
         if err != nil {
-            log.Fatalln(err)
+            if b.closing() {
+                b.Unlock()
+                return
+            }
+            logger.Fatal("websocket read failed", logging.Err(err))
         }
         if _, ok := resp["code"]; ok {
-            log.Fatalln(resp)
+            logger.Fatal("websocket error", "response", resp)
         }
         streamName := resp["stream"].(string)
         channel, ok := b.channels.Load(streamName)
         if !ok {
-            log.Fatalf("channel not found for streamName %v\n", streamName)
+            logger.Fatal("channel not found", "stream", streamName)
         }
-        channel.(chan map[string]interface{}) <- util.MapCopy(resp)
+        b.forward(channel.(chan map[string]interface{}), util.MapCopy(resp))
         b.Unlock()
     }
 }
@@ -67,6 +185,7 @@ type BinanceUSSpreadRecorder struct {
 }
 
 func NewBinanceUSSpreadRecorder(assetPairs []types.AssetPair, assetPairTranslator types.AssetPairTranslator, capacity uint) *BinanceUSSpreadRecorder {
+    ctx, cancel := context.WithCancel(context.Background())
     streamTranslator := make(map[string]types.AssetPair)
     streams := make([]string, len(assetPairs))
     for i, assetPair := range assetPairs {
@@ -78,10 +197,11 @@ func NewBinanceUSSpreadRecorder(assetPairs []types.AssetPair, assetPairTranslato
 
     webSocketConnection, _, err := websocket.DefaultDialer.Dial(endpoint, http.Header{})
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("websocket dial failed", logging.Err(err))
     }
 
     channels := &sync.Map{}
+    cancels := &sync.Map{}
     historicalSpreads := &sync.Map{}
     for _, streamName := range streams {
         channel := make(chan map[string]interface{})
@@ -90,7 +210,7 @@ func NewBinanceUSSpreadRecorder(assetPairs []types.AssetPair, assetPairTranslato
         channels.Store(streamName, channel)
         historicalSpreads.Store(streamTranslator[streamName], historicalSpread)
 
-        go processSpreadUpdates(historicalSpread, channel)
+        go processSpreadUpdates(startStream(ctx, cancels, streamName), streamTranslator[streamName], historicalSpread, channel)
     }
 
     binanceUSSpreadRecorder := &BinanceUSSpreadRecorder{
@@ -98,6 +218,9 @@ func NewBinanceUSSpreadRecorder(assetPairs []types.AssetPair, assetPairTranslato
             webSocketConnection: webSocketConnection,
             assetPairTranslator: assetPairTranslator,
             channels: channels,
+            cancels: cancels,
+            ctx: ctx,
+            cancel: cancel,
         },
         capacity: capacity,
         historicalSpreads: historicalSpreads,
@@ -108,18 +231,21 @@ func NewBinanceUSSpreadRecorder(assetPairs []types.AssetPair, assetPairTranslato
     return binanceUSSpreadRecorder
 }
 
-func processSpreadUpdates(historicalSpread *util.ConcurrentFixedSizeSpreadQueue, channel chan map[string]interface{}) {
+func processSpreadUpdates(ctx context.Context, assetPair types.AssetPair, historicalSpread *util.ConcurrentFixedSizeSpreadQueue, channel chan map[string]interface{}) {
     for {
         select {
+        case <-ctx.Done():
+            return
         case resp := <- channel:
+            metrics.WebSocketMessages.Inc(exchangeName, "spread", assetPair.String())
             rawSpread := resp["data"].(map[string]interface{})
             bid, err := decimal.NewFromString(rawSpread["b"].(string))
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
             }
             ask, err := decimal.NewFromString(rawSpread["a"].(string))
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
             }
 
             historicalSpread.Push(types.Spread{
@@ -127,6 +253,7 @@ func processSpreadUpdates(historicalSpread *util.ConcurrentFixedSizeSpreadQueue,
                 Ask: ask,
                 Timestamp: time.Now(),
             })
+            metrics.SpreadAge.Touch(exchangeName, assetPair.String())
         }
     }
 }
@@ -160,7 +287,7 @@ func (b *BinanceUSSpreadRecorder) RegisterAssetPair(assetPair types.AssetPair) {
         Id: b.id,
     })
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("encoding message failed", logging.AssetPair(assetPair), logging.Err(err))
     }
     b.Lock()
     defer b.Unlock()
@@ -169,14 +296,17 @@ func (b *BinanceUSSpreadRecorder) RegisterAssetPair(assetPair types.AssetPair) {
         var resp map[string]interface{}
         err := b.webSocketConnection.ReadJSON(&resp)
         if err != nil {
-            log.Fatalln(err)
+            if b.closing() {
+                return
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         if _, ok := resp["code"]; ok {
-            log.Fatalln(resp)
+            logger.Fatal("websocket error", logging.AssetPair(assetPair), "response", resp)
         }
         if id, ok := resp["id"]; ok {
             if uint(id.(float64)) != b.id {
-                log.Fatalf("id mismatch between sent %v and received %v\n", b.id, id)
+                logger.Fatal("id mismatch", "sent", b.id, "received", id)
             }
             b.id++
             break
@@ -184,9 +314,9 @@ func (b *BinanceUSSpreadRecorder) RegisterAssetPair(assetPair types.AssetPair) {
         streamName := resp["stream"].(string)
         channel, ok := b.channels.Load(streamName)
         if !ok {
-            log.Fatalf("channel not found for streamName %v\n", streamName)
+            logger.Fatal("channel not found", "stream", streamName)
         }
-        channel.(chan map[string]interface{}) <- util.MapCopy(resp)
+        b.forward(channel.(chan map[string]interface{}), util.MapCopy(resp))
     }
     channel := make(chan map[string]interface{})
     historicalSpread := util.NewConcurrentFixedSizeSpreadQueue(b.capacity)
@@ -194,7 +324,18 @@ func (b *BinanceUSSpreadRecorder) RegisterAssetPair(assetPair types.AssetPair) {
     b.channels.Store(streamName, channel)
     b.historicalSpreads.Store(assetPair, historicalSpread)
 
-    go processSpreadUpdates(historicalSpread, channel)
+    go processSpreadUpdates(startStream(b.ctx, b.cancels, streamName), assetPair, historicalSpread, channel)
+}
+
+func (b *BinanceUSSpreadRecorder) UnregisterAssetPair(assetPair types.AssetPair) {
+    if b.unregister(assetPair, strings.ToLower(b.assetPairTranslator[assetPair]) + "@bookTicker") {
+        b.historicalSpreads.Delete(assetPair)
+    }
+}
+
+// unsubscribes from every asset pair and stops recording, safe to call more than once
+func (b *BinanceUSSpreadRecorder) Close() {
+    b.close()
 }
 
 type BinanceUSOrderBookRecorder struct {
@@ -221,11 +362,11 @@ func selectLimit(depth uint) uint {
 }
 
 func getOrderBookSnapshot(httpClient *http.Client, assetPair types.AssetPair, assetPairTranslator types.AssetPairTranslator, limit uint, channel chan util.ConcurrentOrderBookResponse) {
-    bodyJson := util.HttpGetAndGetBody(httpClient, util.ParseUrlWithQuery(RESTEndpoint + "/api/v3/depth", url.Values{
+    bodyJson, requestId := util.HttpGetAndGetBody(httpClient, util.ParseUrlWithQuery(RESTEndpoint + "/api/v3/depth", url.Values{
         "symbol": []string{assetPairTranslator[assetPair]},
         "limit": []string{strconv.FormatUint(uint64(limit), 10)},
     }))
-    checkError(bodyJson)
+    checkError(requestId, RESTEndpoint + "/api/v3/depth", bodyJson)
 
     lastUpdateId := uint(bodyJson["lastUpdateId"].(float64))
     asks := make([]types.OrderBookEntry, 0)
@@ -233,11 +374,11 @@ func getOrderBookSnapshot(httpClient *http.Client, assetPair types.AssetPair, as
     for _, rawOrderBookEntry := range bodyJson["asks"].([]interface{}) {
         price, err := decimal.NewFromString(rawOrderBookEntry.([]interface{})[0].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         quantity, err := decimal.NewFromString(rawOrderBookEntry.([]interface{})[1].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         asks = append(asks, types.OrderBookEntry{
             Price: price,
@@ -248,11 +389,11 @@ func getOrderBookSnapshot(httpClient *http.Client, assetPair types.AssetPair, as
     for _, rawOrderBookEntry := range bodyJson["bids"].([]interface{}) {
         price, err := decimal.NewFromString(rawOrderBookEntry.([]interface{})[0].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         quantity, err := decimal.NewFromString(rawOrderBookEntry.([]interface{})[1].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         bids = append(bids, types.OrderBookEntry{
             Price: price,
@@ -283,6 +424,7 @@ func getOrderBookSnapshots(httpClient *http.Client, assetPairs []types.AssetPair
 }
 
 func NewBinanceUSOrderBookRecorder(httpClient *http.Client, assetPairs []types.AssetPair, assetPairTranslator types.AssetPairTranslator, depth uint) *BinanceUSOrderBookRecorder {
+    ctx, cancel := context.WithCancel(context.Background())
     streamTranslator := make(map[string]types.AssetPair)
     streams := make([]string, len(assetPairs))
     for i, assetPair := range assetPairs {
@@ -294,10 +436,11 @@ func NewBinanceUSOrderBookRecorder(httpClient *http.Client, assetPairs []types.A
 
     webSocketConnection, _, err := websocket.DefaultDialer.Dial(endpoint, http.Header{})
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("websocket dial failed", logging.Err(err))
     }
 
     channels := &sync.Map{}
+    cancels := &sync.Map{}
     orderBooks, syncOrderBooks := getOrderBookSnapshots(httpClient, assetPairs, assetPairTranslator, depth)
     for _, streamName := range streams {
         assetPair := streamTranslator[streamName]
@@ -305,7 +448,7 @@ func NewBinanceUSOrderBookRecorder(httpClient *http.Client, assetPairs []types.A
 
         channels.Store(streamName, channel)
 
-        go processOrderBookUpdates(httpClient, assetPair, assetPairTranslator, orderBooks[assetPair], channel, depth)
+        go processOrderBookUpdates(startStream(ctx, cancels, streamName), httpClient, assetPair, assetPairTranslator, orderBooks[assetPair], channel, depth)
     }
 
     binanceUSOrderBookRecorder := &BinanceUSOrderBookRecorder{
@@ -313,6 +456,9 @@ func NewBinanceUSOrderBookRecorder(httpClient *http.Client, assetPairs []types.A
             webSocketConnection: webSocketConnection,
             assetPairTranslator: assetPairTranslator,
             channels: channels,
+            cancels: cancels,
+            ctx: ctx,
+            cancel: cancel,
         },
         httpClient: httpClient,
         depth: depth,
@@ -331,10 +477,13 @@ func validateUpdateId(eventId uint, lastUpdateId uint) bool {
     return true
 }
 
-func processOrderBookUpdates(httpClient *http.Client, assetPair types.AssetPair, assetPairTranslator types.AssetPairTranslator, concurrentOrderBook *util.ConcurrentOrderBook, channel chan map[string]interface{}, depth uint) {
+func processOrderBookUpdates(ctx context.Context, httpClient *http.Client, assetPair types.AssetPair, assetPairTranslator types.AssetPairTranslator, concurrentOrderBook *util.ConcurrentOrderBook, channel chan map[string]interface{}, depth uint) {
     for {
         select {
+        case <-ctx.Done():
+            return
         case resp := <- channel:
+            metrics.WebSocketMessages.Inc(exchangeName, "order_book", assetPair.String())
             data := resp["data"].(map[string]interface{})
             bids := concurrentOrderBook.GetBids()
             asks := concurrentOrderBook.GetAsks()
@@ -369,6 +518,7 @@ func processOrderBookUpdates(httpClient *http.Client, assetPair types.AssetPair,
                 concurrentOrderBook.LastUpdateId = lastUpdateId
                 concurrentOrderBook.SetBidsAndAsks(bids[:util.MinUint(depth, uint(len(bids)))], asks[:util.MinUint(depth, uint(len(asks)))])
             } else {
+                metrics.UpdateIdGaps.Inc(exchangeName, assetPair.String())
                 channel := make(chan util.ConcurrentOrderBookResponse)
                 go getOrderBookSnapshot(httpClient, assetPair, assetPairTranslator, selectLimit(depth), channel)
                 select {
@@ -376,6 +526,7 @@ func processOrderBookUpdates(httpClient *http.Client, assetPair types.AssetPair,
                     concurrentOrderBook.FilterAndMerge(resp.ConcurrentOrderBook, true)
                 }
             }
+            metrics.OrderBookAge.Touch(exchangeName, assetPair.String())
         }
     }
 }
@@ -401,7 +552,7 @@ func (b *BinanceUSOrderBookRecorder) RegisterAssetPair(assetPair types.AssetPair
         Id: b.id,
     })
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("encoding message failed", logging.AssetPair(assetPair), logging.Err(err))
     }
     b.Lock()
     defer b.Unlock()
@@ -410,14 +561,17 @@ func (b *BinanceUSOrderBookRecorder) RegisterAssetPair(assetPair types.AssetPair
         var resp map[string]interface{}
         err := b.webSocketConnection.ReadJSON(&resp)
         if err != nil {
-            log.Fatalln(err)
+            if b.closing() {
+                return
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         if _, ok := resp["code"]; ok {
-            log.Fatalln(resp)
+            logger.Fatal("websocket error", logging.AssetPair(assetPair), "response", resp)
         }
         if id, ok := resp["id"]; ok {
             if uint(id.(float64)) != b.id {
-                log.Fatalf("id mismatch between sent %v and received %v\n", b.id, id)
+                logger.Fatal("id mismatch", "sent", b.id, "received", id)
             }
             b.id++
             break
@@ -425,9 +579,9 @@ func (b *BinanceUSOrderBookRecorder) RegisterAssetPair(assetPair types.AssetPair
         streamName := resp["stream"].(string)
         channel, ok := b.channels.Load(streamName)
         if !ok {
-            log.Fatalf("channel not found for streamName %v\n", streamName)
+            logger.Fatal("channel not found", "stream", streamName)
         }
-        channel.(chan map[string]interface{}) <- util.MapCopy(resp)
+        b.forward(channel.(chan map[string]interface{}), util.MapCopy(resp))
     }
     channel := make(chan map[string]interface{})
 
@@ -438,6 +592,183 @@ func (b *BinanceUSOrderBookRecorder) RegisterAssetPair(assetPair types.AssetPair
     select {
     case resp := <- snapshotChannel:
         b.orderBooks.Store(assetPair, resp.ConcurrentOrderBook)
-        go processOrderBookUpdates(b.httpClient, assetPair, b.assetPairTranslator, resp.ConcurrentOrderBook, channel, b.depth)
+        go processOrderBookUpdates(startStream(b.ctx, b.cancels, streamName), b.httpClient, assetPair, b.assetPairTranslator, resp.ConcurrentOrderBook, channel, b.depth)
+    }
+}
+
+func (b *BinanceUSOrderBookRecorder) UnregisterAssetPair(assetPair types.AssetPair) {
+    if b.unregister(assetPair, strings.ToLower(b.assetPairTranslator[assetPair]) + "@depth") {
+        b.orderBooks.Delete(assetPair)
     }
 }
+
+// unsubscribes from every asset pair and stops recording, safe to call more than once
+func (b *BinanceUSOrderBookRecorder) Close() {
+    b.close()
+}
+
+type BinanceUSTradeRecorder struct {
+    binanceUSWebSocketRecorder
+    capacity                 uint
+    // map[types.AssetPair]*util.ConcurrentTradeRing
+    trades                   *sync.Map
+}
+
+func NewBinanceUSTradeRecorder(assetPairs []types.AssetPair, assetPairTranslator types.AssetPairTranslator, capacity uint) *BinanceUSTradeRecorder {
+    ctx, cancel := context.WithCancel(context.Background())
+    streamTranslator := make(map[string]types.AssetPair)
+    streams := make([]string, len(assetPairs))
+    for i, assetPair := range assetPairs {
+        streamName := strings.ToLower(assetPairTranslator[assetPair]) + TradeStream
+        streamTranslator[streamName] = assetPair
+        streams[i] = streamName
+    }
+    endpoint := WebSocketEndpoint + CombinedStreamIndicator + strings.Join(streams, "/")
+
+    webSocketConnection, _, err := websocket.DefaultDialer.Dial(endpoint, http.Header{})
+    if err != nil {
+        logger.Fatal("websocket dial failed", logging.Err(err))
+    }
+
+    channels := &sync.Map{}
+    cancels := &sync.Map{}
+    trades := &sync.Map{}
+    for _, streamName := range streams {
+        channel := make(chan map[string]interface{})
+        tradeRing := util.NewConcurrentTradeRing(capacity)
+
+        channels.Store(streamName, channel)
+        trades.Store(streamTranslator[streamName], tradeRing)
+
+        go processTradeUpdates(startStream(ctx, cancels, streamName), streamTranslator[streamName], tradeRing, channel)
+    }
+
+    binanceUSTradeRecorder := &BinanceUSTradeRecorder{
+        binanceUSWebSocketRecorder: binanceUSWebSocketRecorder{
+            webSocketConnection: webSocketConnection,
+            assetPairTranslator: assetPairTranslator,
+            channels: channels,
+            cancels: cancels,
+            ctx: ctx,
+            cancel: cancel,
+        },
+        capacity: capacity,
+        trades: trades,
+    }
+
+    go binanceUSTradeRecorder.record()
+
+    return binanceUSTradeRecorder
+}
+
+func processTradeUpdates(ctx context.Context, assetPair types.AssetPair, tradeRing *util.ConcurrentTradeRing, channel chan map[string]interface{}) {
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case resp := <- channel:
+            metrics.WebSocketMessages.Inc(exchangeName, "trade", assetPair.String())
+            rawTrade := resp["data"].(map[string]interface{})
+            price, err := decimal.NewFromString(rawTrade["p"].(string))
+            if err != nil {
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
+            }
+            quantity, err := decimal.NewFromString(rawTrade["q"].(string))
+            if err != nil {
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
+            }
+            // the buyer resting on the book means the seller took liquidity
+            side := types.Buy
+            if rawTrade["m"].(bool) {
+                side = types.Sell
+            }
+
+            tradeRing.Push(types.Trade{
+                Price: price,
+                Quantity: quantity,
+                Side: side,
+                Timestamp: time.UnixMilli(int64(rawTrade["T"].(float64))),
+            })
+        }
+    }
+}
+
+func (b *BinanceUSTradeRecorder) GetTradesSince(assetPair types.AssetPair, since time.Time) ([]types.Trade, bool) {
+    result, ok := b.trades.Load(assetPair)
+    if !ok {
+        return make([]types.Trade, 0), false
+    }
+    return result.(*util.ConcurrentTradeRing).Since(since), true
+}
+
+func (b *BinanceUSTradeRecorder) GetVWAP(assetPair types.AssetPair, window time.Duration) (decimal.Decimal, bool) {
+    trades, ok := b.GetTradesSince(assetPair, time.Now().Add(-window))
+    if !ok {
+        return decimal.Zero, false
+    }
+    return util.ComputeVWAP(trades)
+}
+
+func (b *BinanceUSTradeRecorder) RegisterAssetPair(assetPair types.AssetPair) {
+    if _, ok := b.trades.Load(assetPair); ok {
+        return
+    }
+
+    streamName := strings.ToLower(b.assetPairTranslator[assetPair]) + TradeStream
+
+    b.Lock()
+    defer b.Unlock()
+    payloadJson, err := json.Marshal(binanceUSSubscriptionMessage{
+        Method: "SUBSCRIBE",
+        Params: []string{streamName},
+        Id: b.id,
+    })
+    if err != nil {
+        logger.Fatal("encoding message failed", logging.AssetPair(assetPair), logging.Err(err))
+    }
+    b.webSocketConnection.WriteMessage(1, payloadJson)
+    for {
+        var resp map[string]interface{}
+        err := b.webSocketConnection.ReadJSON(&resp)
+        if err != nil {
+            if b.closing() {
+                return
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
+        }
+        if _, ok := resp["code"]; ok {
+            logger.Fatal("websocket error", logging.AssetPair(assetPair), "response", resp)
+        }
+        if id, ok := resp["id"]; ok {
+            if uint(id.(float64)) != b.id {
+                logger.Fatal("id mismatch", "sent", b.id, "received", id)
+            }
+            b.id++
+            break
+        }
+        streamName := resp["stream"].(string)
+        channel, ok := b.channels.Load(streamName)
+        if !ok {
+            logger.Fatal("channel not found", "stream", streamName)
+        }
+        b.forward(channel.(chan map[string]interface{}), util.MapCopy(resp))
+    }
+    channel := make(chan map[string]interface{})
+    tradeRing := util.NewConcurrentTradeRing(b.capacity)
+
+    b.channels.Store(streamName, channel)
+    b.trades.Store(assetPair, tradeRing)
+
+    go processTradeUpdates(startStream(b.ctx, b.cancels, streamName), assetPair, tradeRing, channel)
+}
+
+func (b *BinanceUSTradeRecorder) UnregisterAssetPair(assetPair types.AssetPair) {
+    if b.unregister(assetPair, strings.ToLower(b.assetPairTranslator[assetPair]) + TradeStream) {
+        b.trades.Delete(assetPair)
+    }
+}
+
+// unsubscribes from every asset pair and stops recording, safe to call more than once
+func (b *BinanceUSTradeRecorder) Close() {
+    b.close()
+}
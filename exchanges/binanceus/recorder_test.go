@@ -3,10 +3,12 @@ package binanceus
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	grizzlytesting "github.com/denali-capital/grizzly/testing"
+	"github.com/denali-capital/grizzly/types"
 )
 
 func TestBinanceUSSpreadRecorder(t *testing.T) {
@@ -18,6 +20,15 @@ func TestBinanceUSSpreadRecorder(t *testing.T) {
 	t.Run("RegisterAssetPair", func(t *testing.T) {
 		testSpreadRegisterAssetPair(t, binanceUSSpreadRecorder)
 	})
+	t.Run("UnregisterAssetPair", func(t *testing.T) {
+		testUnregisterAssetPair(t, binanceUSSpreadRecorder, &binanceUSSpreadRecorder.binanceUSWebSocketRecorder, strings.ToLower(grizzlytesting.BinanceUSAssetPairTranslator[grizzlytesting.DOGEUSD]) + "@bookTicker", func() bool {
+			_, ok := binanceUSSpreadRecorder.GetHistoricalSpreads(grizzlytesting.DOGEUSD)
+			return ok
+		})
+	})
+	t.Run("Close", func(t *testing.T) {
+		testClose(t, binanceUSSpreadRecorder, &binanceUSSpreadRecorder.binanceUSWebSocketRecorder)
+	})
 }
 
 func testRecorderGetHistoricalSpreads(t *testing.T, binanceUSSpreadRecorder *BinanceUSSpreadRecorder) {
@@ -51,6 +62,15 @@ func TestBinanceUSOrderBookRecorder(t *testing.T) {
 	t.Run("RegisterAssetPair", func(t *testing.T) {
 		testOrderBookRegisterAssetPair(t, binanceUSOrderBookRecorder)
 	})
+	t.Run("UnregisterAssetPair", func(t *testing.T) {
+		testUnregisterAssetPair(t, binanceUSOrderBookRecorder, &binanceUSOrderBookRecorder.binanceUSWebSocketRecorder, strings.ToLower(grizzlytesting.BinanceUSAssetPairTranslator[grizzlytesting.DOGEUSD]) + "@depth", func() bool {
+			_, ok := binanceUSOrderBookRecorder.GetOrderBook(grizzlytesting.DOGEUSD)
+			return ok
+		})
+	})
+	t.Run("Close", func(t *testing.T) {
+		testClose(t, binanceUSOrderBookRecorder, &binanceUSOrderBookRecorder.binanceUSWebSocketRecorder)
+	})
 }
 
 func testGetOrderBook(t *testing.T, binanceUSOrderBookRecorder *BinanceUSOrderBookRecorder) {
@@ -74,3 +94,96 @@ func testOrderBookRegisterAssetPair(t *testing.T, binanceUSOrderBookRecorder *Bi
 	}
 	fmt.Printf("%v: %v\n", translatedPair, orderBook)
 }
+
+func TestBinanceUSTradeRecorder(t *testing.T) {
+	binanceUSTradeRecorder := NewBinanceUSTradeRecorder(grizzlytesting.AssetPairs, grizzlytesting.BinanceUSAssetPairTranslator, 100)
+	time.Sleep(grizzlytesting.SleepDuration)
+	t.Run("GetTradesSince", func(t *testing.T) {
+		testGetTradesSince(t, binanceUSTradeRecorder)
+	})
+	t.Run("RegisterAssetPair", func(t *testing.T) {
+		testTradeRegisterAssetPair(t, binanceUSTradeRecorder)
+	})
+	t.Run("UnregisterAssetPair", func(t *testing.T) {
+		testUnregisterAssetPair(t, binanceUSTradeRecorder, &binanceUSTradeRecorder.binanceUSWebSocketRecorder, strings.ToLower(grizzlytesting.BinanceUSAssetPairTranslator[grizzlytesting.DOGEUSD]) + TradeStream, func() bool {
+			_, ok := binanceUSTradeRecorder.GetTradesSince(grizzlytesting.DOGEUSD, time.Time{})
+			return ok
+		})
+	})
+	t.Run("Close", func(t *testing.T) {
+		testClose(t, binanceUSTradeRecorder, &binanceUSTradeRecorder.binanceUSWebSocketRecorder)
+	})
+}
+
+func testGetTradesSince(t *testing.T, binanceUSTradeRecorder *BinanceUSTradeRecorder) {
+	for _, assetPair := range grizzlytesting.AssetPairs {
+		translatedPair := grizzlytesting.BinanceUSAssetPairTranslator[assetPair]
+		trades, ok := binanceUSTradeRecorder.GetTradesSince(assetPair, time.Time{})
+		if !ok {
+			t.Fatalf("AssetPair %v should be recorded\n", translatedPair)
+		}
+		fmt.Printf("%v: %v\n", translatedPair, trades)
+	}
+}
+
+func testTradeRegisterAssetPair(t *testing.T, binanceUSTradeRecorder *BinanceUSTradeRecorder) {
+	translatedPair := grizzlytesting.BinanceUSAssetPairTranslator[grizzlytesting.DOGEUSD]
+	binanceUSTradeRecorder.RegisterAssetPair(grizzlytesting.DOGEUSD)
+	time.Sleep(grizzlytesting.SleepDuration)
+	trades, ok := binanceUSTradeRecorder.GetTradesSince(grizzlytesting.DOGEUSD, time.Time{})
+	if !ok {
+		t.Fatalf("AssetPair %v should be recorded\n", translatedPair)
+	}
+	fmt.Printf("%v: %v\n", translatedPair, trades)
+}
+
+// unregisters DOGEUSD, which the RegisterAssetPair subtest subscribed to as key
+func testUnregisterAssetPair(t *testing.T, recorder types.AssetPairRecorder, webSocketRecorder *binanceUSWebSocketRecorder, key string, recorded func() bool) {
+	translatedPair := grizzlytesting.BinanceUSAssetPairTranslator[grizzlytesting.DOGEUSD]
+	channel, ok := webSocketRecorder.channels.Load(key)
+	if !ok {
+		t.Fatalf("AssetPair %v should be subscribed\n", translatedPair)
+	}
+	recorder.UnregisterAssetPair(grizzlytesting.DOGEUSD)
+	if recorded() {
+		t.Fatalf("AssetPair %v should no longer be recorded\n", translatedPair)
+	}
+	if _, ok := webSocketRecorder.cancels.Load(key); ok {
+		t.Fatalf("AssetPair %v should no longer be processed\n", translatedPair)
+	}
+	expectStopped(t, channel.(chan map[string]interface{}))
+	// a second unregister is a no-op
+	recorder.UnregisterAssetPair(grizzlytesting.DOGEUSD)
+}
+
+func testClose(t *testing.T, recorder types.AssetPairRecorder, webSocketRecorder *binanceUSWebSocketRecorder) {
+	channels := make([]chan map[string]interface{}, 0)
+	webSocketRecorder.channels.Range(func(key, value interface{}) bool {
+		channels = append(channels, value.(chan map[string]interface{}))
+		return true
+	})
+	recorder.Close()
+	for _, channel := range channels {
+		expectStopped(t, channel)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		recorder.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("a second Close should return at once\n")
+	}
+}
+
+// fails if anything still receives from channel, the goroutine processing it should have exited
+func expectStopped(t *testing.T, channel chan map[string]interface{}) {
+	select {
+	case channel <- nil:
+		t.Fatalf("goroutine processing the asset pair should have exited\n")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
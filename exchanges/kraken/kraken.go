@@ -5,13 +5,15 @@ import (
     "crypto/sha256"
     "crypto/sha512"
     "encoding/base64"
-    "log"
     "net/http"
     "net/url"
     "strconv"
     "strings"
     "time"
 
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
+    "github.com/denali-capital/grizzly/secrets"
     "github.com/denali-capital/grizzly/types"
     "github.com/denali-capital/grizzly/util"
     "github.com/shopspring/decimal"
@@ -20,6 +22,11 @@ import (
 // docs: https://docs.kraken.com/rest/
 const RESTEndpoint string = "https://api.kraken.com"
 
+// label on metrics and log lines
+const exchangeName string = "Kraken"
+
+var logger *logging.Logger = logging.New("exchanges/kraken").With(logging.Exchange(exchangeName))
+
 type Kraken struct {
     AssetPairTranslator      types.AssetPairTranslator
     ISO4217Translator        types.AssetPairTranslator
@@ -28,20 +35,26 @@ type Kraken struct {
     secretKey                string
     spreadRecorder           types.SpreadRecorder
     orderBookRecorder        types.OrderBookRecorder
+    tradeRecorder            types.TradeRecorder
     latencyEstimator         *util.EwmaEstimator
     orderIdToOrderTranslator *util.ConcurrentOrderIdToOrderPtrMap
     // add timeouts
     httpClient               *http.Client
 }
 
-func NewKraken(apiKey, secretKey string, assetPairTranslator types.AssetPairTranslator, iso4217Translator types.AssetPairTranslator) *Kraken {
+func NewKraken(provider secrets.Provider, assetPairTranslator types.AssetPairTranslator, iso4217Translator types.AssetPairTranslator, spreadCapacity, tradeCapacity, orderBookDepth uint) *Kraken {
+    credentials, err := secrets.Load(provider, "Kraken")
+    if err != nil {
+        logger.Fatal("loading credentials failed", logging.Err(err))
+    }
     assetPairs := assetPairTranslator.GetAssetPairs()
     return &Kraken{
         AssetPairTranslator: assetPairTranslator,
-        apiKey: apiKey,
-        secretKey: secretKey,
-        spreadRecorder: NewKrakenSpreadRecorder(assetPairs, iso4217Translator, 200),
-        orderBookRecorder: NewKrakenOrderBookRecorder(assetPairs, iso4217Translator, 1000),
+        apiKey: credentials.ApiKey,
+        secretKey: credentials.SecretKey,
+        spreadRecorder: NewKrakenSpreadRecorder(assetPairs, iso4217Translator, spreadCapacity),
+        orderBookRecorder: NewKrakenOrderBookRecorder(assetPairs, iso4217Translator, orderBookDepth),
+        tradeRecorder: NewKrakenTradeRecorder(assetPairs, iso4217Translator, tradeCapacity),
         latencyEstimator: util.NewEwmaEstimator(0.125, 0.25, 4),
         orderIdToOrderTranslator: util.NewConcurrentOrderIdToOrderPtrMap(),
         httpClient: &http.Client{},
@@ -52,10 +65,12 @@ func (k *Kraken) String() string {
     return "Kraken"
 }
 
-func checkError(bodyJson map[string]interface{}) {
+// endpoint is the requested URL, only its host and path are recorded
+func checkError(requestId, endpoint string, bodyJson map[string]interface{}) {
     errors := bodyJson["error"].([]interface{})
     if len(errors) > 0 {
-        log.Fatalln(errors)
+        metrics.RestErrors.Inc(util.Endpoint(endpoint))
+        logger.Fatal("request failed", logging.RequestId(requestId), "endpoint", util.Endpoint(endpoint), "errors", errors)
     }
 }
 
@@ -94,20 +109,20 @@ func (k *Kraken) GetCurrentSpread(assetPair types.AssetPair) types.Spread {
     spread, ok := k.spreadRecorder.GetCurrentSpread(assetPair)
     if !ok {
         k.spreadRecorder.RegisterAssetPair(assetPair)
-        bodyJson := util.HttpGetAndGetBody(k.httpClient, util.ParseUrlWithQuery(RESTEndpoint + "/0/public/Ticker", url.Values{
+        bodyJson, requestId := util.HttpGetAndGetBody(k.httpClient, util.ParseUrlWithQuery(RESTEndpoint + "/0/public/Ticker", url.Values{
             "pair": []string{k.AssetPairTranslator[assetPair]},
         }))
-        checkError(bodyJson)
+        checkError(requestId, RESTEndpoint + "/0/public/Ticker", bodyJson)
     
         data := bodyJson["result"].(map[string]interface{})[k.AssetPairTranslator[assetPair]].(map[string]interface{})
     
         bid, err := decimal.NewFromString(data["b"].([]interface{})[0].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         ask, err := decimal.NewFromString(data["a"].([]interface{})[0].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
     
         return types.Spread{
@@ -142,17 +157,100 @@ func (k *Kraken) GetOrderBooks(assetPairs []types.AssetPair) map[types.AssetPair
     return orderBooks
 }
 
+func (k *Kraken) GetTradesSince(assetPair types.AssetPair, since time.Time) ([]types.Trade, bool) {
+    trades, ok := k.tradeRecorder.GetTradesSince(assetPair, since)
+    if !ok {
+        k.tradeRecorder.RegisterAssetPair(assetPair)
+    }
+    return trades, ok
+}
+
+func (k *Kraken) GetVWAP(assetPair types.AssetPair, window time.Duration) (decimal.Decimal, bool) {
+    return k.tradeRecorder.GetVWAP(assetPair, window)
+}
+
+// resolution -> OHLC interval in minutes
+var ohlcIntervals map[time.Duration]int = map[time.Duration]int{
+    time.Minute: 1,
+    5 * time.Minute: 5,
+    15 * time.Minute: 15,
+    30 * time.Minute: 30,
+    time.Hour: 60,
+    4 * time.Hour: 240,
+    24 * time.Hour: 1440,
+}
+
+// at most the 720 most recent candles are served
+func (k *Kraken) GetHistoricalCandles(assetPair types.AssetPair, resolution time.Duration, since time.Time) ([]types.Candle, bool) {
+    interval, ok := ohlcIntervals[resolution]
+    if !ok {
+        return make([]types.Candle, 0), false
+    }
+    bodyJson, requestId := util.HttpGetAndGetBody(k.httpClient, util.ParseUrlWithQuery(RESTEndpoint + "/0/public/OHLC", url.Values{
+        "pair": []string{k.AssetPairTranslator[assetPair]},
+        "interval": []string{strconv.Itoa(interval)},
+        "since": []string{strconv.FormatInt(since.Unix(), 10)},
+    }))
+    checkError(requestId, RESTEndpoint + "/0/public/OHLC", bodyJson)
+
+    // [time, open, high, low, close, vwap, volume, count]
+    data := bodyJson["result"].(map[string]interface{})[k.AssetPairTranslator[assetPair]].([]interface{})
+    candles := make([]types.Candle, 0, len(data))
+    for _, rawCandle := range data {
+        entry := rawCandle.([]interface{})
+        values := make([]decimal.Decimal, 5)
+        for i, index := range []int{1, 2, 3, 4, 6} {
+            value, err := decimal.NewFromString(entry[index].(string))
+            if err != nil {
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
+            }
+            values[i] = value
+        }
+        candles = append(candles, types.Candle{
+            Start: time.Unix(int64(entry[0].(float64)), 0),
+            Open: values[0],
+            High: values[1],
+            Low: values[2],
+            Close: values[3],
+            Volume: values[4],
+            Trades: uint(entry[7].(float64)),
+        })
+    }
+    return candles, true
+}
+
+// starts recording spreads, order books and trades for assetPair ahead of the first request for them
+func (k *Kraken) RegisterAssetPair(assetPair types.AssetPair) {
+    k.spreadRecorder.RegisterAssetPair(assetPair)
+    k.orderBookRecorder.RegisterAssetPair(assetPair)
+    k.tradeRecorder.RegisterAssetPair(assetPair)
+}
+
+func (k *Kraken) UnregisterAssetPair(assetPair types.AssetPair) {
+    k.spreadRecorder.UnregisterAssetPair(assetPair)
+    k.orderBookRecorder.UnregisterAssetPair(assetPair)
+    k.tradeRecorder.UnregisterAssetPair(assetPair)
+}
+
+func (k *Kraken) Close() {
+    k.spreadRecorder.Close()
+    k.orderBookRecorder.Close()
+    k.tradeRecorder.Close()
+}
+
 func (k *Kraken) GetLatency() time.Duration {
     start := time.Now()
 
-    bodyJson := util.HttpGetAndGetBody(k.httpClient, RESTEndpoint + "/0/public/Time")
-    checkError(bodyJson)
+    bodyJson, requestId := util.HttpGetAndGetBody(k.httpClient, RESTEndpoint + "/0/public/Time")
+    checkError(requestId, RESTEndpoint + "/0/public/Time", bodyJson)
 
     duration := time.Since(start)
 
     k.latencyEstimator.Sample(float64(duration.Milliseconds()))
 
-    return time.Duration(k.latencyEstimator.GetEstimate()) * time.Millisecond
+    estimate := time.Duration(k.latencyEstimator.GetEstimate()) * time.Millisecond
+    metrics.ObserveLatency(k.String(), estimate)
+    return estimate
 }
 
 func parseOrderType(ot types.OrderType) string {
@@ -165,7 +263,7 @@ func parseOrderType(ot types.OrderType) string {
 func (k *Kraken) getKrakenSignature(urlPath string, values url.Values) string {
     b64DecodedSecret, err := base64.StdEncoding.DecodeString(k.secretKey)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("decoding secret key failed", logging.Err(err))
     }
 
     sha := sha256.New()
@@ -189,21 +287,22 @@ func (k *Kraken) executeOrder(order types.Order, channel chan types.OrderIdRespo
     }
     request, err := http.NewRequest("POST", RESTEndpoint + "/0/private/AddOrder", strings.NewReader(queryParams.Encode()))
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.AssetPair(order.AssetPair), logging.Err(err))
     }
 
     signature := k.getKrakenSignature("/0/private/AddOrder", queryParams)
     request.Header.Set("API-Sign", signature)
     request.Header.Set("API-Key", k.apiKey)
 
-    bodyJson := util.DoHttpAndGetBody(k.httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(k.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     data := bodyJson["result"].(map[string]interface{})["txid"].([]interface{})
     id := data[0].(string)
 
     k.orderIdToOrderTranslator.Store(types.OrderId(id), &order)
 
+    logger.Info("order placed", logging.OrderId(types.OrderId(id)), logging.AssetPair(order.AssetPair), logging.RequestId(requestId), "type", parseOrderType(order.OrderType), "price", order.Price, "quantity", order.Quantity)
     channel <- types.OrderIdResponse{order, types.OrderId(id)}
 }
 
@@ -218,6 +317,7 @@ func (k *Kraken) ExecuteOrders(orders []types.Order) map[types.Order]types.Order
         response := <- channel
         orderIds[response.Order] = response.OrderId
     }
+    metrics.ObserveSubmitted(k.String(), orderIds)
     return orderIds
 }
 
@@ -236,22 +336,22 @@ func (k *Kraken) GetOrderStatuses(orderIds []types.OrderId) map[types.OrderId]ty
     }
     request, err := http.NewRequest("POST", RESTEndpoint + "/0/private/QueryOrders", strings.NewReader(queryParams.Encode()))
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.Err(err))
     }
 
     signature := k.getKrakenSignature("/0/private/QueryOrders", queryParams)
     request.Header.Set("API-Sign", signature)
     request.Header.Set("API-Key", k.apiKey)
 
-    bodyJson := util.DoHttpAndGetBody(k.httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(k.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     data := bodyJson["result"].(map[types.OrderId]map[string]interface{})
     orderStatuses := make(map[types.OrderId]types.OrderStatus)
     for id, rawOrderData := range data {
         original, ok := k.orderIdToOrderTranslator.Load(id)
         if !ok {
-            log.Fatalf("order with id %v not found\n", id)
+            logger.Fatal("order not found", logging.OrderId(id))
         }
         orderStatus := types.OrderStatus{
             Original: original,
@@ -265,11 +365,11 @@ func (k *Kraken) GetOrderStatuses(orderIds []types.OrderId) map[types.OrderId]ty
         case "closed":
             price, err := decimal.NewFromString(rawOrderData["price"].(string))
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("parsing decimal failed", logging.Err(err))
             }
             quantity, err := decimal.NewFromString(rawOrderData["vol_exec"].(string))
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("parsing decimal failed", logging.Err(err))
             }
             orderStatus.Status = types.Filled
             orderStatus.FilledPrice = &price
@@ -284,6 +384,7 @@ func (k *Kraken) GetOrderStatuses(orderIds []types.OrderId) map[types.OrderId]ty
         }
         orderStatuses[id] = orderStatus
     }
+    metrics.ObserveOrderStatuses(k.String(), orderStatuses)
     return orderStatuses
 }
 
@@ -302,19 +403,89 @@ func (k *Kraken) CancelOrders(orderIds []types.OrderId) {
     }
     request, err := http.NewRequest("POST", RESTEndpoint + "/0/private/CancelOrder", strings.NewReader(queryParams.Encode()))
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.Err(err))
     }
 
     signature := k.getKrakenSignature("/0/private/CancelOrder", queryParams)
     request.Header.Set("API-Sign", signature)
     request.Header.Set("API-Key", k.apiKey)
 
-    bodyJson := util.DoHttpAndGetBody(k.httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(k.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     for _, orderId := range orderIds {
         k.orderIdToOrderTranslator.Delete(orderId)
     }
+    metrics.ObserveCanceled(k.String(), orderIds)
+    logger.Info("orders canceled", "order_ids", orderIds, logging.RequestId(requestId))
+}
+
+func (k *Kraken) GetOpenOrders() map[types.OrderId]types.OrderStatus {
+    queryParams := url.Values{
+        "nonce": []string{strconv.FormatInt(time.Now().UnixMilli(), 10)},
+    }
+    request, err := http.NewRequest("POST", RESTEndpoint + "/0/private/OpenOrders", strings.NewReader(queryParams.Encode()))
+    if err != nil {
+        logger.Fatal("building request failed", logging.Err(err))
+    }
+
+    signature := k.getKrakenSignature("/0/private/OpenOrders", queryParams)
+    request.Header.Set("API-Sign", signature)
+    request.Header.Set("API-Key", k.apiKey)
+
+    bodyJson, requestId := util.DoHttpAndGetBody(k.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
+
+    reverseTranslator := util.ReverseAssetPairTranslator(k.AssetPairTranslator)
+    data := bodyJson["result"].(map[string]interface{})["open"].(map[string]interface{})
+    orderStatuses := make(map[types.OrderId]types.OrderStatus)
+    for id, rawOrderData := range data {
+        orderId := types.OrderId(id)
+        orderData := rawOrderData.(map[string]interface{})
+        original, ok := k.orderIdToOrderTranslator.Load(orderId)
+        if !ok {
+            description := orderData["descr"].(map[string]interface{})
+            // only orders on configured asset pairs can be described
+            if assetPair, ok := reverseTranslator[description["pair"].(string)]; ok {
+                price, err := decimal.NewFromString(description["price"].(string))
+                if err != nil {
+                    logger.Fatal("parsing decimal failed", logging.Err(err))
+                }
+                quantity, err := decimal.NewFromString(orderData["vol"].(string))
+                if err != nil {
+                    logger.Fatal("parsing decimal failed", logging.Err(err))
+                }
+                orderType := types.Buy
+                if description["type"].(string) == "sell" {
+                    orderType = types.Sell
+                }
+                original = &types.Order{
+                    OrderType: orderType,
+                    AssetPair: assetPair,
+                    Price: price,
+                    Quantity: quantity,
+                }
+                k.orderIdToOrderTranslator.Store(orderId, original)
+            }
+        }
+
+        quantity, err := decimal.NewFromString(orderData["vol_exec"].(string))
+        if err != nil {
+            logger.Fatal("parsing decimal failed", logging.Err(err))
+        }
+        orderStatus := types.OrderStatus{
+            Status: types.Unfilled,
+            Original: original,
+        }
+        if orderData["status"].(string) == "pending" {
+            orderStatus.Status = types.Pending
+        } else if quantity.IsPositive() {
+            orderStatus.Status = types.PartiallyFilled
+            orderStatus.FilledQuantity = &quantity
+        }
+        orderStatuses[orderId] = orderStatus
+    }
+    return orderStatuses
 }
 
 func (k *Kraken) GetBalances() map[types.Asset]decimal.Decimal {
@@ -323,15 +494,15 @@ func (k *Kraken) GetBalances() map[types.Asset]decimal.Decimal {
     }
     request, err := http.NewRequest("POST", RESTEndpoint + "/0/private/Balance", strings.NewReader(queryParams.Encode()))
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.Err(err))
     }
 
     signature := k.getKrakenSignature("/0/private/Balance", queryParams)
     request.Header.Set("API-Sign", signature)
     request.Header.Set("API-Key", k.apiKey)
 
-    bodyJson := util.DoHttpAndGetBody(k.httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(k.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     if _, ok := bodyJson["result"]; ok {
         data := bodyJson["result"].(map[types.Asset]string)
@@ -340,7 +511,7 @@ func (k *Kraken) GetBalances() map[types.Asset]decimal.Decimal {
         for asset, balanceString := range data {
             balances[asset], err = decimal.NewFromString(balanceString)
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("parsing decimal failed", logging.Err(err))
             }
         }
         return balances
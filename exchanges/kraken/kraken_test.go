@@ -2,23 +2,21 @@ package kraken
 
 import (
 	"fmt"
-	"os"
 	"testing"
 	"time"
 
+	"github.com/denali-capital/grizzly/secrets"
 	grizzlytesting "github.com/denali-capital/grizzly/testing"
 	"github.com/joho/godotenv"
 )
 
-const apiKey string = "c8Mrlv+qder9EzFm+1trJRthtsYzgSBYHNP8opkB0O5FR+gS3UY52ex0"
-
 // TODO: test use data stuff
 func TestKraken(t *testing.T) {
 	err := godotenv.Load("../../.env")
     if err != nil {
         t.Fatalf("Error loading .env file\n%v\n", err)
     }
-	kraken := NewKraken(apiKey, os.Getenv("KRAKEN" + grizzlytesting.SecretKeySuffix), grizzlytesting.KrakenAssetPairTranslator, grizzlytesting.Iso4217Translator)
+	kraken := NewKraken(secrets.NewEnvProvider(), grizzlytesting.KrakenAssetPairTranslator, grizzlytesting.Iso4217Translator, 200, 1000, 1000)
 	time.Sleep(grizzlytesting.SleepDuration)
 	t.Run("GetHistoricalSpreads", func(t *testing.T) {
 		testKrakenGetHistoricalSpreads(t, kraken)
@@ -29,6 +27,9 @@ func TestKraken(t *testing.T) {
 	t.Run("GetOrderBooks", func(t *testing.T) {
 		testGetOrderBooks(t, kraken)
 	})
+	t.Run("GetHistoricalCandles", func(t *testing.T) {
+		testGetHistoricalCandles(t, kraken)
+	})
 	t.Run("GetLatency", func(t *testing.T) {
 		testGetLatency(t, kraken)
 	})
@@ -66,6 +67,22 @@ func testGetOrderBooks(t *testing.T, kraken *Kraken) {
 	}
 }
 
+func testGetHistoricalCandles(t *testing.T, kraken *Kraken) {
+	candles, ok := kraken.GetHistoricalCandles(grizzlytesting.BTCUSD, time.Minute, time.Now().Add(-time.Hour))
+	if !ok || len(candles) == 0 {
+		t.Fatalf("Candles should not be empty")
+	}
+	for i := 1; i < len(candles); i++ {
+		if !candles[i - 1].Start.Before(candles[i].Start) {
+			t.Fatalf("Candles should be oldest first")
+		}
+	}
+	if _, ok := kraken.GetHistoricalCandles(grizzlytesting.BTCUSD, time.Second, time.Now().Add(-time.Minute)); ok {
+		t.Fatalf("One second candles should not be offered")
+	}
+	fmt.Println(candles[len(candles) - 1])
+}
+
 func testGetLatency(t *testing.T, kraken *Kraken) {
 	latency := kraken.GetLatency()
 	fmt.Println(latency)
@@ -2,10 +2,10 @@ package kraken
 
 import (
     "bytes"
+    "context"
     "encoding/json"
     "fmt"
     "hash/crc32"
-    "log"
     "math"
     "net/http"
     "strconv"
@@ -13,6 +13,8 @@ import (
     "sync"
     "time"
 
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
     "github.com/denali-capital/grizzly/types"
     "github.com/denali-capital/grizzly/util"
     "github.com/gorilla/websocket"
@@ -38,16 +40,16 @@ type krakenSubscriptionMessage struct {
 func initializeWebSocketConnection() *websocket.Conn {
     webSocketConnection, _, err := websocket.DefaultDialer.Dial(WebSocketEndpoint, http.Header{})
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("websocket dial failed", logging.Err(err))
     }
 
     var initialResponse map[string]interface{}
     err = webSocketConnection.ReadJSON(&initialResponse)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("websocket read failed", logging.Err(err))
     }
     if !(initialResponse["event"].(string) == "systemStatus" && initialResponse["status"].(string) == "online") {
-        log.Fatalln(initialResponse)
+        logger.Fatal("unexpected websocket response", "response", initialResponse)
     }
 
     return webSocketConnection
@@ -60,6 +62,121 @@ type krakenWebSocketRecorder struct {
     iso4217Translator   types.AssetPairTranslator
     // map[uint]chan []interface{}
     channels            *sync.Map
+    // map[types.AssetPair]uint
+    channelIds          *sync.Map
+    // map[uint]context.CancelFunc, stops the goroutine processing the channel
+    cancels             *sync.Map
+    // canceled by Close, stops record and every per asset pair goroutine
+    ctx                 context.Context
+    cancel              context.CancelFunc
+    closeOnce           sync.Once
+}
+
+// per asset pair context, canceled by UnregisterAssetPair or along with ctx
+func startChannel(ctx context.Context, cancels *sync.Map, channelId uint) context.Context {
+    channelCtx, cancel := context.WithCancel(ctx)
+    cancels.Store(channelId, cancel)
+    return channelCtx
+}
+
+func (k *krakenWebSocketRecorder) closing() bool {
+    return k.ctx.Err() != nil
+}
+
+// drops resp once the recorder is closing, its goroutine may already be gone
+func (k *krakenWebSocketRecorder) forward(channel chan []interface{}, resp []interface{}) {
+    select {
+    case channel <- resp:
+    case <-k.ctx.Done():
+    }
+}
+
+// sends unsubscribe, then a close frame, and closes the connection
+func (k *krakenWebSocketRecorder) close(unsubscribe krakenSubscriptionMessage) {
+    k.closeOnce.Do(func() {
+        k.cancel()
+        // unblocks the read record is waiting on so that the lock is released
+        k.webSocketConnection.SetReadDeadline(time.Now())
+        k.Lock()
+        defer k.Unlock()
+
+        if len(unsubscribe.Pair) > 0 {
+            payloadJson, err := json.Marshal(unsubscribe)
+            if err != nil {
+                logger.Fatal("encoding message failed", logging.Err(err))
+            }
+            k.webSocketConnection.WriteMessage(websocket.TextMessage, payloadJson)
+        }
+        k.webSocketConnection.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+        k.webSocketConnection.Close()
+    })
+}
+
+// unsubscribes from one asset pair and stops its goroutine, false if it was not subscribed
+func (k *krakenWebSocketRecorder) unregister(assetPair types.AssetPair, subscription krakenSubscription) bool {
+    k.Lock()
+    defer k.Unlock()
+    value, ok := k.channelIds.Load(assetPair)
+    if !ok || k.closing() {
+        return false
+    }
+    channelId := value.(uint)
+    iso4217TranslatedPair := k.iso4217Translator[assetPair]
+
+    payloadJson, err := json.Marshal(krakenSubscriptionMessage{
+        Event: "unsubscribe",
+        Pair: []string{iso4217TranslatedPair},
+        Subscription: subscription,
+    })
+    if err != nil {
+        logger.Fatal("encoding message failed", logging.AssetPair(assetPair), logging.Err(err))
+    }
+    k.webSocketConnection.WriteMessage(1, payloadJson)
+    for {
+        _, msg, err := k.webSocketConnection.ReadMessage()
+        if err != nil {
+            if k.closing() {
+                return false
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
+        }
+        if bytes.Compare(Heartbeat, msg) == 0 {
+            continue
+        }
+        var response map[string]interface{}
+        err = json.Unmarshal(msg, &response)
+        if err != nil {
+            if _, ok := err.(*json.UnmarshalTypeError); !ok {
+                logger.Fatal("decoding message failed", logging.AssetPair(assetPair), logging.Err(err))
+            }
+            var resp []interface{}
+            err = json.Unmarshal(msg, &resp)
+            if err != nil {
+                logger.Fatal("decoding message failed", logging.AssetPair(assetPair), logging.Err(err))
+            }
+            messageChannelId := uint(resp[0].(float64))
+            if messageChannelId == channelId {
+                // still in flight when the unsubscribe was sent
+                continue
+            }
+            channel, ok := k.channels.Load(messageChannelId)
+            if !ok {
+                logger.Fatal("channel not found", "channel_id", messageChannelId)
+            }
+            k.forward(channel.(chan []interface{}), util.SliceCopy(resp))
+            continue
+        }
+        if !(response["event"].(string) == "subscriptionStatus" && response["pair"].(string) == iso4217TranslatedPair && response["status"].(string) == "unsubscribed") {
+            logger.Fatal("unexpected websocket response", logging.AssetPair(assetPair), "response", response)
+        }
+        break
+    }
+
+    k.channels.Delete(channelId)
+    k.channelIds.Delete(assetPair)
+    cancel, _ := k.cancels.LoadAndDelete(channelId)
+    cancel.(context.CancelFunc)()
+    return true
 }
 
 func (k *krakenWebSocketRecorder) record() {
@@ -68,18 +185,22 @@ func (k *krakenWebSocketRecorder) record() {
         k.Lock()
         _, msg, err := k.webSocketConnection.ReadMessage()
         if err != nil {
-            log.Fatalln(err)
+            if k.closing() {
+                k.Unlock()
+                return
+            }
+            logger.Fatal("websocket read failed", logging.Err(err))
         } else if bytes.Compare(Heartbeat, msg) != 0 {
             err = json.Unmarshal(msg, &resp)
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("decoding message failed", logging.Err(err))
             }
             channelId := uint(resp[0].(float64))
             channel, ok := k.channels.Load(channelId)
             if !ok {
-                log.Fatalf("channel not found for channelId %v\n", channelId)
+                logger.Fatal("channel not found", "channel_id", channelId)
             }
-            channel.(chan []interface{}) <- util.SliceCopy(resp)
+            k.forward(channel.(chan []interface{}), util.SliceCopy(resp))
         }
         k.Unlock()
     }
@@ -93,6 +214,7 @@ type KrakenSpreadRecorder struct {
 }
 
 func NewKrakenSpreadRecorder(assetPairs []types.AssetPair, iso4217Translator types.AssetPairTranslator, capacity uint) *KrakenSpreadRecorder {
+    ctx, cancel := context.WithCancel(context.Background())
     webSocketConnection := initializeWebSocketConnection()
 
     reverseIso4217Translator := util.ReverseAssetPairTranslator(iso4217Translator)
@@ -109,29 +231,34 @@ func NewKrakenSpreadRecorder(assetPairs []types.AssetPair, iso4217Translator typ
         },
     })
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("encoding message failed", logging.Err(err))
     }
     webSocketConnection.WriteMessage(1, payloadJson)
 
     channels := &sync.Map{}
+    channelIds := &sync.Map{}
+    cancels := &sync.Map{}
     historicalSpreads := &sync.Map{}
     var initialResponse map[string]interface{}
     for i := 0; i < len(iso4217TranslatedPairs); i++ {
         err = webSocketConnection.ReadJSON(&initialResponse)
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("websocket read failed", logging.Err(err))
         }
         if !(initialResponse["event"].(string) == "subscriptionStatus" && util.Contains(iso4217TranslatedPairs, initialResponse["pair"].(string)) && initialResponse["status"].(string) == "subscribed") {
             // we assume here that all subscription messages come one right after another
-            log.Fatalln(initialResponse)
+            logger.Fatal("unexpected websocket response", "response", initialResponse)
         }
         channel := make(chan []interface{})
         historicalSpread := util.NewConcurrentFixedSizeSpreadQueue(capacity)
 
-        channels.Store(uint(initialResponse["channelID"].(float64)), channel)
-        historicalSpreads.Store(reverseIso4217Translator[initialResponse["pair"].(string)], historicalSpread)
+        assetPair := reverseIso4217Translator[initialResponse["pair"].(string)]
+        channelId := uint(initialResponse["channelID"].(float64))
+        channels.Store(channelId, channel)
+        channelIds.Store(assetPair, channelId)
+        historicalSpreads.Store(assetPair, historicalSpread)
 
-        go processSpreadUpdates(historicalSpread, channel)
+        go processSpreadUpdates(startChannel(ctx, cancels, channelId), assetPair, historicalSpread, channel)
     }
 
     krakenWebSocketRecorder := &KrakenSpreadRecorder{
@@ -139,6 +266,10 @@ func NewKrakenSpreadRecorder(assetPairs []types.AssetPair, iso4217Translator typ
             webSocketConnection: webSocketConnection,
             iso4217Translator: iso4217Translator,
             channels: channels,
+            channelIds: channelIds,
+            cancels: cancels,
+            ctx: ctx,
+            cancel: cancel,
         },
         capacity: capacity,
         historicalSpreads: historicalSpreads,
@@ -149,22 +280,25 @@ func NewKrakenSpreadRecorder(assetPairs []types.AssetPair, iso4217Translator typ
     return krakenWebSocketRecorder
 }
 
-func processSpreadUpdates(historicalSpread *util.ConcurrentFixedSizeSpreadQueue, channel chan []interface{}) {
+func processSpreadUpdates(ctx context.Context, assetPair types.AssetPair, historicalSpread *util.ConcurrentFixedSizeSpreadQueue, channel chan []interface{}) {
     for {
         select {
+        case <-ctx.Done():
+            return
         case resp := <- channel:
+            metrics.WebSocketMessages.Inc(exchangeName, "spread", assetPair.String())
             rawSpread := resp[1].([]interface{})
             bid, err := decimal.NewFromString(rawSpread[0].(string))
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
             }
             ask, err := decimal.NewFromString(rawSpread[1].(string))
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
             }
             timestamp, err := strconv.ParseFloat(rawSpread[2].(string), 64)
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("parsing number failed", logging.AssetPair(assetPair), logging.Err(err))
             }
             timestampInteger, timestampFraction := math.Modf(timestamp)
             
@@ -175,6 +309,7 @@ func processSpreadUpdates(historicalSpread *util.ConcurrentFixedSizeSpreadQueue,
                 // fraction is in ns
                 Timestamp: time.Unix(int64(timestampInteger), int64(timestampFraction * 1000000)),
             })
+            metrics.SpreadAge.Touch(exchangeName, assetPair.String())
         }
     }
 }
@@ -210,7 +345,7 @@ func (k *KrakenSpreadRecorder) RegisterAssetPair(assetPair types.AssetPair) {
         },
     })
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("encoding message failed", logging.AssetPair(assetPair), logging.Err(err))
     }
     k.Lock()
     defer k.Unlock()
@@ -220,7 +355,10 @@ func (k *KrakenSpreadRecorder) RegisterAssetPair(assetPair types.AssetPair) {
     for {
         _, msg, err := k.webSocketConnection.ReadMessage()
         if err != nil {
-            log.Fatalln(err)
+            if k.closing() {
+                return
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         if bytes.Compare(Heartbeat, msg) != 0 {
             // not a heartbeat
@@ -228,34 +366,58 @@ func (k *KrakenSpreadRecorder) RegisterAssetPair(assetPair types.AssetPair) {
             if err != nil {
                 _, ok := err.(*json.UnmarshalTypeError)
                 if !ok {
-                    log.Fatalln(err)
+                    logger.Fatal("decoding message failed", logging.AssetPair(assetPair), logging.Err(err))
                 }
                 err = json.Unmarshal(msg, &resp)
                 if err != nil {
-                    log.Fatalln(err)
+                    logger.Fatal("decoding message failed", logging.AssetPair(assetPair), logging.Err(err))
                 }
                 channelId := uint(resp[0].(float64))
                 channel, ok := k.channels.Load(channelId)
                 if !ok {
-                    log.Fatalf("channel not found for channelId %v\n", channelId)
+                    logger.Fatal("channel not found", "channel_id", channelId)
                 }
-                channel.(chan []interface{}) <- util.SliceCopy(resp)
+                k.forward(channel.(chan []interface{}), util.SliceCopy(resp))
                 continue
             }
             if !(initialResponse["event"].(string) == "subscriptionStatus" && initialResponse["pair"].(string) == iso4217TranslatedPair && initialResponse["status"].(string) == "subscribed") {
-                log.Fatalln(initialResponse)
+                logger.Fatal("unexpected websocket response", logging.AssetPair(assetPair), "response", initialResponse)
             }
             break
         }
     }
 
+    channelId := uint(initialResponse["channelID"].(float64))
     channel := make(chan []interface{})
     historicalSpread := util.NewConcurrentFixedSizeSpreadQueue(k.capacity)
 
-    k.channels.Store(uint(initialResponse["channelID"].(float64)), channel)
+    k.channels.Store(channelId, channel)
+    k.channelIds.Store(assetPair, channelId)
     k.historicalSpreads.Store(assetPair, historicalSpread)
 
-    go processSpreadUpdates(historicalSpread, channel)
+    go processSpreadUpdates(startChannel(k.ctx, k.cancels, channelId), assetPair, historicalSpread, channel)
+}
+
+func (k *KrakenSpreadRecorder) UnregisterAssetPair(assetPair types.AssetPair) {
+    if k.unregister(assetPair, krakenSubscription{Name: "spread"}) {
+        k.historicalSpreads.Delete(assetPair)
+    }
+}
+
+// unsubscribes from every asset pair and stops recording, safe to call more than once
+func (k *KrakenSpreadRecorder) Close() {
+    pairs := make([]string, 0)
+    k.historicalSpreads.Range(func(key, value interface{}) bool {
+        pairs = append(pairs, k.iso4217Translator[key.(types.AssetPair)])
+        return true
+    })
+    k.close(krakenSubscriptionMessage{
+        Event: "unsubscribe",
+        Pair: pairs,
+        Subscription: krakenSubscription{
+            Name: "spread",
+        },
+    })
 }
 
 // very much inspired by https://github.com/jurijbajzelj/kraken_ws_orderbook
@@ -267,6 +429,7 @@ type KrakenOrderBookRecorder struct {
 }
 
 func NewKrakenOrderBookRecorder(assetPairs []types.AssetPair, iso4217Translator types.AssetPairTranslator, depth uint) *KrakenOrderBookRecorder {
+    ctx, cancel := context.WithCancel(context.Background())
     webSocketConnection := initializeWebSocketConnection()
 
     reverseIso4217Translator := util.ReverseAssetPairTranslator(iso4217Translator)
@@ -284,7 +447,7 @@ func NewKrakenOrderBookRecorder(assetPairs []types.AssetPair, iso4217Translator
         },
     })
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("encoding message failed", logging.Err(err))
     }
     webSocketConnection.WriteMessage(1, payloadJson)
 
@@ -293,11 +456,11 @@ func NewKrakenOrderBookRecorder(assetPairs []types.AssetPair, iso4217Translator
     for i := 0; i < len(iso4217TranslatedPairs); i++ {
         err = webSocketConnection.ReadJSON(&initialResponse)
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("websocket read failed", logging.Err(err))
         }
         if !(initialResponse["event"].(string) == "subscriptionStatus" && util.Contains(iso4217TranslatedPairs, initialResponse["pair"].(string)) && initialResponse["status"].(string) == "subscribed") {
             // we assume here that all subscription messages come one right after another
-            log.Fatalln(initialResponse)
+            logger.Fatal("unexpected websocket response", "response", initialResponse)
         }
         channelId := uint(initialResponse["channelID"].(float64))
         assetPair := reverseIso4217Translator[initialResponse["pair"].(string)]
@@ -306,19 +469,21 @@ func NewKrakenOrderBookRecorder(assetPairs []types.AssetPair, iso4217Translator
 
     // get initial books
     channels := &sync.Map{}
+    channelIds := &sync.Map{}
+    cancels := &sync.Map{}
     orderBooks := &sync.Map{}
     var resp []interface{}
     for i := 0; i < len(iso4217TranslatedPairs); i++ {
         for {
             _, msg, err := webSocketConnection.ReadMessage()
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("websocket read failed", logging.Err(err))
             }
             if bytes.Compare(Heartbeat, msg) != 0 {
                 // not a hearbeat
                 err := json.Unmarshal(msg, &resp)
                 if err != nil {
-                    log.Fatalln(err)
+                    logger.Fatal("decoding message failed", logging.Err(err))
                 }
                 break
             }
@@ -354,8 +519,9 @@ func NewKrakenOrderBookRecorder(assetPairs []types.AssetPair, iso4217Translator
         channel := make(chan []interface{})
 
         channels.Store(channelId, channel)
+        channelIds.Store(channelIdTranslator[channelId], channelId)
         orderBooks.Store(channelIdTranslator[channelId], concurrentOrderBook)
-        go processOrderBookUpdates(concurrentOrderBook, channel, depth)
+        go processOrderBookUpdates(startChannel(ctx, cancels, channelId), channelIdTranslator[channelId], concurrentOrderBook, channel, depth)
     }
 
     krakenOrderBookRecorder := &KrakenOrderBookRecorder{
@@ -363,6 +529,10 @@ func NewKrakenOrderBookRecorder(assetPairs []types.AssetPair, iso4217Translator
             webSocketConnection: webSocketConnection,
             iso4217Translator: iso4217Translator,
             channels: channels,
+            channelIds: channelIds,
+            cancels: cancels,
+            ctx: ctx,
+            cancel: cancel,
         },
         depth: depth,
         orderBooks: orderBooks,
@@ -413,18 +583,23 @@ func getChecksumInput(bids []types.OrderBookEntry, asks []types.OrderBookEntry)
     return str.String()
 }
 
-func verifyOrderBookChecksum(bids []types.OrderBookEntry, asks []types.OrderBookEntry, checksum string) {
+// mismatches are counted and logged rather than fatal so that they show up in /metrics
+func verifyOrderBookChecksum(assetPair types.AssetPair, bids []types.OrderBookEntry, asks []types.OrderBookEntry, checksum string) {
     checksumInput := getChecksumInput(bids, asks)
     crc := crc32.ChecksumIEEE([]byte(checksumInput))
     if fmt.Sprint(crc) != checksum {
-        log.Fatalln("order book checksum not the same ", " ", crc, " ", checksum)
+        metrics.ChecksumFailures.Inc(exchangeName, assetPair.String())
+        logger.Warn("order book checksum mismatch", logging.AssetPair(assetPair), "computed", crc, "expected", checksum)
     }
 }
 
-func processOrderBookUpdates(concurrentOrderBook *util.ConcurrentOrderBook, channel chan []interface{}, depth uint) {
+func processOrderBookUpdates(ctx context.Context, assetPair types.AssetPair, concurrentOrderBook *util.ConcurrentOrderBook, channel chan []interface{}, depth uint) {
     for {
         select {
+        case <-ctx.Done():
+            return
         case resp := <- channel:
+            metrics.WebSocketMessages.Inc(exchangeName, "order_book", assetPair.String())
             bids := concurrentOrderBook.GetBids()
             asks := concurrentOrderBook.GetAsks()
             if len(resp) == 4 {
@@ -475,7 +650,7 @@ func processOrderBookUpdates(concurrentOrderBook *util.ConcurrentOrderBook, chan
                         }
                     }
                 }
-                verifyOrderBookChecksum(bids, asks, checksum)
+                verifyOrderBookChecksum(assetPair, bids, asks, checksum)
             } else {
                 // both bids and asks are updated
                 orderBookDiffAsks := resp[1].(map[string]interface{})
@@ -522,9 +697,10 @@ func processOrderBookUpdates(concurrentOrderBook *util.ConcurrentOrderBook, chan
                         }
                     }
                 }
-                verifyOrderBookChecksum(bids, asks, checksum)
+                verifyOrderBookChecksum(assetPair, bids, asks, checksum)
             }
             concurrentOrderBook.SetBidsAndAsks(bids[:depth], asks[:depth])
+            metrics.OrderBookAge.Touch(exchangeName, assetPair.String())
         }
     }
 }
@@ -553,7 +729,7 @@ func (k *KrakenOrderBookRecorder) RegisterAssetPair(assetPair types.AssetPair) {
         },
     })
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("encoding message failed", logging.AssetPair(assetPair), logging.Err(err))
     }
     k.Lock()
     defer k.Unlock()
@@ -563,7 +739,10 @@ func (k *KrakenOrderBookRecorder) RegisterAssetPair(assetPair types.AssetPair) {
     for {
         _, msg, err := k.webSocketConnection.ReadMessage()
         if err != nil {
-            log.Fatalln(err)
+            if k.closing() {
+                return
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         if bytes.Compare(Heartbeat, msg) != 0 {
             // not a heartbeat
@@ -571,22 +750,22 @@ func (k *KrakenOrderBookRecorder) RegisterAssetPair(assetPair types.AssetPair) {
             if err != nil {
                 _, ok := err.(*json.UnmarshalTypeError)
                 if !ok {
-                    log.Fatalln(err)
+                    logger.Fatal("decoding message failed", logging.AssetPair(assetPair), logging.Err(err))
                 }
                 err = json.Unmarshal(msg, &resp)
                 if err != nil {
-                    log.Fatalln(err)
+                    logger.Fatal("decoding message failed", logging.AssetPair(assetPair), logging.Err(err))
                 }
                 channelId := uint(resp[0].(float64))
                 channel, ok := k.channels.Load(channelId)
                 if !ok {
-                    log.Fatalf("channel not found for channelId %v\n", channelId)
+                    logger.Fatal("channel not found", "channel_id", channelId)
                 }
-                channel.(chan []interface{}) <- util.SliceCopy(resp)
+                k.forward(channel.(chan []interface{}), util.SliceCopy(resp))
                 continue
             }
             if !(initialResponse["event"].(string) == "subscriptionStatus" && initialResponse["pair"].(string) == iso4217TranslatedPair && initialResponse["status"].(string) == "subscribed") {
-                log.Fatalln(initialResponse)
+                logger.Fatal("unexpected websocket response", logging.AssetPair(assetPair), "response", initialResponse)
             }
             break
         }
@@ -601,20 +780,23 @@ func (k *KrakenOrderBookRecorder) RegisterAssetPair(assetPair types.AssetPair) {
     for {
         _, msg, err := k.webSocketConnection.ReadMessage()
         if err != nil {
-            log.Fatalln(err)
+            if k.closing() {
+                return
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         if bytes.Compare(Heartbeat, msg) != 0 {
             // not a hearbeat
             err := json.Unmarshal(msg, &resp)
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("decoding message failed", logging.AssetPair(assetPair), logging.Err(err))
             }
             if messageChannelId := uint(resp[0].(float64)); messageChannelId != channelId {
                 channel, ok := k.channels.Load(messageChannelId)
                 if !ok {
-                    log.Fatalf("channel not found for channelId %v\n", messageChannelId)
+                    logger.Fatal("channel not found", "channel_id", messageChannelId)
                 }
-                channel.(chan []interface{}) <- util.SliceCopy(resp)
+                k.forward(channel.(chan []interface{}), util.SliceCopy(resp))
                 continue
             }
             break
@@ -645,7 +827,242 @@ func (k *KrakenOrderBookRecorder) RegisterAssetPair(assetPair types.AssetPair) {
     }
     concurrentOrderBook := util.NewConcurrentOrderBook(bids, asks)
 
+    k.channelIds.Store(assetPair, channelId)
     k.orderBooks.Store(assetPair, concurrentOrderBook)
 
-    go processOrderBookUpdates(concurrentOrderBook, channel, k.depth)
+    go processOrderBookUpdates(startChannel(k.ctx, k.cancels, channelId), assetPair, concurrentOrderBook, channel, k.depth)
+}
+
+func (k *KrakenOrderBookRecorder) UnregisterAssetPair(assetPair types.AssetPair) {
+    if k.unregister(assetPair, krakenSubscription{Name: "book", Depth: k.depth}) {
+        k.orderBooks.Delete(assetPair)
+    }
+}
+
+// unsubscribes from every asset pair and stops recording, safe to call more than once
+func (k *KrakenOrderBookRecorder) Close() {
+    pairs := make([]string, 0)
+    k.orderBooks.Range(func(key, value interface{}) bool {
+        pairs = append(pairs, k.iso4217Translator[key.(types.AssetPair)])
+        return true
+    })
+    k.close(krakenSubscriptionMessage{
+        Event: "unsubscribe",
+        Pair: pairs,
+        Subscription: krakenSubscription{
+            Name: "book",
+            Depth: k.depth,
+        },
+    })
+}
+
+type KrakenTradeRecorder struct {
+    krakenWebSocketRecorder
+    capacity                uint
+    // map[types.AssetPair]*util.ConcurrentTradeRing
+    trades                  *sync.Map
+}
+
+func NewKrakenTradeRecorder(assetPairs []types.AssetPair, iso4217Translator types.AssetPairTranslator, capacity uint) *KrakenTradeRecorder {
+    ctx, cancel := context.WithCancel(context.Background())
+    webSocketConnection := initializeWebSocketConnection()
+
+    reverseIso4217Translator := util.ReverseAssetPairTranslator(iso4217Translator)
+    iso4217TranslatedPairs := make([]string, len(assetPairs))
+    for i, assetPair := range assetPairs {
+        iso4217TranslatedPairs[i] = iso4217Translator[assetPair]
+    }
+
+    payloadJson, err := json.Marshal(krakenSubscriptionMessage{
+        Event: "subscribe",
+        Pair: iso4217TranslatedPairs,
+        Subscription: krakenSubscription{
+            Name: "trade",
+        },
+    })
+    if err != nil {
+        logger.Fatal("encoding message failed", logging.Err(err))
+    }
+    webSocketConnection.WriteMessage(1, payloadJson)
+
+    channels := &sync.Map{}
+    channelIds := &sync.Map{}
+    cancels := &sync.Map{}
+    trades := &sync.Map{}
+    var initialResponse map[string]interface{}
+    for i := 0; i < len(iso4217TranslatedPairs); i++ {
+        err = webSocketConnection.ReadJSON(&initialResponse)
+        if err != nil {
+            logger.Fatal("websocket read failed", logging.Err(err))
+        }
+        if !(initialResponse["event"].(string) == "subscriptionStatus" && util.Contains(iso4217TranslatedPairs, initialResponse["pair"].(string)) && initialResponse["status"].(string) == "subscribed") {
+            // we assume here that all subscription messages come one right after another
+            logger.Fatal("unexpected websocket response", "response", initialResponse)
+        }
+        channel := make(chan []interface{})
+        tradeRing := util.NewConcurrentTradeRing(capacity)
+
+        assetPair := reverseIso4217Translator[initialResponse["pair"].(string)]
+        channelId := uint(initialResponse["channelID"].(float64))
+        channels.Store(channelId, channel)
+        channelIds.Store(assetPair, channelId)
+        trades.Store(assetPair, tradeRing)
+
+        go processTradeUpdates(startChannel(ctx, cancels, channelId), assetPair, tradeRing, channel)
+    }
+
+    krakenTradeRecorder := &KrakenTradeRecorder{
+        krakenWebSocketRecorder: krakenWebSocketRecorder{
+            webSocketConnection: webSocketConnection,
+            iso4217Translator: iso4217Translator,
+            channels: channels,
+            channelIds: channelIds,
+            cancels: cancels,
+            ctx: ctx,
+            cancel: cancel,
+        },
+        capacity: capacity,
+        trades: trades,
+    }
+
+    go krakenTradeRecorder.record()
+
+    return krakenTradeRecorder
+}
+
+// each message carries one or more [price, volume, time, side, orderType, misc]
+func processTradeUpdates(ctx context.Context, assetPair types.AssetPair, tradeRing *util.ConcurrentTradeRing, channel chan []interface{}) {
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case resp := <- channel:
+            metrics.WebSocketMessages.Inc(exchangeName, "trade", assetPair.String())
+            for _, rawTrade := range resp[1].([]interface{}) {
+                price, quantity := util.GetPriceAndQuantity(rawTrade.([]interface{}))
+                timestamp, err := strconv.ParseFloat(rawTrade.([]interface{})[2].(string), 64)
+                if err != nil {
+                    logger.Fatal("parsing number failed", logging.AssetPair(assetPair), logging.Err(err))
+                }
+                timestampInteger, timestampFraction := math.Modf(timestamp)
+                side := types.Buy
+                if rawTrade.([]interface{})[3].(string) == "s" {
+                    side = types.Sell
+                }
+
+                tradeRing.Push(types.Trade{
+                    Price: price,
+                    Quantity: quantity,
+                    Side: side,
+                    Timestamp: time.Unix(int64(timestampInteger), int64(timestampFraction * 1e9)),
+                })
+            }
+        }
+    }
+}
+
+func (k *KrakenTradeRecorder) GetTradesSince(assetPair types.AssetPair, since time.Time) ([]types.Trade, bool) {
+    result, ok := k.trades.Load(assetPair)
+    if !ok {
+        return make([]types.Trade, 0), false
+    }
+    return result.(*util.ConcurrentTradeRing).Since(since), true
+}
+
+func (k *KrakenTradeRecorder) GetVWAP(assetPair types.AssetPair, window time.Duration) (decimal.Decimal, bool) {
+    trades, ok := k.GetTradesSince(assetPair, time.Now().Add(-window))
+    if !ok {
+        return decimal.Zero, false
+    }
+    return util.ComputeVWAP(trades)
+}
+
+func (k *KrakenTradeRecorder) RegisterAssetPair(assetPair types.AssetPair) {
+    if _, ok := k.trades.Load(assetPair); ok {
+        return
+    }
+
+    iso4217TranslatedPair := k.iso4217Translator[assetPair]
+
+    payloadJson, err := json.Marshal(krakenSubscriptionMessage{
+        Event: "subscribe",
+        Pair: []string{iso4217TranslatedPair},
+        Subscription: krakenSubscription{
+            Name: "trade",
+        },
+    })
+    if err != nil {
+        logger.Fatal("encoding message failed", logging.AssetPair(assetPair), logging.Err(err))
+    }
+    k.Lock()
+    defer k.Unlock()
+    k.webSocketConnection.WriteMessage(1, payloadJson)
+    var initialResponse map[string]interface{}
+    var resp []interface{}
+    for {
+        _, msg, err := k.webSocketConnection.ReadMessage()
+        if err != nil {
+            if k.closing() {
+                return
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
+        }
+        if bytes.Compare(Heartbeat, msg) != 0 {
+            // not a heartbeat
+            err := json.Unmarshal(msg, &initialResponse)
+            if err != nil {
+                _, ok := err.(*json.UnmarshalTypeError)
+                if !ok {
+                    logger.Fatal("decoding message failed", logging.AssetPair(assetPair), logging.Err(err))
+                }
+                err = json.Unmarshal(msg, &resp)
+                if err != nil {
+                    logger.Fatal("decoding message failed", logging.AssetPair(assetPair), logging.Err(err))
+                }
+                channelId := uint(resp[0].(float64))
+                channel, ok := k.channels.Load(channelId)
+                if !ok {
+                    logger.Fatal("channel not found", "channel_id", channelId)
+                }
+                k.forward(channel.(chan []interface{}), util.SliceCopy(resp))
+                continue
+            }
+            if !(initialResponse["event"].(string) == "subscriptionStatus" && initialResponse["pair"].(string) == iso4217TranslatedPair && initialResponse["status"].(string) == "subscribed") {
+                logger.Fatal("unexpected websocket response", logging.AssetPair(assetPair), "response", initialResponse)
+            }
+            break
+        }
+    }
+
+    channelId := uint(initialResponse["channelID"].(float64))
+    channel := make(chan []interface{})
+    tradeRing := util.NewConcurrentTradeRing(k.capacity)
+
+    k.channels.Store(channelId, channel)
+    k.channelIds.Store(assetPair, channelId)
+    k.trades.Store(assetPair, tradeRing)
+
+    go processTradeUpdates(startChannel(k.ctx, k.cancels, channelId), assetPair, tradeRing, channel)
+}
+
+func (k *KrakenTradeRecorder) UnregisterAssetPair(assetPair types.AssetPair) {
+    if k.unregister(assetPair, krakenSubscription{Name: "trade"}) {
+        k.trades.Delete(assetPair)
+    }
+}
+
+// unsubscribes from every asset pair and stops recording, safe to call more than once
+func (k *KrakenTradeRecorder) Close() {
+    pairs := make([]string, 0)
+    k.trades.Range(func(key, value interface{}) bool {
+        pairs = append(pairs, k.iso4217Translator[key.(types.AssetPair)])
+        return true
+    })
+    k.close(krakenSubscriptionMessage{
+        Event: "unsubscribe",
+        Pair: pairs,
+        Subscription: krakenSubscription{
+            Name: "trade",
+        },
+    })
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	grizzlytesting "github.com/denali-capital/grizzly/testing"
+	"github.com/denali-capital/grizzly/types"
 )
 
 func TestKrakenSpreadRecorder(t *testing.T) {
@@ -17,6 +18,15 @@ func TestKrakenSpreadRecorder(t *testing.T) {
 	t.Run("RegisterAssetPair", func(t *testing.T) {
 		testSpreadRegisterAssetPair(t, krakenSpreadRecorder)
 	})
+	t.Run("UnregisterAssetPair", func(t *testing.T) {
+		testUnregisterAssetPair(t, krakenSpreadRecorder, &krakenSpreadRecorder.krakenWebSocketRecorder, func() bool {
+			_, ok := krakenSpreadRecorder.GetHistoricalSpreads(grizzlytesting.DOGEUSD)
+			return ok
+		})
+	})
+	t.Run("Close", func(t *testing.T) {
+		testClose(t, krakenSpreadRecorder, &krakenSpreadRecorder.krakenWebSocketRecorder)
+	})
 }
 
 func testRecorderGetHistoricalSpreads(t *testing.T, krakenSpreadRecorder *KrakenSpreadRecorder) {
@@ -50,6 +60,15 @@ func TestKrakenOrderBookRecorder(t *testing.T) {
 	t.Run("RegisterAssetPair", func(t *testing.T) {
 		testOrderBookRegisterAssetPair(t, krakenOrderBookRecorder)
 	})
+	t.Run("UnregisterAssetPair", func(t *testing.T) {
+		testUnregisterAssetPair(t, krakenOrderBookRecorder, &krakenOrderBookRecorder.krakenWebSocketRecorder, func() bool {
+			_, ok := krakenOrderBookRecorder.GetOrderBook(grizzlytesting.DOGEUSD)
+			return ok
+		})
+	})
+	t.Run("Close", func(t *testing.T) {
+		testClose(t, krakenOrderBookRecorder, &krakenOrderBookRecorder.krakenWebSocketRecorder)
+	})
 }
 
 func testGetOrderBook(t *testing.T, krakenOrderBookRecorder *KrakenOrderBookRecorder) {
@@ -73,3 +92,97 @@ func testOrderBookRegisterAssetPair(t *testing.T, krakenOrderBookRecorder *Krake
 	}
 	fmt.Printf("%v: %v\n", translatedPair, orderBook)
 }
+
+func TestKrakenTradeRecorder(t *testing.T) {
+	krakenTradeRecorder := NewKrakenTradeRecorder(grizzlytesting.AssetPairs, grizzlytesting.Iso4217Translator, 100)
+	time.Sleep(grizzlytesting.SleepDuration)
+	t.Run("GetTradesSince", func(t *testing.T) {
+		testGetTradesSince(t, krakenTradeRecorder)
+	})
+	t.Run("RegisterAssetPair", func(t *testing.T) {
+		testTradeRegisterAssetPair(t, krakenTradeRecorder)
+	})
+	t.Run("UnregisterAssetPair", func(t *testing.T) {
+		testUnregisterAssetPair(t, krakenTradeRecorder, &krakenTradeRecorder.krakenWebSocketRecorder, func() bool {
+			_, ok := krakenTradeRecorder.GetTradesSince(grizzlytesting.DOGEUSD, time.Time{})
+			return ok
+		})
+	})
+	t.Run("Close", func(t *testing.T) {
+		testClose(t, krakenTradeRecorder, &krakenTradeRecorder.krakenWebSocketRecorder)
+	})
+}
+
+func testGetTradesSince(t *testing.T, krakenTradeRecorder *KrakenTradeRecorder) {
+	for _, assetPair := range grizzlytesting.AssetPairs {
+		translatedPair := grizzlytesting.Iso4217Translator[assetPair]
+		trades, ok := krakenTradeRecorder.GetTradesSince(assetPair, time.Time{})
+		if !ok {
+			t.Fatalf("AssetPair %v should be recorded\n", translatedPair)
+		}
+		fmt.Printf("%v: %v\n", translatedPair, trades)
+	}
+}
+
+func testTradeRegisterAssetPair(t *testing.T, krakenTradeRecorder *KrakenTradeRecorder) {
+	translatedPair := grizzlytesting.Iso4217Translator[grizzlytesting.DOGEUSD]
+	krakenTradeRecorder.RegisterAssetPair(grizzlytesting.DOGEUSD)
+	time.Sleep(grizzlytesting.SleepDuration)
+	trades, ok := krakenTradeRecorder.GetTradesSince(grizzlytesting.DOGEUSD, time.Time{})
+	if !ok {
+		t.Fatalf("AssetPair %v should be recorded\n", translatedPair)
+	}
+	fmt.Printf("%v: %v\n", translatedPair, trades)
+}
+
+// unregisters DOGEUSD, which the RegisterAssetPair subtest subscribed to
+func testUnregisterAssetPair(t *testing.T, recorder types.AssetPairRecorder, webSocketRecorder *krakenWebSocketRecorder, recorded func() bool) {
+	translatedPair := grizzlytesting.Iso4217Translator[grizzlytesting.DOGEUSD]
+	value, ok := webSocketRecorder.channelIds.Load(grizzlytesting.DOGEUSD)
+	if !ok {
+		t.Fatalf("AssetPair %v should be subscribed\n", translatedPair)
+	}
+	channel, _ := webSocketRecorder.channels.Load(value)
+	recorder.UnregisterAssetPair(grizzlytesting.DOGEUSD)
+	if recorded() {
+		t.Fatalf("AssetPair %v should no longer be recorded\n", translatedPair)
+	}
+	if _, ok := webSocketRecorder.cancels.Load(value); ok {
+		t.Fatalf("AssetPair %v should no longer be processed\n", translatedPair)
+	}
+	expectStopped(t, channel.(chan []interface{}))
+	// a second unregister is a no-op
+	recorder.UnregisterAssetPair(grizzlytesting.DOGEUSD)
+}
+
+func testClose(t *testing.T, recorder types.AssetPairRecorder, webSocketRecorder *krakenWebSocketRecorder) {
+	channels := make([]chan []interface{}, 0)
+	webSocketRecorder.channels.Range(func(key, value interface{}) bool {
+		channels = append(channels, value.(chan []interface{}))
+		return true
+	})
+	recorder.Close()
+	for _, channel := range channels {
+		expectStopped(t, channel)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		recorder.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("a second Close should return at once\n")
+	}
+}
+
+// fails if anything still receives from channel, the goroutine processing it should have exited
+func expectStopped(t *testing.T, channel chan []interface{}) {
+	select {
+	case channel <- nil:
+		t.Fatalf("goroutine processing the asset pair should have exited\n")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
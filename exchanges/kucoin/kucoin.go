@@ -6,12 +6,14 @@ import (
     "crypto/sha256"
     "encoding/base64"
     "encoding/json"
-    "log"
     "net/http"
     "net/url"
     "strconv"
     "time"
 
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
+    "github.com/denali-capital/grizzly/secrets"
     "github.com/denali-capital/grizzly/types"
     "github.com/denali-capital/grizzly/util"
     "github.com/google/uuid"
@@ -21,6 +23,11 @@ import (
 // docs: https://docs.kucoin.com/
 const RESTEndpoint string = "https://api.kucoin.com"
 
+// label on metrics and log lines
+const exchangeName string = "KuCoin"
+
+var logger *logging.Logger = logging.New("exchanges/kucoin").With(logging.Exchange(exchangeName))
+
 type KuCoin struct {
     AssetPairTranslator      types.AssetPairTranslator
 
@@ -29,22 +36,28 @@ type KuCoin struct {
     apiPassphrase            string
     spreadRecorder           types.SpreadRecorder
     orderBookRecorder        types.OrderBookRecorder
+    tradeRecorder            types.TradeRecorder
     latencyEstimator         *util.EwmaEstimator
     orderIdToOrderTranslator *util.ConcurrentOrderIdToOrderPtrMap
     // add timeouts
     httpClient               *http.Client
 }
 
-func NewKuCoin(apiKey, secretKey, apiPassphrase string, assetPairTranslator types.AssetPairTranslator) *KuCoin {
+func NewKuCoin(provider secrets.Provider, assetPairTranslator types.AssetPairTranslator, spreadCapacity, tradeCapacity, orderBookDepth uint) *KuCoin {
+    credentials, err := secrets.Load(provider, "KuCoin")
+    if err != nil {
+        logger.Fatal("loading credentials failed", logging.Err(err))
+    }
     assetPairs := assetPairTranslator.GetAssetPairs()
     httpClient := &http.Client{}
     return &KuCoin{
         AssetPairTranslator: assetPairTranslator,
-        apiKey: apiKey,
-        secretKey: secretKey,
-        apiPassphrase: apiPassphrase,
-        spreadRecorder: NewKuCoinSpreadRecorder(httpClient, assetPairs, assetPairTranslator, 200),
-        orderBookRecorder: NewKuCoinOrderBookRecorder(httpClient, apiKey, secretKey, apiPassphrase, assetPairs, assetPairTranslator, 1000),
+        apiKey: credentials.ApiKey,
+        secretKey: credentials.SecretKey,
+        apiPassphrase: credentials.Passphrase,
+        spreadRecorder: NewKuCoinSpreadRecorder(httpClient, assetPairs, assetPairTranslator, spreadCapacity),
+        orderBookRecorder: NewKuCoinOrderBookRecorder(httpClient, credentials.ApiKey, credentials.SecretKey, credentials.Passphrase, assetPairs, assetPairTranslator, orderBookDepth),
+        tradeRecorder: NewKuCoinTradeRecorder(httpClient, assetPairs, assetPairTranslator, tradeCapacity),
         latencyEstimator: util.NewEwmaEstimator(0.125, 0.25, 4),
         orderIdToOrderTranslator: util.NewConcurrentOrderIdToOrderPtrMap(),
         httpClient: httpClient,
@@ -55,9 +68,11 @@ func (k *KuCoin) String() string {
     return "KuCoin"
 }
 
-func checkError(bodyJson map[string]interface{}) {
+// endpoint is the requested URL, only its host and path are recorded
+func checkError(requestId, endpoint string, bodyJson map[string]interface{}) {
     if bodyJson["code"].(string) != "200000" {
-        log.Fatalln(bodyJson)
+        metrics.RestErrors.Inc(util.Endpoint(endpoint))
+        logger.Fatal("request failed", logging.RequestId(requestId), "endpoint", util.Endpoint(endpoint), "code", bodyJson["code"], "msg", bodyJson["msg"])
     }
 }
 
@@ -96,19 +111,19 @@ func (k *KuCoin) GetCurrentSpread(assetPair types.AssetPair) types.Spread {
     spread, ok := k.spreadRecorder.GetCurrentSpread(assetPair)
     if !ok {
         k.spreadRecorder.RegisterAssetPair(assetPair)
-        bodyJson := util.HttpGetAndGetBody(k.httpClient, util.ParseUrlWithQuery(RESTEndpoint + "/api/v1/market/orderbook/level1", url.Values{
+        bodyJson, requestId := util.HttpGetAndGetBody(k.httpClient, util.ParseUrlWithQuery(RESTEndpoint + "/api/v1/market/orderbook/level1", url.Values{
             "symbol": []string{k.AssetPairTranslator[assetPair]},
         }))
-        checkError(bodyJson)
+        checkError(requestId, RESTEndpoint + "/api/v1/market/orderbook/level1", bodyJson)
     
         data := bodyJson["data"].(map[string]interface{})
         bid, err := decimal.NewFromString(data["bestBid"].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         ask, err := decimal.NewFromString(data["bestAsk"].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
     
         return types.Spread{
@@ -143,17 +158,104 @@ func (k *KuCoin) GetOrderBooks(assetPairs []types.AssetPair) map[types.AssetPair
     return orderBooks
 }
 
+func (k *KuCoin) GetTradesSince(assetPair types.AssetPair, since time.Time) ([]types.Trade, bool) {
+    trades, ok := k.tradeRecorder.GetTradesSince(assetPair, since)
+    if !ok {
+        k.tradeRecorder.RegisterAssetPair(assetPair)
+    }
+    return trades, ok
+}
+
+func (k *KuCoin) GetVWAP(assetPair types.AssetPair, window time.Duration) (decimal.Decimal, bool) {
+    return k.tradeRecorder.GetVWAP(assetPair, window)
+}
+
+// resolution -> candle type
+var candleTypes map[time.Duration]string = map[time.Duration]string{
+    time.Minute: "1min",
+    5 * time.Minute: "5min",
+    15 * time.Minute: "15min",
+    30 * time.Minute: "30min",
+    time.Hour: "1hour",
+    4 * time.Hour: "4hour",
+    24 * time.Hour: "1day",
+}
+
+// at most 1500 candles are served; KuCoin does not report trade counts
+func (k *KuCoin) GetHistoricalCandles(assetPair types.AssetPair, resolution time.Duration, since time.Time) ([]types.Candle, bool) {
+    candleType, ok := candleTypes[resolution]
+    if !ok {
+        return make([]types.Candle, 0), false
+    }
+    bodyJson, requestId := util.HttpGetAndGetBody(k.httpClient, util.ParseUrlWithQuery(RESTEndpoint + "/api/v1/market/candles", url.Values{
+        "symbol": []string{k.AssetPairTranslator[assetPair]},
+        "type": []string{candleType},
+        "startAt": []string{strconv.FormatInt(since.Unix(), 10)},
+        "endAt": []string{strconv.FormatInt(time.Now().Unix(), 10)},
+    }))
+    checkError(requestId, RESTEndpoint + "/api/v1/market/candles", bodyJson)
+
+    // [time, open, close, high, low, volume, turnover], newest first
+    data := bodyJson["data"].([]interface{})
+    candles := make([]types.Candle, len(data))
+    for i, rawCandle := range data {
+        entry := rawCandle.([]interface{})
+        start, err := strconv.ParseInt(entry[0].(string), 10, 64)
+        if err != nil {
+            logger.Fatal("parsing int failed", logging.AssetPair(assetPair), logging.Err(err))
+        }
+        values := make([]decimal.Decimal, 5)
+        for j := range values {
+            value, err := decimal.NewFromString(entry[j + 1].(string))
+            if err != nil {
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
+            }
+            values[j] = value
+        }
+        candles[len(data) - 1 - i] = types.Candle{
+            Start: time.Unix(start, 0),
+            Open: values[0],
+            High: values[2],
+            Low: values[3],
+            Close: values[1],
+            Volume: values[4],
+        }
+    }
+    return candles, true
+}
+
+// starts recording spreads, order books and trades for assetPair ahead of the first request for them
+func (k *KuCoin) RegisterAssetPair(assetPair types.AssetPair) {
+    k.spreadRecorder.RegisterAssetPair(assetPair)
+    k.orderBookRecorder.RegisterAssetPair(assetPair)
+    k.tradeRecorder.RegisterAssetPair(assetPair)
+}
+
+func (k *KuCoin) UnregisterAssetPair(assetPair types.AssetPair) {
+    k.spreadRecorder.UnregisterAssetPair(assetPair)
+    k.orderBookRecorder.UnregisterAssetPair(assetPair)
+    k.tradeRecorder.UnregisterAssetPair(assetPair)
+}
+
+func (k *KuCoin) Close() {
+    k.spreadRecorder.Close()
+    k.orderBookRecorder.Close()
+    k.tradeRecorder.Close()
+}
+
 func (k *KuCoin) GetLatency() time.Duration {
     start := time.Now()
 
-    bodyJson := util.HttpGetAndGetBody(k.httpClient, RESTEndpoint + "/api/v1/timestamp")
-    checkError(bodyJson)
+    bodyJson, requestId := util.HttpGetAndGetBody(k.httpClient, RESTEndpoint + "/api/v1/timestamp")
+    checkError(requestId, RESTEndpoint + "/api/v1/timestamp", bodyJson)
 
     duration := time.Since(start)
 
     k.latencyEstimator.Sample(float64(duration.Milliseconds()))
 
-    return time.Duration(k.latencyEstimator.GetEstimate()) * time.Millisecond
+    estimate := time.Duration(k.latencyEstimator.GetEstimate()) * time.Millisecond
+    metrics.ObserveLatency(k.String(), estimate)
+    return estimate
 }
 
 func parseOrderType(ot types.OrderType) string {
@@ -185,7 +287,7 @@ func (k *KuCoin) executeOrder(order types.Order, channel chan types.OrderIdRespo
         "size": order.Quantity.String(),
     })
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("encoding message failed", logging.AssetPair(order.AssetPair), logging.Err(err))
     }
 
     time := strconv.FormatInt(time.Now().UnixMilli(), 10)
@@ -194,7 +296,7 @@ func (k *KuCoin) executeOrder(order types.Order, channel chan types.OrderIdRespo
 
     request, err := http.NewRequest("POST", RESTEndpoint + "/api/v1/orders", bytes.NewReader(data))
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.AssetPair(order.AssetPair), logging.Err(err))
     }
     request.Header.Set("KC-API-SIGN", signature)
     request.Header.Set("KC-API-TIMESTAMP", time)
@@ -202,8 +304,8 @@ func (k *KuCoin) executeOrder(order types.Order, channel chan types.OrderIdRespo
     request.Header.Set("KC-API-PASSPHRASE", passphrase)
     request.Header.Set("KC-API-KEY-VERSION", "2")
 
-    bodyJson := util.DoHttpAndGetBody(k.httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(k.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     jsonData := bodyJson["data"].(map[string]interface{})
 
@@ -211,6 +313,7 @@ func (k *KuCoin) executeOrder(order types.Order, channel chan types.OrderIdRespo
 
     k.orderIdToOrderTranslator.Store(orderId, &order)
 
+    logger.Info("order placed", logging.OrderId(orderId), logging.AssetPair(order.AssetPair), logging.RequestId(requestId), "type", parseOrderType(order.OrderType), "price", order.Price, "quantity", order.Quantity)
     channel <- types.OrderIdResponse{order, orderId}
 }
 
@@ -225,13 +328,14 @@ func (k *KuCoin) ExecuteOrders(orders []types.Order) map[types.Order]types.Order
         response := <- channel
         orderIds[response.Order] = response.OrderId
     }
+    metrics.ObserveSubmitted(k.String(), orderIds)
     return orderIds
 }
 
 func (k *KuCoin) getOrderStatus(orderId types.OrderId, channel chan types.OrderStatusResponse) {
     order, ok := k.orderIdToOrderTranslator.Load(orderId)
     if !ok {
-        log.Fatalf("order with id %v not found\n", orderId)
+        logger.Fatal("order not found", logging.OrderId(orderId))
     }
 
     time := strconv.FormatInt(time.Now().UnixMilli(), 10)
@@ -241,7 +345,7 @@ func (k *KuCoin) getOrderStatus(orderId types.OrderId, channel chan types.OrderS
 
     request, err := http.NewRequest("GET", RESTEndpoint + path, nil)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.OrderId(orderId), logging.Err(err))
     }
     request.Header.Set("KC-API-SIGN", signature)
     request.Header.Set("KC-API-TIMESTAMP", time)
@@ -249,8 +353,8 @@ func (k *KuCoin) getOrderStatus(orderId types.OrderId, channel chan types.OrderS
     request.Header.Set("KC-API-PASSPHRASE", passphrase)
     request.Header.Set("KC-API-KEY-VERSION", "2")
 
-    bodyJson := util.DoHttpAndGetBody(k.httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(k.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     data := bodyJson["data"].(map[string]interface{})
 
@@ -267,11 +371,11 @@ func (k *KuCoin) getOrderStatus(orderId types.OrderId, channel chan types.OrderS
         } else {
             price, err := decimal.NewFromString(data["price"].(string))
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("parsing decimal failed", logging.OrderId(orderId), logging.Err(err))
             }
             quantity, err := decimal.NewFromString(data["size"].(string))
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("parsing decimal failed", logging.OrderId(orderId), logging.Err(err))
             }
             orderStatus.Status = types.Filled
             orderStatus.FilledPrice = &price
@@ -294,6 +398,7 @@ func (k *KuCoin) GetOrderStatuses(orderIds []types.OrderId) map[types.OrderId]ty
         response := <- channel
         orderStatuses[response.OrderId] = response.OrderStatus
     }
+    metrics.ObserveOrderStatuses(k.String(), orderStatuses)
     return orderStatuses
 }
 
@@ -305,7 +410,7 @@ func (k *KuCoin) cancelOrder(orderId types.OrderId) {
 
     request, err := http.NewRequest("DELETE", RESTEndpoint + path, nil)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.OrderId(orderId), logging.Err(err))
     }
     request.Header.Set("KC-API-SIGN", signature)
     request.Header.Set("KC-API-TIMESTAMP", time)
@@ -313,10 +418,12 @@ func (k *KuCoin) cancelOrder(orderId types.OrderId) {
     request.Header.Set("KC-API-PASSPHRASE", passphrase)
     request.Header.Set("KC-API-KEY-VERSION", "2")
 
-    bodyJson := util.DoHttpAndGetBody(k.httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(k.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     k.orderIdToOrderTranslator.Delete(orderId)
+    metrics.ObserveCanceled(k.String(), []types.OrderId{orderId})
+    logger.Info("order canceled", logging.OrderId(orderId), logging.RequestId(requestId))
 }
 
 func (k *KuCoin) CancelOrders(orderIds []types.OrderId) {
@@ -325,6 +432,75 @@ func (k *KuCoin) CancelOrders(orderIds []types.OrderId) {
     }
 }
 
+func (k *KuCoin) GetOpenOrders() map[types.OrderId]types.OrderStatus {
+    time := strconv.FormatInt(time.Now().UnixMilli(), 10)
+    path := "/api/v1/orders?status=active"
+
+    signature, passphrase := getKuCoinSignatureAndPassphrase(k.secretKey, k.apiPassphrase, time, "GET", path, "")
+
+    request, err := http.NewRequest("GET", RESTEndpoint + path, nil)
+    if err != nil {
+        logger.Fatal("building request failed", logging.Err(err))
+    }
+    request.Header.Set("KC-API-SIGN", signature)
+    request.Header.Set("KC-API-TIMESTAMP", time)
+    request.Header.Set("KC-API-KEY", k.apiKey)
+    request.Header.Set("KC-API-PASSPHRASE", passphrase)
+    request.Header.Set("KC-API-KEY-VERSION", "2")
+
+    bodyJson, requestId := util.DoHttpAndGetBody(k.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
+
+    reverseTranslator := util.ReverseAssetPairTranslator(k.AssetPairTranslator)
+    items := bodyJson["data"].(map[string]interface{})["items"].([]interface{})
+    orderStatuses := make(map[types.OrderId]types.OrderStatus)
+    for _, rawOrderData := range items {
+        orderData := rawOrderData.(map[string]interface{})
+        assetPair, ok := reverseTranslator[orderData["symbol"].(string)]
+        if !ok {
+            continue
+        }
+        orderId := types.OrderId(orderData["id"].(string))
+        original, ok := k.orderIdToOrderTranslator.Load(orderId)
+        if !ok {
+            price, err := decimal.NewFromString(orderData["price"].(string))
+            if err != nil {
+                logger.Fatal("parsing decimal failed", logging.Err(err))
+            }
+            quantity, err := decimal.NewFromString(orderData["size"].(string))
+            if err != nil {
+                logger.Fatal("parsing decimal failed", logging.Err(err))
+            }
+            orderType := types.Buy
+            if orderData["side"].(string) == "sell" {
+                orderType = types.Sell
+            }
+            original = &types.Order{
+                OrderType: orderType,
+                AssetPair: assetPair,
+                Price: price,
+                Quantity: quantity,
+            }
+            k.orderIdToOrderTranslator.Store(orderId, original)
+        }
+
+        quantity, err := decimal.NewFromString(orderData["dealSize"].(string))
+        if err != nil {
+            logger.Fatal("parsing decimal failed", logging.Err(err))
+        }
+        orderStatus := types.OrderStatus{
+            Status: types.Unfilled,
+            Original: original,
+        }
+        if quantity.IsPositive() {
+            orderStatus.Status = types.PartiallyFilled
+            orderStatus.FilledQuantity = &quantity
+        }
+        orderStatuses[orderId] = orderStatus
+    }
+    return orderStatuses
+}
+
 func (k *KuCoin) GetBalances() map[types.Asset]decimal.Decimal {
     time := strconv.FormatInt(time.Now().UnixMilli(), 10)
 
@@ -332,7 +508,7 @@ func (k *KuCoin) GetBalances() map[types.Asset]decimal.Decimal {
 
     request, err := http.NewRequest("GET", RESTEndpoint + "/api/v1/accounts", nil)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.Err(err))
     }
     request.Header.Set("KC-API-SIGN", signature)
     request.Header.Set("KC-API-TIMESTAMP", time)
@@ -340,8 +516,8 @@ func (k *KuCoin) GetBalances() map[types.Asset]decimal.Decimal {
     request.Header.Set("KC-API-PASSPHRASE", passphrase)
     request.Header.Set("KC-API-KEY-VERSION", "2")
 
-    bodyJson := util.DoHttpAndGetBody(k.httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(k.httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     data := bodyJson["data"].([]interface{})
 
@@ -350,7 +526,7 @@ func (k *KuCoin) GetBalances() map[types.Asset]decimal.Decimal {
         data := rawData.(map[string]string)
         balance, err := decimal.NewFromString(data["balance"])
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.Err(err))
         }
         balances[types.Asset(data["currency"])] = balances[types.Asset(data["currency"])].Add(balance)
     }
@@ -2,23 +2,21 @@ package kucoin
 
 import (
 	"fmt"
-	"os"
 	"testing"
 	"time"
 
+	"github.com/denali-capital/grizzly/secrets"
 	grizzlytesting "github.com/denali-capital/grizzly/testing"
 	"github.com/joho/godotenv"
 )
 
-const apiKey string = "61cff0fbd5e581000154c4a5"
-
 // TODO: test use data stuff
 func TestKuCoin(t *testing.T) {
 	err := godotenv.Load("../../.env")
     if err != nil {
         t.Fatalf("Error loading .env file\n%v\n", err)
     }
-	kuCoin := NewKuCoin(apiKey, os.Getenv("KUCOIN" + grizzlytesting.SecretKeySuffix), os.Getenv("KUCOIN_API_PASSPHRASE"), grizzlytesting.KuCoinAssetPairTranslator)
+	kuCoin := NewKuCoin(secrets.NewEnvProvider(), grizzlytesting.KuCoinAssetPairTranslator, 200, 1000, 1000)
 	time.Sleep(grizzlytesting.SleepDuration)
 	t.Run("GetHistoricalSpreads", func(t *testing.T) {
 		testKuCoinGetHistoricalSpreads(t, kuCoin)
@@ -29,6 +27,9 @@ func TestKuCoin(t *testing.T) {
 	t.Run("GetOrderBooks", func(t *testing.T) {
 		testGetOrderBooks(t, kuCoin)
 	})
+	t.Run("GetHistoricalCandles", func(t *testing.T) {
+		testGetHistoricalCandles(t, kuCoin)
+	})
 	t.Run("GetLatency", func(t *testing.T) {
 		testGetLatency(t, kuCoin)
 	})
@@ -66,6 +67,22 @@ func testGetOrderBooks(t *testing.T, kuCoin *KuCoin) {
 	}
 }
 
+func testGetHistoricalCandles(t *testing.T, kuCoin *KuCoin) {
+	candles, ok := kuCoin.GetHistoricalCandles(grizzlytesting.ETHUSDT, time.Minute, time.Now().Add(-time.Hour))
+	if !ok || len(candles) == 0 {
+		t.Fatalf("Candles should not be empty")
+	}
+	for i := 1; i < len(candles); i++ {
+		if !candles[i - 1].Start.Before(candles[i].Start) {
+			t.Fatalf("Candles should be oldest first")
+		}
+	}
+	if _, ok := kuCoin.GetHistoricalCandles(grizzlytesting.ETHUSDT, time.Second, time.Now().Add(-time.Minute)); ok {
+		t.Fatalf("One second candles should not be offered")
+	}
+	fmt.Println(candles[len(candles) - 1])
+}
+
 func testGetLatency(t *testing.T, kuCoin *KuCoin) {
 	latency := kuCoin.GetLatency()
 	fmt.Println(latency)
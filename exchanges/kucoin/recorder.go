@@ -1,8 +1,8 @@
 package kucoin
 
 import (
+    "context"
     "encoding/json"
-    "log"
     "net/http"
     "net/url"
     "strconv"
@@ -10,6 +10,8 @@ import (
     "sync"
     "time"
 
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
     "github.com/denali-capital/grizzly/types"
     "github.com/denali-capital/grizzly/util"
     "github.com/gorilla/websocket"
@@ -28,11 +30,11 @@ type kuCoinMessage struct {
 func initializeWebSocketConnection(httpClient *http.Client) (time.Duration, *websocket.Conn) {
     request, err := http.NewRequest("POST", RESTEndpoint + "/api/v1/bullet-public", nil)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.Err(err))
     }
 
-    bodyJson := util.DoHttpAndGetBody(httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     data := bodyJson["data"].(map[string]interface{})
     instanceServer := data["instanceServers"].([]interface{})[0].(map[string]interface{})
@@ -43,16 +45,16 @@ func initializeWebSocketConnection(httpClient *http.Client) (time.Duration, *web
 
     webSocketConnection, _, err := websocket.DefaultDialer.Dial(endpoint, http.Header{})
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("websocket dial failed", logging.Err(err))
     }
 
     var initialResponse map[string]interface{}
     err = webSocketConnection.ReadJSON(&initialResponse)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("websocket read failed", logging.Err(err))
     }
     if initialResponse["type"].(string) != "welcome" {
-        log.Fatalln(initialResponse)
+        logger.Fatal("unexpected websocket response", "response", initialResponse)
     }
 
     return time.Duration(int64(instanceServer["pingInterval"].(float64))) * time.Millisecond, webSocketConnection
@@ -65,6 +67,115 @@ type kuCoinWebSocketRecorder struct {
     assetPairTranslator types.AssetPairTranslator
     // map[string]chan map[string]interface{}
     channels            *sync.Map
+    // map[string]context.CancelFunc, stops the goroutine processing the topic
+    cancels             *sync.Map
+    // canceled by Close, stops record, ping and every per asset pair goroutine
+    ctx                 context.Context
+    cancel              context.CancelFunc
+    closeOnce           sync.Once
+}
+
+// per topic context, canceled by UnregisterAssetPair or along with ctx
+func startTopic(ctx context.Context, cancels *sync.Map, topic string) context.Context {
+    topicCtx, cancel := context.WithCancel(ctx)
+    cancels.Store(topic, cancel)
+    return topicCtx
+}
+
+func (k *kuCoinWebSocketRecorder) closing() bool {
+    return k.ctx.Err() != nil
+}
+
+// drops resp once the recorder is closing, its goroutine may already be gone
+func (k *kuCoinWebSocketRecorder) forward(channel chan map[string]interface{}, resp map[string]interface{}) {
+    select {
+    case channel <- resp:
+    case <-k.ctx.Done():
+    }
+}
+
+// unsubscribes from every topic, then sends a close frame and closes the connection
+func (k *kuCoinWebSocketRecorder) close() {
+    k.closeOnce.Do(func() {
+        k.cancel()
+        // unblocks the read record is waiting on so that the lock is released
+        k.webSocketConnection.SetReadDeadline(time.Now())
+        k.Lock()
+        defer k.Unlock()
+
+        k.channels.Range(func(key, value interface{}) bool {
+            payloadJson, err := json.Marshal(kuCoinMessage{
+                Id: strconv.FormatInt(time.Now().UnixNano(), 10),
+                Type: "unsubscribe",
+                Topic: key.(string),
+            })
+            if err != nil {
+                logger.Fatal("encoding message failed", logging.Err(err))
+            }
+            k.webSocketConnection.WriteMessage(websocket.TextMessage, payloadJson)
+            return true
+        })
+        k.webSocketConnection.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+        k.webSocketConnection.Close()
+    })
+}
+
+// unsubscribes from one topic and stops its goroutine, false if it was not subscribed
+func (k *kuCoinWebSocketRecorder) unregister(assetPair types.AssetPair, topic string) bool {
+    k.Lock()
+    defer k.Unlock()
+    if _, ok := k.channels.Load(topic); !ok || k.closing() {
+        return false
+    }
+
+    id := strconv.FormatInt(time.Now().UnixMilli(), 10)
+    payloadJson, err := json.Marshal(kuCoinMessage{
+        Id: id,
+        Type: "unsubscribe",
+        Topic: topic,
+        Response: true,
+    })
+    if err != nil {
+        logger.Fatal("encoding message failed", logging.AssetPair(assetPair), logging.Err(err))
+    }
+    k.webSocketConnection.WriteMessage(1, payloadJson)
+    for {
+        var resp map[string]interface{}
+        err := k.webSocketConnection.ReadJSON(&resp)
+        if err != nil {
+            if k.closing() {
+                return false
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
+        }
+        if resp["type"].(string) == "error" {
+            logger.Fatal("websocket error", logging.AssetPair(assetPair), "response", resp)
+        }
+        if msgId, ok := resp["id"]; ok {
+            if msgId.(string) != id {
+                logger.Fatal("id mismatch", "sent", id, "received", msgId)
+            }
+            if tpe := resp["type"].(string); tpe != "ack" {
+                logger.Fatal("expected ack", "type", tpe)
+            }
+            break
+        }
+        messageTopic := resp["topic"].(string)
+        if messageTopic == topic {
+            // still in flight when the unsubscribe was sent
+            continue
+        }
+        channel, ok := k.channels.Load(messageTopic)
+        if !ok {
+            logger.Fatal("channel not found", "topic", messageTopic)
+        }
+        k.forward(channel.(chan map[string]interface{}), util.MapCopy(resp))
+    }
+
+    k.channels.Delete(topic)
+    cancel, _ := k.cancels.LoadAndDelete(topic)
+    cancel.(context.CancelFunc)()
+    return true
 }
 
 func (k *kuCoinWebSocketRecorder) ping(interval time.Duration) {
@@ -75,7 +186,7 @@ func (k *kuCoinWebSocketRecorder) ping(interval time.Duration) {
             Type: "ping",
         })
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("encoding message failed", logging.Err(err))
         }
         k.Lock()
         k.webSocketConnection.WriteMessage(1, payloadJson)
@@ -83,29 +194,38 @@ func (k *kuCoinWebSocketRecorder) ping(interval time.Duration) {
             var resp map[string]interface{}
             err := k.webSocketConnection.ReadJSON(&resp)
             if err != nil {
-                log.Fatalln(err)
+                if k.closing() {
+                    k.Unlock()
+                    return
+                }
+                logger.Fatal("websocket read failed", logging.Err(err))
             }
             if resp["type"].(string) == "error" {
-                log.Fatalln(resp)
+                logger.Fatal("websocket error", "response", resp)
             }
             if msgId, ok := resp["id"]; ok {
                 if msgId.(string) != id {
-                    log.Fatalf("id mismatch between sent %v and received %v\n", id, msgId)
+                    logger.Fatal("id mismatch", "sent", id, "received", msgId)
                 }
                 if tpe := resp["type"].(string); tpe != "pong" {
-                    log.Fatalf("expected pong, got %v\n", tpe)
+                    logger.Fatal("expected pong", "type", tpe)
                 }
                 break
             }
             topic := resp["topic"].(string)
             channel, ok := k.channels.Load(topic)
             if !ok {
-                log.Fatalf("channel not found for topic %v\n", topic)
+                logger.Fatal("channel not found", "topic", topic)
             }
-            channel.(chan map[string]interface{}) <- util.MapCopy(resp)
+            k.forward(channel.(chan map[string]interface{}), util.MapCopy(resp))
         }
         k.Unlock()
-        time.Sleep(interval)
+
+        select {
+        case <-k.ctx.Done():
+            return
+        case <-time.After(interval):
+        }
     }
 }
 
@@ -115,17 +235,21 @@ func (k *kuCoinWebSocketRecorder) record() {
         k.Lock()
         err := k.webSocketConnection.ReadJSON(&resp)
         if err != nil {
-            log.Fatalln(err)
+            if k.closing() {
+                k.Unlock()
+                return
+            }
+            logger.Fatal("websocket read failed", logging.Err(err))
         }
         if resp["type"].(string) == "error" {
-            log.Fatalln(resp)
+            logger.Fatal("websocket error", "response", resp)
         }
         topic := resp["topic"].(string)
         channel, ok := k.channels.Load(topic)
         if !ok {
-            log.Fatalf("channel not found for topic %v\n", topic)
+            logger.Fatal("channel not found", "topic", topic)
         }
-        channel.(chan map[string]interface{}) <- util.MapCopy(resp)
+        k.forward(channel.(chan map[string]interface{}), util.MapCopy(resp))
         k.Unlock()
     }
 }
@@ -138,6 +262,7 @@ type KuCoinSpreadRecorder struct {
 }
 
 func NewKuCoinSpreadRecorder(httpClient *http.Client, assetPairs []types.AssetPair, assetPairTranslator types.AssetPairTranslator, capacity uint) *KuCoinSpreadRecorder {
+    ctx, cancel := context.WithCancel(context.Background())
     pingInterval, webSocketConnection := initializeWebSocketConnection(httpClient)
 
     assetPairNames := make([]string, len(assetPairs))
@@ -153,29 +278,31 @@ func NewKuCoinSpreadRecorder(httpClient *http.Client, assetPairs []types.AssetPa
         Response: true,
     })
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("encoding message failed", logging.Err(err))
     }
     webSocketConnection.WriteMessage(1, payloadJson)
 
     var initialResponse map[string]interface{}
     err = webSocketConnection.ReadJSON(&initialResponse)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("websocket read failed", logging.Err(err))
     }
     if !(initialResponse["id"].(string) == id && initialResponse["type"].(string) == "ack") {
-        log.Fatalln(initialResponse)
+        logger.Fatal("unexpected websocket response", "response", initialResponse)
     }
 
     channels := &sync.Map{}
+    cancels := &sync.Map{}
     historicalSpreads := &sync.Map{}
     for _, assetPair := range assetPairs {
+        topic := "/market/ticker:" + assetPairTranslator[assetPair]
         channel := make(chan map[string]interface{})
         historicalSpread := util.NewConcurrentFixedSizeSpreadQueue(capacity)
 
-        channels.Store("/market/ticker:" + assetPairTranslator[assetPair], channel)
+        channels.Store(topic, channel)
         historicalSpreads.Store(assetPair, historicalSpread)
 
-        go processSpreadUpdates(historicalSpread, channel)
+        go processSpreadUpdates(startTopic(ctx, cancels, topic), assetPair, historicalSpread, channel)
     }
 
     kuCoinSpreadRecorder := &KuCoinSpreadRecorder{
@@ -183,6 +310,9 @@ func NewKuCoinSpreadRecorder(httpClient *http.Client, assetPairs []types.AssetPa
             webSocketConnection: webSocketConnection,
             assetPairTranslator: assetPairTranslator,
             channels: channels,
+            cancels: cancels,
+            ctx: ctx,
+            cancel: cancel,
         },
         capacity: capacity,
         historicalSpreads: historicalSpreads,
@@ -194,18 +324,21 @@ func NewKuCoinSpreadRecorder(httpClient *http.Client, assetPairs []types.AssetPa
     return kuCoinSpreadRecorder
 }
 
-func processSpreadUpdates(historicalSpread *util.ConcurrentFixedSizeSpreadQueue, channel chan map[string]interface{}) {
+func processSpreadUpdates(ctx context.Context, assetPair types.AssetPair, historicalSpread *util.ConcurrentFixedSizeSpreadQueue, channel chan map[string]interface{}) {
     for {
         select {
+        case <-ctx.Done():
+            return
         case resp := <- channel:
+            metrics.WebSocketMessages.Inc(exchangeName, "spread", assetPair.String())
             rawSpread := resp["data"].(map[string]interface{})
             bid, err := decimal.NewFromString(rawSpread["bestBid"].(string))
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
             }
             ask, err := decimal.NewFromString(rawSpread["bestAsk"].(string))
             if err != nil {
-                log.Fatalln(err)
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
             }
 
             historicalSpread.Push(types.Spread{
@@ -213,6 +346,7 @@ func processSpreadUpdates(historicalSpread *util.ConcurrentFixedSizeSpreadQueue,
                 Ask: ask,
                 Timestamp: time.UnixMilli(int64(rawSpread["time"].(float64))),
             })
+            metrics.SpreadAge.Touch(exchangeName, assetPair.String())
         }
     }
 }
@@ -248,7 +382,7 @@ func (k *KuCoinSpreadRecorder) RegisterAssetPair(assetPair types.AssetPair) {
         Response: true,
     })
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("encoding message failed", logging.AssetPair(assetPair), logging.Err(err))
     }
     k.Lock()
     defer k.Unlock()
@@ -257,26 +391,29 @@ func (k *KuCoinSpreadRecorder) RegisterAssetPair(assetPair types.AssetPair) {
         var resp map[string]interface{}
         err := k.webSocketConnection.ReadJSON(&resp)
         if err != nil {
-            log.Fatalln(err)
+            if k.closing() {
+                return
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         if resp["type"].(string) == "error" {
-            log.Fatalln(resp)
+            logger.Fatal("websocket error", logging.AssetPair(assetPair), "response", resp)
         }
         if msgId, ok := resp["id"]; ok {
             if msgId.(string) != id {
-                log.Fatalf("id mismatch between sent %v and received %v\n", id, msgId)
+                logger.Fatal("id mismatch", "sent", id, "received", msgId)
             }
             if tpe := resp["type"].(string); tpe != "ack" {
-                log.Fatalf("expected ack, got %v\n", tpe)
+                logger.Fatal("expected ack", "type", tpe)
             }
             break
         }
         topic := resp["topic"].(string)
         channel, ok := k.channels.Load(topic)
         if !ok {
-            log.Fatalf("channel not found for topic %v\n", topic)
+            logger.Fatal("channel not found", "topic", topic)
         }
-        channel.(chan map[string]interface{}) <- util.MapCopy(resp)
+        k.forward(channel.(chan map[string]interface{}), util.MapCopy(resp))
     }
     channel := make(chan map[string]interface{})
     historicalSpread := util.NewConcurrentFixedSizeSpreadQueue(k.capacity)
@@ -284,7 +421,18 @@ func (k *KuCoinSpreadRecorder) RegisterAssetPair(assetPair types.AssetPair) {
     k.channels.Store(topic, channel)
     k.historicalSpreads.Store(assetPair, historicalSpread)
 
-    go processSpreadUpdates(historicalSpread, channel)
+    go processSpreadUpdates(startTopic(k.ctx, k.cancels, topic), assetPair, historicalSpread, channel)
+}
+
+func (k *KuCoinSpreadRecorder) UnregisterAssetPair(assetPair types.AssetPair) {
+    if k.unregister(assetPair, "/market/ticker:" + k.assetPairTranslator[assetPair]) {
+        k.historicalSpreads.Delete(assetPair)
+    }
+}
+
+// unsubscribes from every asset pair and stops recording, safe to call more than once
+func (k *KuCoinSpreadRecorder) Close() {
+    k.close()
 }
 
 type KuCoinOrderBookRecorder struct {
@@ -308,7 +456,7 @@ func getOrderBookSnapshot(httpClient *http.Client, apiKey, secretKey, apiPassphr
 
     request, err := http.NewRequest("GET", RESTEndpoint + path, nil)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", logging.AssetPair(assetPair), logging.Err(err))
     }
     request.Header.Set("KC-API-SIGN", signature)
     request.Header.Set("KC-API-TIMESTAMP", time)
@@ -316,25 +464,25 @@ func getOrderBookSnapshot(httpClient *http.Client, apiKey, secretKey, apiPassphr
     request.Header.Set("KC-API-PASSPHRASE", passphrase)
     request.Header.Set("KC-API-KEY-VERSION", "2")
 
-    bodyJson := util.DoHttpAndGetBody(httpClient, request)
-    checkError(bodyJson)
+    bodyJson, requestId := util.DoHttpAndGetBody(httpClient, request)
+    checkError(requestId, request.URL.String(), bodyJson)
 
     data := bodyJson["data"].(map[string]interface{})
 
     sequence, err := strconv.ParseUint(data["sequence"].(string), 10, 64)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("parsing number failed", logging.AssetPair(assetPair), logging.Err(err))
     }
     asks := make([]types.OrderBookEntry, 0)
     bids := make([]types.OrderBookEntry, 0)
     for _, rawOrderBookEntry := range data["asks"].([]interface{}) {
         price, err := decimal.NewFromString(rawOrderBookEntry.([]interface{})[0].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         quantity, err := decimal.NewFromString(rawOrderBookEntry.([]interface{})[1].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         asks = append(asks, types.OrderBookEntry{
             Price: price,
@@ -348,11 +496,11 @@ func getOrderBookSnapshot(httpClient *http.Client, apiKey, secretKey, apiPassphr
     for _, rawOrderBookEntry := range data["bids"].([]interface{}) {
         price, err := decimal.NewFromString(rawOrderBookEntry.([]interface{})[0].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         quantity, err := decimal.NewFromString(rawOrderBookEntry.([]interface{})[1].(string))
         if err != nil {
-            log.Fatalln(err)
+            logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         bids = append(bids, types.OrderBookEntry{
             Price: price,
@@ -387,6 +535,7 @@ func getOrderBookSnapshots(httpClient *http.Client, apiKey, secretKey, apiPassph
 }
 
 func NewKuCoinOrderBookRecorder(httpClient *http.Client, apiKey, secretKey, apiPassphrase string, assetPairs []types.AssetPair, assetPairTranslator types.AssetPairTranslator, depth uint) *KuCoinOrderBookRecorder {
+    ctx, cancel := context.WithCancel(context.Background())
     pingInterval, webSocketConnection := initializeWebSocketConnection(httpClient)
 
     assetPairNames := make([]string, len(assetPairs))
@@ -402,27 +551,29 @@ func NewKuCoinOrderBookRecorder(httpClient *http.Client, apiKey, secretKey, apiP
         Response: true,
     })
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("encoding message failed", logging.Err(err))
     }
     webSocketConnection.WriteMessage(1, payloadJson)
 
     var initialResponse map[string]interface{}
     err = webSocketConnection.ReadJSON(&initialResponse)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("websocket read failed", logging.Err(err))
     }
     if !(initialResponse["id"].(string) == id && initialResponse["type"].(string) == "ack") {
-        log.Fatalln(initialResponse)
+        logger.Fatal("unexpected websocket response", "response", initialResponse)
     }
 
     channels := &sync.Map{}
+    cancels := &sync.Map{}
     orderBooks, syncOrderBooks := getOrderBookSnapshots(httpClient, apiKey, secretKey, apiPassphrase, assetPairs, assetPairTranslator, depth)
     for _, assetPair := range assetPairs {
+        topic := "/market/level2:" + assetPairTranslator[assetPair]
         channel := make(chan map[string]interface{})
 
-        channels.Store("/market/level2:" + assetPairTranslator[assetPair], channel)
+        channels.Store(topic, channel)
 
-        go processOrderBookUpdates(httpClient, assetPair, assetPairTranslator, orderBooks[assetPair], channel, depth)
+        go processOrderBookUpdates(startTopic(ctx, cancels, topic), httpClient, assetPair, assetPairTranslator, orderBooks[assetPair], channel, depth)
     }
 
     kuCoinOrderBookRecorder := &KuCoinOrderBookRecorder{
@@ -430,6 +581,9 @@ func NewKuCoinOrderBookRecorder(httpClient *http.Client, apiKey, secretKey, apiP
             webSocketConnection: webSocketConnection,
             assetPairTranslator: assetPairTranslator,
             channels: channels,
+            cancels: cancels,
+            ctx: ctx,
+            cancel: cancel,
         },
         apiKey: apiKey,
         secretKey: secretKey,
@@ -445,10 +599,13 @@ func NewKuCoinOrderBookRecorder(httpClient *http.Client, apiKey, secretKey, apiP
     return kuCoinOrderBookRecorder
 }
 
-func processOrderBookUpdates(httpClient *http.Client, assetPair types.AssetPair, assetPairTranslator types.AssetPairTranslator, concurrentOrderBook *util.ConcurrentOrderBook, channel chan map[string]interface{}, depth uint) {
+func processOrderBookUpdates(ctx context.Context, httpClient *http.Client, assetPair types.AssetPair, assetPairTranslator types.AssetPairTranslator, concurrentOrderBook *util.ConcurrentOrderBook, channel chan map[string]interface{}, depth uint) {
     for {
         select {
+        case <-ctx.Done():
+            return
         case resp := <- channel:
+            metrics.WebSocketMessages.Inc(exchangeName, "order_book", assetPair.String())
             changes := resp["data"].(map[string]interface{})["changes"].(map[string]interface{})
             bids := concurrentOrderBook.GetBids()
             asks := concurrentOrderBook.GetAsks()
@@ -456,7 +613,7 @@ func processOrderBookUpdates(httpClient *http.Client, assetPair types.AssetPair,
             for _, rawOrderBookEntry := range changes["bids"].([]interface{}) {
                 sequence, err := strconv.ParseUint(rawOrderBookEntry.([]interface{})[2].(string), 10, 64)
                 if err != nil {
-                    log.Fatalln(err)
+                    logger.Fatal("parsing number failed", logging.AssetPair(assetPair), logging.Err(err))
                 }
                 if uint(sequence) < concurrentOrderBook.LastUpdateId {
                     continue
@@ -479,7 +636,7 @@ func processOrderBookUpdates(httpClient *http.Client, assetPair types.AssetPair,
             for _, rawOrderBookEntry := range changes["asks"].([]interface{}) {
                 sequence, err := strconv.ParseUint(rawOrderBookEntry.([]interface{})[2].(string), 10, 64)
                 if err != nil {
-                    log.Fatalln(err)
+                    logger.Fatal("parsing number failed", logging.AssetPair(assetPair), logging.Err(err))
                 }
                 if uint(sequence) < concurrentOrderBook.LastUpdateId {
                     continue
@@ -503,6 +660,7 @@ func processOrderBookUpdates(httpClient *http.Client, assetPair types.AssetPair,
                 concurrentOrderBook.LastUpdateId = uint(maxSequence)
             }
             concurrentOrderBook.SetBidsAndAsks(bids[:util.MinUint(depth, uint(len(bids)))], asks[:util.MinUint(depth, uint(len(asks)))])
+            metrics.OrderBookAge.Touch(exchangeName, assetPair.String())
         }
     }
 }
@@ -530,7 +688,7 @@ func (k *KuCoinOrderBookRecorder) RegisterAssetPair(assetPair types.AssetPair) {
         Response: true,
     })
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("encoding message failed", logging.AssetPair(assetPair), logging.Err(err))
     }
     k.Lock()
     defer k.Unlock()
@@ -539,26 +697,29 @@ func (k *KuCoinOrderBookRecorder) RegisterAssetPair(assetPair types.AssetPair) {
         var resp map[string]interface{}
         err := k.webSocketConnection.ReadJSON(&resp)
         if err != nil {
-            log.Fatalln(err)
+            if k.closing() {
+                return
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
         }
         if resp["type"].(string) == "error" {
-            log.Fatalln(resp)
+            logger.Fatal("websocket error", logging.AssetPair(assetPair), "response", resp)
         }
         if msgId, ok := resp["id"]; ok {
             if msgId.(string) != id {
-                log.Fatalf("id mismatch between sent %v and received %v\n", id, msgId)
+                logger.Fatal("id mismatch", "sent", id, "received", msgId)
             }
             if tpe := resp["type"].(string); tpe != "ack" {
-                log.Fatalf("expected ack, got %v\n", tpe)
+                logger.Fatal("expected ack", "type", tpe)
             }
             break
         }
         topic := resp["topic"].(string)
         channel, ok := k.channels.Load(topic)
         if !ok {
-            log.Fatalf("channel not found for topic %v\n", topic)
+            logger.Fatal("channel not found", "topic", topic)
         }
-        channel.(chan map[string]interface{}) <- util.MapCopy(resp)
+        k.forward(channel.(chan map[string]interface{}), util.MapCopy(resp))
     }
     channel := make(chan map[string]interface{})
 
@@ -569,6 +730,208 @@ func (k *KuCoinOrderBookRecorder) RegisterAssetPair(assetPair types.AssetPair) {
     select {
     case resp := <- snapshotChannel:
         k.orderBooks.Store(assetPair, resp.ConcurrentOrderBook)
-        go processOrderBookUpdates(k.httpClient, assetPair, k.assetPairTranslator, resp.ConcurrentOrderBook, channel, k.depth)
+        go processOrderBookUpdates(startTopic(k.ctx, k.cancels, topic), k.httpClient, assetPair, k.assetPairTranslator, resp.ConcurrentOrderBook, channel, k.depth)
+    }
+}
+
+func (k *KuCoinOrderBookRecorder) UnregisterAssetPair(assetPair types.AssetPair) {
+    if k.unregister(assetPair, "/market/level2:" + k.assetPairTranslator[assetPair]) {
+        k.orderBooks.Delete(assetPair)
+    }
+}
+
+// unsubscribes from every asset pair and stops recording, safe to call more than once
+func (k *KuCoinOrderBookRecorder) Close() {
+    k.close()
+}
+
+type KuCoinTradeRecorder struct {
+    kuCoinWebSocketRecorder
+    capacity                 uint
+    // map[types.AssetPair]*util.ConcurrentTradeRing
+    trades                   *sync.Map
+}
+
+func NewKuCoinTradeRecorder(httpClient *http.Client, assetPairs []types.AssetPair, assetPairTranslator types.AssetPairTranslator, capacity uint) *KuCoinTradeRecorder {
+    ctx, cancel := context.WithCancel(context.Background())
+    pingInterval, webSocketConnection := initializeWebSocketConnection(httpClient)
+
+    assetPairNames := make([]string, len(assetPairs))
+    for i, assetPair := range assetPairs {
+        assetPairNames[i] = assetPairTranslator[assetPair]
+    }
+
+    id := strconv.FormatInt(time.Now().UnixMilli(), 10)
+    payloadJson, err := json.Marshal(kuCoinMessage{
+        Id: id,
+        Type: "subscribe",
+        Topic: "/market/match:" + strings.Join(assetPairNames, ","),
+        Response: true,
+    })
+    if err != nil {
+        logger.Fatal("encoding message failed", logging.Err(err))
+    }
+    webSocketConnection.WriteMessage(1, payloadJson)
+
+    var initialResponse map[string]interface{}
+    err = webSocketConnection.ReadJSON(&initialResponse)
+    if err != nil {
+        logger.Fatal("websocket read failed", logging.Err(err))
+    }
+    if !(initialResponse["id"].(string) == id && initialResponse["type"].(string) == "ack") {
+        logger.Fatal("unexpected websocket response", "response", initialResponse)
+    }
+
+    channels := &sync.Map{}
+    cancels := &sync.Map{}
+    trades := &sync.Map{}
+    for _, assetPair := range assetPairs {
+        topic := "/market/match:" + assetPairTranslator[assetPair]
+        channel := make(chan map[string]interface{})
+        tradeRing := util.NewConcurrentTradeRing(capacity)
+
+        channels.Store(topic, channel)
+        trades.Store(assetPair, tradeRing)
+
+        go processTradeUpdates(startTopic(ctx, cancels, topic), assetPair, tradeRing, channel)
     }
+
+    kuCoinTradeRecorder := &KuCoinTradeRecorder{
+        kuCoinWebSocketRecorder: kuCoinWebSocketRecorder{
+            webSocketConnection: webSocketConnection,
+            assetPairTranslator: assetPairTranslator,
+            channels: channels,
+            cancels: cancels,
+            ctx: ctx,
+            cancel: cancel,
+        },
+        capacity: capacity,
+        trades: trades,
+    }
+
+    go kuCoinTradeRecorder.ping(pingInterval)
+    go kuCoinTradeRecorder.record()
+
+    return kuCoinTradeRecorder
+}
+
+func processTradeUpdates(ctx context.Context, assetPair types.AssetPair, tradeRing *util.ConcurrentTradeRing, channel chan map[string]interface{}) {
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case resp := <- channel:
+            metrics.WebSocketMessages.Inc(exchangeName, "trade", assetPair.String())
+            rawTrade := resp["data"].(map[string]interface{})
+            price, err := decimal.NewFromString(rawTrade["price"].(string))
+            if err != nil {
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
+            }
+            quantity, err := decimal.NewFromString(rawTrade["size"].(string))
+            if err != nil {
+                logger.Fatal("parsing decimal failed", logging.AssetPair(assetPair), logging.Err(err))
+            }
+            // in ns
+            timestamp, err := strconv.ParseInt(rawTrade["time"].(string), 10, 64)
+            if err != nil {
+                logger.Fatal("parsing number failed", logging.AssetPair(assetPair), logging.Err(err))
+            }
+            // side is the taker's
+            side := types.Buy
+            if rawTrade["side"].(string) == "sell" {
+                side = types.Sell
+            }
+
+            tradeRing.Push(types.Trade{
+                Price: price,
+                Quantity: quantity,
+                Side: side,
+                Timestamp: time.Unix(0, timestamp),
+            })
+        }
+    }
+}
+
+func (k *KuCoinTradeRecorder) GetTradesSince(assetPair types.AssetPair, since time.Time) ([]types.Trade, bool) {
+    result, ok := k.trades.Load(assetPair)
+    if !ok {
+        return make([]types.Trade, 0), false
+    }
+    return result.(*util.ConcurrentTradeRing).Since(since), true
+}
+
+func (k *KuCoinTradeRecorder) GetVWAP(assetPair types.AssetPair, window time.Duration) (decimal.Decimal, bool) {
+    trades, ok := k.GetTradesSince(assetPair, time.Now().Add(-window))
+    if !ok {
+        return decimal.Zero, false
+    }
+    return util.ComputeVWAP(trades)
+}
+
+func (k *KuCoinTradeRecorder) RegisterAssetPair(assetPair types.AssetPair) {
+    if _, ok := k.trades.Load(assetPair); ok {
+        return
+    }
+
+    topic := "/market/match:" + k.assetPairTranslator[assetPair]
+
+    id := strconv.FormatInt(time.Now().UnixMilli(), 10)
+    payloadJson, err := json.Marshal(kuCoinMessage{
+        Id: id,
+        Type: "subscribe",
+        Topic: topic,
+        Response: true,
+    })
+    if err != nil {
+        logger.Fatal("encoding message failed", logging.AssetPair(assetPair), logging.Err(err))
+    }
+    k.Lock()
+    defer k.Unlock()
+    k.webSocketConnection.WriteMessage(1, payloadJson)
+    for {
+        var resp map[string]interface{}
+        err := k.webSocketConnection.ReadJSON(&resp)
+        if err != nil {
+            if k.closing() {
+                return
+            }
+            logger.Fatal("websocket read failed", logging.AssetPair(assetPair), logging.Err(err))
+        }
+        if resp["type"].(string) == "error" {
+            logger.Fatal("websocket error", logging.AssetPair(assetPair), "response", resp)
+        }
+        if msgId, ok := resp["id"]; ok {
+            if msgId.(string) != id {
+                logger.Fatal("id mismatch", "sent", id, "received", msgId)
+            }
+            if tpe := resp["type"].(string); tpe != "ack" {
+                logger.Fatal("expected ack", "type", tpe)
+            }
+            break
+        }
+        topic := resp["topic"].(string)
+        channel, ok := k.channels.Load(topic)
+        if !ok {
+            logger.Fatal("channel not found", "topic", topic)
+        }
+        k.forward(channel.(chan map[string]interface{}), util.MapCopy(resp))
+    }
+    channel := make(chan map[string]interface{})
+    tradeRing := util.NewConcurrentTradeRing(k.capacity)
+
+    k.channels.Store(topic, channel)
+    k.trades.Store(assetPair, tradeRing)
+
+    go processTradeUpdates(startTopic(k.ctx, k.cancels, topic), assetPair, tradeRing, channel)
+}
+
+func (k *KuCoinTradeRecorder) UnregisterAssetPair(assetPair types.AssetPair) {
+    if k.unregister(assetPair, "/market/match:" + k.assetPairTranslator[assetPair]) {
+        k.trades.Delete(assetPair)
+    }
+}
+
+// unsubscribes from every asset pair and stops recording, safe to call more than once
+func (k *KuCoinTradeRecorder) Close() {
+    k.close()
 }
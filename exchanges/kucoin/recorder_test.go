@@ -3,11 +3,12 @@ package kucoin
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"testing"
 	"time"
 
+	"github.com/denali-capital/grizzly/secrets"
 	grizzlytesting "github.com/denali-capital/grizzly/testing"
+	"github.com/denali-capital/grizzly/types"
 	"github.com/joho/godotenv"
 )
 
@@ -20,6 +21,15 @@ func TestKuCoinSpreadRecorder(t *testing.T) {
 	t.Run("RegisterAssetPair", func(t *testing.T) {
 		testSpreadRegisterAssetPair(t, kuCoinSpreadRecorder)
 	})
+	t.Run("UnregisterAssetPair", func(t *testing.T) {
+		testUnregisterAssetPair(t, kuCoinSpreadRecorder, &kuCoinSpreadRecorder.kuCoinWebSocketRecorder, "/market/ticker:" + grizzlytesting.KuCoinAssetPairTranslator[grizzlytesting.BTCUSDC], func() bool {
+			_, ok := kuCoinSpreadRecorder.GetHistoricalSpreads(grizzlytesting.BTCUSDC)
+			return ok
+		})
+	})
+	t.Run("Close", func(t *testing.T) {
+		testClose(t, kuCoinSpreadRecorder, &kuCoinSpreadRecorder.kuCoinWebSocketRecorder)
+	})
 }
 
 func testRecorderGetHistoricalSpreads(t *testing.T, kuCoinSpreadRecorder *KuCoinSpreadRecorder) {
@@ -49,7 +59,11 @@ func TestKuCoinOrderBookRecorder(t *testing.T) {
     if err != nil {
         t.Fatalf("Error loading .env file\n%v\n", err)
     }
-	kuCoinOrderBookRecorder := NewKuCoinOrderBookRecorder(&http.Client{}, apiKey, os.Getenv("KUCOIN" + grizzlytesting.SecretKeySuffix), os.Getenv("KUCOIN_API_PASSPHRASE"), grizzlytesting.KuCoinAssetPairs, grizzlytesting.KuCoinAssetPairTranslator, 100)
+	credentials, err := secrets.Load(secrets.NewEnvProvider(), "KuCoin")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	kuCoinOrderBookRecorder := NewKuCoinOrderBookRecorder(&http.Client{}, credentials.ApiKey, credentials.SecretKey, credentials.Passphrase, grizzlytesting.KuCoinAssetPairs, grizzlytesting.KuCoinAssetPairTranslator, 100)
 	time.Sleep(grizzlytesting.SleepDuration)
 	t.Run("GetOrderBook", func(t *testing.T) {
 		testGetOrderBook(t, kuCoinOrderBookRecorder)
@@ -57,6 +71,15 @@ func TestKuCoinOrderBookRecorder(t *testing.T) {
 	t.Run("RegisterAssetPair", func(t *testing.T) {
 		testOrderBookRegisterAssetPair(t, kuCoinOrderBookRecorder)
 	})
+	t.Run("UnregisterAssetPair", func(t *testing.T) {
+		testUnregisterAssetPair(t, kuCoinOrderBookRecorder, &kuCoinOrderBookRecorder.kuCoinWebSocketRecorder, "/market/level2:" + grizzlytesting.KuCoinAssetPairTranslator[grizzlytesting.BTCUSDC], func() bool {
+			_, ok := kuCoinOrderBookRecorder.GetOrderBook(grizzlytesting.BTCUSDC)
+			return ok
+		})
+	})
+	t.Run("Close", func(t *testing.T) {
+		testClose(t, kuCoinOrderBookRecorder, &kuCoinOrderBookRecorder.kuCoinWebSocketRecorder)
+	})
 }
 
 func testGetOrderBook(t *testing.T, kuCoinOrderBookRecorder *KuCoinOrderBookRecorder) {
@@ -80,3 +103,96 @@ func testOrderBookRegisterAssetPair(t *testing.T, kuCoinOrderBookRecorder *KuCoi
 	}
 	fmt.Printf("%v: %v\n", translatedPair, orderBook)
 }
+
+func TestKuCoinTradeRecorder(t *testing.T) {
+	kuCoinTradeRecorder := NewKuCoinTradeRecorder(&http.Client{}, grizzlytesting.KuCoinAssetPairs, grizzlytesting.KuCoinAssetPairTranslator, 100)
+	time.Sleep(grizzlytesting.SleepDuration)
+	t.Run("GetTradesSince", func(t *testing.T) {
+		testGetTradesSince(t, kuCoinTradeRecorder)
+	})
+	t.Run("RegisterAssetPair", func(t *testing.T) {
+		testTradeRegisterAssetPair(t, kuCoinTradeRecorder)
+	})
+	t.Run("UnregisterAssetPair", func(t *testing.T) {
+		testUnregisterAssetPair(t, kuCoinTradeRecorder, &kuCoinTradeRecorder.kuCoinWebSocketRecorder, "/market/match:" + grizzlytesting.KuCoinAssetPairTranslator[grizzlytesting.BTCUSDC], func() bool {
+			_, ok := kuCoinTradeRecorder.GetTradesSince(grizzlytesting.BTCUSDC, time.Time{})
+			return ok
+		})
+	})
+	t.Run("Close", func(t *testing.T) {
+		testClose(t, kuCoinTradeRecorder, &kuCoinTradeRecorder.kuCoinWebSocketRecorder)
+	})
+}
+
+func testGetTradesSince(t *testing.T, kuCoinTradeRecorder *KuCoinTradeRecorder) {
+	for _, assetPair := range grizzlytesting.KuCoinAssetPairs {
+		translatedPair := grizzlytesting.KuCoinAssetPairTranslator[assetPair]
+		trades, ok := kuCoinTradeRecorder.GetTradesSince(assetPair, time.Time{})
+		if !ok {
+			t.Fatalf("AssetPair %v should be recorded\n", translatedPair)
+		}
+		fmt.Printf("%v: %v\n", translatedPair, trades)
+	}
+}
+
+func testTradeRegisterAssetPair(t *testing.T, kuCoinTradeRecorder *KuCoinTradeRecorder) {
+	translatedPair := grizzlytesting.KuCoinAssetPairTranslator[grizzlytesting.BTCUSDC]
+	kuCoinTradeRecorder.RegisterAssetPair(grizzlytesting.BTCUSDC)
+	time.Sleep(grizzlytesting.SleepDuration)
+	trades, ok := kuCoinTradeRecorder.GetTradesSince(grizzlytesting.BTCUSDC, time.Time{})
+	if !ok {
+		t.Fatalf("AssetPair %v should be recorded\n", translatedPair)
+	}
+	fmt.Printf("%v: %v\n", translatedPair, trades)
+}
+
+// unregisters BTCUSDC, which the RegisterAssetPair subtest subscribed to as key
+func testUnregisterAssetPair(t *testing.T, recorder types.AssetPairRecorder, webSocketRecorder *kuCoinWebSocketRecorder, key string, recorded func() bool) {
+	translatedPair := grizzlytesting.KuCoinAssetPairTranslator[grizzlytesting.BTCUSDC]
+	channel, ok := webSocketRecorder.channels.Load(key)
+	if !ok {
+		t.Fatalf("AssetPair %v should be subscribed\n", translatedPair)
+	}
+	recorder.UnregisterAssetPair(grizzlytesting.BTCUSDC)
+	if recorded() {
+		t.Fatalf("AssetPair %v should no longer be recorded\n", translatedPair)
+	}
+	if _, ok := webSocketRecorder.cancels.Load(key); ok {
+		t.Fatalf("AssetPair %v should no longer be processed\n", translatedPair)
+	}
+	expectStopped(t, channel.(chan map[string]interface{}))
+	// a second unregister is a no-op
+	recorder.UnregisterAssetPair(grizzlytesting.BTCUSDC)
+}
+
+func testClose(t *testing.T, recorder types.AssetPairRecorder, webSocketRecorder *kuCoinWebSocketRecorder) {
+	channels := make([]chan map[string]interface{}, 0)
+	webSocketRecorder.channels.Range(func(key, value interface{}) bool {
+		channels = append(channels, value.(chan map[string]interface{}))
+		return true
+	})
+	recorder.Close()
+	for _, channel := range channels {
+		expectStopped(t, channel)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		recorder.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("a second Close should return at once\n")
+	}
+}
+
+// fails if anything still receives from channel, the goroutine processing it should have exited
+func expectStopped(t *testing.T, channel chan map[string]interface{}) {
+	select {
+	case channel <- nil:
+		t.Fatalf("goroutine processing the asset pair should have exited\n")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
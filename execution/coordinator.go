@@ -0,0 +1,156 @@
+package execution
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    "github.com/denali-capital/grizzly/control"
+    "github.com/denali-capital/grizzly/conversion"
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+var logger *logging.Logger = logging.New("execution")
+
+// single place where strategies (pairwise or multi-hop routing) hand off opportunities for execution
+type Coordinator struct {
+    exchanges     map[string]types.Exchange
+    opportunities chan types.Opportunity
+    // paused exchange pairs and the kill switch
+    state         *control.State
+    // prices orders and balances in the numeraire the limits are in
+    converter     *conversion.Converter
+    limits        Limits
+    // opportunities older than this when dequeued are dropped
+    maxAge        time.Duration
+    mutex         sync.Mutex
+    // opportunities with orders not yet seen finished, closed out on shutdown
+    pending       []*placement
+    // fills of finished opportunities whose legs did not all fill, flattened on shutdown
+    unhedged      []types.Leg
+    executed      int
+    // UTC day the opening balances were taken, and the balances the day's loss is measured from
+    day           string
+    opening       map[string]map[types.Asset]decimal.Decimal
+}
+
+func NewCoordinator(exchanges []types.Exchange, state *control.State, converter *conversion.Converter, limits Limits, capacity uint, maxAge time.Duration) *Coordinator {
+    exchangeMap := make(map[string]types.Exchange, len(exchanges))
+    for _, exchange := range exchanges {
+        exchangeMap[exchange.String()] = exchange
+    }
+    return &Coordinator{
+        exchanges: exchangeMap,
+        opportunities: make(chan types.Opportunity, capacity),
+        state: state,
+        converter: converter,
+        limits: limits,
+        maxAge: maxAge,
+    }
+}
+
+// non-blocking; returns false if the queue is full and the opportunity was dropped
+func (c *Coordinator) Submit(opportunity types.Opportunity) bool {
+    select {
+    case c.opportunities <- opportunity:
+        return true
+    default:
+        return false
+    }
+}
+
+// returns once ctx is canceled, after the opportunity being executed if any has been placed;
+// the day's loss is checked every LossInterval and open orders are polled every SettleInterval
+func (c *Coordinator) Run(ctx context.Context) {
+    c.CheckLoss(time.Now())
+    var losses, settles <-chan time.Time
+    if c.limits.LossInterval > 0 {
+        ticker := time.NewTicker(c.limits.LossInterval)
+        defer ticker.Stop()
+        losses = ticker.C
+    }
+    if c.limits.SettleInterval > 0 {
+        ticker := time.NewTicker(c.limits.SettleInterval)
+        defer ticker.Stop()
+        settles = ticker.C
+    }
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case now := <-losses:
+            c.CheckLoss(now)
+        case <-settles:
+            c.settle()
+        case opportunity := <-c.opportunities:
+            if time.Since(opportunity.Timestamp) > c.maxAge {
+                continue
+            }
+            c.Place(opportunity)
+        }
+    }
+}
+
+// executes opportunity within the limits unless the state refuses it, false if nothing was placed
+func (c *Coordinator) Place(opportunity types.Opportunity) bool {
+    if !c.state.Allows(opportunity) {
+        return false
+    }
+    opportunity, ok := c.limit(opportunity)
+    if !ok {
+        return false
+    }
+    return c.Execute(opportunity) != nil
+}
+
+// number of opportunities placed so far
+func (c *Coordinator) Executed() int {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    return c.executed
+}
+
+func (c *Coordinator) executeOnExchange(exchange types.Exchange, orders []types.Order, channel chan types.ExecutionResponse) {
+    channel <- types.ExecutionResponse{
+        Exchange: exchange.String(),
+        OrderIds: exchange.ExecuteOrders(orders),
+    }
+}
+
+// places every leg, concurrently across exchanges; nothing is placed and nil is returned if
+// any leg is on an exchange the coordinator was not given
+func (c *Coordinator) Execute(opportunity types.Opportunity) map[string]map[types.Order]types.OrderId {
+    ordersByExchange := make(map[string][]types.Order)
+    for _, leg := range opportunity.Legs {
+        if _, ok := c.exchanges[leg.Exchange]; !ok {
+            logger.Error("exchange is not registered with the coordinator, opportunity dropped", logging.Exchange(leg.Exchange), logging.AssetPair(leg.Order.AssetPair))
+            return nil
+        }
+        ordersByExchange[leg.Exchange] = append(ordersByExchange[leg.Exchange], leg.Order)
+    }
+
+    channel := make(chan types.ExecutionResponse)
+    for exchangeName, orders := range ordersByExchange {
+        go c.executeOnExchange(c.exchanges[exchangeName], orders, channel)
+    }
+
+    orderIds := make(map[string]map[types.Order]types.OrderId)
+    for i := 0; i < len(ordersByExchange); i++ {
+        response := <- channel
+        orderIds[response.Exchange] = response.OrderIds
+    }
+    c.track(orderIds)
+    expectedProfit, _ := opportunity.ExpectedProfit.Float64()
+    metrics.ExpectedPnl.Add(expectedProfit, string(opportunity.Asset))
+    return orderIds
+}
+
+func (c *Coordinator) track(orderIds map[string]map[types.Order]types.OrderId) {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    c.executed++
+    c.pending = append(c.pending, newPlacement(orderIds))
+}
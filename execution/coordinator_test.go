@@ -0,0 +1,155 @@
+package execution
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/control"
+	"github.com/denali-capital/grizzly/conversion"
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+func opportunity(quantity int64) types.Opportunity {
+	return types.Opportunity{
+		Legs: []types.Leg{
+			{Exchange: "Fake", Order: order(types.Buy, quantity)},
+			{Exchange: "Fake", Order: order(types.Sell, quantity)},
+		},
+		Asset: "USD",
+		Size: decimal.NewFromInt(100 * quantity),
+		ExpectedProfit: decimal.NewFromInt(quantity),
+		Timestamp: time.Now(),
+	}
+}
+
+func TestLimits(t *testing.T) {
+	exchange := newFakeExchange()
+	coordinator := NewCoordinator([]types.Exchange{exchange}, control.NewState(0.5), conversion.NewConverter("USD"), Limits{
+		MaxOrderNotional: decimal.NewFromInt(1000),
+		MaxOpenOrders: 3,
+	}, 1, time.Second)
+
+	// 20 BTC at 100 is twice the notional limit
+	if !coordinator.Place(opportunity(20)) {
+		t.Fatalf("expected the opportunity to be shrunk and placed\n")
+	}
+	for _, placed := range exchange.orders {
+		if !placed.Quantity.Equal(decimal.NewFromInt(10)) {
+			t.Fatalf("expected orders shrunk to 10, got %v\n", placed)
+		}
+	}
+
+	// both orders are still open, two more would be 4
+	if coordinator.Place(opportunity(1)) || len(exchange.orders) != 2 {
+		t.Fatalf("expected the open order limit to refuse the opportunity, got %v orders\n", len(exchange.orders))
+	}
+	for orderId, orderStatus := range exchange.statuses {
+		orderStatus.Status = types.Filled
+		exchange.statuses[orderId] = orderStatus
+	}
+	if !coordinator.Place(opportunity(1)) || len(exchange.orders) != 4 {
+		t.Fatalf("expected filled orders to make room, got %v orders\n", len(exchange.orders))
+	}
+
+	// nothing to price ETH/BTC in USD with
+	unpriced := opportunity(1)
+	for i := range unpriced.Legs {
+		unpriced.Legs[i].Order.AssetPair = types.NewAssetPair("ETH", "BTC")
+	}
+	if coordinator.Place(unpriced) {
+		t.Fatalf("expected an opportunity that cannot be priced to be refused\n")
+	}
+	// one leg priced is not enough, the other's order would not be bounded
+	partlyPriced := opportunity(1)
+	partlyPriced.Legs[1].Order.AssetPair = types.NewAssetPair("ETH", "BTC")
+	if coordinator.Place(partlyPriced) || len(exchange.orders) != 4 {
+		t.Fatalf("expected an opportunity with a leg that cannot be priced to be refused, got %v orders\n", len(exchange.orders))
+	}
+}
+
+func TestCheckLoss(t *testing.T) {
+	exchange := newFakeExchange()
+	exchange.setBalance("USD", 1000)
+	exchange.setBalance("BTC", 1)
+	state := control.NewState(0.5)
+	coordinator := NewCoordinator([]types.Exchange{exchange}, state, conversion.NewConverter("USD"), Limits{MaxDailyLoss: decimal.NewFromInt(100)}, 1, time.Second)
+	day := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	coordinator.CheckLoss(day)
+	exchange.setBalance("USD", 950)
+	// inventory is not realized
+	exchange.setBalance("BTC", 0)
+	coordinator.CheckLoss(day.Add(time.Hour))
+	if state.Killed() {
+		t.Fatalf("expected a loss of 50 to be within the limit\n")
+	}
+
+	// the next day's loss is measured from 950
+	coordinator.CheckLoss(day.Add(24 * time.Hour))
+	exchange.setBalance("USD", 880)
+	coordinator.CheckLoss(day.Add(25 * time.Hour))
+	if state.Killed() {
+		t.Fatalf("expected a loss of 70 on the second day to be within the limit\n")
+	}
+	exchange.setBalance("USD", 850)
+	coordinator.CheckLoss(day.Add(26 * time.Hour))
+	if !state.Killed() {
+		t.Fatalf("expected a loss of 100 to kill trading\n")
+	}
+	if coordinator.Place(opportunity(1)) {
+		t.Fatalf("expected nothing to be placed once killed\n")
+	}
+}
+
+func TestRun(t *testing.T) {
+	exchange := newFakeExchange()
+	exchange.setBalance("USD", 1000)
+	state := control.NewState(0.5)
+	coordinator := NewCoordinator([]types.Exchange{exchange}, state, conversion.NewConverter("USD"), Limits{
+		MaxOrderNotional: decimal.NewFromInt(1000),
+		MaxOpenOrders: 2,
+		MaxDailyLoss: decimal.NewFromInt(100),
+		LossInterval: time.Millisecond,
+	}, 2, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		coordinator.Run(ctx)
+		close(done)
+	}()
+
+	// the second opportunity would exceed the open order limit
+	coordinator.Submit(opportunity(1))
+	coordinator.Submit(opportunity(1))
+	deadline := time.Now().Add(5 * time.Second)
+	for coordinator.Executed() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	exchange.setBalance("USD", 900)
+	for !state.Killed() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !state.Killed() {
+		t.Fatalf("expected the daily loss to kill trading\n")
+	}
+	cancel()
+	<-done
+	if coordinator.Executed() != 1 || len(exchange.orders) != 2 {
+		t.Fatalf("expected one opportunity placed, got %v with %v orders\n", coordinator.Executed(), len(exchange.orders))
+	}
+}
+
+func TestUnknownExchange(t *testing.T) {
+	exchange := newFakeExchange()
+	coordinator := NewCoordinator([]types.Exchange{exchange}, control.NewState(0.5), nil, Limits{}, 1, time.Second)
+	unknown := opportunity(1)
+	unknown.Legs[1].Exchange = "Unknown"
+	if orderIds := coordinator.Execute(unknown); orderIds != nil || len(exchange.orders) != 0 || coordinator.Executed() != 0 {
+		t.Fatalf("expected the opportunity to be dropped without placing its known leg, got %v\n", orderIds)
+	}
+	if coordinator.Place(unknown) {
+		t.Fatalf("expected nothing to be placed\n")
+	}
+}
@@ -0,0 +1,130 @@
+package execution
+
+import (
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+// one executed opportunity's orders by exchange, with the last status seen of each
+type placement struct {
+    orders   map[string]map[types.OrderId]types.Order
+    statuses map[string]map[types.OrderId]types.OrderStatus
+}
+
+func newPlacement(orderIds map[string]map[types.Order]types.OrderId) *placement {
+    p := &placement{
+        orders: make(map[string]map[types.OrderId]types.Order, len(orderIds)),
+        statuses: make(map[string]map[types.OrderId]types.OrderStatus, len(orderIds)),
+    }
+    for exchange, ids := range orderIds {
+        p.orders[exchange] = make(map[types.OrderId]types.Order, len(ids))
+        p.statuses[exchange] = make(map[types.OrderId]types.OrderStatus, len(ids))
+        for order, orderId := range ids {
+            p.orders[exchange][orderId] = order
+        }
+    }
+    return p
+}
+
+// orders not yet seen finished, by exchange
+func (p *placement) open() map[string][]types.OrderId {
+    open := make(map[string][]types.OrderId)
+    for exchange, orders := range p.orders {
+        for orderId := range orders {
+            if orderStatus, ok := p.statuses[exchange][orderId]; !ok || isOpen(orderStatus.Status) {
+                open[exchange] = append(open[exchange], orderId)
+            }
+        }
+    }
+    return open
+}
+
+// records the statuses of this placement's orders, others are ignored
+func (p *placement) update(orderStatuses map[string]map[types.OrderId]types.OrderStatus) {
+    for exchange, orders := range p.orders {
+        for orderId := range orders {
+            if orderStatus, ok := orderStatuses[exchange][orderId]; ok {
+                p.statuses[exchange][orderId] = orderStatus
+            }
+        }
+    }
+}
+
+// what filled of each order, nil if every order filled completely or none filled at all, since
+// the opportunity then left no exposure
+func (p *placement) unhedged() []types.Leg {
+    fills := make([]types.Leg, 0)
+    complete := true
+    for exchange, orders := range p.orders {
+        for orderId, order := range orders {
+            orderStatus := p.statuses[exchange][orderId]
+            filled := decimal.Zero
+            if orderStatus.FilledQuantity != nil {
+                filled = *orderStatus.FilledQuantity
+            } else if orderStatus.Status == types.Filled {
+                filled = order.Quantity
+            }
+            if filled.LessThan(order.Quantity) {
+                complete = false
+            }
+            if filled.IsPositive() {
+                order.Quantity = filled
+                fills = append(fills, types.Leg{Exchange: exchange, Order: order})
+            }
+        }
+    }
+    if complete || len(fills) == 0 {
+        return nil
+    }
+    return fills
+}
+
+// orders of opportunities still open, by exchange
+func openOrderIds(pending []*placement) map[string][]types.OrderId {
+    open := make(map[string][]types.OrderId)
+    for _, p := range pending {
+        for exchange, orderIds := range p.open() {
+            open[exchange] = append(open[exchange], orderIds...)
+        }
+    }
+    return open
+}
+
+func (c *Coordinator) countOpen() int {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    count := 0
+    for _, orderIds := range openOrderIds(c.pending) {
+        count += len(orderIds)
+    }
+    return count
+}
+
+// polls the orders of opportunities still open and forgets the opportunities that finished,
+// keeping the fills of those that left exposure for shutdown
+func (c *Coordinator) settle() {
+    c.mutex.Lock()
+    open := openOrderIds(c.pending)
+    c.mutex.Unlock()
+    if len(open) == 0 {
+        return
+    }
+
+    orderStatuses := make(map[string]map[types.OrderId]types.OrderStatus, len(open))
+    for exchange, orderIds := range open {
+        orderStatuses[exchange] = c.exchanges[exchange].GetOrderStatuses(orderIds)
+    }
+
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    pending := make([]*placement, 0, len(c.pending))
+    for _, p := range c.pending {
+        p.update(orderStatuses)
+        if len(p.open()) > 0 {
+            pending = append(pending, p)
+            continue
+        }
+        c.unhedged = append(c.unhedged, p.unhedged()...)
+    }
+    c.pending = pending
+}
@@ -0,0 +1,125 @@
+package execution
+
+import (
+    "time"
+
+    "github.com/denali-capital/grizzly/conversion"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+// what the coordinator refuses to place, notional and loss are in the converter's numeraire;
+// zero disables a limit
+type Limits struct {
+    // orders larger than this shrink the whole opportunity
+    MaxOrderNotional decimal.Decimal
+    MaxOpenOrders    uint
+    // realized since the start of the UTC day, trips the kill switch
+    MaxDailyLoss     decimal.Decimal
+    // how often Run checks the day's loss
+    LossInterval     time.Duration
+    // how often Run polls open orders, so that finished opportunities are forgotten
+    SettleInterval   time.Duration
+}
+
+// the largest leg in the numeraire; false if the converter cannot price any leg, whose
+// order MaxOrderNotional could then not bound
+func orderNotional(converter *conversion.Converter, legs []types.Leg) (decimal.Decimal, bool) {
+    notional := decimal.Zero
+    for _, leg := range legs {
+        value := leg.Order.Price.Mul(leg.Order.Quantity)
+        rate, known := converter.Rate(leg.Order.AssetPair.Quote, converter.Numeraire())
+        if !known {
+            value = leg.Order.Quantity
+            rate, known = converter.Rate(leg.Order.AssetPair.Base, converter.Numeraire())
+        }
+        if !known || rate.IsZero() {
+            return decimal.Zero, false
+        }
+        notional = decimal.Max(notional, value.Mul(rate))
+    }
+    return notional, true
+}
+
+// every leg and the expected profit scaled by fraction
+func shrink(opportunity types.Opportunity, fraction decimal.Decimal) types.Opportunity {
+    legs := make([]types.Leg, len(opportunity.Legs))
+    for i, leg := range opportunity.Legs {
+        legs[i] = leg
+        legs[i].Order.Quantity = leg.Order.Quantity.Mul(fraction)
+    }
+    opportunity.Legs = legs
+    opportunity.Size = opportunity.Size.Mul(fraction)
+    opportunity.ExpectedProfit = opportunity.ExpectedProfit.Mul(fraction)
+    return opportunity
+}
+
+// opportunity shrunk to MaxOrderNotional, false if any leg cannot be priced or it would open more than MaxOpenOrders
+func (c *Coordinator) limit(opportunity types.Opportunity) (types.Opportunity, bool) {
+    if c.limits.MaxOrderNotional.IsPositive() {
+        notional, ok := orderNotional(c.converter, opportunity.Legs)
+        if !ok {
+            logger.Warn("cannot price opportunity in the numeraire, refused", "asset", opportunity.Asset, "legs", len(opportunity.Legs))
+            return opportunity, false
+        }
+        if notional.GreaterThan(c.limits.MaxOrderNotional) {
+            logger.Debug("shrinking opportunity to the order notional limit", "notional", notional, "limit", c.limits.MaxOrderNotional)
+            opportunity = shrink(opportunity, c.limits.MaxOrderNotional.Div(notional))
+        }
+    }
+    if c.limits.MaxOpenOrders > 0 && c.openOrders(len(opportunity.Legs)) + len(opportunity.Legs) > int(c.limits.MaxOpenOrders) {
+        logger.Warn("open order limit reached, opportunity refused", "limit", c.limits.MaxOpenOrders, "legs", len(opportunity.Legs))
+        return opportunity, false
+    }
+    return opportunity, true
+}
+
+// orders placed by the coordinator still open, statuses are only polled when placing more
+// orders would exceed the limit, since orders are never reopened
+func (c *Coordinator) openOrders(more int) int {
+    if count := c.countOpen(); count + more <= int(c.limits.MaxOpenOrders) {
+        return count
+    }
+    c.settle()
+    return c.countOpen()
+}
+
+// balances of the assets the converter prices, the rest are inventory whose changes are unrealized
+func (c *Coordinator) convertibleBalances() map[string]map[types.Asset]decimal.Decimal {
+    balances := make(map[string]map[types.Asset]decimal.Decimal, len(c.exchanges))
+    for name, exchange := range c.exchanges {
+        all := exchange.GetBalances()
+        balances[name] = make(map[types.Asset]decimal.Decimal)
+        for _, asset := range c.converter.Assets() {
+            balances[name][asset] = all[asset]
+        }
+    }
+    return balances
+}
+
+// trips the kill switch once the loss realized since the start of now's UTC day reaches
+// MaxDailyLoss, balances are valued at current rates
+func (c *Coordinator) CheckLoss(now time.Time) {
+    if !c.limits.MaxDailyLoss.IsPositive() {
+        return
+    }
+    balances := c.convertibleBalances()
+    day := now.UTC().Format("2006-01-02")
+
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    if day != c.day {
+        c.day, c.opening = day, balances
+        return
+    }
+    loss := decimal.Zero
+    for exchange, assets := range balances {
+        for asset, balance := range assets {
+            rate, _ := c.converter.Rate(asset, c.converter.Numeraire())
+            loss = loss.Add(c.opening[exchange][asset].Sub(balance).Mul(rate))
+        }
+    }
+    if loss.GreaterThanOrEqual(c.limits.MaxDailyLoss) && c.state.Kill() {
+        logger.Error("daily loss limit reached, trading killed", "loss", loss, "limit", c.limits.MaxDailyLoss, "numeraire", c.converter.Numeraire())
+    }
+}
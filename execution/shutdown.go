@@ -0,0 +1,264 @@
+package execution
+
+import (
+    "context"
+    "fmt"
+    "sort"
+
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+// what happens on shutdown to orders the coordinator placed
+type ClosePolicy string
+
+const (
+    // leave open orders and filled positions as they are
+    Keep ClosePolicy = "keep"
+    // cancel orders that are still open
+    Cancel ClosePolicy = "cancel"
+    // cancel, then reverse what opportunities left unhedged at the current bid or ask
+    Flatten ClosePolicy = "flatten"
+)
+
+func ParseClosePolicy(s string) (ClosePolicy, error) {
+    switch policy := ClosePolicy(s); policy {
+    case Keep, Cancel, Flatten:
+        return policy, nil
+    }
+    return "", fmt.Errorf("unknown close policy %q, expected keep, cancel or flatten", s)
+}
+
+// what CloseOut did on one exchange
+type CloseOutReport struct {
+    Canceled  []types.OrderId
+    // reverse orders placed to flatten filled positions
+    Flattened map[types.Order]types.OrderId
+}
+
+func isOpen(status types.StatusType) bool {
+    return status == types.Pending || status == types.Unfilled || status == types.PartiallyFilled
+}
+
+// signed filled quantity per asset across every exchange, positive when more was received than
+// given; a buy receives its base asset and gives price times quantity of its quote, a sell the reverse
+func Exposures(fills []types.Leg) map[types.Asset]decimal.Decimal {
+    exposures := make(map[types.Asset]decimal.Decimal)
+    for _, fill := range fills {
+        quantity, notional := fill.Order.Quantity, fill.Order.Price.Mul(fill.Order.Quantity)
+        if fill.Order.OrderType == types.Sell {
+            quantity, notional = quantity.Neg(), notional.Neg()
+        }
+        base, quote := fill.Order.AssetPair.Base, fill.Order.AssetPair.Quote
+        exposures[base] = exposures[base].Add(quantity)
+        exposures[quote] = exposures[quote].Sub(notional)
+    }
+    for asset, exposure := range exposures {
+        if exposure.IsZero() {
+            delete(exposures, asset)
+        }
+    }
+    return exposures
+}
+
+type venue struct {
+    exchange  string
+    assetPair types.AssetPair
+    orderType types.OrderType
+}
+
+// unpriced orders reversing exposures through the fills that built them up, largest first, on
+// the exchange and asset pair they filled on: positive exposures sell what was bought and negative
+// ones buy back what was sold; assets only ever paid with, the quote assets, are left as they are
+// and the rest of what no fill can reverse is returned as left over
+func FlattenLegs(fills []types.Leg, exposures map[types.Asset]decimal.Decimal) ([]types.Leg, map[types.Asset]decimal.Decimal) {
+    traded := make(map[types.Asset]bool)
+    for _, fill := range fills {
+        traded[fill.Order.AssetPair.Base] = true
+    }
+    sorted := append([]types.Leg{}, fills...)
+    sort.SliceStable(sorted, func(i, j int) bool {
+        return sorted[i].Order.Quantity.GreaterThan(sorted[j].Order.Quantity)
+    })
+
+    quantities := make(map[venue]decimal.Decimal)
+    venues := make([]venue, 0)
+    leftOver := make(map[types.Asset]decimal.Decimal)
+    for asset, exposure := range exposures {
+        if !traded[asset] {
+            continue
+        }
+        orderType := types.Buy
+        if exposure.IsNegative() {
+            orderType = types.Sell
+        }
+        remaining := exposure.Abs()
+        for _, fill := range sorted {
+            if !remaining.IsPositive() {
+                break
+            }
+            if fill.Order.AssetPair.Base != asset || fill.Order.OrderType != orderType {
+                continue
+            }
+            key := venue{fill.Exchange, fill.Order.AssetPair, orderType}
+            if _, ok := quantities[key]; !ok {
+                venues = append(venues, key)
+            }
+            quantity := decimal.Min(remaining, fill.Order.Quantity)
+            quantities[key] = quantities[key].Add(quantity)
+            remaining = remaining.Sub(quantity)
+        }
+        if remaining.IsPositive() {
+            leftOver[asset] = remaining
+        }
+    }
+
+    legs := make([]types.Leg, len(venues))
+    for i, key := range venues {
+        reverse := types.Sell
+        if key.orderType == types.Sell {
+            reverse = types.Buy
+        }
+        legs[i] = types.Leg{Exchange: key.exchange, Order: types.Order{OrderType: reverse, AssetPair: key.assetPair, Quantity: quantities[key]}}
+    }
+    return legs, leftOver
+}
+
+// orders priced to cross the book, selling at the bid and buying at the ask; orders on asset
+// pairs without a quote are skipped
+func FlattenOrders(orders []types.Order, spreads map[types.AssetPair]types.Spread) []types.Order {
+    priced := make([]types.Order, 0, len(orders))
+    for _, order := range orders {
+        spread := spreads[order.AssetPair]
+        if order.OrderType == types.Sell && spread.Bid.IsPositive() {
+            order.Price = spread.Bid
+        } else if order.OrderType == types.Buy && spread.Ask.IsPositive() {
+            order.Price = spread.Ask
+        } else {
+            continue
+        }
+        priced = append(priced, order)
+    }
+    return priced
+}
+
+// what canceling left on one exchange
+type cancellation struct {
+    canceled      []types.OrderId
+    orderStatuses map[types.OrderId]types.OrderStatus
+}
+
+// cancels the orders still open, the statuses are those seen after the cancel
+func cancelOpen(exchange types.Exchange, orderIds []types.OrderId) cancellation {
+    result := cancellation{orderStatuses: exchange.GetOrderStatuses(orderIds)}
+    for orderId, orderStatus := range result.orderStatuses {
+        if isOpen(orderStatus.Status) {
+            result.canceled = append(result.canceled, orderId)
+        }
+    }
+    if len(result.canceled) > 0 {
+        exchange.CancelOrders(result.canceled)
+        // partial fills may have advanced before the cancel landed
+        result.orderStatuses = exchange.GetOrderStatuses(orderIds)
+    }
+    return result
+}
+
+// places orders reversing fills at the current bid or ask
+func flattenExchange(exchange types.Exchange, orders []types.Order) map[types.Order]types.OrderId {
+    spreads := make(map[types.AssetPair]types.Spread, len(orders))
+    for _, order := range orders {
+        spreads[order.AssetPair] = exchange.GetCurrentSpread(order.AssetPair)
+    }
+    priced := FlattenOrders(orders, spreads)
+    if len(priced) < len(orders) {
+        logger.Warn("no quote to flatten at, positions left open", logging.Exchange(exchange.String()), "orders", orders)
+    }
+    if len(priced) == 0 {
+        return nil
+    }
+    return exchange.ExecuteOrders(priced)
+}
+
+// runs do for every exchange in work concurrently; exchanges not done when ctx is canceled are
+// missing from the results
+func eachExchange[W, R any](ctx context.Context, work map[string]W, do func(exchange string, w W) R) map[string]R {
+    type response struct {
+        exchange string
+        result   R
+    }
+    // buffered so that exchanges finishing after ctx is canceled do not block forever
+    channel := make(chan response, len(work))
+    for exchange, w := range work {
+        go func(exchange string, w W) {
+            channel <- response{exchange, do(exchange, w)}
+        }(exchange, w)
+    }
+
+    results := make(map[string]R, len(work))
+    for len(results) < len(work) {
+        select {
+        case <-ctx.Done():
+            for exchange := range work {
+                if _, ok := results[exchange]; !ok {
+                    logger.Error("close out did not finish", logging.Exchange(exchange), logging.Err(ctx.Err()))
+                }
+            }
+            return results
+        case response := <-channel:
+            results[response.exchange] = response.result
+        }
+    }
+    return results
+}
+
+// applies policy to the orders of opportunities still open, concurrently across exchanges; with
+// Flatten only the exposure opportunities left by filling some legs and not others is reversed,
+// netted across exchanges, so that completed arbitrages stay as they are; exchanges not done when
+// ctx is canceled are missing from the reports
+func (c *Coordinator) CloseOut(ctx context.Context, policy ClosePolicy) map[string]CloseOutReport {
+    reports := make(map[string]CloseOutReport)
+    if policy == Keep {
+        return reports
+    }
+
+    c.mutex.Lock()
+    pending, fills := c.pending, c.unhedged
+    c.pending, c.unhedged = nil, nil
+    c.mutex.Unlock()
+
+    cancellations := eachExchange(ctx, openOrderIds(pending), func(exchange string, orderIds []types.OrderId) cancellation {
+        return cancelOpen(c.exchanges[exchange], orderIds)
+    })
+    orderStatuses := make(map[string]map[types.OrderId]types.OrderStatus, len(cancellations))
+    for exchange, result := range cancellations {
+        reports[exchange] = CloseOutReport{Canceled: result.canceled}
+        orderStatuses[exchange] = result.orderStatuses
+    }
+    if policy != Flatten {
+        return reports
+    }
+
+    for _, p := range pending {
+        p.update(orderStatuses)
+        fills = append(fills, p.unhedged()...)
+    }
+    legs, leftOver := FlattenLegs(fills, Exposures(fills))
+    if len(leftOver) > 0 {
+        logger.Warn("exposure no fill can reverse, left open", "exposures", leftOver)
+    }
+    orders := make(map[string][]types.Order)
+    for _, leg := range legs {
+        orders[leg.Exchange] = append(orders[leg.Exchange], leg.Order)
+    }
+    flattened := eachExchange(ctx, orders, func(exchange string, orders []types.Order) map[types.Order]types.OrderId {
+        return flattenExchange(c.exchanges[exchange], orders)
+    })
+    for exchange, orderIds := range flattened {
+        report := reports[exchange]
+        report.Flattened = orderIds
+        reports[exchange] = report
+    }
+    return reports
+}
@@ -0,0 +1,202 @@
+package execution
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/control"
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+var BTCUSD types.AssetPair = types.NewAssetPair("BTC", "USD")
+
+// orders fill half their quantity and stay open until canceled
+type fakeExchange struct {
+	types.Exchange
+	name     string
+	orders   map[types.OrderId]types.Order
+	statuses map[types.OrderId]types.OrderStatus
+	canceled []types.OrderId
+	mutex    sync.Mutex
+	balances map[types.Asset]decimal.Decimal
+}
+
+func newFakeExchange() *fakeExchange {
+	return &fakeExchange{
+		name:     "Fake",
+		orders:   make(map[types.OrderId]types.Order),
+		statuses: make(map[types.OrderId]types.OrderStatus),
+		balances: make(map[types.Asset]decimal.Decimal),
+	}
+}
+
+func (f *fakeExchange) String() string {
+	return f.name
+}
+
+func (f *fakeExchange) ExecuteOrders(orders []types.Order) map[types.Order]types.OrderId {
+	orderIds := make(map[types.Order]types.OrderId)
+	for _, order := range orders {
+		orderId := types.OrderId(decimal.NewFromInt(int64(len(f.orders) + 1)).String())
+		filled := order.Quantity.Div(decimal.NewFromInt(2))
+		f.orders[orderId] = order
+		f.statuses[orderId] = types.OrderStatus{Status: types.PartiallyFilled, FilledQuantity: &filled}
+		orderIds[order] = orderId
+	}
+	return orderIds
+}
+
+func (f *fakeExchange) GetOrderStatuses(orderIds []types.OrderId) map[types.OrderId]types.OrderStatus {
+	orderStatuses := make(map[types.OrderId]types.OrderStatus)
+	for _, orderId := range orderIds {
+		orderStatuses[orderId] = f.statuses[orderId]
+	}
+	return orderStatuses
+}
+
+func (f *fakeExchange) CancelOrders(orderIds []types.OrderId) {
+	f.canceled = append(f.canceled, orderIds...)
+	for _, orderId := range orderIds {
+		orderStatus := f.statuses[orderId]
+		orderStatus.Status = types.Canceled
+		f.statuses[orderId] = orderStatus
+	}
+}
+
+// every order fills completely
+func (f *fakeExchange) fillAll() {
+	for orderId, order := range f.orders {
+		quantity := order.Quantity
+		f.statuses[orderId] = types.OrderStatus{Status: types.Filled, FilledQuantity: &quantity}
+	}
+}
+
+func (f *fakeExchange) GetBalances() map[types.Asset]decimal.Decimal {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	balances := make(map[types.Asset]decimal.Decimal)
+	for asset, balance := range f.balances {
+		balances[asset] = balance
+	}
+	return balances
+}
+
+func (f *fakeExchange) setBalance(asset types.Asset, balance int64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.balances[asset] = decimal.NewFromInt(balance)
+}
+
+func (f *fakeExchange) GetCurrentSpread(assetPair types.AssetPair) types.Spread {
+	return types.Spread{Bid: decimal.NewFromInt(99), Ask: decimal.NewFromInt(101)}
+}
+
+func order(orderType types.OrderType, quantity int64) types.Order {
+	return types.Order{OrderType: orderType, AssetPair: BTCUSD, Price: decimal.NewFromInt(100), Quantity: decimal.NewFromInt(quantity)}
+}
+
+func TestParseClosePolicy(t *testing.T) {
+	if policy, err := ParseClosePolicy("flatten"); err != nil || policy != Flatten {
+		t.Fatalf("expected flatten, got %v %v\n", policy, err)
+	}
+	if _, err := ParseClosePolicy("liquidate"); err == nil {
+		t.Fatalf("expected an unknown policy to be rejected\n")
+	}
+}
+
+func TestExposures(t *testing.T) {
+	fill := func(exchange string, orderType types.OrderType, quantity int64) types.Leg {
+		return types.Leg{Exchange: exchange, Order: order(orderType, quantity)}
+	}
+	fills := []types.Leg{fill("A", types.Buy, 3), fill("B", types.Buy, 1), fill("B", types.Sell, 2)}
+	exposures := Exposures(fills)
+	if !exposures["BTC"].Equal(decimal.NewFromInt(2)) || !exposures["USD"].Equal(decimal.NewFromInt(-200)) {
+		t.Fatalf("expected 2 BTC bought for 200 USD, got %v\n", exposures)
+	}
+
+	// the largest buy is sold back first, the USD paid is left as it is
+	legs, leftOver := FlattenLegs(fills, exposures)
+	if len(legs) != 1 || legs[0].Exchange != "A" || legs[0].Order.OrderType != types.Sell || !legs[0].Order.Quantity.Equal(decimal.NewFromInt(2)) || len(leftOver) != 0 {
+		t.Fatalf("expected to sell 2 on A, got %v and %v left over\n", legs, leftOver)
+	}
+
+	orders := FlattenOrders([]types.Order{legs[0].Order}, map[types.AssetPair]types.Spread{BTCUSD: {Bid: decimal.NewFromInt(99), Ask: decimal.NewFromInt(101)}})
+	if len(orders) != 1 || !orders[0].Price.Equal(decimal.NewFromInt(99)) {
+		t.Fatalf("expected to sell at the bid, got %v\n", orders)
+	}
+	if orders := FlattenOrders([]types.Order{legs[0].Order}, map[types.AssetPair]types.Spread{}); len(orders) != 0 {
+		t.Fatalf("expected no order without a quote, got %v\n", orders)
+	}
+}
+
+func TestCloseOut(t *testing.T) {
+	for _, test := range []struct {
+		policy    ClosePolicy
+		canceled  int
+		flattened int
+	}{
+		{Keep, 0, 0},
+		{Cancel, 2, 0},
+		{Flatten, 2, 1},
+	} {
+		exchange := newFakeExchange()
+		coordinator := NewCoordinator([]types.Exchange{exchange}, control.NewState(0.5), nil, Limits{}, 1, time.Second)
+		coordinator.Execute(types.Opportunity{Legs: []types.Leg{
+			{Exchange: "Fake", Order: order(types.Buy, 4)},
+			{Exchange: "Fake", Order: order(types.Sell, 2)},
+		}})
+		if coordinator.Executed() != 1 {
+			t.Fatalf("expected 1 executed opportunity, got %v\n", coordinator.Executed())
+		}
+
+		report := coordinator.CloseOut(context.Background(), test.policy)["Fake"]
+		if len(report.Canceled) != test.canceled || len(report.Flattened) != test.flattened {
+			t.Fatalf("%v: unexpected report %+v\n", test.policy, report)
+		}
+		for flattened := range report.Flattened {
+			// bought 2 and sold 1 before the cancel
+			if flattened.OrderType != types.Sell || !flattened.Quantity.Equal(decimal.NewFromInt(1)) {
+				t.Fatalf("expected to sell 1, got %v\n", flattened)
+			}
+		}
+	}
+}
+
+func TestCloseOutHedged(t *testing.T) {
+	exchange1, exchange2 := newFakeExchange(), newFakeExchange()
+	exchange1.name, exchange2.name = "A", "B"
+	coordinator := NewCoordinator([]types.Exchange{exchange1, exchange2}, control.NewState(0.5), nil, Limits{}, 1, time.Second)
+	arbitrage := types.Opportunity{Legs: []types.Leg{
+		{Exchange: "A", Order: order(types.Buy, 2)},
+		{Exchange: "B", Order: order(types.Sell, 2)},
+	}}
+
+	// bought on A and sold on B, there is nothing to flatten
+	coordinator.Execute(arbitrage)
+	exchange1.fillAll()
+	exchange2.fillAll()
+	coordinator.settle()
+	if len(coordinator.pending) != 0 || len(coordinator.unhedged) != 0 {
+		t.Fatalf("expected the filled opportunity to be forgotten, got %v pending and %v unhedged\n", len(coordinator.pending), len(coordinator.unhedged))
+	}
+	reports := coordinator.CloseOut(context.Background(), Flatten)
+	if len(reports["A"].Flattened) != 0 || len(reports["B"].Flattened) != 0 {
+		t.Fatalf("expected no flatten orders, got %+v\n", reports)
+	}
+
+	// the sell on B only half filled, so the BTC bought on A that it did not sell is sold back there
+	coordinator.Execute(arbitrage)
+	exchange1.fillAll()
+	reports = coordinator.CloseOut(context.Background(), Flatten)
+	if len(reports["B"].Canceled) != 1 || len(reports["B"].Flattened) != 0 || len(reports["A"].Flattened) != 1 {
+		t.Fatalf("expected the sell on B canceled and only A flattened, got %+v\n", reports)
+	}
+	for flattened := range reports["A"].Flattened {
+		if flattened.OrderType != types.Sell || !flattened.Quantity.Equal(decimal.NewFromInt(1)) {
+			t.Fatalf("expected to sell 1 on A, got %v\n", flattened)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package features
+
+import (
+    "context"
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/denali-capital/grizzly/candles"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/denali-capital/grizzly/util"
+    "github.com/shopspring/decimal"
+)
+
+type Options struct {
+    // volatility is 0 without one
+    Aggregator *candles.Aggregator
+    Volatility util.VolatilityOptions
+    // exchange -> taker fee fraction
+    Fees       map[string]decimal.Decimal
+    // quote amount liquidity and edge are measured at, converted to base at exchange 1's mid
+    Notional   decimal.Decimal
+}
+
+type key struct {
+    exchange  string
+    assetPair types.AssetPair
+}
+
+// what one exchange and asset pair contributed to the last observation
+type side struct {
+    spread     types.Spread
+    orderBook  *types.OrderBook
+    volatility float64
+    // start of the candle period volatility was computed in
+    period     time.Time
+}
+
+// computes Observations laid out by a schema, caching per exchange and asset pair so that
+// order books are only refetched when the spread moves and volatility only once per candle
+type Extractor struct {
+    mutex     sync.Mutex
+    schema    Schema
+    features  []Feature
+    options   Options
+    latencies map[string]time.Duration
+    sides     map[key]side
+}
+
+// errors when the schema names an unregistered feature
+func NewExtractor(schema Schema, options Options) (*Extractor, error) {
+    features := make([]Feature, len(schema.Features))
+    for i, name := range schema.Features {
+        feature, ok := Lookup(name)
+        if !ok {
+            return nil, fmt.Errorf("schema %v uses unknown feature %q", schema, name)
+        }
+        features[i] = feature
+    }
+    return &Extractor{
+        schema: schema,
+        features: features,
+        options: options,
+        latencies: make(map[string]time.Duration),
+        sides: make(map[key]side),
+    }, nil
+}
+
+func (e *Extractor) Schema() Schema {
+    return e.schema
+}
+
+func (e *Extractor) ObserveLatency(exchange string, latency time.Duration) {
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+    e.latencies[exchange] = latency
+}
+
+// last observed, 0 before any
+func (e *Extractor) Latency(exchange string) time.Duration {
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+    return e.latencies[exchange]
+}
+
+// GetLatency makes a REST request, so it is measured in the background rather than per observation
+func (e *Extractor) MeasureLatencies(ctx context.Context, exchanges []types.Exchange, interval time.Duration) {
+    for {
+        for _, exchange := range exchanges {
+            e.ObserveLatency(exchange.String(), exchange.GetLatency())
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-time.After(interval):
+        }
+    }
+}
+
+func (e *Extractor) side(exchange types.Exchange, assetPair types.AssetPair, now time.Time) side {
+    k := key{exchange.String(), assetPair}
+    e.mutex.Lock()
+    cached, ok := e.sides[k]
+    e.mutex.Unlock()
+
+    // fetched outside the lock, concurrent extractions of the same side only duplicate work
+    spread := exchange.GetCurrentSpread(assetPair)
+    if !ok || !spread.Timestamp.Equal(cached.spread.Timestamp) || cached.orderBook == nil {
+        cached.orderBook = exchange.GetOrderBooks([]types.AssetPair{assetPair})[assetPair]
+    }
+    cached.spread = spread
+    if e.options.Aggregator != nil {
+        if period := now.Truncate(e.options.Volatility.Interval); !ok || !period.Equal(cached.period) {
+            cached.volatility = e.options.Aggregator.Volatility(k.exchange, assetPair, e.options.Volatility)
+            cached.period = period
+        }
+    }
+
+    e.mutex.Lock()
+    e.sides[k] = cached
+    e.mutex.Unlock()
+    return cached
+}
+
+// current features of trading assetPair between exchange1 and exchange2
+func (e *Extractor) Extract(exchange1, exchange2 types.Exchange, assetPair types.AssetPair) types.Observation {
+    return e.ExtractAt(exchange1, exchange2, assetPair, time.Now())
+}
+
+// Extract as of now, for exchanges replaying recorded data
+func (e *Extractor) ExtractAt(exchange1, exchange2 types.Exchange, assetPair types.AssetPair, now time.Time) types.Observation {
+    side1 := e.side(exchange1, assetPair, now)
+    side2 := e.side(exchange2, assetPair, now)
+
+    e.mutex.Lock()
+    latency1, latency2 := e.latencies[exchange1.String()], e.latencies[exchange2.String()]
+    e.mutex.Unlock()
+    size := decimal.Zero
+    if quoted(side1.spread) {
+        size = e.options.Notional.Div(util.Midpoint(side1.spread))
+    }
+    input := &Input{
+        AssetPair: assetPair,
+        Spread1: side1.spread,
+        Spread2: side2.spread,
+        OrderBook1: side1.orderBook,
+        OrderBook2: side2.orderBook,
+        Latency1: latency1,
+        Latency2: latency2,
+        Volatility1: side1.volatility,
+        Volatility2: side2.volatility,
+        Fee1: e.options.Fees[exchange1.String()],
+        Fee2: e.options.Fees[exchange2.String()],
+        Size: size,
+        Timestamp: now,
+    }
+
+    values := make([]float32, len(e.features))
+    for i, feature := range e.features {
+        values[i] = feature(input)
+    }
+    return types.Observation{
+        Exchange1: exchange1.String(),
+        Exchange2: exchange2.String(),
+        AssetPair: assetPair,
+        Timestamp: now,
+        Schema: e.schema.String(),
+        Features: values,
+    }
+}
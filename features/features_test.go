@@ -0,0 +1,130 @@
+package features
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+var BTCUSD types.AssetPair = types.NewAssetPair("BTC", "USD")
+
+type fakeExchange struct {
+	types.Exchange
+	name       string
+	spread     types.Spread
+	orderBook  *types.OrderBook
+	orderBooks int
+}
+
+func (f *fakeExchange) String() string {
+	return f.name
+}
+
+func (f *fakeExchange) GetCurrentSpread(assetPair types.AssetPair) types.Spread {
+	return f.spread
+}
+
+func (f *fakeExchange) GetOrderBooks(assetPairs []types.AssetPair) map[types.AssetPair]*types.OrderBook {
+	f.orderBooks++
+	return map[types.AssetPair]*types.OrderBook{BTCUSD: f.orderBook}
+}
+
+func (f *fakeExchange) GetLatency() time.Duration {
+	return 50 * time.Millisecond
+}
+
+func entry(price, quantity int64) types.OrderBookEntry {
+	return types.OrderBookEntry{Price: decimal.NewFromInt(price), Quantity: decimal.NewFromInt(quantity)}
+}
+
+func newFakeExchange(name string, bid, ask int64, timestamp time.Time) *fakeExchange {
+	return &fakeExchange{
+		name: name,
+		spread: types.Spread{Bid: decimal.NewFromInt(bid), Ask: decimal.NewFromInt(ask), Timestamp: timestamp},
+		orderBook: &types.OrderBook{
+			Bids: []types.OrderBookEntry{entry(bid, 3), entry(bid - 1, 10)},
+			Asks: []types.OrderBookEntry{entry(ask, 1), entry(ask + 1, 10)},
+		},
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := LookupSchema("grizzly/1")
+	if err != nil || schema.String() != Default.String() || len(schema.Features) != 7 {
+		t.Fatalf("expected the default schema, got %v %v\n", schema, err)
+	}
+	if schema.Index("latency_2") != 4 || schema.Index("edge") != -1 {
+		t.Fatalf("expected latency_2 at 4 and no edge, got %v %v\n", schema.Index("latency_2"), schema.Index("edge"))
+	}
+	if _, err := LookupSchema("grizzly/0"); err == nil {
+		t.Fatalf("expected an error for an unknown schema\n")
+	}
+	if _, err := NewExtractor(Schema{Name: "test", Version: 1, Features: []string{"price_delta", "nonexistent"}}, Options{}); err == nil {
+		t.Fatalf("expected an error for an unknown feature\n")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	now := time.Now()
+	exchange1 := newFakeExchange("Exchange1", 99, 101, now)
+	exchange2 := newFakeExchange("Exchange2", 104, 106, now)
+	extractor, err := NewExtractor(Extended, Options{
+		Fees: map[string]decimal.Decimal{"Exchange1": decimal.NewFromFloat(0.001), "Exchange2": decimal.NewFromFloat(0.001)},
+		Notional: decimal.NewFromInt(100),
+	})
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	extractor.ObserveLatency("Exchange1", 20 * time.Millisecond)
+
+	observation := extractor.Extract(exchange1, exchange2, BTCUSD)
+	if observation.Schema != "grizzly/2" || len(observation.Features) != len(Extended.Features) || observation.Exchange2 != "Exchange2" {
+		t.Fatalf("expected a grizzly/2 observation, got %+v\n", observation)
+	}
+	feature := func(name string) float64 {
+		return float64(observation.Features[Extended.Index(name)])
+	}
+	for _, test := range []struct {
+		name     string
+		expected float64
+	}{
+		{"price_delta", 0.05},
+		{"latency_1", 0.02},
+		// never measured
+		{"latency_2", 0},
+		// no aggregator
+		{"volatility_1", 0},
+		{"imbalance_1", 0.5},
+		// buying 1 on exchange 1 at 101 and selling it on exchange 2 at 104, less 0.205 of fees
+		{"edge", 2.795 / 101.101},
+	} {
+		if math.Abs(feature(test.name) - test.expected) > 1e-6 {
+			t.Fatalf("expected %v of %v, got %v\n", test.name, test.expected, feature(test.name))
+		}
+	}
+	if feature("liquidity_1") <= 0 || feature("liquidity_1") >= 1 {
+		t.Fatalf("expected liquidity_1 within (0, 1), got %v\n", feature("liquidity_1"))
+	}
+
+	// order books are refetched only once the spread moves
+	extractor.Extract(exchange1, exchange2, BTCUSD)
+	if exchange1.orderBooks != 1 {
+		t.Fatalf("expected 1 order book fetch, got %v\n", exchange1.orderBooks)
+	}
+	exchange1.spread.Timestamp = now.Add(time.Second)
+	extractor.Extract(exchange1, exchange2, BTCUSD)
+	if exchange1.orderBooks != 2 || exchange2.orderBooks != 1 {
+		t.Fatalf("expected 2 and 1 order book fetches, got %v %v\n", exchange1.orderBooks, exchange2.orderBooks)
+	}
+
+	// too thin to fill the notional, and no edge without a side that can fill it
+	exchange2.orderBook = &types.OrderBook{Bids: []types.OrderBookEntry{entry(104, 1)}, Asks: []types.OrderBookEntry{entry(106, 1)}}
+	extractor, _ = NewExtractor(Extended, Options{Notional: decimal.NewFromInt(1000)})
+	observation = extractor.Extract(exchange1, exchange2, BTCUSD)
+	if feature("liquidity_2") != 1 || feature("edge") != 0 {
+		t.Fatalf("expected liquidity_2 of 1 and no edge, got %v %v\n", feature("liquidity_2"), feature("edge"))
+	}
+}
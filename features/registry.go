@@ -0,0 +1,147 @@
+package features
+
+import (
+    "sync"
+    "time"
+
+    "github.com/denali-capital/grizzly/arbitrage"
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/denali-capital/grizzly/util"
+    "github.com/shopspring/decimal"
+)
+
+var logger *logging.Logger = logging.New("features")
+
+// everything features are computed from for one exchange pair and asset pair;
+// suffixes 1 and 2 are the two exchanges
+type Input struct {
+    AssetPair   types.AssetPair
+    Spread1     types.Spread
+    Spread2     types.Spread
+    // nil until recorded
+    OrderBook1  *types.OrderBook
+    OrderBook2  *types.OrderBook
+    Latency1    time.Duration
+    Latency2    time.Duration
+    Volatility1 float64
+    Volatility2 float64
+    // taker fee fractions
+    Fee1        decimal.Decimal
+    Fee2        decimal.Decimal
+    // base quantity liquidity and edge are measured at
+    Size        decimal.Decimal
+    Timestamp   time.Time
+}
+
+type Feature func(input *Input) float32
+
+var registryMutex sync.RWMutex
+var registry map[string]Feature = make(map[string]Feature)
+
+func Register(name string, feature Feature) {
+    registryMutex.Lock()
+    defer registryMutex.Unlock()
+    if _, ok := registry[name]; ok {
+        logger.Fatal("feature is already registered", "feature", name)
+    }
+    registry[name] = feature
+}
+
+func Lookup(name string) (Feature, bool) {
+    registryMutex.RLock()
+    defer registryMutex.RUnlock()
+    feature, ok := registry[name]
+    return feature, ok
+}
+
+func float(d decimal.Decimal) float32 {
+    return float32(d.InexactFloat64())
+}
+
+func quoted(spread types.Spread) bool {
+    return spread.Bid.IsPositive() && spread.Ask.IsPositive()
+}
+
+// relative difference of the midpoints, exchange 2 against exchange 1
+func priceDelta(input *Input) float32 {
+    if !quoted(input.Spread1) || !quoted(input.Spread2) {
+        return 0
+    }
+    mid1 := util.Midpoint(input.Spread1)
+    return float(util.Midpoint(input.Spread2).Sub(mid1).Div(mid1))
+}
+
+// slippage at Size, 1 when either side of the book cannot fill it
+func liquidity(orderBook *types.OrderBook, size decimal.Decimal) float32 {
+    if orderBook == nil || len(orderBook.Bids) == 0 || len(orderBook.Asks) == 0 || !size.IsPositive() {
+        return 1
+    }
+    for _, side := range [][]types.OrderBookEntry{orderBook.Bids, orderBook.Asks} {
+        available := decimal.Zero
+        for _, entry := range side {
+            available = available.Add(entry.Quantity)
+        }
+        if available.LessThan(size) {
+            return 1
+        }
+    }
+    return float(util.ComputeSlippage(orderBook, size))
+}
+
+// top of book (bid - ask) / (bid + ask) quantity, positive when buyers dominate
+func imbalance(orderBook *types.OrderBook) float32 {
+    if orderBook == nil || len(orderBook.Bids) == 0 || len(orderBook.Asks) == 0 {
+        return 0
+    }
+    bid, ask := orderBook.Bids[0].Quantity, orderBook.Asks[0].Quantity
+    if total := bid.Add(ask); total.IsPositive() {
+        return float(bid.Sub(ask).Div(total))
+    }
+    return 0
+}
+
+// seconds since the spread last changed
+func staleness(spread types.Spread, now time.Time) float32 {
+    if spread.Timestamp.IsZero() {
+        return 0
+    }
+    return float32(now.Sub(spread.Timestamp).Seconds())
+}
+
+// the better fee-aware return at Size of buying on one exchange and selling on the other,
+// 0 when the books cannot fill Size
+func edge(input *Input) float32 {
+    if input.OrderBook1 == nil || input.OrderBook2 == nil || !input.Size.IsPositive() {
+        return 0
+    }
+    best := decimal.Zero
+    found := false
+    for _, books := range [][2]*types.OrderBook{{input.OrderBook1, input.OrderBook2}, {input.OrderBook2, input.OrderBook1}} {
+        buyFee, sellFee := input.Fee1, input.Fee2
+        if books[0] == input.OrderBook2 {
+            buyFee, sellFee = input.Fee2, input.Fee1
+        }
+        crossEdge, err := arbitrage.ComputeCrossEdge(books[0], books[1], buyFee, sellFee, input.Size)
+        if err == nil && (!found || crossEdge.Return.GreaterThan(best)) {
+            best = crossEdge.Return
+            found = true
+        }
+    }
+    return float(best)
+}
+
+func init() {
+    Register("price_delta", priceDelta)
+    Register("liquidity_1", func(input *Input) float32 { return liquidity(input.OrderBook1, input.Size) })
+    Register("liquidity_2", func(input *Input) float32 { return liquidity(input.OrderBook2, input.Size) })
+    Register("latency_1", func(input *Input) float32 { return float32(input.Latency1.Seconds()) })
+    Register("latency_2", func(input *Input) float32 { return float32(input.Latency2.Seconds()) })
+    Register("volatility_1", func(input *Input) float32 { return float32(input.Volatility1) })
+    Register("volatility_2", func(input *Input) float32 { return float32(input.Volatility2) })
+    Register("imbalance_1", func(input *Input) float32 { return imbalance(input.OrderBook1) })
+    Register("imbalance_2", func(input *Input) float32 { return imbalance(input.OrderBook2) })
+    Register("staleness_1", func(input *Input) float32 { return staleness(input.Spread1, input.Timestamp) })
+    Register("staleness_2", func(input *Input) float32 { return staleness(input.Spread2, input.Timestamp) })
+    Register("edge", edge)
+}
@@ -0,0 +1,82 @@
+package features
+
+import (
+    "fmt"
+    "sort"
+    "sync"
+)
+
+// named, versioned layout of feature vectors; models and datasets record the schema they
+// were built with so that vectors are never read positionally against the wrong layout
+type Schema struct {
+    Name     string
+    Version  uint
+    // registered feature names, in vector order
+    Features []string
+}
+
+func (s Schema) String() string {
+    return fmt.Sprintf("%v/%v", s.Name, s.Version)
+}
+
+// position of the named feature, -1 when the schema does not have it
+func (s Schema) Index(name string) int {
+    for i, feature := range s.Features {
+        if feature == name {
+            return i
+        }
+    }
+    return -1
+}
+
+// the seven features KillerInstinct was trained on, in its input order
+var Default Schema = Schema{
+    Name: "grizzly",
+    Version: 1,
+    Features: []string{"price_delta", "liquidity_1", "liquidity_2", "latency_1", "latency_2", "volatility_1", "volatility_2"},
+}
+
+// Default with order book imbalance, quote staleness and the fee-aware edge
+var Extended Schema = Schema{
+    Name: "grizzly",
+    Version: 2,
+    Features: append(append([]string{}, Default.Features...), "imbalance_1", "imbalance_2", "staleness_1", "staleness_2", "edge"),
+}
+
+var schemasMutex sync.RWMutex
+var schemas map[string]Schema = make(map[string]Schema)
+
+func init() {
+    RegisterSchema(Default)
+    RegisterSchema(Extended)
+}
+
+// every feature must be registered first
+func RegisterSchema(schema Schema) {
+    for _, name := range schema.Features {
+        if _, ok := Lookup(name); !ok {
+            logger.Fatal("schema uses an unregistered feature", "schema", schema, "feature", name)
+        }
+    }
+    schemasMutex.Lock()
+    defer schemasMutex.Unlock()
+    if _, ok := schemas[schema.String()]; ok {
+        logger.Fatal("schema is already registered", "schema", schema)
+    }
+    schemas[schema.String()] = schema
+}
+
+// by name/version, e.g. grizzly/1
+func LookupSchema(s string) (Schema, error) {
+    schemasMutex.RLock()
+    defer schemasMutex.RUnlock()
+    if schema, ok := schemas[s]; ok {
+        return schema, nil
+    }
+    names := make([]string, 0, len(schemas))
+    for name := range schemas {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return Schema{}, fmt.Errorf("unknown feature schema %q, expected one of %v", s, names)
+}
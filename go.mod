@@ -1,13 +1,13 @@
 module github.com/denali-capital/grizzly
 
-go 1.16
+go 1.21
 
-require github.com/BurntSushi/toml v0.4.1 // indirect
+require github.com/BurntSushi/toml v0.4.1
 
 require (
-	github.com/google/uuid v1.3.0 // indirect
-	github.com/gorilla/websocket v1.4.2 // indirect
-	github.com/joho/godotenv v1.4.0 // indirect
+	github.com/google/uuid v1.3.0
+	github.com/gorilla/websocket v1.4.2
+	github.com/joho/godotenv v1.4.0
 	github.com/montanaflynn/stats v0.6.6
-	github.com/shopspring/decimal v1.3.1 // indirect
+	github.com/shopspring/decimal v1.3.1
 )
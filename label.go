@@ -0,0 +1,159 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "time"
+
+    "github.com/denali-capital/grizzly/candles"
+    "github.com/denali-capital/grizzly/config"
+    "github.com/denali-capital/grizzly/labeling"
+    "github.com/denali-capital/grizzly/recording"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/denali-capital/grizzly/util"
+)
+
+func labelingOptions(cfg *config.Config, exchanges []types.Exchange) labeling.Options {
+    return labeling.Options{
+        Horizon: cfg.LabelHorizon(),
+        Threshold: cfg.LabelThreshold(),
+        Fees: fees(cfg, exchanges),
+        Notional: cfg.FeatureNotional(),
+    }
+}
+
+type labelSummary struct {
+    snapshots  int
+    detected   int
+    profitable int
+    labeled    int
+    // opportunities without a book within the horizon, or still pending when the recording ended
+    dropped    int
+    start      time.Time
+    end        time.Time
+}
+
+// replays the recording at path, detecting opportunities between every pair of enabled exchanges
+// each step of recorded time, and hands every labeled observation to emit
+func labelRecording(ctx context.Context, cfg *config.Config, path string, step time.Duration, emit func(observation types.Observation) error) (labelSummary, error) {
+    summary := labelSummary{}
+    reader, err := recording.NewReader(path)
+    if err != nil {
+        return summary, err
+    }
+    defer reader.Close()
+
+    replays := make(map[string]*recording.ReplayExchange)
+    exchanges := make([]types.Exchange, 0)
+    for _, name := range cfg.EnabledExchanges() {
+        replays[name] = recording.NewReplayExchange(name, cfg.Exchanges[name].SpreadCapacity)
+        exchanges = append(exchanges, replays[name])
+    }
+    assetPairs := configuredAssetPairs(cfg, exchanges)
+    // volatility features come from candles of the replayed quotes
+    aggregator := candles.NewAggregator(exchanges, assetPairs, cfg.CandleCapacity(), cfg.Candles.Mid == "microprice")
+    extractor := featureExtractor(cfg, exchanges, aggregator)
+    labeler := labeling.NewLabeler(extractor, labelingOptions(cfg, exchanges))
+    exchangePairs := make([][]types.Exchange, 0)
+    for exchangePair := range util.ExchangeCombinations(exchanges, 2) {
+        exchangePairs = append(exchangePairs, exchangePair)
+    }
+
+    var next time.Time
+    // an interrupt ends the replay early, observations labeled so far are kept
+    for ctx.Err() == nil {
+        snapshot, err := reader.Next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return summary, err
+        }
+        replay, ok := replays[snapshot.Exchange]
+        if !ok {
+            continue
+        }
+        replay.Apply(snapshot)
+        // labels and features both see the latency recorded with the data
+        extractor.ObserveLatency(snapshot.Exchange, replay.GetLatency())
+        summary.snapshots++
+        if summary.start.IsZero() {
+            summary.start = snapshot.Timestamp
+            next = snapshot.Timestamp
+        }
+        summary.end = snapshot.Timestamp
+
+        for _, observation := range labeler.Apply(snapshot) {
+            summary.labeled++
+            summary.profitable += int(observation.Label)
+            if err := emit(observation); err != nil {
+                return summary, err
+            }
+        }
+
+        if snapshot.Timestamp.Before(next) {
+            continue
+        }
+        next = snapshot.Timestamp.Add(step)
+        aggregator.Poll()
+        for _, exchangePair := range exchangePairs {
+            commonAssetPairs := util.AssetPairIntersection(assetPairs[exchangePair[0].String()], assetPairs[exchangePair[1].String()])
+            for _, assetPair := range commonAssetPairs {
+                if labeler.Detect(exchangePair[0], exchangePair[1], assetPair, snapshot.Timestamp) {
+                    summary.detected++
+                }
+            }
+        }
+    }
+    summary.dropped = labeler.Dropped() + labeler.Pending()
+    return summary, nil
+}
+
+func printLabelSummary(path string, summary labelSummary) {
+    fmt.Printf("replayed %v snapshots of %v from %v to %v\n", summary.snapshots, path, summary.start.Format(time.RFC3339), summary.end.Format(time.RFC3339))
+    fmt.Printf("%v opportunities detected, %v labeled (%v profitable), %v dropped\n", summary.detected, summary.labeled, summary.profitable, summary.dropped)
+}
+
+// replays a recording and labels every opportunity found in it by whether it would have paid
+func labelCommand(ctx context.Context, cfg *config.Config, args []string) error {
+    flags := flag.NewFlagSet("label", flag.ExitOnError)
+    in := flags.String("in", "", "recording written by grizzly record")
+    out := flags.String("out", "", "JSON lines of labeled observations to write (default none, only the summary is printed)")
+    step := flags.Duration("step", cfg.Trading.SleepDuration.Duration, "recorded time between opportunity detections")
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: grizzly label -in recording [flags]\n")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+    if *in == "" {
+        flags.Usage()
+        return errors.New("-in is required")
+    }
+
+    emit := func(observation types.Observation) error {
+        return nil
+    }
+    if *out != "" {
+        file, err := os.OpenFile(*out, os.O_CREATE | os.O_EXCL | os.O_WRONLY, 0644)
+        if err != nil {
+            return err
+        }
+        defer file.Close()
+        encoder := json.NewEncoder(file)
+        emit = func(observation types.Observation) error {
+            return encoder.Encode(observation)
+        }
+    }
+
+    summary, err := labelRecording(ctx, cfg, *in, *step, emit)
+    if err != nil {
+        return err
+    }
+    printLabelSummary(*in, summary)
+    return nil
+}
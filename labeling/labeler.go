@@ -0,0 +1,166 @@
+package labeling
+
+import (
+    "time"
+
+    "github.com/denali-capital/grizzly/arbitrage"
+    "github.com/denali-capital/grizzly/features"
+    "github.com/denali-capital/grizzly/recording"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/denali-capital/grizzly/util"
+    "github.com/shopspring/decimal"
+)
+
+type Options struct {
+    // how long after a leg reaches its exchange the labeler waits for a book to execute it against
+    Horizon   time.Duration
+    // minimum return at execution for a label of 1, e.g. 0.001 for 0.1%; the profit must be
+    // positive too, so 0 labels any profitable opportunity 1
+    Threshold decimal.Decimal
+    // exchange -> taker fee fraction
+    Fees      map[string]decimal.Decimal
+    // quote amount traded, converted to base at the buy side's mid
+    Notional  decimal.Decimal
+}
+
+// buying Size of the observation's asset pair on Buy and selling it on Sell
+type Opportunity struct {
+    Observation types.Observation
+    Buy         string
+    Sell        string
+    Size        decimal.Decimal
+}
+
+// one leg of a pending opportunity
+type leg struct {
+    exchange  string
+    // when the order reaches the exchange, detection plus its latency
+    arrival   time.Time
+    orderBook *types.OrderBook
+}
+
+type pending struct {
+    opportunity Opportunity
+    buy         leg
+    sell        leg
+}
+
+// labels opportunities detected in replayed or live market data by whether they would have paid:
+// each leg executes as a taker order against the first book its exchange recorded once the
+// order arrived, so that latency, slippage and fees are all priced in
+type Labeler struct {
+    options   Options
+    extractor *features.Extractor
+    pending   []pending
+    dropped   int
+}
+
+func NewLabeler(extractor *features.Extractor, options Options) *Labeler {
+    return &Labeler{
+        options: options,
+        extractor: extractor,
+        pending: make([]pending, 0),
+    }
+}
+
+// opportunities whose legs found no book within the horizon, and are never labeled
+func (l *Labeler) Dropped() int {
+    return l.dropped
+}
+
+// opportunities still waiting on a leg's book
+func (l *Labeler) Pending() int {
+    return len(l.pending)
+}
+
+func (l *Labeler) label(p pending) types.Observation {
+    observation := p.opportunity.Observation
+    observation.Label = 0
+    observation.Profit = 0
+    // a side too thin to fill Size at execution is as unprofitable as a negative edge, but
+    // with no profit or loss to record
+    edge, err := arbitrage.ComputeCrossEdge(p.buy.orderBook, p.sell.orderBook, l.options.Fees[p.buy.exchange], l.options.Fees[p.sell.exchange], p.opportunity.Size)
+    if err != nil {
+        return observation
+    }
+    observation.Profit = edge.Profit.InexactFloat64()
+    if edge.Profit.IsPositive() && edge.Return.GreaterThanOrEqual(l.options.Threshold) {
+        observation.Label = 1
+    }
+    return observation
+}
+
+// fills in legs executed by snapshot, and returns the observations of every opportunity
+// that now has both legs, labeled, oldest detection first
+func (l *Labeler) Apply(snapshot recording.Snapshot) []types.Observation {
+    labeled := make([]types.Observation, 0)
+    remaining := l.pending[:0]
+    for _, p := range l.pending {
+        for _, leg := range []*leg{&p.buy, &p.sell} {
+            if leg.orderBook != nil || leg.exchange != snapshot.Exchange || snapshot.AssetPair != p.opportunity.Observation.AssetPair {
+                continue
+            }
+            if snapshot.OrderBook != nil && !snapshot.Timestamp.Before(leg.arrival) {
+                leg.orderBook = snapshot.OrderBook
+            }
+        }
+
+        switch {
+        case p.buy.orderBook != nil && p.sell.orderBook != nil:
+            labeled = append(labeled, l.label(p))
+        case l.expired(p.buy, snapshot.Timestamp) || l.expired(p.sell, snapshot.Timestamp):
+            l.dropped++
+        default:
+            remaining = append(remaining, p)
+        }
+    }
+    l.pending = remaining
+    return labeled
+}
+
+func (l *Labeler) expired(leg leg, now time.Time) bool {
+    return leg.orderBook == nil && now.After(leg.arrival.Add(l.options.Horizon))
+}
+
+// queues an opportunity when buying on one exchange and selling on the other pays after fees
+// and slippage at now, the time exchange1 and exchange2 are at; false otherwise
+//
+// legs arrive after the latencies last observed by the extractor
+func (l *Labeler) Detect(exchange1, exchange2 types.Exchange, assetPair types.AssetPair, now time.Time) bool {
+    latencies := make(map[string]time.Duration)
+    orderBooks := make(map[string]*types.OrderBook)
+    spreads := make(map[string]types.Spread)
+    for _, exchange := range []types.Exchange{exchange1, exchange2} {
+        latencies[exchange.String()] = l.extractor.Latency(exchange.String())
+        orderBooks[exchange.String()] = exchange.GetOrderBooks([]types.AssetPair{assetPair})[assetPair]
+        spreads[exchange.String()] = exchange.GetCurrentSpread(assetPair)
+    }
+
+    var best *Opportunity
+    bestReturn := decimal.Zero
+    for _, exchanges := range [][2]types.Exchange{{exchange1, exchange2}, {exchange2, exchange1}} {
+        buy, sell := exchanges[0].String(), exchanges[1].String()
+        spread := spreads[buy]
+        if !spread.Bid.IsPositive() || !spread.Ask.IsPositive() || orderBooks[buy] == nil || orderBooks[sell] == nil {
+            continue
+        }
+        size := l.options.Notional.Div(util.Midpoint(spread))
+        edge, err := arbitrage.ComputeCrossEdge(orderBooks[buy], orderBooks[sell], l.options.Fees[buy], l.options.Fees[sell], size)
+        if err != nil || !edge.Profit.IsPositive() || (best != nil && !edge.Return.GreaterThan(bestReturn)) {
+            continue
+        }
+        best = &Opportunity{Buy: buy, Sell: sell, Size: size}
+        bestReturn = edge.Return
+    }
+    if best == nil {
+        return false
+    }
+
+    best.Observation = l.extractor.ExtractAt(exchange1, exchange2, assetPair, now)
+    l.pending = append(l.pending, pending{
+        opportunity: *best,
+        buy: leg{exchange: best.Buy, arrival: now.Add(latencies[best.Buy])},
+        sell: leg{exchange: best.Sell, arrival: now.Add(latencies[best.Sell])},
+    })
+    return true
+}
@@ -0,0 +1,108 @@
+package labeling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/features"
+	"github.com/denali-capital/grizzly/recording"
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+var BTCUSD types.AssetPair = types.NewAssetPair("BTC", "USD")
+
+var start time.Time = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func snapshot(exchange string, offset time.Duration, bid, ask int64) recording.Snapshot {
+	timestamp := start.Add(offset)
+	return recording.Snapshot{
+		Exchange: exchange,
+		AssetPair: BTCUSD,
+		Timestamp: timestamp,
+		Spread: &types.Spread{Bid: decimal.NewFromInt(bid), Ask: decimal.NewFromInt(ask), Timestamp: timestamp},
+		OrderBook: &types.OrderBook{
+			Bids: []types.OrderBookEntry{{Price: decimal.NewFromInt(bid), Quantity: decimal.NewFromInt(5)}},
+			Asks: []types.OrderBookEntry{{Price: decimal.NewFromInt(ask), Quantity: decimal.NewFromInt(5)}},
+		},
+	}
+}
+
+func latency(exchange string, latency time.Duration) recording.Snapshot {
+	return recording.Snapshot{Exchange: exchange, Timestamp: start, Latency: &latency}
+}
+
+func newLabeler(t *testing.T, horizon time.Duration) (*Labeler, map[string]*recording.ReplayExchange) {
+	fees := map[string]decimal.Decimal{"Exchange1": decimal.NewFromFloat(0.001), "Exchange2": decimal.NewFromFloat(0.001)}
+	extractor, err := features.NewExtractor(features.Default, features.Options{Fees: fees, Notional: decimal.NewFromInt(100)})
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	replays := map[string]*recording.ReplayExchange{
+		"Exchange1": recording.NewReplayExchange("Exchange1", 10),
+		"Exchange2": recording.NewReplayExchange("Exchange2", 10),
+	}
+	return NewLabeler(extractor, Options{Horizon: horizon, Threshold: decimal.NewFromFloat(0.001), Fees: fees, Notional: decimal.NewFromInt(100)}), replays
+}
+
+// applies snapshots in order, returning every labeled observation
+func replay(labeler *Labeler, replays map[string]*recording.ReplayExchange, snapshots ...recording.Snapshot) []types.Observation {
+	labeled := make([]types.Observation, 0)
+	for _, s := range snapshots {
+		replays[s.Exchange].Apply(s)
+		labeler.extractor.ObserveLatency(s.Exchange, replays[s.Exchange].GetLatency())
+		labeled = append(labeled, labeler.Apply(s)...)
+	}
+	return labeled
+}
+
+func TestLabeler(t *testing.T) {
+	labeler, replays := newLabeler(t, time.Second)
+	replay(labeler, replays, latency("Exchange1", 200 * time.Millisecond), latency("Exchange2", 500 * time.Millisecond), snapshot("Exchange1", 0, 99, 100), snapshot("Exchange2", 0, 99, 100))
+	// no edge between identical books
+	if labeler.Detect(replays["Exchange1"], replays["Exchange2"], BTCUSD, start) {
+		t.Fatalf("expected no opportunity\n")
+	}
+
+	// buying on Exchange1 at 100 and selling on Exchange2 at 105 pays 4.8%
+	replay(labeler, replays, snapshot("Exchange2", 100 * time.Millisecond, 105, 106))
+	if !labeler.Detect(replays["Exchange1"], replays["Exchange2"], BTCUSD, start.Add(100 * time.Millisecond)) {
+		t.Fatalf("expected an opportunity\n")
+	}
+	// the buy arrives at 300ms, so the book recorded at 200ms is too early for it
+	labeled := replay(labeler, replays, snapshot("Exchange1", 200 * time.Millisecond, 101, 102), snapshot("Exchange1", 400 * time.Millisecond, 99, 100))
+	if len(labeled) != 0 || labeler.Pending() != 1 {
+		t.Fatalf("expected the sell leg to be pending, got %v labeled and %v pending\n", len(labeled), labeler.Pending())
+	}
+	// the sell arrives at 600ms, when the edge has closed
+	labeled = replay(labeler, replays, snapshot("Exchange2", 700 * time.Millisecond, 99, 100))
+	if len(labeled) != 1 || labeled[0].Label != 0 || labeled[0].Schema != "grizzly/1" || !labeled[0].Timestamp.Equal(start.Add(100 * time.Millisecond)) {
+		t.Fatalf("expected an unprofitable grizzly/1 observation detected at 100ms, got %+v\n", labeled)
+	}
+	// latencies are features too
+	if latency1 := labeled[0].Features[features.Default.Index("latency_1")]; latency1 != 0.2 {
+		t.Fatalf("expected latency_1 of 0.2, got %v\n", latency1)
+	}
+
+	// the edge holds until both legs land
+	replay(labeler, replays, snapshot("Exchange2", time.Second, 105, 106))
+	labeler.Detect(replays["Exchange1"], replays["Exchange2"], BTCUSD, start.Add(time.Second))
+	labeled = replay(labeler, replays, snapshot("Exchange1", 1300 * time.Millisecond, 99, 100), snapshot("Exchange2", 1600 * time.Millisecond, 104, 105))
+	// (104 * 0.999 - 100 * 1.001) * 100 / 99.5
+	if len(labeled) != 1 || labeled[0].Label != 1 || labeled[0].Profit < 3.815 || labeled[0].Profit > 3.816 {
+		t.Fatalf("expected a profitable observation, got %+v\n", labeled)
+	}
+}
+
+func TestDropped(t *testing.T) {
+	labeler, replays := newLabeler(t, time.Second)
+	replay(labeler, replays, snapshot("Exchange1", 0, 99, 100), snapshot("Exchange2", 0, 105, 106))
+	if !labeler.Detect(replays["Exchange1"], replays["Exchange2"], BTCUSD, start) {
+		t.Fatalf("expected an opportunity\n")
+	}
+	// Exchange2 records nothing within the horizon
+	labeled := replay(labeler, replays, snapshot("Exchange1", time.Second, 99, 100), snapshot("Exchange1", 2 * time.Second, 99, 100))
+	if len(labeled) != 0 || labeler.Pending() != 0 || labeler.Dropped() != 1 {
+		t.Fatalf("expected the opportunity to be dropped, got %v labeled, %v pending and %v dropped\n", len(labeled), labeler.Pending(), labeler.Dropped())
+	}
+}
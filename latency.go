@@ -0,0 +1,46 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "time"
+
+    "github.com/denali-capital/grizzly/config"
+    "github.com/montanaflynn/stats"
+)
+
+func latencyCommand(ctx context.Context, cfg *config.Config, args []string) error {
+    flags := flag.NewFlagSet("latency", flag.ExitOnError)
+    samples := flags.Uint("samples", 20, "requests per exchange")
+    interval := flags.Duration("interval", 250 * time.Millisecond, "time between requests")
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: grizzly latency [flags] [exchange...]\n")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+
+    exchanges, err := newExchanges(cfg, flags.Args())
+    if err != nil {
+        return err
+    }
+
+    fmt.Printf("%-10v %10v %10v %10v %10v %10v\n", "exchange", "min", "median", "p90", "max", "estimate")
+    for _, exchange := range exchanges {
+        // round trips are timed here, GetLatency itself reports the smoothed estimate
+        roundTrips := make([]float64, *samples)
+        var estimate time.Duration
+        for i := range roundTrips {
+            start := time.Now()
+            estimate = exchange.GetLatency()
+            roundTrips[i] = float64(time.Since(start).Microseconds()) / 1000
+            time.Sleep(*interval)
+        }
+        minimum, _ := stats.Min(roundTrips)
+        median, _ := stats.Median(roundTrips)
+        p90, _ := stats.Percentile(roundTrips, 90)
+        maximum, _ := stats.Max(roundTrips)
+        fmt.Printf("%-10v %8.1fms %8.1fms %8.1fms %8.1fms %10v\n", exchange, minimum, median, p90, maximum, estimate)
+    }
+    return nil
+}
@@ -0,0 +1,74 @@
+package logging
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+)
+
+var logger *Logger = New("logging")
+
+type levelsResponse struct {
+    Default    string            `json:"default"`
+    // effective level of every known component
+    Components map[string]string `json:"components"`
+}
+
+func writeLevels(w http.ResponseWriter) {
+    defaultLevel, levels := Levels()
+    response := levelsResponse{
+        Default: LevelName(defaultLevel),
+        Components: make(map[string]string),
+    }
+    for _, component := range Components() {
+        level, ok := levels[component]
+        if !ok {
+            level = defaultLevel
+        }
+        response.Components[component] = LevelName(level)
+    }
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(response)
+}
+
+// GET lists levels
+// PUT ?level=debug sets the default, PUT ?component=exchanges/kraken&level=debug sets one component
+// and PUT ?component=exchanges/kraken&level=default makes it follow the default again
+type levelHandler struct{}
+
+func (levelHandler) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+    switch request.Method {
+    case http.MethodGet:
+    case http.MethodPut, http.MethodPost:
+        component := request.URL.Query().Get("component")
+        levelString := request.URL.Query().Get("level")
+        if component != "" && levelString == "default" {
+            ResetLevel(component)
+            break
+        }
+        level, err := ParseLevel(levelString)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        SetLevel(component, level)
+        logger.Info("log level changed", "target", componentOrDefault(component), "level", LevelName(level))
+    default:
+        w.Header().Set("Allow", "GET, PUT, POST")
+        http.Error(w, fmt.Sprintf("method %v not allowed", request.Method), http.StatusMethodNotAllowed)
+        return
+    }
+    writeLevels(w)
+}
+
+func componentOrDefault(component string) string {
+    if component == "" {
+        return "default"
+    }
+    return component
+}
+
+// serves and changes log levels at runtime
+func Handler() http.Handler {
+    return levelHandler{}
+}
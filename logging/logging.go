@@ -0,0 +1,270 @@
+package logging
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "log"
+    "log/slog"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "sync/atomic"
+)
+
+// field names shared by every component so that lines can be joined across exchanges
+const (
+    ComponentKey string = "component"
+    ExchangeKey  string = "exchange"
+    AssetPairKey string = "asset_pair"
+    OrderIdKey   string = "order_id"
+    RequestIdKey string = "request_id"
+    ErrorKey     string = "error"
+)
+
+// logged by Fatal right before exiting
+const LevelFatal slog.Level = slog.LevelError + 4
+
+type Format string
+
+const (
+    Text Format = "text"
+    JSON Format = "json"
+)
+
+var (
+    mutex        sync.RWMutex
+    base         slog.Handler = newBaseHandler(Text, os.Stderr)
+    // bumped whenever base changes so component handlers rebuild lazily
+    generation   uint64
+    defaultLevel slog.Level = slog.LevelInfo
+    // component -> level, components without an entry follow defaultLevel
+    levels       map[string]slog.Level = make(map[string]slog.Level)
+    // every component a logger was created for
+    components   map[string]bool = make(map[string]bool)
+)
+
+func replaceLevel(groups []string, attr slog.Attr) slog.Attr {
+    if attr.Key == slog.LevelKey && len(groups) == 0 {
+        if level, ok := attr.Value.Any().(slog.Level); ok && level == LevelFatal {
+            attr.Value = slog.StringValue("FATAL")
+        }
+    }
+    return attr
+}
+
+func newBaseHandler(format Format, w io.Writer) slog.Handler {
+    // filtering is done per component, the base handler lets everything through
+    options := &slog.HandlerOptions{
+        Level: slog.Level(-1 << 10),
+        ReplaceAttr: replaceLevel,
+    }
+    if format == JSON {
+        return slog.NewJSONHandler(w, options)
+    }
+    return slog.NewTextHandler(w, options)
+}
+
+func ParseFormat(s string) (Format, error) {
+    switch Format(s) {
+    case Text, JSON:
+        return Format(s), nil
+    }
+    return "", fmt.Errorf("unknown log format %q, expected text or json", s)
+}
+
+// accepts debug, info, warn, error and fatal in any case, with optional offsets such as debug-2
+func ParseLevel(s string) (slog.Level, error) {
+    if strings.EqualFold(s, "fatal") {
+        return LevelFatal, nil
+    }
+    var level slog.Level
+    err := level.UnmarshalText([]byte(s))
+    return level, err
+}
+
+func LevelName(level slog.Level) string {
+    if level == LevelFatal {
+        return "FATAL"
+    }
+    return level.String()
+}
+
+// switches every logger, including ones already created, to format on w
+func Configure(format Format, w io.Writer) {
+    mutex.Lock()
+    defer mutex.Unlock()
+    base = newBaseHandler(format, w)
+    atomic.AddUint64(&generation, 1)
+}
+
+// component "" sets the default for components without a level of their own
+func SetLevel(component string, level slog.Level) {
+    mutex.Lock()
+    defer mutex.Unlock()
+    if component == "" {
+        defaultLevel = level
+        return
+    }
+    levels[component] = level
+}
+
+// component falls back to the default level again
+func ResetLevel(component string) {
+    mutex.Lock()
+    defer mutex.Unlock()
+    delete(levels, component)
+}
+
+// the default level and every component level set explicitly
+func Levels() (slog.Level, map[string]slog.Level) {
+    mutex.RLock()
+    defer mutex.RUnlock()
+    components := make(map[string]slog.Level, len(levels))
+    for component, level := range levels {
+        components[component] = level
+    }
+    return defaultLevel, components
+}
+
+// every component a logger was created for, sorted
+func Components() []string {
+    mutex.RLock()
+    defer mutex.RUnlock()
+    names := make([]string, 0, len(components))
+    for name := range components {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+func enabled(component string, level slog.Level) bool {
+    mutex.RLock()
+    defer mutex.RUnlock()
+    if componentLevel, ok := levels[component]; ok {
+        return level >= componentLevel
+    }
+    return level >= defaultLevel
+}
+
+type built struct {
+    generation uint64
+    handler    slog.Handler
+}
+
+// filters by the component's level and forwards to whatever base handler is current
+type componentHandler struct {
+    component string
+    // WithAttrs and WithGroup calls, replayed on top of base
+    ops       []func(slog.Handler) slog.Handler
+    cache     *atomic.Value
+}
+
+func newComponentHandler(component string, ops []func(slog.Handler) slog.Handler) *componentHandler {
+    return &componentHandler{
+        component: component,
+        ops: ops,
+        cache: &atomic.Value{},
+    }
+}
+
+func (h *componentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+    return enabled(h.component, level)
+}
+
+func (h *componentHandler) handler() slog.Handler {
+    current := atomic.LoadUint64(&generation)
+    if cached, ok := h.cache.Load().(built); ok && cached.generation == current {
+        return cached.handler
+    }
+    mutex.RLock()
+    handler := base
+    mutex.RUnlock()
+    for _, op := range h.ops {
+        handler = op(handler)
+    }
+    h.cache.Store(built{current, handler})
+    return handler
+}
+
+func (h *componentHandler) Handle(ctx context.Context, record slog.Record) error {
+    return h.handler().Handle(ctx, record)
+}
+
+func (h *componentHandler) with(op func(slog.Handler) slog.Handler) *componentHandler {
+    ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops) + 1)
+    copy(ops, h.ops)
+    return newComponentHandler(h.component, append(ops, op))
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+    return h.with(func(handler slog.Handler) slog.Handler {
+        return handler.WithAttrs(attrs)
+    })
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+    return h.with(func(handler slog.Handler) slog.Handler {
+        return handler.WithGroup(name)
+    })
+}
+
+func Err(err error) slog.Attr {
+    return slog.Any(ErrorKey, err)
+}
+
+func Exchange(exchange interface{}) slog.Attr {
+    return slog.String(ExchangeKey, fmt.Sprint(exchange))
+}
+
+func AssetPair(assetPair fmt.Stringer) slog.Attr {
+    return slog.String(AssetPairKey, assetPair.String())
+}
+
+func OrderId(orderId interface{}) slog.Attr {
+    return slog.String(OrderIdKey, fmt.Sprint(orderId))
+}
+
+func RequestId(requestId string) slog.Attr {
+    return slog.String(RequestIdKey, requestId)
+}
+
+type Logger struct {
+    *slog.Logger
+}
+
+// component is what per-package levels are keyed on, e.g. "exchanges/kraken"
+func New(component string) *Logger {
+    mutex.Lock()
+    components[component] = true
+    mutex.Unlock()
+    handler := newComponentHandler(component, nil).WithAttrs([]slog.Attr{slog.String(ComponentKey, component)})
+    return &Logger{slog.New(handler)}
+}
+
+func (l *Logger) With(args ...interface{}) *Logger {
+    return &Logger{l.Logger.With(args...)}
+}
+
+// logs at LevelFatal and exits, like log.Fatal
+func (l *Logger) Fatal(msg string, args ...interface{}) {
+    l.Log(context.Background(), LevelFatal, msg, args...)
+    os.Exit(1)
+}
+
+// routes the standard library's log package, used by dependencies, through component
+func RedirectStandardLog(component string) {
+    log.SetFlags(0)
+    log.SetOutput(&standardLogWriter{New(component)})
+}
+
+type standardLogWriter struct {
+    logger *Logger
+}
+
+func (w *standardLogWriter) Write(p []byte) (int, error) {
+    w.logger.Info(strings.TrimRight(string(p), "\n"))
+    return len(p), nil
+}
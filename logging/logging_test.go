@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/denali-capital/grizzly/types"
+)
+
+func reset() {
+	Configure(Text, os.Stderr)
+	SetLevel("", slog.LevelInfo)
+	mutex.Lock()
+	levels = make(map[string]slog.Level)
+	mutex.Unlock()
+}
+
+func TestJSON(t *testing.T) {
+	defer reset()
+	buffer := &bytes.Buffer{}
+	// loggers created before Configure switch over too
+	logger := New("exchanges/test").With(Exchange("Test"))
+	Configure(JSON, buffer)
+
+	logger.Info("order placed", AssetPair(types.NewAssetPair("BTC", "USD")), OrderId(types.OrderId("42")), RequestId("r-1"))
+
+	line := make(map[string]interface{})
+	if err := json.Unmarshal(buffer.Bytes(), &line); err != nil {
+		t.Fatalf("%v in %q\n", err, buffer.String())
+	}
+	expected := map[string]string{
+		"msg": "order placed",
+		"level": "INFO",
+		ComponentKey: "exchanges/test",
+		ExchangeKey: "Test",
+		AssetPairKey: "BTCUSD",
+		OrderIdKey: "42",
+		RequestIdKey: "r-1",
+	}
+	for key, value := range expected {
+		if line[key] != value {
+			t.Fatalf("expected %v=%v, got %v\n", key, value, line[key])
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	defer reset()
+	buffer := &bytes.Buffer{}
+	Configure(Text, buffer)
+	quiet := New("quiet")
+	loud := New("loud")
+
+	SetLevel("", slog.LevelWarn)
+	SetLevel("loud", slog.LevelDebug)
+	quiet.Info("dropped")
+	loud.Debug("kept")
+	if output := buffer.String(); strings.Contains(output, "dropped") || !strings.Contains(output, "kept") {
+		t.Fatalf("per-component levels not applied:\n%v\n", output)
+	}
+
+	ResetLevel("loud")
+	buffer.Reset()
+	loud.Debug("dropped")
+	if buffer.Len() != 0 {
+		t.Fatalf("expected loud to follow the default level again, got %v\n", buffer.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, expected := range map[string]slog.Level{"debug": slog.LevelDebug, "WARN": slog.LevelWarn, "info+2": slog.LevelInfo + 2, "fatal": LevelFatal} {
+		if level, err := ParseLevel(s); err != nil || level != expected {
+			t.Fatalf("expected %v for %v, got %v %v\n", expected, s, level, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Fatalf("expected an unknown level to be rejected\n")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	defer reset()
+	Configure(Text, &bytes.Buffer{})
+	New("exchanges/handler")
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("PUT", "/debug/log?component=exchanges/handler&level=debug", nil))
+	response := levelsResponse{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if response.Components["exchanges/handler"] != "DEBUG" || response.Default != "INFO" {
+		t.Fatalf("unexpected levels %+v\n", response)
+	}
+
+	recorder = httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("PUT", "/debug/log?level=loud", nil))
+	if recorder.Code != 400 {
+		t.Fatalf("expected 400 for an unknown level, got %v\n", recorder.Code)
+	}
+}
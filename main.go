@@ -1,130 +1,100 @@
 package main
 
 import (
-    "log"
+    "context"
+    "flag"
+    "fmt"
     "os"
-    "strings"
-    "time"
+    "os/signal"
+    "sort"
+    "syscall"
 
-    "github.com/denali-capital/grizzly/exchanges/binanceus"
-    "github.com/denali-capital/grizzly/exchanges/kraken"
-    "github.com/denali-capital/grizzly/exchanges/kucoin"
-
-    "github.com/denali-capital/grizzly/model/bootstrap"
-    "github.com/denali-capital/grizzly/model/nn"
-    "github.com/denali-capital/grizzly/types"
-    "github.com/denali-capital/grizzly/util"
+    "github.com/denali-capital/grizzly/config"
+    "github.com/denali-capital/grizzly/features"
+    "github.com/denali-capital/grizzly/logging"
     _ "github.com/joho/godotenv/autoload"
 )
 
+var logger *logging.Logger = logging.New("main")
+
 // each exchange will have their own module that implements Exchange interface above
 // can have module to compute statistics in background and access them
 // Neural network module that handles predict / fit functionality
 
-const configPath string = "config"
-const secretKeySuffix string = "_SECRET_KEY"
-const sleepDuration time.Duration = 100 * time.Millisecond
-
-func grizzly(exchange1 *types.Exchange, exchange2 *types.Exchange, allowedAssetPairs []types.AssetPair, killerInstinct *nn.KillerInstinct) {
-    for {
-        if killerInstinct.Predict()
-
-        time.Sleep(sleepDuration)
-    }
+type command struct {
+    summary string
+    // ctx is canceled on SIGINT or SIGTERM
+    run     func(ctx context.Context, cfg *config.Config, args []string) error
 }
 
-func main() {
-    // need to create array of exchange objects
-    // pass this array into function that computes statistics in background
-
-    implementedExchanges := util.DiscoverTypes("github.com/denali-capital/grizzly/exchanges")
-
-    exchangeList := util.ReadCsvFile(configPath + "/exchanges.csv")
-    if exchangeList[0][0] != "exchange" || exchangeList[0][1] != "api_key" || exchangeList[0][2] != "fees" {
-        log.Fatalln("Labels must be \"exchange,api_key,fees,...\"")
-    }
-
-    zippedExchangeList := util.Zip(exchangeList...)
-    allowedExchanges := util.StringIntersection(implementedExchanges, zippedExchangeList[0][1:])
+var commands map[string]command = map[string]command{
+    "record": {"run recorders only and write spreads and order books to disk", recordCommand},
+    "paper": {"trade against live market data with simulated balances", paperCommand},
+    "live": {"trade with real orders", liveCommand},
+    "backtest": {"replay a recording through the paper trading engine", backtestCommand},
+    "dataset": {"write time split feature/label datasets from recordings for training", datasetCommand},
+    "label": {"label the opportunities in a recording by whether they would have paid", labelCommand},
+    "shadow": {"compare the live and challenger models' precision, recall and simulated PnL", shadowCommand},
+    "balances": {"print balances on every enabled exchange", balancesCommand},
+    "orders": {"list or cancel open orders", ordersCommand},
+    "latency": {"benchmark REST latency of every enabled exchange", latencyCommand},
+    "top": {"live dashboard of cross-exchange spreads, edges and depth", topCommand},
+    "secrets": {"add, rotate and verify exchange credentials", secretsCommand},
+}
 
-    if len(allowedExchanges) == 0 {
-        log.Fatalln("No exchanges are both implemented and have requisite config info")
+func usage() {
+    fmt.Fprintf(os.Stderr, "usage: grizzly [-config path] [-filters path] <command> [args]\n\ncommands:\n")
+    names := make([]string, 0, len(commands))
+    for name := range commands {
+        names = append(names, name)
     }
-
-    exchangeInfo := make(map[string][]string)
-    for i := 1; i < len(exchangeList); i++ {
-        info := exchangeList[i]
-        exchangeName := info[0]
-        if util.Contains(allowedExchanges, exchangeName) {
-            if info[1] == "" {
-                log.Fatalln("API key not provided for %v", exchangeName)
-            }
-            if info[2] == "" {
-                log.Println("warning: Fees not provided for %v, assuming 0", exchangeName)
-            }
-            exchangeInfo[exchangeName] := info
-        }
+    sort.Strings(names)
+    for _, name := range names {
+        fmt.Fprintf(os.Stderr, "    %-10v %v\n", name, commands[name].summary)
     }
+    fmt.Fprintf(os.Stderr, "\nflags:\n")
+    flag.PrintDefaults()
+}
 
-    assetPairsList := util.ReadCsvFile(configPath + "/assetpairs.csv")
-    if assetPairsList[0][0] != "canonical" || assetPairsList[0][1] != "ISO4217" {
-        log.Fatalln("Labels must be \"canonical,ISO4217,...\"")
+func main() {
+    configPath := flag.String("config", config.DefaultPath, "TOML configuration")
+    filtersPath := flag.String("filters", config.DefaultFiltersPath, "TOML filters of enabled exchanges and asset pairs")
+    flag.Usage = usage
+    flag.Parse()
+
+    if flag.NArg() == 0 {
+        usage()
+        os.Exit(2)
     }
-
-    exchangeIndices := make(map[string]uint)
-    assetPairTranslators := make(map[string]types.AssetPairTranslator)
-    for i, exchangeName := range assetPairsList[0][1:] {
-        exchangeIndices[exchangeName] = i + 1
-        assetPairTranslators[exchangeName] := make(types.AssetPairTranslator)
+    command, ok := commands[flag.Arg(0)]
+    if !ok {
+        fmt.Fprintf(os.Stderr, "unknown command %v\n\n", flag.Arg(0))
+        usage()
+        os.Exit(2)
     }
 
-    assetPairCanonicalTranslator := make(types.AssetPairTranslator)
-    zippedAssetPairsList := util.Zip(assetPairsList...)
-    for i, assetPairCanonical := range zippedAssetPairsList[0][1:] {
-        assetPairCanonicalTranslator[i + 1] = assetPairCanonical
-        for exchangeName, translator := range assetPairTranslators {
-            assetPairSpecific := zippedAssetPairsList[exchangeIndices[exchangeName]][i + 1]
-            if assetPairSpecific != "" {
-                translator[i + 1] = assetPairSpecific
-            }
-        }
+    cfg, err := config.Load(*configPath, *filtersPath)
+    if err != nil {
+        // one line per problem reads better than a log record
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
     }
-
-    exchanges := make([]*Exchange, len(allowedExchanges))
-    for i, exchangeName := range allowedExchanges {
-        apiKey := exchangeInfo[exchangeName][1]
-        secretKeyEnvVar := strings.ToUpper(exchangeName) + secretKeySuffix
-        secretKey := os.Getenv(secretKeyEnvVar)
-        if secretKey == "" {
-            log.Fatalln("Secret key not provided for %v (searching for %v)", exchangeName, secretKeyEnvVar)
-        }
-        switch exchangeName {
-        case "BinanceUS":
-            exchanges[i] = binanceus.NewBinanceUS(apiKey, secretKey, assetPairTranslators["BinanceUS"])
-        case "Kraken":
-            exchanges[i] = kraken.NewKraken(apiKey, secretKey, assetPairTranslators["Kraken"], assetPairTranslators["ISO4217"])
-        case "KuCoin":
-            apiPassphrase := os.Getenv("KUCOIN_API_PASSPHRASE")
-            if apiPassphrase = "" {
-                log.Fatalln("KuCoin API Passphrase not provided")
-            }
-            exchanges[i] = kucoin.NewKuCoin(apiKey, secretKey, apiPassphrase, assetPairTranslators["KuCoin"])
-        default:
-            log.Fatalln("Exchange implementation not found for %v", exchangeName)
-        }
+    // schemas are registered by the features package, which config does not import
+    if _, err := features.LookupSchema(cfg.FeatureSchema()); err != nil {
+        fmt.Fprintf(os.Stderr, "%v: features.schema: %v\n", *configPath, err)
+        os.Exit(1)
     }
-
-    // run algo
-
-    killerInstinct := nn.NewKillerInstinct()
-
-    for exchangePair := range util.ExchangeCombinations(exchanges, 2) {
-        commonAssetPairs := util.AssetPairIntersection(
-            assetPairTranslators[exchangePair[0].String()].GetAssetPairs(),
-            assetPairTranslators[exchangePair[1].String()].GetAssetPairs()
-        )
-
-        // start go routines and predictions here
-        go grizzly(exchangePair[0], exchangePair[1], commonAssetPairs)
+    configureLogging(cfg)
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    go func() {
+        // a second signal kills the process while it is shutting down
+        <-ctx.Done()
+        stop()
+    }()
+    err = command.run(ctx, cfg, flag.Args()[1:])
+    stop()
+    if err != nil {
+        logger.Fatal("command failed", "command", flag.Arg(0), logging.Err(err))
     }
 }
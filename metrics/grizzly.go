@@ -0,0 +1,121 @@
+package metrics
+
+import (
+    "sync"
+    "time"
+
+    "github.com/denali-capital/grizzly/types"
+)
+
+// recorder is "spread" or "order_book"
+var WebSocketMessages *CounterVec = NewCounterVec("grizzly_websocket_messages_total", "WebSocket messages received per recorder and asset pair.", "exchange", "recorder", "asset_pair")
+var SpreadAge *AgeVec = NewAgeVec("grizzly_spread_age_seconds", "Seconds since the last spread update.", "exchange", "asset_pair")
+var OrderBookAge *AgeVec = NewAgeVec("grizzly_order_book_age_seconds", "Seconds since the last order book update.", "exchange", "asset_pair")
+var ChecksumFailures *CounterVec = NewCounterVec("grizzly_order_book_checksum_failures_total", "Order book updates whose checksum did not match the exchange's.", "exchange", "asset_pair")
+var UpdateIdGaps *CounterVec = NewCounterVec("grizzly_order_book_update_id_gaps_total", "Order book diffs that did not follow the last update id and forced a snapshot.", "exchange", "asset_pair")
+
+var LatencyEstimate *GaugeVec = NewGaugeVec("grizzly_latency_estimate_seconds", "EwmaEstimator round trip latency estimate.", "exchange")
+// endpoint is host and path, without the query
+var RestErrors *CounterVec = NewCounterVec("grizzly_rest_errors_total", "REST requests that failed or returned an API error.", "endpoint")
+
+// event is "submitted", "filled" or "canceled"
+var Orders *CounterVec = NewCounterVec("grizzly_orders_total", "Orders by lifecycle event.", "exchange", "event")
+
+var ExpectedPnl *GaugeVec = NewGaugeVec("grizzly_expected_pnl", "Expected profit of every executed opportunity, in the opportunity's asset.", "asset")
+var Balance *GaugeVec = NewGaugeVec("grizzly_balance", "Last polled balance.", "exchange", "asset")
+var Pnl *GaugeVec = NewGaugeVec("grizzly_pnl", "Change in balance since the first poll.", "exchange", "asset")
+
+// model is "live" or "shadow"
+var ModelLogLoss *GaugeVec = NewGaugeVec("grizzly_model_log_loss", "Validation log-loss in the last training round.", "model")
+var ModelExpectedPnl *GaugeVec = NewGaugeVec("grizzly_model_expected_pnl", "Validation profit of trading every observation predicted above the threshold in the last training round.", "model")
+var ModelPromotions *CounterVec = NewCounterVec("grizzly_model_promotions_total", "Shadow models promoted to live by the trainer.")
+// result is loaded, rejected or rolled_back
+var ModelReloads *CounterVec = NewCounterVec("grizzly_model_reloads_total", "Model versions loaded from, rejected from or rolled back to in the model store.", "result")
+var PredictionBatches *CounterVec = NewCounterVec("grizzly_prediction_batches_total", "Batches of observations predicted in one call to the model.")
+var PredictionBatchObservations *CounterVec = NewCounterVec("grizzly_prediction_batch_observations_total", "Observations predicted in batches.")
+var PredictionBatchSize *GaugeVec = NewGaugeVec("grizzly_prediction_batch_size", "Observations in the last predicted batch.")
+var PredictionRequests *CounterVec = NewCounterVec("grizzly_prediction_requests_total", "Prediction requests batched, one per exchange pair and round.")
+var PredictionQueueSeconds *CounterVec = NewCounterVec("grizzly_prediction_queue_seconds_total", "Time prediction requests waited for their batch to be predicted.")
+var PredictionQueueDelay *GaugeVec = NewGaugeVec("grizzly_prediction_queue_delay_seconds", "Longest time a request in the last predicted batch waited for it.")
+// variable is a feature name or "output"
+var DriftPSI *GaugeVec = NewGaugeVec("grizzly_drift_psi", "Population stability index of the live window against the model version's reference.", "variable")
+var DriftKS *GaugeVec = NewGaugeVec("grizzly_drift_ks", "Kolmogorov-Smirnov statistic of the live window against the model version's reference.", "variable")
+var DriftAlert *GaugeVec = NewGaugeVec("grizzly_drift_alert", "1 while any feature or the model output has drifted past its threshold.")
+// decision is "trade" or "pass"
+var Decisions *CounterVec = NewCounterVec("grizzly_decisions_total", "Opportunities the expected value rule traded or passed on.", "exchange_pair", "decision")
+
+// exchange/order id -> struct{}, orders submitted and not yet seen finished, so that repeated
+// status polls count a fill or cancel once and finished orders are forgotten
+var openOrders *sync.Map = &sync.Map{}
+
+func orderKey(exchange string, orderId types.OrderId) string {
+    return exchange + "/" + string(orderId)
+}
+
+func finishOrder(exchange string, orderId types.OrderId, event string) {
+    if _, loaded := openOrders.LoadAndDelete(orderKey(exchange, orderId)); loaded {
+        Orders.Inc(exchange, event)
+    }
+}
+
+func ObserveSubmitted(exchange string, orderIds map[types.Order]types.OrderId) {
+    for _, orderId := range orderIds {
+        openOrders.Store(orderKey(exchange, orderId), struct{}{})
+    }
+    Orders.Add(float64(len(orderIds)), exchange, "submitted")
+}
+
+// counts each submitted order the first time it is seen Filled, Canceled or Expired
+func ObserveOrderStatuses(exchange string, orderStatuses map[types.OrderId]types.OrderStatus) {
+    for orderId, orderStatus := range orderStatuses {
+        switch orderStatus.Status {
+        case types.Filled:
+            finishOrder(exchange, orderId, "filled")
+        case types.Canceled, types.Expired:
+            finishOrder(exchange, orderId, "canceled")
+        }
+    }
+}
+
+func ObserveCanceled(exchange string, orderIds []types.OrderId) {
+    for _, orderId := range orderIds {
+        finishOrder(exchange, orderId, "canceled")
+    }
+}
+
+func ObserveLatency(exchange string, estimate time.Duration) {
+    LatencyEstimate.Set(estimate.Seconds(), exchange)
+}
+
+func ObserveTraining(model string, logLoss, expectedPnl float64) {
+    ModelLogLoss.Set(logLoss, model)
+    ModelExpectedPnl.Set(expectedPnl, model)
+}
+
+func ObservePredictionBatch(observations int, delays []time.Duration) {
+    PredictionBatches.Inc()
+    PredictionBatchObservations.Add(float64(observations))
+    PredictionBatchSize.Set(float64(observations))
+    PredictionRequests.Add(float64(len(delays)))
+    longest := time.Duration(0)
+    for _, delay := range delays {
+        PredictionQueueSeconds.Add(delay.Seconds())
+        if delay > longest {
+            longest = delay
+        }
+    }
+    PredictionQueueDelay.Set(longest.Seconds())
+}
+
+func ObserveDrift(variable string, psi, ks float64) {
+    DriftPSI.Set(psi, variable)
+    DriftKS.Set(ks, variable)
+}
+
+func ObserveDrifted(drifted bool) {
+    if drifted {
+        DriftAlert.Set(1)
+    } else {
+        DriftAlert.Set(0)
+    }
+}
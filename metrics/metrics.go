@@ -0,0 +1,260 @@
+package metrics
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "math"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/denali-capital/grizzly/logging"
+)
+
+var logger *logging.Logger = logging.New("metrics")
+
+// text exposition format understood by Prometheus
+// docs: https://prometheus.io/docs/instrumenting/exposition_formats
+const ContentType string = "text/plain; version=0.0.4; charset=utf-8"
+
+type series struct {
+    labelValues []string
+    value       float64
+    // for ages, the value is computed from this at scrape time
+    updated     time.Time
+}
+
+// a metric family: one name, any number of label combinations
+type vec struct {
+    name       string
+    help       string
+    kind       string
+    labelNames []string
+    mutex      sync.Mutex
+    // joined label values -> series
+    series     map[string]*series
+}
+
+func newVec(name, help, kind string, labelNames []string) *vec {
+    return &vec{
+        name: name,
+        help: help,
+        kind: kind,
+        labelNames: labelNames,
+        series: make(map[string]*series),
+    }
+}
+
+// callers must hold the lock
+func (v *vec) get(labelValues []string) *series {
+    if len(labelValues) != len(v.labelNames) {
+        logger.Fatal("wrong number of label values", "metric", v.name, "labels", v.labelNames, "values", labelValues)
+    }
+    key := strings.Join(labelValues, "\xff")
+    s, ok := v.series[key]
+    if !ok {
+        s = &series{
+            labelValues: append([]string{}, labelValues...),
+        }
+        v.series[key] = s
+    }
+    return s
+}
+
+func (v *vec) add(delta float64, labelValues []string) {
+    v.mutex.Lock()
+    defer v.mutex.Unlock()
+    v.get(labelValues).value += delta
+}
+
+func (v *vec) set(value float64, labelValues []string) {
+    v.mutex.Lock()
+    defer v.mutex.Unlock()
+    v.get(labelValues).value = value
+}
+
+func (v *vec) value(labelValues []string) float64 {
+    v.mutex.Lock()
+    defer v.mutex.Unlock()
+    return v.get(labelValues).value
+}
+
+var helpEscaper *strings.Replacer = strings.NewReplacer("\\", "\\\\", "\n", "\\n")
+var labelValueEscaper *strings.Replacer = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\"", "\\\"")
+
+func formatValue(value float64) string {
+    switch {
+    case math.IsInf(value, 1):
+        return "+Inf"
+    case math.IsInf(value, -1):
+        return "-Inf"
+    case math.IsNaN(value):
+        return "NaN"
+    }
+    return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func (v *vec) write(w io.Writer, now time.Time) {
+    v.mutex.Lock()
+    defer v.mutex.Unlock()
+
+    fmt.Fprintf(w, "# HELP %v %v\n", v.name, helpEscaper.Replace(v.help))
+    kind := v.kind
+    if kind == "age" {
+        kind = "gauge"
+    }
+    fmt.Fprintf(w, "# TYPE %v %v\n", v.name, kind)
+
+    keys := make([]string, 0, len(v.series))
+    for key := range v.series {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        s := v.series[key]
+        value := s.value
+        if v.kind == "age" {
+            value = now.Sub(s.updated).Seconds()
+        }
+        if len(s.labelValues) == 0 {
+            fmt.Fprintf(w, "%v %v\n", v.name, formatValue(value))
+            continue
+        }
+        labels := make([]string, len(s.labelValues))
+        for i, labelValue := range s.labelValues {
+            labels[i] = v.labelNames[i] + "=\"" + labelValueEscaper.Replace(labelValue) + "\""
+        }
+        fmt.Fprintf(w, "%v{%v} %v\n", v.name, strings.Join(labels, ","), formatValue(value))
+    }
+}
+
+// only ever goes up
+type CounterVec struct {
+    *vec
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+    c.add(1, labelValues)
+}
+
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+    if delta < 0 {
+        logger.Fatal("counters cannot decrease", "metric", c.name, "delta", delta)
+    }
+    c.add(delta, labelValues)
+}
+
+func (c *CounterVec) Value(labelValues ...string) float64 {
+    return c.value(labelValues)
+}
+
+type GaugeVec struct {
+    *vec
+}
+
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+    g.set(value, labelValues)
+}
+
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+    g.add(delta, labelValues)
+}
+
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+    return g.value(labelValues)
+}
+
+// gauge of the seconds since each series was last touched
+type AgeVec struct {
+    *vec
+}
+
+func (a *AgeVec) Touch(labelValues ...string) {
+    a.mutex.Lock()
+    defer a.mutex.Unlock()
+    a.get(labelValues).updated = time.Now()
+}
+
+type Registry struct {
+    mutex sync.Mutex
+    vecs  map[string]*vec
+}
+
+func NewRegistry() *Registry {
+    return &Registry{
+        vecs: make(map[string]*vec),
+    }
+}
+
+func (r *Registry) register(v *vec) {
+    r.mutex.Lock()
+    defer r.mutex.Unlock()
+    if _, ok := r.vecs[v.name]; ok {
+        logger.Fatal("metric is already registered", "metric", v.name)
+    }
+    r.vecs[v.name] = v
+}
+
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+    v := newVec(name, help, "counter", labelNames)
+    r.register(v)
+    return &CounterVec{v}
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+    v := newVec(name, help, "gauge", labelNames)
+    r.register(v)
+    return &GaugeVec{v}
+}
+
+func (r *Registry) NewAgeVec(name, help string, labelNames ...string) *AgeVec {
+    v := newVec(name, help, "age", labelNames)
+    r.register(v)
+    return &AgeVec{v}
+}
+
+// every registered family in name order
+func (r *Registry) Write(w io.Writer) error {
+    r.mutex.Lock()
+    names := make([]string, 0, len(r.vecs))
+    for name := range r.vecs {
+        names = append(names, name)
+    }
+    r.mutex.Unlock()
+    sort.Strings(names)
+
+    buffer := bufio.NewWriter(w)
+    now := time.Now()
+    for _, name := range names {
+        r.mutex.Lock()
+        v := r.vecs[name]
+        r.mutex.Unlock()
+        v.write(buffer, now)
+    }
+    return buffer.Flush()
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+    w.Header().Set("Content-Type", ContentType)
+    if err := r.Write(w); err != nil {
+        logger.Warn("writing metrics failed", logging.Err(err))
+    }
+}
+
+var DefaultRegistry *Registry = NewRegistry()
+
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+    return DefaultRegistry.NewCounterVec(name, help, labelNames...)
+}
+
+func NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+    return DefaultRegistry.NewGaugeVec(name, help, labelNames...)
+}
+
+func NewAgeVec(name, help string, labelNames ...string) *AgeVec {
+    return DefaultRegistry.NewAgeVec(name, help, labelNames...)
+}
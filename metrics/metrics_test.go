@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+func TestWrite(t *testing.T) {
+	registry := NewRegistry()
+	messages := registry.NewCounterVec("test_messages_total", "Messages.\nSecond line.", "exchange", "asset_pair")
+	latency := registry.NewGaugeVec("test_latency_seconds", "Latency.", "exchange")
+	age := registry.NewAgeVec("test_age_seconds", "Age.", "exchange")
+
+	messages.Inc("Kraken", "BTCUSD")
+	messages.Add(2, "Kraken", "BTCUSD")
+	messages.Inc("Kraken", "ETH\"USD")
+	latency.Set(0.25, "KuCoin")
+	age.Touch("BinanceUS")
+
+	buffer := &bytes.Buffer{}
+	if err := registry.Write(buffer); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	output := buffer.String()
+	for _, expected := range []string{
+		"# HELP test_messages_total Messages.\\nSecond line.\n# TYPE test_messages_total counter\n",
+		"test_messages_total{exchange=\"Kraken\",asset_pair=\"BTCUSD\"} 3\n",
+		"test_messages_total{exchange=\"Kraken\",asset_pair=\"ETH\\\"USD\"} 1\n",
+		"# TYPE test_latency_seconds gauge\ntest_latency_seconds{exchange=\"KuCoin\"} 0.25\n",
+		"# TYPE test_age_seconds gauge\ntest_age_seconds{exchange=\"BinanceUS\"} ",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in:\n%v\n", expected, output)
+		}
+	}
+	// families are written in name order
+	if strings.Index(output, "test_age_seconds") > strings.Index(output, "test_latency_seconds") {
+		t.Fatalf("families out of order:\n%v\n", output)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGaugeVec("test_gauge", "Gauge.").Set(1)
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if contentType := recorder.Header().Get("Content-Type"); contentType != ContentType {
+		t.Fatalf("expected content type %v, got %v\n", ContentType, contentType)
+	}
+	if body := recorder.Body.String(); !strings.Contains(body, "\ntest_gauge 1\n") {
+		t.Fatalf("unexpected body:\n%v\n", body)
+	}
+}
+
+func TestObserveOrderStatuses(t *testing.T) {
+	statuses := map[types.OrderId]types.OrderStatus{
+		"1": types.OrderStatus{Status: types.Filled},
+		"2": types.OrderStatus{Status: types.Unfilled},
+		"3": types.OrderStatus{Status: types.Expired},
+	}
+	ObserveSubmitted("TestExchange", map[types.Order]types.OrderId{
+		{Quantity: decimal.NewFromInt(1)}: "1",
+		{Quantity: decimal.NewFromInt(2)}: "2",
+		{Quantity: decimal.NewFromInt(3)}: "3",
+	})
+	// polling the same statuses again must not count them twice
+	ObserveOrderStatuses("TestExchange", statuses)
+	ObserveOrderStatuses("TestExchange", statuses)
+	ObserveCanceled("TestExchange", []types.OrderId{"1", "2"})
+
+	// nor count orders it never submitted
+	ObserveCanceled("TestExchange", []types.OrderId{"4"})
+
+	for event, expected := range map[string]float64{"submitted": 3, "filled": 1, "canceled": 2} {
+		if value := Orders.Value("TestExchange", event); value != expected {
+			t.Fatalf("expected %v %v orders, got %v\n", expected, event, value)
+		}
+	}
+	openOrders.Range(func(key, value any) bool {
+		t.Fatalf("expected finished orders to be forgotten, got %v\n", key)
+		return false
+	})
+}
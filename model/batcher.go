@@ -0,0 +1,107 @@
+package model
+
+import (
+    "context"
+    "time"
+
+    "github.com/denali-capital/grizzly/metrics"
+    "github.com/denali-capital/grizzly/types"
+)
+
+type BatcherOptions struct {
+    // how long the first request of a batch waits for others to join it
+    Window   time.Duration
+    // observations at which a batch is predicted without waiting out Window
+    MaxBatch int
+}
+
+type request struct {
+    observations []types.Observation
+    enqueued     time.Time
+    predictions  chan []float32
+}
+
+// a Model that predicts the observations of every caller within Window in one call to model,
+// so that exchange pairs share one batch rather than taking turns on the model's lock
+type Batcher struct {
+    model    Model
+    options  BatcherOptions
+    requests chan request
+    done     chan struct{}
+}
+
+func NewBatcher(model Model, options BatcherOptions) *Batcher {
+    return &Batcher{
+        model: model,
+        options: options,
+        requests: make(chan request),
+        done: make(chan struct{}),
+    }
+}
+
+// blocks until the batch the observations joined is predicted; nil once Run has returned
+func (b *Batcher) Predict(observations []types.Observation) []float32 {
+    if len(observations) == 0 {
+        return []float32{}
+    }
+    r := request{observations: observations, enqueued: time.Now(), predictions: make(chan []float32, 1)}
+    select {
+    case b.requests <- r:
+    case <-b.done:
+        return nil
+    }
+    return <-r.predictions
+}
+
+// collects a batch starting with first until Window has passed since it arrived or MaxBatch
+// observations are in
+func (b *Batcher) collect(ctx context.Context, first request) []request {
+    batch := []request{first}
+    size := len(first.observations)
+    deadline := time.NewTimer(b.options.Window)
+    defer deadline.Stop()
+    for size < b.options.MaxBatch {
+        select {
+        case <-ctx.Done():
+            return batch
+        case <-deadline.C:
+            return batch
+        case r := <-b.requests:
+            batch = append(batch, r)
+            size += len(r.observations)
+        }
+    }
+    return batch
+}
+
+func (b *Batcher) predict(batch []request) {
+    observations := make([]types.Observation, 0, len(batch[0].observations))
+    for _, r := range batch {
+        observations = append(observations, r.observations...)
+    }
+    start := time.Now()
+    delays := make([]time.Duration, len(batch))
+    for i, r := range batch {
+        delays[i] = start.Sub(r.enqueued)
+    }
+    predictions := b.model.Predict(observations)
+    metrics.ObservePredictionBatch(len(observations), delays)
+
+    for _, r := range batch {
+        r.predictions <- predictions[:len(r.observations):len(r.observations)]
+        predictions = predictions[len(r.observations):]
+    }
+}
+
+// predicts batches until ctx is canceled
+func (b *Batcher) Run(ctx context.Context) {
+    defer close(b.done)
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case first := <-b.requests:
+            b.predict(b.collect(ctx, first))
+        }
+    }
+}
@@ -0,0 +1,188 @@
+package model
+
+import (
+    "fmt"
+    "math"
+    "sort"
+)
+
+// sigmoid(-(A * logit(p) + B)), fitted the way Platt scaling fits SVM scores, on the
+// model's logits; A is negative for a model that ranks at all
+type Platt struct {
+    A float64 `json:"a"`
+    B float64 `json:"b"`
+}
+
+func logit(p float32) float64 {
+    clipped := math.Min(math.Max(float64(p), epsilon), 1 - epsilon)
+    return math.Log(clipped / (1 - clipped))
+}
+
+func (p Platt) Calibrate(probability float32) float32 {
+    return float32(1 / (1 + math.Exp(p.A * logit(probability) + p.B)))
+}
+
+// negative log-likelihood of targets under A and B
+func plattLoss(scores, targets []float64, a, b float64) float64 {
+    loss := 0.0
+    for i, score := range scores {
+        if z := score * a + b; z >= 0 {
+            loss += targets[i] * z + math.Log1p(math.Exp(-z))
+        } else {
+            loss += (targets[i] - 1) * z + math.Log1p(math.Exp(z))
+        }
+    }
+    return loss
+}
+
+// Newton's method with backtracking, after Lin, Lin and Weng's note on Platt's probabilistic
+// outputs; labels are smoothed towards 0.5 so that separable data still gives finite A and B
+func FitPlatt(predictions []float32, labels []int32) Platt {
+    positives, negatives := 0.0, 0.0
+    for _, label := range labels {
+        if label == 1 {
+            positives++
+        } else {
+            negatives++
+        }
+    }
+    scores := make([]float64, len(predictions))
+    targets := make([]float64, len(predictions))
+    for i, prediction := range predictions {
+        scores[i] = logit(prediction)
+        targets[i] = 1 / (negatives + 2)
+        if labels[i] == 1 {
+            targets[i] = (positives + 1) / (positives + 2)
+        }
+    }
+
+    a, b := 0.0, math.Log((negatives + 1) / (positives + 1))
+    loss := plattLoss(scores, targets, a, b)
+    for iteration := 0; iteration < 100; iteration++ {
+        // gradient and Hessian, regularized so that it always inverts
+        h11, h22, h21, g1, g2 := 1e-12, 1e-12, 0.0, 0.0, 0.0
+        for i, score := range scores {
+            p := 1 / (1 + math.Exp(score * a + b))
+            h11 += score * score * p * (1 - p)
+            h22 += p * (1 - p)
+            h21 += score * p * (1 - p)
+            g1 += score * (targets[i] - p)
+            g2 += targets[i] - p
+        }
+        if math.Abs(g1) < 1e-5 && math.Abs(g2) < 1e-5 {
+            break
+        }
+        determinant := h11 * h22 - h21 * h21
+        da := -(h22 * g1 - h21 * g2) / determinant
+        db := -(-h21 * g1 + h11 * g2) / determinant
+        descent := g1 * da + g2 * db
+
+        step := 1.0
+        for ; step >= 1e-10; step /= 2 {
+            if next := plattLoss(scores, targets, a + step * da, b + step * db); next < loss + 1e-4 * step * descent {
+                a, b, loss = a + step * da, b + step * db, next
+                break
+            }
+        }
+        if step < 1e-10 {
+            break
+        }
+    }
+    return Platt{A: a, B: b}
+}
+
+// the fraction of profitable observations, non-decreasing in the model's probability: piecewise
+// linear through the points, and flat beyond the first and last
+type Isotonic struct {
+    X []float64 `json:"x"`
+    Y []float64 `json:"y"`
+}
+
+func (i Isotonic) Calibrate(probability float32) float32 {
+    if len(i.X) == 0 {
+        return probability
+    }
+    x := float64(probability)
+    upper := sort.SearchFloat64s(i.X, x)
+    switch {
+    case upper == 0:
+        return float32(i.Y[0])
+    case upper == len(i.X):
+        return float32(i.Y[len(i.Y) - 1])
+    }
+    lower := upper - 1
+    return float32(i.Y[lower] + (i.Y[upper] - i.Y[lower]) * (x - i.X[lower]) / (i.X[upper] - i.X[lower]))
+}
+
+// pool adjacent violators over the predictions in order, each pooled block becoming a point
+// at its mean prediction and mean label
+func FitIsotonic(predictions []float32, labels []int32) Isotonic {
+    order := make([]int, len(predictions))
+    for i := range order {
+        order[i] = i
+    }
+    sort.SliceStable(order, func(i, j int) bool {
+        return predictions[order[i]] < predictions[order[j]]
+    })
+
+    type block struct {
+        x, y, weight float64
+    }
+    blocks := make([]block, 0, len(order))
+    for _, i := range order {
+        blocks = append(blocks, block{float64(predictions[i]), float64(labels[i]), 1})
+        // equal predictions must calibrate equally, so they are pooled whatever their labels
+        for n := len(blocks); n > 1 && (blocks[n - 2].y >= blocks[n - 1].y || blocks[n - 2].x == blocks[n - 1].x); n = len(blocks) {
+            last, previous := blocks[n - 1], blocks[n - 2]
+            weight := previous.weight + last.weight
+            blocks[n - 2] = block{
+                (previous.x * previous.weight + last.x * last.weight) / weight,
+                (previous.y * previous.weight + last.y * last.weight) / weight,
+                weight,
+            }
+            blocks = blocks[:n - 1]
+        }
+    }
+
+    isotonic := Isotonic{X: make([]float64, len(blocks)), Y: make([]float64, len(blocks))}
+    for i, b := range blocks {
+        isotonic.X[i], isotonic.Y[i] = b.x, b.y
+    }
+    return isotonic
+}
+
+// how a version's probabilities are calibrated, at most one method is set; a nil Calibration
+// leaves probabilities as they are
+type Calibration struct {
+    Platt    *Platt    `json:"platt,omitempty"`
+    Isotonic *Isotonic `json:"isotonic,omitempty"`
+}
+
+func (c *Calibration) Calibrate(probability float32) float32 {
+    switch {
+    // a model that could not predict, which no mapping should turn into a probability
+    case c == nil || math.IsNaN(float64(probability)):
+        return probability
+    case c.Platt != nil:
+        return c.Platt.Calibrate(probability)
+    case c.Isotonic != nil:
+        return c.Isotonic.Calibrate(probability)
+    }
+    return probability
+}
+
+// fits method, platt, isotonic or none, to the model's predictions of labeled observations;
+// nil for none or ""
+func Calibrate(method string, predictions []float32, labels []int32) (*Calibration, error) {
+    switch method {
+    case "", "none":
+        return nil, nil
+    case "platt":
+        platt := FitPlatt(predictions, labels)
+        return &Calibration{Platt: &platt}, nil
+    case "isotonic":
+        isotonic := FitIsotonic(predictions, labels)
+        return &Calibration{Isotonic: &isotonic}, nil
+    }
+    return nil, fmt.Errorf("unknown calibration method %q, must be one of none, platt or isotonic", method)
+}
@@ -0,0 +1,333 @@
+package dense
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "os"
+    "path/filepath"
+    "sync"
+
+    "github.com/denali-capital/grizzly/features"
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/model"
+    "github.com/denali-capital/grizzly/types"
+)
+
+var logger *logging.Logger = logging.New("dense")
+
+// what model/nn/definition/export.py writes next to the SavedModel
+const WeightsFile = "weights.json"
+
+// a Keras Dense layer, activation(inputs · Kernel + Bias)
+type Layer struct {
+    // inputs x units
+    Kernel     [][]float32 `json:"kernel"`
+    Bias       []float32   `json:"bias"`
+    // relu, sigmoid or linear
+    Activation string      `json:"activation"`
+    // Adam's first and second moment estimates, zero when not exported
+    KernelM    [][]float32 `json:"kernel_m,omitempty"`
+    KernelV    [][]float32 `json:"kernel_v,omitempty"`
+    BiasM      []float32   `json:"bias_m,omitempty"`
+    BiasV      []float32   `json:"bias_v,omitempty"`
+}
+
+// Keras' Adam, without amsgrad
+type Adam struct {
+    LearningRate float64 `json:"learning_rate"`
+    Beta1        float64 `json:"beta_1"`
+    Beta2        float64 `json:"beta_2"`
+    Epsilon      float64 `json:"epsilon"`
+    Iterations   int64   `json:"iterations"`
+}
+
+type Weights struct {
+    // the features.Schema the first layer's inputs follow
+    Schema    string  `json:"schema"`
+    Layers    []Layer `json:"layers"`
+    Optimizer Adam    `json:"optimizer"`
+}
+
+// a dense network evaluated in Go, so that predicting does not need the TensorFlow C library;
+// the last layer is a single sigmoid unit, trained on binary cross-entropy
+type Network struct {
+    sync.RWMutex
+    weights Weights
+}
+
+func zeros(rows, columns int) [][]float32 {
+    matrix := make([][]float32, rows)
+    for i := range matrix {
+        matrix[i] = make([]float32, columns)
+    }
+    return matrix
+}
+
+func New(weights Weights) (*Network, error) {
+    schema, err := features.LookupSchema(weights.Schema)
+    if err != nil {
+        return nil, err
+    }
+    if len(weights.Layers) == 0 {
+        return nil, fmt.Errorf("no layers")
+    }
+    inputs := len(schema.Features)
+    for i := range weights.Layers {
+        layer := &weights.Layers[i]
+        units := len(layer.Bias)
+        if len(layer.Kernel) != inputs {
+            return nil, fmt.Errorf("layer %v: expected %v inputs, got %v", i, inputs, len(layer.Kernel))
+        }
+        for _, row := range layer.Kernel {
+            if len(row) != units {
+                return nil, fmt.Errorf("layer %v: expected %v units in every kernel row, got %v", i, units, len(row))
+            }
+        }
+        switch layer.Activation {
+        case "relu", "sigmoid", "linear":
+        default:
+            return nil, fmt.Errorf("layer %v: unknown activation %q", i, layer.Activation)
+        }
+        if layer.KernelM == nil {
+            layer.KernelM, layer.KernelV = zeros(inputs, units), zeros(inputs, units)
+            layer.BiasM, layer.BiasV = make([]float32, units), make([]float32, units)
+        }
+        inputs = units
+    }
+    if last := weights.Layers[len(weights.Layers) - 1]; len(last.Bias) != 1 || last.Activation != "sigmoid" {
+        return nil, fmt.Errorf("expected a single sigmoid unit last, got %v %v", len(last.Bias), last.Activation)
+    }
+    if weights.Optimizer.LearningRate <= 0 {
+        return nil, fmt.Errorf("learning rate must be positive, got %v", weights.Optimizer.LearningRate)
+    }
+    return &Network{weights: weights}, nil
+}
+
+// a version from a model.Store, or the weights export.py wrote next to KillerInstinct
+func Load(directory string) (model.Learner, error) {
+    data, err := os.ReadFile(filepath.Join(directory, WeightsFile))
+    if err != nil {
+        return nil, err
+    }
+    var weights Weights
+    if err := json.Unmarshal(data, &weights); err != nil {
+        return nil, fmt.Errorf("%v: %w", WeightsFile, err)
+    }
+    return New(weights)
+}
+
+// the schema of the observations the network reads, name/version
+func (n *Network) Schema() string {
+    n.RLock()
+    defer n.RUnlock()
+    return n.weights.Schema
+}
+
+// observations in one row-major batch; observations of any other schema than the network's
+// would be read positionally against the wrong layout, so they are refused
+func (n *Network) data(observations []types.Observation) ([]float32, error) {
+    inputs := len(n.weights.Layers[0].Kernel)
+    data := make([]float32, 0, len(observations) * inputs)
+    for _, observation := range observations {
+        if observation.Schema != n.weights.Schema || len(observation.Features) != inputs {
+            return nil, fmt.Errorf("expected %v observations, got %v with %v features", n.weights.Schema, observation.Schema, len(observation.Features))
+        }
+        data = append(data, observation.Features...)
+    }
+    return data, nil
+}
+
+func activate(activation string, z float32) float32 {
+    switch activation {
+    case "relu":
+        return float32(math.Max(float64(z), 0))
+    case "sigmoid":
+        return float32(1 / (1 + math.Exp(-float64(z))))
+    }
+    return z
+}
+
+// d activation / dz, from the activation's output
+func derivative(activation string, a float32) float32 {
+    switch activation {
+    case "relu":
+        if a > 0 {
+            return 1
+        }
+        return 0
+    case "sigmoid":
+        return a * (1 - a)
+    }
+    return 1
+}
+
+// every layer's inputs and the output, each row-major over the batch, and the output's
+// pre-activations
+func (n *Network) forward(data []float32, batch int) ([][]float32, []float32) {
+    activations := [][]float32{data}
+    var z []float32
+    for _, layer := range n.weights.Layers {
+        inputs, units := len(layer.Kernel), len(layer.Bias)
+        z = make([]float32, batch * units)
+        for b := 0; b < batch; b++ {
+            row := z[b * units:(b + 1) * units]
+            copy(row, layer.Bias)
+            for i, x := range data[b * inputs:(b + 1) * inputs] {
+                for j, w := range layer.Kernel[i] {
+                    row[j] += x * w
+                }
+            }
+        }
+        data = make([]float32, len(z))
+        for i := range z {
+            data[i] = activate(layer.Activation, z[i])
+        }
+        activations = append(activations, data)
+    }
+    return activations, z
+}
+
+// NaN for every observation when any is not of the network's schema
+func (n *Network) Predict(observations []types.Observation) []float32 {
+    n.RLock()
+    defer n.RUnlock()
+    data, err := n.data(observations)
+    if err != nil {
+        logger.Error("cannot predict", logging.Err(err))
+        predictions := make([]float32, len(observations))
+        for i := range predictions {
+            predictions[i] = float32(math.NaN())
+        }
+        return predictions
+    }
+    activations, _ := n.forward(data, len(observations))
+    return activations[len(activations) - 1]
+}
+
+// one Adam step of gradient g on parameter w with moments m and v
+func (a Adam) step(w, m, v *float32, g float32, rate float64) {
+    *m = float32(a.Beta1 * float64(*m) + (1 - a.Beta1) * float64(g))
+    *v = float32(a.Beta2 * float64(*v) + (1 - a.Beta2) * float64(g) * float64(g))
+    *w -= float32(rate * float64(*m) / (math.Sqrt(float64(*v)) + a.Epsilon))
+}
+
+// one step of Adam on the observations' mean binary cross-entropy, which it returns as it
+// was before the step, the way KillerInstinct's learn signature does; NaN without a step
+// when any observation is not of the network's schema or has a label other than 0 or 1
+func (n *Network) Learn(observations []types.Observation) float32 {
+    batch := len(observations)
+    labels := make([]float32, batch)
+    for i, observation := range observations {
+        if label := observation.Label; label != 0 && label != 1 {
+            logger.Error("cannot learn", logging.Err(fmt.Errorf("label must be one of {0, 1}, got %v", label)))
+            return float32(math.NaN())
+        }
+        labels[i] = float32(observation.Label)
+    }
+
+    n.Lock()
+    defer n.Unlock()
+    data, err := n.data(observations)
+    if err != nil {
+        logger.Error("cannot learn", logging.Err(err))
+        return float32(math.NaN())
+    }
+    activations, z := n.forward(data, batch)
+
+    // the sigmoid and cross-entropy together, on the logits: (p - y) / batch
+    loss := 0.0
+    delta := make([]float32, batch)
+    for b, p := range activations[len(activations) - 1] {
+        logit := float64(z[b])
+        loss += math.Max(logit, 0) - logit * float64(labels[b]) + math.Log1p(math.Exp(-math.Abs(logit)))
+        delta[b] = (p - labels[b]) / float32(batch)
+    }
+
+    adam := &n.weights.Optimizer
+    adam.Iterations++
+    t := float64(adam.Iterations)
+    rate := adam.LearningRate * math.Sqrt(1 - math.Pow(adam.Beta2, t)) / (1 - math.Pow(adam.Beta1, t))
+    for l := len(n.weights.Layers) - 1; l >= 0; l-- {
+        layer := &n.weights.Layers[l]
+        inputs, units := len(layer.Kernel), len(layer.Bias)
+        x := activations[l]
+
+        // the previous layer's delta, through the kernel before it is stepped
+        var previous []float32
+        if l > 0 {
+            activation := n.weights.Layers[l - 1].Activation
+            previous = make([]float32, batch * inputs)
+            for b := 0; b < batch; b++ {
+                for i := 0; i < inputs; i++ {
+                    var sum float32
+                    for j := 0; j < units; j++ {
+                        sum += delta[b * units + j] * layer.Kernel[i][j]
+                    }
+                    previous[b * inputs + i] = sum * derivative(activation, x[b * inputs + i])
+                }
+            }
+        }
+
+        for i := 0; i < inputs; i++ {
+            for j := 0; j < units; j++ {
+                var gradient float32
+                for b := 0; b < batch; b++ {
+                    gradient += x[b * inputs + i] * delta[b * units + j]
+                }
+                adam.step(&layer.Kernel[i][j], &layer.KernelM[i][j], &layer.KernelV[i][j], gradient, rate)
+            }
+        }
+        for j := 0; j < units; j++ {
+            var gradient float32
+            for b := 0; b < batch; b++ {
+                gradient += delta[b * units + j]
+            }
+            adam.step(&layer.Bias[j], &layer.BiasM[j], &layer.BiasV[j], gradient, rate)
+        }
+        delta = previous
+    }
+    return float32(loss / float64(batch))
+}
+
+// float32s survive a round trip through JSON exactly
+func (n *Network) Clone() (model.Learner, error) {
+    n.RLock()
+    data, err := json.Marshal(n.weights)
+    n.RUnlock()
+    if err != nil {
+        return nil, err
+    }
+    var weights Weights
+    if err := json.Unmarshal(data, &weights); err != nil {
+        return nil, err
+    }
+    return New(weights)
+}
+
+func (n *Network) Swap(other model.Learner) error {
+    clone, ok := other.(*Network)
+    if !ok {
+        return fmt.Errorf("expected a *Network, got %T", other)
+    }
+    clone.RLock()
+    defer clone.RUnlock()
+
+    n.Lock()
+    defer n.Unlock()
+    n.weights = clone.weights
+    return nil
+}
+
+func (n *Network) Save(directory string) error {
+    if err := os.MkdirAll(directory, 0755); err != nil {
+        return err
+    }
+    n.RLock()
+    data, err := json.MarshalIndent(n.weights, "", "  ")
+    n.RUnlock()
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(filepath.Join(directory, WeightsFile), data, 0644)
+}
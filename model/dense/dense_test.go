@@ -0,0 +1,179 @@
+package dense
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/denali-capital/grizzly/features"
+	"github.com/denali-capital/grizzly/types"
+)
+
+func observations(inputs [][]float32, labels ...int32) []types.Observation {
+	observations := make([]types.Observation, len(inputs))
+	for i, vector := range inputs {
+		observations[i] = types.Observation{Schema: features.Default.String(), Features: vector}
+		if i < len(labels) {
+			observations[i].Label = labels[i]
+		}
+	}
+	return observations
+}
+
+// predictions the SavedModel made of the same inputs, written by export.py
+func TestPredict(t *testing.T) {
+	network, err := Load("../nn/definition/KillerInstinct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("testdata/killerinstinct.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Inputs      [][]float32 `json:"inputs"`
+		Predictions []float32   `json:"predictions"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+
+	predictions := network.Predict(observations(fixture.Inputs))
+	if len(predictions) != len(fixture.Predictions) {
+		t.Fatalf("expected %v predictions, got %v", len(fixture.Predictions), len(predictions))
+	}
+	for i, prediction := range predictions {
+		if math.Abs(float64(prediction - fixture.Predictions[i])) > 1e-6 {
+			t.Errorf("input %v: expected %v, got %v", i, fixture.Predictions[i], prediction)
+		}
+	}
+}
+
+func network(t *testing.T) *Network {
+	kernel := make([][]float32, len(features.Default.Features))
+	for i := range kernel {
+		kernel[i] = []float32{0.1 * float32(i + 1), -0.05 * float32(i)}
+	}
+	network, err := New(Weights{
+		Schema: features.Default.String(),
+		Layers: []Layer{
+			{Kernel: kernel, Bias: []float32{0.1, 0.1}, Activation: "relu"},
+			{Kernel: [][]float32{{0.5}, {-0.5}}, Bias: []float32{0}, Activation: "sigmoid"},
+		},
+		Optimizer: Adam{LearningRate: 0.01, Beta1: 0.9, Beta2: 0.999, Epsilon: 1e-7},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return network
+}
+
+var inputs = [][]float32{
+	{1, 0, 0, 0, 0, 0, 1},
+	{0, 1, 0, 0, 0, 1, 0},
+	{0, 0, 1, 0, 1, 0, 0},
+	{0, 0, 0, 1, 0, 0, 0},
+}
+
+func TestLearn(t *testing.T) {
+	n := network(t)
+	before := n.Predict(observations(inputs))
+	bias := n.weights.Layers[1].Bias[0]
+
+	// every prediction is above 0.5 and every label is 0, so the output bias' gradient is positive
+	// and Adam's first step moves it by the learning rate
+	first := n.Learn(observations(inputs, 0, 0, 0, 0))
+	if step := bias - n.weights.Layers[1].Bias[0]; math.Abs(float64(step) - 0.01) > 1e-6 {
+		t.Fatalf("expected the output bias to step down by 0.01, got %v", step)
+	}
+	loss := 0.0
+	for _, p := range before {
+		loss -= math.Log(1 - float64(p))
+	}
+	if math.Abs(float64(first) - loss / 4) > 1e-6 {
+		t.Fatalf("expected a loss of %v, got %v", loss / 4, first)
+	}
+
+	last := first
+	for i := 0; i < 200; i++ {
+		last = n.Learn(observations(inputs, 0, 0, 1, 1))
+	}
+	predictions := n.Predict(observations(inputs))
+	if last >= first || predictions[0] >= 0.5 || predictions[1] >= 0.5 || predictions[2] <= 0.5 || predictions[3] <= 0.5 {
+		t.Fatalf("expected to separate the labels, got %v with a loss of %v", predictions, last)
+	}
+}
+
+func TestClone(t *testing.T) {
+	n := network(t)
+	before := n.Predict(observations(inputs))
+
+	clone, err := n.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		clone.Learn(observations(inputs, 1, 1, 1, 1))
+	}
+	if after := n.Predict(observations(inputs)); after[0] != before[0] {
+		t.Fatalf("expected learning on the clone to leave the original alone, got %v and %v", before, after)
+	}
+
+	directory := t.TempDir()
+	if err := clone.Save(directory); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Swap(loaded); err != nil {
+		t.Fatal(err)
+	}
+	expected, got := clone.Predict(observations(inputs)), n.Predict(observations(inputs))
+	for i := range expected {
+		if expected[i] != got[i] {
+			t.Fatalf("expected the saved clone's predictions %v, got %v", expected, got)
+		}
+	}
+	if n.weights.Optimizer.Iterations != 10 {
+		t.Fatalf("expected the optimizer's state to be saved, got %+v", n.weights.Optimizer)
+	}
+}
+
+func TestNew(t *testing.T) {
+	weights := network(t).weights
+	weights.Layers = weights.Layers[:1]
+	if _, err := New(weights); err == nil {
+		t.Fatal("expected a relu output to be refused")
+	}
+	weights = network(t).weights
+	weights.Schema = features.Extended.String()
+	if _, err := New(weights); err == nil {
+		t.Fatal("expected 7 inputs to be refused for 12 features")
+	}
+}
+
+func TestSchemaMismatch(t *testing.T) {
+	n := network(t)
+	if n.Schema() != features.Default.String() {
+		t.Fatalf("expected %v, got %v", features.Default, n.Schema())
+	}
+	mismatched := observations(inputs[:2])
+	mismatched[1].Schema = features.Extended.String()
+	predictions := n.Predict(mismatched)
+	if len(predictions) != 2 || !math.IsNaN(float64(predictions[0])) || !math.IsNaN(float64(predictions[1])) {
+		t.Fatalf("expected NaN for a batch with another schema, got %v", predictions)
+	}
+	if loss := n.Learn(mismatched); !math.IsNaN(float64(loss)) || n.weights.Optimizer.Iterations != 0 {
+		t.Fatalf("expected no step on another schema, got a loss of %v", loss)
+	}
+}
+
+func TestLabelRefused(t *testing.T) {
+	n := network(t)
+	if loss := n.Learn(observations(inputs[:2], 0, 2)); !math.IsNaN(float64(loss)) || n.weights.Optimizer.Iterations != 0 {
+		t.Fatalf("expected no step on a label of 2, got a loss of %v", loss)
+	}
+}
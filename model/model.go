@@ -0,0 +1,69 @@
+package model
+
+import (
+    "math"
+
+    "github.com/denali-capital/grizzly/types"
+)
+
+// probabilities that observations are profitable, implemented by nn.KillerInstinct
+type Model interface {
+    Predict(observations []types.Observation) []float32
+}
+
+// a model that can be trained online; every method is safe to call alongside Predict
+type Learner interface {
+    Model
+    // one training step on labeled observations, returns the loss
+    Learn(observations []types.Observation) float32
+    // an independent copy with the same weights, so that it can be trained without
+    // touching the live model
+    Clone() (Learner, error)
+    // takes other's weights, atomically with respect to Predict; other must come from Clone
+    // or the same implementation's loader
+    Swap(other Learner) error
+    // writes the model to directory so that it can be loaded again
+    Save(directory string) error
+}
+
+// implemented by models that only read observations of one schema
+type SchemaReader interface {
+    // name/version, e.g. grizzly/1
+    Schema() string
+}
+
+// how a model did on labeled observations
+type Evaluation struct {
+    Observations int     `json:"observations"`
+    // mean binary cross entropy
+    LogLoss      float64 `json:"log_loss"`
+    // Profit of every observation predicted at or above the threshold, what trading on
+    // the model's decisions would have made
+    ExpectedPnl  float64 `json:"expected_pnl"`
+    // observations predicted at or above the threshold
+    Trades       int     `json:"trades"`
+}
+
+// probabilities are clipped so that a confident miss costs a large but finite loss
+const epsilon float64 = 1e-7
+
+func Evaluate(model Model, observations []types.Observation, threshold float32) Evaluation {
+    evaluation := Evaluation{Observations: len(observations)}
+    if len(observations) == 0 {
+        return evaluation
+    }
+    for i, prediction := range model.Predict(observations) {
+        p := math.Min(math.Max(float64(prediction), epsilon), 1 - epsilon)
+        if observations[i].Label == 1 {
+            evaluation.LogLoss -= math.Log(p)
+        } else {
+            evaluation.LogLoss -= math.Log(1 - p)
+        }
+        if prediction >= threshold {
+            evaluation.ExpectedPnl += observations[i].Profit
+            evaluation.Trades++
+        }
+    }
+    evaluation.LogLoss /= float64(len(observations))
+    return evaluation
+}
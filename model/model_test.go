@@ -0,0 +1,414 @@
+package model
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/types"
+)
+
+// predicts the fraction of profitable observations it has learned
+type constant struct {
+	ones  int
+	total int
+	p     float32
+}
+
+func (c *constant) Predict(observations []types.Observation) []float32 {
+	predictions := make([]float32, len(observations))
+	for i := range predictions {
+		predictions[i] = c.p
+	}
+	return predictions
+}
+
+func (c *constant) Learn(observations []types.Observation) float32 {
+	for _, observation := range observations {
+		c.ones += int(observation.Label)
+		c.total++
+	}
+	c.p = float32(c.ones) / float32(c.total)
+	return 0
+}
+
+func (c *constant) Clone() (Learner, error) {
+	clone := *c
+	return &clone, nil
+}
+
+func (c *constant) Swap(other Learner) error {
+	*c = *other.(*constant)
+	return nil
+}
+
+func (c *constant) Save(directory string) error {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(directory, "p"), []byte{byte(c.p * 100)}, 0644)
+}
+
+func load(directory string) (Learner, error) {
+	p, err := os.ReadFile(filepath.Join(directory, "p"))
+	if err != nil {
+		return nil, err
+	}
+	return &constant{p: float32(p[0]) / 100}, nil
+}
+
+const schema string = "test/1"
+
+var start time.Time = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// one observation a minute, profitable when labeled 1
+func observations(labels ...int32) []types.Observation {
+	observations := make([]types.Observation, len(labels))
+	for i, label := range labels {
+		observations[i] = types.Observation{Schema: schema, Timestamp: start.Add(time.Duration(i) * time.Minute), Label: label, Profit: float64(label) - 0.5}
+	}
+	return observations
+}
+
+func trainer(live Learner, directory string) *Trainer {
+	return NewTrainer(NewStore(directory, schema, live, load), TrainerOptions{
+		Window: 2 * time.Minute,
+		MinObservations: 3,
+		Capacity: 100,
+		BatchSize: 2,
+		Epochs: 1,
+		Threshold: func() float32 { return 0.5 },
+	})
+}
+
+func TestEvaluate(t *testing.T) {
+	evaluation := Evaluate(&constant{p: 0.5}, observations(1, 0, 1), 0.5)
+	if evaluation.Observations != 3 || math.Abs(evaluation.LogLoss - math.Ln2) > 1e-6 {
+		t.Fatalf("expected log-loss ln 2 over 3 observations, got %+v", evaluation)
+	}
+	if evaluation.Trades != 3 || evaluation.ExpectedPnl != 0.5 {
+		t.Fatalf("expected 3 trades making 0.5, got %+v", evaluation)
+	}
+
+	evaluation = Evaluate(&constant{p: 0}, observations(1), 0.5)
+	if evaluation.Trades != 0 || evaluation.ExpectedPnl != 0 || math.IsInf(evaluation.LogLoss, 0) || evaluation.LogLoss < 10 {
+		t.Fatalf("expected a large finite log-loss and no trades, got %+v", evaluation)
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	isotonic := FitIsotonic([]float32{0.4, 0.1, 0.3, 0.2, 0.2}, []int32{1, 0, 0, 1, 0})
+	// 0.2 twice pools to 0.5, then 0.3 violates it
+	expected := Isotonic{X: []float64{0.1, 0.7 / 3, 0.4}, Y: []float64{0, 1.0 / 3, 1}}
+	for i := range expected.X {
+		if len(isotonic.X) != len(expected.X) || math.Abs(isotonic.X[i] - expected.X[i]) > 1e-6 || math.Abs(isotonic.Y[i] - expected.Y[i]) > 1e-6 {
+			t.Fatalf("expected %+v, got %+v", expected, isotonic)
+		}
+	}
+	for probability, calibrated := range map[float32]float64{0.05: 0, 0.1: 0, 0.4: 1, 0.9: 1, 0.3: 0.6} {
+		if actual := isotonic.Calibrate(probability); math.Abs(float64(actual) - calibrated) > 1e-6 {
+			t.Fatalf("expected %v to calibrate to %v, got %v", probability, calibrated, actual)
+		}
+	}
+
+	// an overconfident model: the observed rate at p is sigmoid(logit(p) / 2)
+	predictions := make([]float32, 0)
+	labels := make([]int32, 0)
+	for i := 1; i < 20; i++ {
+		p := float32(i) / 20
+		rate := 1 / (1 + math.Exp(-logit(p) / 2))
+		for j := 0; j < 1000; j++ {
+			predictions = append(predictions, p)
+			label := int32(0)
+			if float64(j) < math.Round(rate * 1000) {
+				label = 1
+			}
+			labels = append(labels, label)
+		}
+	}
+	platt := FitPlatt(predictions, labels)
+	if math.Abs(platt.A + 0.5) > 0.01 || math.Abs(platt.B) > 0.01 {
+		t.Fatalf("expected A -0.5 and B 0, got %+v", platt)
+	}
+
+	if _, err := Calibrate("beta", predictions, labels); err == nil {
+		t.Fatal("expected an unknown method to fail")
+	}
+	if calibration, err := Calibrate("none", predictions, labels); calibration != nil || err != nil || calibration.Calibrate(0.3) != 0.3 {
+		t.Fatalf("expected no calibration, got %+v %v", calibration, err)
+	}
+}
+
+func TestTrain(t *testing.T) {
+	directory := t.TempDir()
+	live := &constant{p: 0.5}
+	trainer := trainer(live, directory)
+
+	trainer.Add(observations(1, 1, 1)...)
+	if _, err := trainer.Train(); err != ErrTooFewObservations {
+		t.Fatalf("expected ErrTooFewObservations, got %v", err)
+	}
+
+	// the newest three are held out
+	trainer.Add(observations(1, 1, 1, 1, 1, 1)[3:]...)
+	round, err := trainer.Train()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the oldest held out observation is left for calibration
+	if round.Trained != 3 || round.Live.Observations != 2 || !round.Promoted {
+		t.Fatalf("expected a promoted round of 3 training and 2 validation observations, got %+v", round)
+	}
+	if live.p != 1 {
+		t.Fatalf("expected live to be swapped for the shadow, got p %v", live.p)
+	}
+	if metadata, err := Verify(filepath.Join(directory, round.Version)); err != nil || metadata.Schema != schema || metadata.End != start.Add(2 * time.Minute) || metadata.Evaluation != round.Shadow {
+		t.Fatalf("expected a checkpoint of the promoted model, got %+v %v", metadata, err)
+	}
+
+	// nothing new since the promotion
+	if _, err := trainer.Train(); err != ErrTooFewObservations {
+		t.Fatalf("expected ErrTooFewObservations, got %v", err)
+	}
+}
+
+func TestTrainRejects(t *testing.T) {
+	directory := t.TempDir()
+	live := &constant{p: 0.5}
+	trainer := trainer(live, directory)
+
+	// the shadow learns that everything is profitable, the held out window says otherwise
+	trainer.Add(observations(1, 1, 1, 0, 0, 0)...)
+	for i := 0; i < 2; i++ {
+		round, err := trainer.Train()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if round.Promoted || round.Trained != 3 || round.Shadow.LogLoss <= round.Live.LogLoss {
+			t.Fatalf("expected the worse shadow to be rejected and its observations kept, got %+v", round)
+		}
+	}
+	if live.p != 0.5 {
+		t.Fatalf("expected live to be untouched, got p %v", live.p)
+	}
+	if entries, _ := os.ReadDir(directory); len(entries) != 0 {
+		t.Fatalf("expected no checkpoints, got %v", len(entries))
+	}
+}
+
+func TestTrainCalibrated(t *testing.T) {
+	directory := t.TempDir()
+	live := &constant{p: 0.5}
+	trainer := trainer(live, directory)
+	trainer.options.Calibration = "isotonic"
+	// live trades 0.1 once calibrated, and never at the threshold of 0.5
+	if _, err := trainer.store.Promote(&constant{p: 0.5}, Metadata{Schema: schema, Calibration: &Calibration{Isotonic: &Isotonic{X: []float64{0, 1}, Y: []float64{0.1, 0.1}}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the shadow learns 1, the held out window is 1, 0, 0
+	trainer.Add(observations(1, 1, 1, 1, 0, 0)...)
+	round, err := trainer.Train()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(round.Live.LogLoss + math.Log(0.9)) > 1e-6 || round.Live.Trades != 0 {
+		t.Fatalf("expected live to be evaluated calibrated, got %+v", round.Live)
+	}
+	// fitted to the whole window the shadow would predict 1/3 and not trade; fitted to the
+	// older 1 alone it trades both 0s it is compared on
+	if round.Shadow.Observations != 2 || round.Shadow.Trades != 2 || round.Promoted {
+		t.Fatalf("expected the shadow calibrated on the older held out observation only, got %+v", round)
+	}
+}
+
+// saves a model reading schema as version, the way a model trained elsewhere would be added
+func add(t *testing.T, directory, version, schema string, p float32) {
+	t.Helper()
+	if err := (&constant{p: p}).Save(filepath.Join(directory, version)); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteMetadata(filepath.Join(directory, version), Metadata{Version: version, Schema: schema}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	directory := t.TempDir()
+	live := &constant{p: 0.5}
+	store := NewStore(directory, schema, live, load)
+	if reloaded, err := store.Reload(); reloaded || err != nil {
+		t.Fatalf("expected nothing to load, got %v %v", reloaded, err)
+	}
+
+	promoted, err := store.Promote(&constant{p: 0.6}, Metadata{Schema: schema, Calibration: &Calibration{Isotonic: &Isotonic{X: []float64{0, 1}, Y: []float64{0.2, 0.4}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Current() != promoted || live.p != 0.6 {
+		t.Fatalf("expected %v to be live, got %v with p %v", promoted, store.Current(), live.p)
+	}
+	if predictions := store.Predict(observations(1)); math.Abs(float64(predictions[0]) - 0.32) > 1e-6 {
+		t.Fatalf("expected the promoted calibration to map 0.6 to 0.32, got %v", predictions)
+	}
+	if _, err := store.Promote(&constant{p: 0.6}, Metadata{Schema: "test/2"}); err == nil {
+		t.Fatal("expected a model of another schema to be refused")
+	}
+
+	// newer versions appear while trading
+	add(t, directory, "99990101T000000Z", "test/2", 0.7)
+	if reloaded, err := store.Reload(); reloaded || err == nil || !strings.Contains(err.Error(), "test/2") {
+		t.Fatalf("expected a schema mismatch, got %v %v", reloaded, err)
+	}
+	add(t, directory, "99990102T000000Z", schema, 0.8)
+	os.WriteFile(filepath.Join(directory, "99990102T000000Z", "p"), []byte{90}, 0644)
+	if _, err := store.Reload(); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	add(t, directory, "99990103T000000Z", schema, 0.9)
+	add(t, directory, "99990104T000000Z", "test/2", 0.7)
+	// refusing the newest falls back to the next newest
+	if reloaded, err := store.Reload(); !reloaded || err == nil || store.Current() != "99990103T000000Z" || live.p != 0.9 {
+		t.Fatalf("expected 99990103T000000Z to be loaded, got %v %v %v with p %v", reloaded, err, store.Current(), live.p)
+	}
+	if reloaded, err := store.Reload(); reloaded || err != nil {
+		t.Fatalf("expected nothing newer, got %v %v", reloaded, err)
+	}
+
+	// rejected versions are skipped on the way back
+	if predictions := store.Predict(observations(1)); predictions[0] != 0.9 {
+		t.Fatalf("expected an uncalibrated version to predict as is, got %v", predictions)
+	}
+	version, err := store.Rollback()
+	if err != nil || version != promoted || live.p != 0.6 {
+		t.Fatalf("expected a rollback to %v, got %v %v with p %v", promoted, version, err, live.p)
+	}
+	if predictions := store.Predict(observations(1)); math.Abs(float64(predictions[0]) - 0.32) > 1e-6 {
+		t.Fatalf("expected the calibration to be rolled back too, got %v", predictions)
+	}
+	if reloaded, err := store.Reload(); reloaded || err != nil {
+		t.Fatalf("expected the rolled back version to stay unloaded, got %v %v", reloaded, err)
+	}
+	if _, err := store.Rollback(); err == nil {
+		t.Fatal("expected nothing older to roll back to")
+	}
+	if versions, _ := store.Versions(); len(versions) != 5 {
+		t.Fatalf("expected 5 versions and no temporary directories, got %v", versions)
+	}
+}
+
+func TestPromoteVersions(t *testing.T) {
+	directory := t.TempDir()
+	store := NewStore(directory, schema, &constant{p: 0.5}, load)
+	first, err := store.Promote(&constant{p: 0.6}, Metadata{Schema: schema})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// within the same second
+	second, err := store.Promote(&constant{p: 0.7}, Metadata{Schema: schema})
+	if err != nil || second <= first {
+		t.Fatalf("expected a version after %v, got %v %v", first, second, err)
+	}
+
+	// a clock behind the newest version
+	add(t, directory, "99990101T000000Z", schema, 0.8)
+	for _, expected := range []string{"99990101T000000Z-0001", "99990101T000000Z-0002"} {
+		if version, err := store.Promote(&constant{p: 0.9}, Metadata{Schema: schema}); err != nil || version != expected {
+			t.Fatalf("expected %v, got %v %v", expected, version, err)
+		}
+	}
+	if reloaded, err := store.Reload(); reloaded || err != nil || store.Current() != "99990101T000000Z-0002" {
+		t.Fatalf("expected the newest promotion to stay live, got %v %v %v", reloaded, err, store.Current())
+	}
+}
+
+// a constant that reads one schema
+type schemed struct {
+	constant
+	schema string
+}
+
+func (s *schemed) Schema() string {
+	return s.schema
+}
+
+func TestCheckSchema(t *testing.T) {
+	if err := NewStore(t.TempDir(), schema, &constant{p: 0.5}, load).CheckSchema(); err != nil {
+		t.Fatalf("expected a model that does not know its schema to be accepted, got %v", err)
+	}
+	if err := NewStore(t.TempDir(), schema, &schemed{schema: schema}, load).CheckSchema(); err != nil {
+		t.Fatalf("expected a model of the store's schema to be accepted, got %v", err)
+	}
+	if err := NewStore(t.TempDir(), "test/2", &schemed{schema: schema}, load).CheckSchema(); err == nil || !strings.Contains(err.Error(), "test/2") {
+		t.Fatalf("expected a model of another schema to be refused, got %v", err)
+	}
+	// nothing maps a failed prediction to a probability
+	calibration := &Calibration{Isotonic: &Isotonic{X: []float64{0, 1}, Y: []float64{0.2, 0.4}}}
+	if p := calibration.Calibrate(float32(math.NaN())); !math.IsNaN(float64(p)) {
+		t.Fatalf("expected NaN to stay NaN, got %v", p)
+	}
+}
+
+// predicts each observation's label, recording the size of every batch
+type echo struct {
+	mutex   sync.Mutex
+	batches []int
+}
+
+func (e *echo) Predict(observations []types.Observation) []float32 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.batches = append(e.batches, len(observations))
+	predictions := make([]float32, len(observations))
+	for i, observation := range observations {
+		predictions[i] = float32(observation.Label)
+	}
+	return predictions
+}
+
+func TestBatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	model := &echo{}
+	batcher := NewBatcher(model, BatcherOptions{Window: 100 * time.Millisecond, MaxBatch: 5})
+	done := make(chan struct{})
+	go func() {
+		batcher.Run(ctx)
+		close(done)
+	}()
+
+	// three exchange pairs within the window share one batch, and get their own predictions back
+	var wait sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		wait.Add(1)
+		go func(label int32) {
+			defer wait.Done()
+			predictions := batcher.Predict(observations(label, label))
+			if len(predictions) != 2 || predictions[0] != float32(label) || predictions[1] != float32(label) {
+				t.Errorf("expected two predictions of %v, got %v", label, predictions)
+			}
+		}(int32(i))
+	}
+	wait.Wait()
+	if len(model.batches) != 1 || model.batches[0] != 6 {
+		t.Fatalf("expected one batch of 6, got %v", model.batches)
+	}
+
+	// a full batch does not wait out the window
+	model.batches = nil
+	start := time.Now()
+	batcher.Predict(observations(1, 0, 1, 0, 1))
+	if elapsed := time.Since(start); elapsed > 50 * time.Millisecond || len(model.batches) != 1 {
+		t.Fatalf("expected one batch predicted at once, got %v after %v", model.batches, elapsed)
+	}
+
+	cancel()
+	<-done
+	if predictions := batcher.Predict(observations(1)); predictions != nil {
+		t.Fatalf("expected no predictions once stopped, got %v", predictions)
+	}
+}
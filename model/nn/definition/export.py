@@ -1,8 +1,61 @@
+import json
+
 import tensorflow as tf
 
 from killerinstinct import KillerInstinct
 
 
+def fixture_inputs(num_features):
+    # deterministic, so that the fixtures can be regenerated without a seeded generator
+    return [
+        [((i * num_features + j) * 37 % 101) / 25.0 - 2.0 for j in range(num_features)]
+        for i in range(16)
+    ]
+
+
+def export_weights(ki, directory):
+    """
+    weights.json for the pure Go backend in model/dense: every Dense layer's kernel
+    (inputs x units), bias, activation and Adam moments, and the optimizer's state
+    """
+    optimizer = ki._optimizer
+    layers = []
+    for layer in ki._model.layers:
+        layers.append({
+            "kernel": layer.kernel.numpy().tolist(),
+            "bias": layer.bias.numpy().tolist(),
+            "activation": layer.activation.__name__,
+            "kernel_m": optimizer.get_slot(layer.kernel, "m").numpy().tolist(),
+            "kernel_v": optimizer.get_slot(layer.kernel, "v").numpy().tolist(),
+            "bias_m": optimizer.get_slot(layer.bias, "m").numpy().tolist(),
+            "bias_v": optimizer.get_slot(layer.bias, "v").numpy().tolist(),
+        })
+    weights = {
+        "schema": "grizzly/1",
+        "layers": layers,
+        "optimizer": {
+            "learning_rate": float(optimizer.learning_rate.numpy()),
+            "beta_1": float(optimizer.beta_1.numpy()),
+            "beta_2": float(optimizer.beta_2.numpy()),
+            "epsilon": optimizer.epsilon,
+            "iterations": int(optimizer.iterations.numpy()),
+        },
+    }
+    with open(directory + "/weights.json", "w") as f:
+        json.dump(weights, f, indent=2)
+
+
+def export_fixtures(ki, path):
+    """the SavedModel's predictions, which model/dense's tests check its own against"""
+    inputs = fixture_inputs(ki.num_features)
+    predictions = ki.predict(tf.constant(inputs, dtype=tf.float32))["predictions"]
+    with open(path, "w") as f:
+        json.dump({
+            "inputs": inputs,
+            "predictions": [float(p) for p in tf.reshape(predictions, [-1]).numpy()],
+        }, f, indent=2)
+
+
 def main():
     ki = KillerInstinct()
 
@@ -24,6 +77,8 @@ def main():
             "predict": ki.predict
         }
     )
+    export_weights(ki, "KillerInstinct")
+    export_fixtures(ki, "../../dense/testdata/killerinstinct.json")
 
 
 if __name__ == "__main__":
@@ -1,24 +1,76 @@
+//go:build tensorflow
+
 package nn
 
 import (
+    "fmt"
     "log"
+    "math"
+    "os"
+    "path/filepath"
     "sync"
 
+    "github.com/denali-capital/grizzly/features"
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/model"
     "github.com/denali-capital/grizzly/types"
     tg "github.com/galeone/tfgo"
     tf "github.com/tensorflow/tensorflow/tensorflow/go"
 )
 
-// lock or lock-free; for now, lock bc easier
+var logger *logging.Logger = logging.New("nn")
+
+// predictions share the read lock, learning and swaps take the write lock
 type KillerInstinct struct {
     sync.RWMutex
     model *tg.Model
+    // saved_model.pb, written out again by Save
+    graph []byte
 }
 
-func NewKillerInstinct() *KillerInstinct {
+func load(directory string) (*KillerInstinct, error) {
+    graph, err := os.ReadFile(filepath.Join(directory, "saved_model.pb"))
+    if err != nil {
+        return nil, err
+    }
     return &KillerInstinct{
-        model: tg.LoadModel("KillerInstinct", []string{"serve"}, nil)
+        model: tg.LoadModel(directory, []string{"serve"}, nil),
+        graph: graph,
+    }, nil
+}
+
+// a version from a model.Store
+func Load(directory string) (model.Learner, error) {
+    k, err := load(directory)
+    if err != nil {
+        return nil, err
     }
+    return k, nil
+}
+
+func NewKillerInstinct() *KillerInstinct {
+    k, err := load("KillerInstinct")
+    if err != nil {
+        log.Fatalln(err)
+    }
+    return k
+}
+
+// KillerInstinct was trained on features.Default
+func (k *KillerInstinct) Schema() string {
+    return features.Default.String()
+}
+
+// observations of any other schema would be read positionally against the wrong layout
+func observationData(observations []types.Observation) ([][][]float32, error) {
+    data := make([][][]float32, len(observations))
+    for i, observation := range observations {
+        if observation.Schema != features.Default.String() || len(observation.Features) != len(features.Default.Features) {
+            return nil, fmt.Errorf("expected %v observations, got %v with %v features", features.Default, observation.Schema, len(observation.Features))
+        }
+        data[i] = [][]float32{observation.Features}
+    }
+    return data, nil
 }
 
 func learn(model *tg.Model, data *tf.Tensor, labels *tf.Tensor) float32 {
@@ -35,25 +87,23 @@ func learn(model *tg.Model, data *tf.Tensor, labels *tf.Tensor) float32 {
     return loss.Value().(float32)
 }
 
+// NaN without learning when any observation is not of KillerInstinct's schema or has a
+// label other than 0 or 1
 func (k *KillerInstinct) Learn(observations []types.Observation) float32 {
     size := len(observations)
-    var data [size][1][7]float32
-    var labels [size]int32
+    data, err := observationData(observations)
+    if err != nil {
+        logger.Error("cannot learn", logging.Err(err))
+        return float32(math.NaN())
+    }
+    labels := make([]int32, size)
 
     for i := 0; i < size; i++ {
-        data[i][0] = [7]float32{
-            observations[i].PriceDelta,
-            observations[i].Liquidity1,
-            observations[i].Liquidity2,
-            observations[i].Latency1,
-            observations[i].Latency2,
-            observations[i].Volatility1,
-            observations[i].Volatility2,
-        }
-        if label := observations[i].label; label == 0 || label == 1 {
+        if label := observations[i].Label; label == 0 || label == 1 {
             labels[i] = label
         } else {
-            log.Fatalln("label must be one of {0, 1}")
+            logger.Error("cannot learn", logging.Err(fmt.Errorf("label must be one of {0, 1}, got %v", label)))
+            return float32(math.NaN())
         }
     }
 
@@ -66,8 +116,9 @@ func (k *KillerInstinct) Learn(observations []types.Observation) float32 {
         log.Fatalln(err)
     }
 
-    k.RLock()
-    defer k.RUnlock()
+    // learning updates the variables predictions read
+    k.Lock()
+    defer k.Unlock()
     return learn(k.model, dataTensor, labelTensor)
 }
 
@@ -89,20 +140,16 @@ func predict(model *tg.Model, data *tf.Tensor) []float32 {
     return predictions
 }
 
+// NaN for every observation when any is not of KillerInstinct's schema
 func (k *KillerInstinct) Predict(observations []types.Observation) []float32 {
-    size := len(observations)
-    var data [size][1][7]float32
-
-    for i := 0; i < size; i++ {
-        data[i][0] = [7]float32{
-            observations[i].PriceDelta,
-            observations[i].Liquidity1,
-            observations[i].Liquidity2,
-            observations[i].Latency1,
-            observations[i].Latency2,
-            observations[i].Volatility1,
-            observations[i].Volatility2,
+    data, err := observationData(observations)
+    if err != nil {
+        logger.Error("cannot predict", logging.Err(err))
+        predictions := make([]float32, len(observations))
+        for i := range predictions {
+            predictions[i] = float32(math.NaN())
         }
+        return predictions
     }
 
     dataTensor, err := tf.NewTensor(data)
@@ -110,7 +157,59 @@ func (k *KillerInstinct) Predict(observations []types.Observation) []float32 {
         log.Fatalln(err)
     }
 
+    // sessions run concurrently, so predictions only exclude learning
+    k.RLock()
+    defer k.RUnlock()
+    return predict(k.model, dataTensor)
+}
+
+// restores into a fresh session from a checkpoint in a temporary directory
+func (k *KillerInstinct) Clone() (model.Learner, error) {
+    directory, err := os.MkdirTemp("", "KillerInstinct")
+    if err != nil {
+        return nil, err
+    }
+    defer os.RemoveAll(directory)
+    if err := k.Save(directory); err != nil {
+        return nil, err
+    }
+    return Load(directory)
+}
+
+func (k *KillerInstinct) Swap(other model.Learner) error {
+    clone, ok := other.(*KillerInstinct)
+    if !ok {
+        return fmt.Errorf("expected a *KillerInstinct, got %T", other)
+    }
+    clone.RLock()
+    defer clone.RUnlock()
+
     k.Lock()
     defer k.Unlock()
-    return predict(k.model, dataTensor)
-}
\ No newline at end of file
+    k.model, k.graph = clone.model, clone.graph
+    return nil
+}
+
+// the SavedModel layout NewKillerInstinct loads; the exported signatures come with a saver
+// restoring from and writing to saver_filename
+func (k *KillerInstinct) Save(directory string) error {
+    if err := os.MkdirAll(filepath.Join(directory, "variables"), 0755); err != nil {
+        return err
+    }
+    filename, err := tf.NewTensor(filepath.Join(directory, "variables", "variables"))
+    if err != nil {
+        return err
+    }
+
+    k.RLock()
+    defer k.RUnlock()
+    k.model.Exec(
+        []tf.Output{
+            k.model.Op("StatefulPartitionedCall_2", 0),
+        },
+        map[tf.Output]*tf.Tensor{
+            k.model.Op("saver_filename", 0): filename,
+        },
+    )
+    return os.WriteFile(filepath.Join(directory, "saved_model.pb"), k.graph, 0644)
+}
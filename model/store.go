@@ -0,0 +1,390 @@
+package model
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/denali-capital/grizzly/drift"
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
+    "github.com/denali-capital/grizzly/types"
+)
+
+// written into every version directory alongside the model
+const MetadataFile string = "metadata.json"
+
+// the format of the version directories Promote creates, which sort in the order they were made
+const versionFormat string = "20060102T150405Z"
+
+// the version after newest, for promotions within the same second as newest or after the clock
+// stepped back; newest-0001, newest-0002 and so on sort after newest and before the next second
+func nextVersion(newest string) string {
+    base, sequence, found := strings.Cut(newest, "-")
+    if n, err := strconv.Atoi(sequence); found && err == nil {
+        return fmt.Sprintf("%v-%04d", base, n + 1)
+    }
+    return newest + "-0001"
+}
+
+type Metadata struct {
+    Version     string            `json:"version"`
+    // feature schema the model reads, as name/version
+    Schema      string            `json:"schema"`
+    // first and last observations trained on
+    Start       time.Time         `json:"start"`
+    End         time.Time         `json:"end"`
+    // on the held out observations the model was promoted on
+    Evaluation  Evaluation        `json:"evaluation"`
+    // of the model's probabilities, fitted on the same held out observations
+    Calibration *Calibration      `json:"calibration,omitempty"`
+    // distributions of the features and calibrated outputs trained on, which drift is measured from
+    Reference   *drift.Reference  `json:"reference,omitempty"`
+    // sha256 of every other file in the version directory, by slash separated relative path
+    Checksums   map[string]string `json:"checksums"`
+}
+
+func checksums(directory string) (map[string]string, error) {
+    sums := make(map[string]string)
+    err := filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+        if err != nil || entry.IsDir() {
+            return err
+        }
+        relative, err := filepath.Rel(directory, path)
+        if err != nil || relative == MetadataFile {
+            return err
+        }
+        file, err := os.Open(path)
+        if err != nil {
+            return err
+        }
+        defer file.Close()
+        hash := sha256.New()
+        if _, err := io.Copy(hash, file); err != nil {
+            return err
+        }
+        sums[filepath.ToSlash(relative)] = hex.EncodeToString(hash.Sum(nil))
+        return nil
+    })
+    return sums, err
+}
+
+// checksums every file in directory into metadata and writes it there, for models saved
+// outside the trainer to be loaded by a Store
+func WriteMetadata(directory string, metadata Metadata) error {
+    sums, err := checksums(directory)
+    if err != nil {
+        return err
+    }
+    metadata.Checksums = sums
+    encoded, err := json.MarshalIndent(metadata, "", "  ")
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(filepath.Join(directory, MetadataFile), append(encoded, '\n'), 0644)
+}
+
+// the metadata of directory, once every file in it matches its checksum
+func Verify(directory string) (Metadata, error) {
+    metadata := Metadata{}
+    encoded, err := os.ReadFile(filepath.Join(directory, MetadataFile))
+    if err != nil {
+        return metadata, err
+    }
+    if err := json.Unmarshal(encoded, &metadata); err != nil {
+        return metadata, fmt.Errorf("%v: %w", MetadataFile, err)
+    }
+    sums, err := checksums(directory)
+    if err != nil {
+        return metadata, err
+    }
+    for path, sum := range metadata.Checksums {
+        if sums[path] != sum {
+            return metadata, fmt.Errorf("checksum mismatch for %v", path)
+        }
+    }
+    for path := range sums {
+        if _, ok := metadata.Checksums[path]; !ok {
+            return metadata, fmt.Errorf("%v has no checksum", path)
+        }
+    }
+    return metadata, nil
+}
+
+// reads a Learner back from a directory its Save wrote
+type Loader func(directory string) (Learner, error)
+
+type calibrated struct {
+    model       Model
+    calibration *Calibration
+}
+
+func (c calibrated) Predict(observations []types.Observation) []float32 {
+    predictions := c.model.Predict(observations)
+    for i, prediction := range predictions {
+        predictions[i] = c.calibration.Calibrate(prediction)
+    }
+    return predictions
+}
+
+// a version outside any store, e.g. a challenger to the live model, predicting calibrated by its
+// own calibration; versions for another feature schema than schema are refused
+func LoadCalibrated(directory string, schema string, load Loader) (Model, error) {
+    metadata, err := Verify(directory)
+    if err != nil {
+        return nil, err
+    }
+    if metadata.Schema != schema {
+        return nil, fmt.Errorf("reads %v features, the extractor makes %v", metadata.Schema, schema)
+    }
+    learner, err := load(directory)
+    if err != nil {
+        return nil, err
+    }
+    return calibrated{learner, metadata.Calibration}, nil
+}
+
+// versions of the live model, one directory each under a common directory; versions are
+// swapped into live so that whatever holds it keeps predicting throughout
+type Store struct {
+    mutex       sync.Mutex
+    directory   string
+    // feature schema of the live extractor, versions for any other are refused
+    schema      string
+    live        Learner
+    // of the current version, read by Predict without taking mutex so that loads never hold it up
+    calibration atomic.Pointer[Calibration]
+    reference   atomic.Pointer[drift.Reference]
+    load        Loader
+    // "" while live is the model the store was created with
+    current     string
+    // versions that failed to load or were rolled back from, which Reload skips
+    rejected    map[string]bool
+}
+
+func NewStore(directory string, schema string, live Learner, load Loader) *Store {
+    return &Store{
+        directory: directory,
+        schema: schema,
+        live: live,
+        load: load,
+        rejected: make(map[string]bool),
+    }
+}
+
+// an error if live reads another schema than the extractor makes; versions of the wrong schema
+// are refused when loaded, but the model the process starts with is not a version
+func (s *Store) CheckSchema() error {
+    reader, ok := s.live.(SchemaReader)
+    if !ok {
+        return nil
+    }
+    if schema := reader.Schema(); schema != s.schema {
+        return fmt.Errorf("the model reads %v features, the extractor makes %v", schema, s.schema)
+    }
+    return nil
+}
+
+func (s *Store) Live() Learner {
+    return s.live
+}
+
+// of the current version, nil when it has none
+func (s *Store) Reference() *drift.Reference {
+    return s.reference.Load()
+}
+
+func (s *Store) Current() string {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    return s.current
+}
+
+// every version directory, oldest first; directories being written start with a dot and are left out
+func (s *Store) Versions() ([]string, error) {
+    entries, err := os.ReadDir(s.directory)
+    if errors.Is(err, fs.ErrNotExist) {
+        return []string{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    versions := make([]string, 0, len(entries))
+    for _, entry := range entries {
+        if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+            versions = append(versions, entry.Name())
+        }
+    }
+    sort.Strings(versions)
+    return versions, nil
+}
+
+// calibrated probabilities of the live model; predictions made across a swap may pair one
+// version's probabilities with the other's calibration
+func (s *Store) Predict(observations []types.Observation) []float32 {
+    predictions := s.live.Predict(observations)
+    calibration := s.calibration.Load()
+    for i, prediction := range predictions {
+        predictions[i] = calibration.Calibrate(prediction)
+    }
+    return predictions
+}
+
+func (s *Store) swap(version string, learner Learner, metadata Metadata) error {
+    if err := s.live.Swap(learner); err != nil {
+        return err
+    }
+    s.calibration.Store(metadata.Calibration)
+    s.reference.Store(metadata.Reference)
+    s.current = version
+    return nil
+}
+
+// loading happens before the swap, so Predict is only held up for the swap itself
+func (s *Store) loadVersion(version string) error {
+    directory := filepath.Join(s.directory, version)
+    metadata, err := Verify(directory)
+    if err != nil {
+        return err
+    }
+    if metadata.Schema != s.schema {
+        return fmt.Errorf("reads %v features, the extractor makes %v", metadata.Schema, s.schema)
+    }
+    learner, err := s.load(directory)
+    if err != nil {
+        return err
+    }
+    return s.swap(version, learner, metadata)
+}
+
+// loads the newest version that is newer than the current one and has not been rejected,
+// returns whether live changed and why any newer version was refused
+func (s *Store) Reload() (bool, error) {
+    versions, err := s.Versions()
+    if err != nil {
+        return false, err
+    }
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    refused := make([]error, 0)
+    for i := len(versions) - 1; i >= 0 && versions[i] > s.current; i-- {
+        if s.rejected[versions[i]] {
+            continue
+        }
+        if err := s.loadVersion(versions[i]); err != nil {
+            // not retried, a fixed model should be written as a new version
+            s.rejected[versions[i]] = true
+            metrics.ModelReloads.Inc("rejected")
+            refused = append(refused, fmt.Errorf("version %v: %w", versions[i], err))
+            continue
+        }
+        metrics.ModelReloads.Inc("loaded")
+        return true, errors.Join(refused...)
+    }
+    return false, errors.Join(refused...)
+}
+
+// swaps the newest loadable version older than the current one into live, and keeps Reload
+// from loading the current one again; returns the version rolled back to
+func (s *Store) Rollback() (string, error) {
+    versions, err := s.Versions()
+    if err != nil {
+        return "", err
+    }
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    if s.current == "" {
+        return "", errors.New("no version has been loaded to roll back from")
+    }
+    for i := len(versions) - 1; i >= 0; i-- {
+        if versions[i] >= s.current || s.rejected[versions[i]] {
+            continue
+        }
+        from := s.current
+        if err := s.loadVersion(versions[i]); err != nil {
+            s.rejected[versions[i]] = true
+            logger.Warn("skipped version while rolling back", "version", versions[i], logging.Err(err))
+            continue
+        }
+        s.rejected[from] = true
+        metrics.ModelReloads.Inc("rolled_back")
+        return versions[i], nil
+    }
+    return "", fmt.Errorf("no loadable version older than %v", s.current)
+}
+
+// saves learner as a new version with metadata and swaps it into live, returns the version
+//
+// the version is named after the time, or after the newest version when that is not older, so
+// that Reload never mistakes it for an old one; it is written under a hidden name and renamed
+// into place, so that a Store watching the same directory never sees it half written
+func (s *Store) Promote(learner Learner, metadata Metadata) (string, error) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    if metadata.Schema != s.schema {
+        return "", fmt.Errorf("model reads %v features, the extractor makes %v", metadata.Schema, s.schema)
+    }
+    if err := os.MkdirAll(s.directory, 0755); err != nil {
+        return "", err
+    }
+    versions, err := s.Versions()
+    if err != nil {
+        return "", err
+    }
+    newest := s.current
+    if len(versions) > 0 && versions[len(versions) - 1] > newest {
+        newest = versions[len(versions) - 1]
+    }
+    metadata.Version = time.Now().UTC().Format(versionFormat)
+    if metadata.Version <= newest {
+        metadata.Version = nextVersion(newest)
+    }
+    temporary, err := os.MkdirTemp(s.directory, "." + metadata.Version)
+    if err != nil {
+        return "", err
+    }
+    defer os.RemoveAll(temporary)
+    if err := learner.Save(temporary); err != nil {
+        return "", err
+    }
+    if err := WriteMetadata(temporary, metadata); err != nil {
+        return "", err
+    }
+    if err := os.Rename(temporary, filepath.Join(s.directory, metadata.Version)); err != nil {
+        return "", err
+    }
+    return metadata.Version, s.swap(metadata.Version, learner, metadata)
+}
+
+// reloads every interval until ctx is canceled
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+        reloaded, err := s.Reload()
+        if err != nil {
+            logger.Error("refused model version", logging.Err(err))
+        }
+        if reloaded {
+            logger.Info("loaded model version", "version", s.Current())
+        }
+    }
+}
@@ -0,0 +1,204 @@
+package model
+
+import (
+    "context"
+    "errors"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/denali-capital/grizzly/drift"
+    "github.com/denali-capital/grizzly/features"
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
+    "github.com/denali-capital/grizzly/types"
+)
+
+var logger *logging.Logger = logging.New("model")
+
+// returned by Train when there is not enough new data for a round
+var ErrTooFewObservations error = errors.New("too few labeled observations to train on")
+
+type TrainerOptions struct {
+    // time between training rounds
+    Interval        time.Duration
+    // the newest observations within Window are held out to validate on rather than trained on
+    Window          time.Duration
+    // new training observations needed for a round
+    MinObservations int
+    // labeled observations kept, oldest dropped first
+    Capacity        int
+    // observations per Learn call
+    BatchSize       int
+    // passes over the new training observations per round
+    Epochs          int
+    // probability at or above which an observation is traded, for Evaluation.ExpectedPnl
+    Threshold       func() float32
+    // none, platt or isotonic, fitted to the shadow on the older half of the held out observations
+    // before it is compared with live on the newer half; "" is none
+    Calibration     string
+}
+
+// outcome of one training round
+type Round struct {
+    Trained  int
+    Live     Evaluation
+    Shadow   Evaluation
+    Promoted bool
+    // store version of the promoted model
+    Version  string
+}
+
+// retrains the store's live model on a shadow copy as labeled observations arrive, and promotes
+// the copy only when it beats live on the newest, held out observations
+type Trainer struct {
+    mutex        sync.Mutex
+    store        *Store
+    options      TrainerOptions
+    // oldest first
+    observations []types.Observation
+    // observations up to here have been learned by a promoted model
+    trained      time.Time
+}
+
+func NewTrainer(store *Store, options TrainerOptions) *Trainer {
+    return &Trainer{
+        store: store,
+        options: options,
+        observations: make([]types.Observation, 0, options.Capacity),
+    }
+}
+
+// labeled observations
+func (t *Trainer) Add(observations ...types.Observation) {
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+    t.observations = append(t.observations, observations...)
+    // labels resolve out of detection order, so the buffer is kept sorted for Train
+    sort.SliceStable(t.observations, func(i, j int) bool {
+        return t.observations[i].Timestamp.Before(t.observations[j].Timestamp)
+    })
+    if len(t.observations) > t.options.Capacity {
+        t.observations = append(t.observations[:0], t.observations[len(t.observations) - t.options.Capacity:]...)
+    }
+}
+
+// observations not yet learned by live and older than the window, and those within it
+func (t *Trainer) split() ([]types.Observation, []types.Observation) {
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+    if len(t.observations) == 0 {
+        return nil, nil
+    }
+    holdout := t.observations[len(t.observations) - 1].Timestamp.Add(-t.options.Window)
+    training := make([]types.Observation, 0)
+    validation := make([]types.Observation, 0)
+    for _, observation := range t.observations {
+        switch {
+        case !observation.Timestamp.Before(holdout):
+            validation = append(validation, observation)
+        case observation.Timestamp.After(t.trained):
+            training = append(training, observation)
+        }
+    }
+    return training, validation
+}
+
+// one round: learns the new observations on a clone of live, calibrates it on the older half of
+// the held out window, and swaps it in if its calibrated log-loss is lower and its expected PnL
+// no lower than live's on the newer half, live predicting calibrated as it trades
+//
+// observations stay new until a round is promoted, so rejected rounds are retried with more data
+func (t *Trainer) Train() (Round, error) {
+    training, validation := t.split()
+    if len(training) < t.options.MinObservations || len(training) == 0 || len(validation) < 2 {
+        return Round{}, ErrTooFewObservations
+    }
+    // compared on observations the calibration was not fitted to
+    fitting, evaluation := validation[:len(validation) / 2], validation[len(validation) / 2:]
+
+    live := t.store.Live()
+    shadow, err := live.Clone()
+    if err != nil {
+        return Round{}, err
+    }
+    for epoch := 0; epoch < t.options.Epochs; epoch++ {
+        for start := 0; start < len(training); start += t.options.BatchSize {
+            end := start + t.options.BatchSize
+            if end > len(training) {
+                end = len(training)
+            }
+            shadow.Learn(training[start:end])
+        }
+    }
+
+    labels := make([]int32, len(fitting))
+    for i, observation := range fitting {
+        labels[i] = observation.Label
+    }
+    calibration, err := Calibrate(t.options.Calibration, shadow.Predict(fitting), labels)
+    if err != nil {
+        return Round{}, err
+    }
+
+    threshold := t.options.Threshold()
+    round := Round{
+        Trained: len(training),
+        Live: Evaluate(t.store, evaluation, threshold),
+        Shadow: Evaluate(calibrated{shadow, calibration}, evaluation, threshold),
+    }
+    metrics.ObserveTraining("live", round.Live.LogLoss, round.Live.ExpectedPnl)
+    metrics.ObserveTraining("shadow", round.Shadow.LogLoss, round.Shadow.ExpectedPnl)
+    if round.Shadow.LogLoss >= round.Live.LogLoss || round.Shadow.ExpectedPnl < round.Live.ExpectedPnl {
+        return round, nil
+    }
+
+    // what live observations are checked for drift against while this version trades
+    var reference *drift.Reference
+    if schema, err := features.LookupSchema(training[0].Schema); err == nil {
+        reference = drift.NewReference(schema, training, calibrated{shadow, calibration}.Predict(training))
+    }
+    // every promoted version is on disk before it trades
+    round.Version, err = t.store.Promote(shadow, Metadata{
+        Schema: training[0].Schema,
+        Start: training[0].Timestamp,
+        End: training[len(training) - 1].Timestamp,
+        Evaluation: round.Shadow,
+        Calibration: calibration,
+        Reference: reference,
+    })
+    if err != nil {
+        return round, err
+    }
+    round.Promoted = true
+    metrics.ModelPromotions.Inc()
+
+    t.mutex.Lock()
+    t.trained = training[len(training) - 1].Timestamp
+    t.mutex.Unlock()
+    return round, nil
+}
+
+// trains every options.Interval until ctx is canceled
+func (t *Trainer) Run(ctx context.Context) {
+    ticker := time.NewTicker(t.options.Interval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+        round, err := t.Train()
+        switch {
+        case err == ErrTooFewObservations:
+            logger.Debug("skipped training round", logging.Err(err))
+        case err != nil:
+            logger.Error("training round failed", logging.Err(err))
+        default:
+            logger.Info("trained shadow model", "observations", round.Trained, "promoted", round.Promoted, "version", round.Version,
+                "live_log_loss", round.Live.LogLoss, "shadow_log_loss", round.Shadow.LogLoss,
+                "live_expected_pnl", round.Live.ExpectedPnl, "shadow_expected_pnl", round.Shadow.ExpectedPnl)
+        }
+    }
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+
+    "github.com/denali-capital/grizzly/config"
+    "github.com/denali-capital/grizzly/types"
+)
+
+const ordersUsage string = `usage: grizzly orders <command>
+
+commands:
+    list [exchange...]             list open orders
+    cancel <exchange> <id...>      cancel open orders by id
+    cancel-all [exchange...]       cancel every open order
+`
+
+var statusNames map[types.StatusType]string = map[types.StatusType]string{
+    types.Pending: "pending",
+    types.Unfilled: "unfilled",
+    types.PartiallyFilled: "partially filled",
+    types.Filled: "filled",
+    types.Canceled: "canceled",
+    types.Expired: "expired",
+}
+
+func printOrder(exchange string, orderId types.OrderId, orderStatus types.OrderStatus) {
+    description := "unknown asset pair"
+    if order := orderStatus.Original; order != nil {
+        side := "buy"
+        if order.OrderType == types.Sell {
+            side = "sell"
+        }
+        description = fmt.Sprintf("%v %v %v @ %v", side, order.Quantity, order.AssetPair, order.Price)
+    }
+    filled := ""
+    if orderStatus.FilledQuantity != nil {
+        filled = fmt.Sprintf(", %v filled", *orderStatus.FilledQuantity)
+    }
+    fmt.Printf("%-10v %-40v %v (%v%v)\n", exchange, orderId, description, statusNames[orderStatus.Status], filled)
+}
+
+func ordersCommand(ctx context.Context, cfg *config.Config, args []string) error {
+    flags := flag.NewFlagSet("orders", flag.ExitOnError)
+    flags.Usage = func() {
+        fmt.Fprint(flags.Output(), ordersUsage)
+    }
+    flags.Parse(args)
+    if flags.NArg() == 0 {
+        return errors.New(ordersUsage)
+    }
+
+    switch flags.Arg(0) {
+    case "list", "cancel-all":
+        exchanges, err := newExchanges(cfg, flags.Args()[1:])
+        if err != nil {
+            return err
+        }
+        for _, exchange := range exchanges {
+            openOrders := exchange.GetOpenOrders()
+            orderIds := make([]types.OrderId, 0, len(openOrders))
+            for orderId, orderStatus := range openOrders {
+                printOrder(exchange.String(), orderId, orderStatus)
+                orderIds = append(orderIds, orderId)
+            }
+            if flags.Arg(0) == "cancel-all" && len(orderIds) > 0 {
+                exchange.CancelOrders(orderIds)
+                fmt.Printf("%v: canceled %v orders\n", exchange, len(orderIds))
+            }
+        }
+        return nil
+    case "cancel":
+        if flags.NArg() < 3 {
+            return errors.New(ordersUsage)
+        }
+        exchanges, err := newExchanges(cfg, flags.Args()[1:2])
+        if err != nil {
+            return err
+        }
+        exchange := exchanges[0]
+        openOrders := exchange.GetOpenOrders()
+        orderIds := make([]types.OrderId, 0)
+        for _, id := range flags.Args()[2:] {
+            if _, ok := openOrders[types.OrderId(id)]; !ok {
+                return fmt.Errorf("%v has no open order %v", exchange, id)
+            }
+            orderIds = append(orderIds, types.OrderId(id))
+        }
+        exchange.CancelOrders(orderIds)
+        fmt.Printf("%v: canceled %v orders\n", exchange, len(orderIds))
+        return nil
+    }
+    return errors.New(ordersUsage)
+}
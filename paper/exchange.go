@@ -0,0 +1,199 @@
+package paper
+
+import (
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/denali-capital/grizzly/metrics"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+// trades against the live (or replayed) market data of the wrapped exchange without placing real orders
+// orders fill immediately against the current order book up to their limit price, any remainder is canceled
+type Exchange struct {
+    types.Exchange
+
+    // fraction of the quote notional charged per fill
+    fee      decimal.Decimal
+    mutex    sync.Mutex
+    balances map[types.Asset]decimal.Decimal
+    orders   map[types.OrderId]types.OrderStatus
+    nextId   uint64
+}
+
+func NewExchange(exchange types.Exchange, fee decimal.Decimal, balances map[types.Asset]decimal.Decimal) *Exchange {
+    initial := make(map[types.Asset]decimal.Decimal, len(balances))
+    for asset, balance := range balances {
+        initial[asset] = balance
+    }
+    return &Exchange{
+        Exchange: exchange,
+        fee: fee,
+        balances: initial,
+        orders: make(map[types.OrderId]types.OrderStatus),
+    }
+}
+
+// walks side while the limit price allows, returns the quantity filled and the quote notional
+func fill(side []types.OrderBookEntry, order types.Order) (decimal.Decimal, decimal.Decimal) {
+    filled := decimal.Zero
+    notional := decimal.Zero
+    for _, entry := range side {
+        if order.OrderType == types.Buy && entry.Price.GreaterThan(order.Price) {
+            break
+        }
+        if order.OrderType == types.Sell && entry.Price.LessThan(order.Price) {
+            break
+        }
+        quantity := decimal.Min(entry.Quantity, order.Quantity.Sub(filled))
+        filled = filled.Add(quantity)
+        notional = notional.Add(quantity.Mul(entry.Price))
+        if filled.Equal(order.Quantity) {
+            break
+        }
+    }
+    return filled, notional
+}
+
+// partial fills are reported as Canceled with the filled quantity set
+// callers must hold the lock
+func (e *Exchange) executeOrder(order types.Order, orderBook *types.OrderBook) types.OrderStatus {
+    original := order
+    status := types.OrderStatus{
+        Status: types.Canceled,
+        Original: &original,
+    }
+    if orderBook == nil {
+        return status
+    }
+
+    one := decimal.NewFromInt(1)
+    var filled, notional decimal.Decimal
+    if order.OrderType == types.Buy {
+        filled, notional = fill(orderBook.Asks, order)
+        cost := notional.Mul(one.Add(e.fee))
+        // rejected outright rather than partially filled when funds are short
+        if cost.GreaterThan(e.balances[order.AssetPair.Quote]) {
+            return status
+        }
+        e.balances[order.AssetPair.Quote] = e.balances[order.AssetPair.Quote].Sub(cost)
+        e.balances[order.AssetPair.Base] = e.balances[order.AssetPair.Base].Add(filled)
+    } else {
+        filled, notional = fill(orderBook.Bids, order)
+        if filled.GreaterThan(e.balances[order.AssetPair.Base]) {
+            return status
+        }
+        e.balances[order.AssetPair.Base] = e.balances[order.AssetPair.Base].Sub(filled)
+        e.balances[order.AssetPair.Quote] = e.balances[order.AssetPair.Quote].Add(notional.Mul(one.Sub(e.fee)))
+    }
+
+    if filled.IsZero() {
+        return status
+    }
+    price := notional.Div(filled)
+    status.FilledPrice = &price
+    status.FilledQuantity = &filled
+    if filled.Equal(order.Quantity) {
+        status.Status = types.Filled
+    }
+    return status
+}
+
+func (e *Exchange) ExecuteOrders(orders []types.Order) map[types.Order]types.OrderId {
+    assetPairs := make([]types.AssetPair, 0, len(orders))
+    for _, order := range orders {
+        assetPairs = append(assetPairs, order.AssetPair)
+    }
+    orderBooks := e.Exchange.GetOrderBooks(assetPairs)
+
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+
+    orderIds := make(map[types.Order]types.OrderId)
+    orderStatuses := make(map[types.OrderId]types.OrderStatus)
+    for _, order := range orders {
+        e.nextId++
+        orderId := types.OrderId(fmt.Sprintf("paper-%v-%v", e.String(), e.nextId))
+        e.orders[orderId] = e.executeOrder(order, orderBooks[order.AssetPair])
+        orderIds[order] = orderId
+        orderStatuses[orderId] = e.orders[orderId]
+    }
+    // paper orders are final as soon as they are placed
+    metrics.ObserveSubmitted(e.String(), orderIds)
+    metrics.ObserveOrderStatuses(e.String(), orderStatuses)
+    return orderIds
+}
+
+func (e *Exchange) GetOrderStatuses(orderIds []types.OrderId) map[types.OrderId]types.OrderStatus {
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+
+    orderStatuses := make(map[types.OrderId]types.OrderStatus)
+    for _, orderId := range orderIds {
+        if orderStatus, ok := e.orders[orderId]; ok {
+            orderStatuses[orderId] = orderStatus
+        }
+    }
+    return orderStatuses
+}
+
+// forwarded to the wrapped exchange when it records market data
+func (e *Exchange) RegisterAssetPair(assetPair types.AssetPair) {
+    if recorder, ok := e.Exchange.(types.AssetPairRecorder); ok {
+        recorder.RegisterAssetPair(assetPair)
+    }
+}
+
+// false when the wrapped exchange does not record trades
+func (e *Exchange) GetTradesSince(assetPair types.AssetPair, since time.Time) ([]types.Trade, bool) {
+    if recorder, ok := e.Exchange.(types.TradeRecorder); ok {
+        return recorder.GetTradesSince(assetPair, since)
+    }
+    return make([]types.Trade, 0), false
+}
+
+func (e *Exchange) GetVWAP(assetPair types.AssetPair, window time.Duration) (decimal.Decimal, bool) {
+    if recorder, ok := e.Exchange.(types.TradeRecorder); ok {
+        return recorder.GetVWAP(assetPair, window)
+    }
+    return decimal.Zero, false
+}
+
+func (e *Exchange) GetHistoricalCandles(assetPair types.AssetPair, resolution time.Duration, since time.Time) ([]types.Candle, bool) {
+    if provider, ok := e.Exchange.(types.CandleProvider); ok {
+        return provider.GetHistoricalCandles(assetPair, resolution, since)
+    }
+    return make([]types.Candle, 0), false
+}
+
+func (e *Exchange) UnregisterAssetPair(assetPair types.AssetPair) {
+    if recorder, ok := e.Exchange.(types.AssetPairRecorder); ok {
+        recorder.UnregisterAssetPair(assetPair)
+    }
+}
+
+func (e *Exchange) Close() {
+    if recorder, ok := e.Exchange.(types.AssetPairRecorder); ok {
+        recorder.Close()
+    }
+}
+
+// paper orders never rest on the book
+func (e *Exchange) CancelOrders(orderIds []types.OrderId) {}
+
+func (e *Exchange) GetOpenOrders() map[types.OrderId]types.OrderStatus {
+    return make(map[types.OrderId]types.OrderStatus)
+}
+
+func (e *Exchange) GetBalances() map[types.Asset]decimal.Decimal {
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+
+    balances := make(map[types.Asset]decimal.Decimal, len(e.balances))
+    for asset, balance := range e.balances {
+        balances[asset] = balance
+    }
+    return balances
+}
@@ -0,0 +1,80 @@
+package paper
+
+import (
+	"testing"
+
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+var BTCUSD types.AssetPair = types.NewAssetPair("BTC", "USD")
+
+// only GetOrderBooks and String are used by the paper exchange
+type bookExchange struct {
+	types.Exchange
+	orderBook *types.OrderBook
+}
+
+func (b *bookExchange) String() string {
+	return "Book"
+}
+
+func (b *bookExchange) GetOrderBooks(assetPairs []types.AssetPair) map[types.AssetPair]*types.OrderBook {
+	return map[types.AssetPair]*types.OrderBook{BTCUSD: b.orderBook}
+}
+
+func entry(price, quantity int64) types.OrderBookEntry {
+	return types.OrderBookEntry{
+		Price:    decimal.NewFromInt(price),
+		Quantity: decimal.NewFromInt(quantity),
+	}
+}
+
+func order(orderType types.OrderType, price, quantity int64) types.Order {
+	return types.Order{
+		OrderType: orderType,
+		AssetPair: BTCUSD,
+		Price:     decimal.NewFromInt(price),
+		Quantity:  decimal.NewFromInt(quantity),
+	}
+}
+
+func TestExchange(t *testing.T) {
+	exchange := NewExchange(&bookExchange{
+		orderBook: &types.OrderBook{
+			Bids: []types.OrderBookEntry{entry(99, 1), entry(98, 1)},
+			Asks: []types.OrderBookEntry{entry(100, 1), entry(101, 1)},
+		},
+	}, decimal.NewFromFloat(0.01), map[types.Asset]decimal.Decimal{"USD": decimal.NewFromInt(1000)})
+
+	buy := order(types.Buy, 101, 2)
+	orderIds := exchange.ExecuteOrders([]types.Order{buy})
+	status := exchange.GetOrderStatuses([]types.OrderId{orderIds[buy]})[orderIds[buy]]
+	if status.Status != types.Filled || !status.FilledPrice.Equal(decimal.NewFromFloat(100.5)) {
+		t.Fatalf("expected fill of 2 at 100.5, got %v\n", status)
+	}
+	balances := exchange.GetBalances()
+	// 201 notional plus 1% fee
+	if !balances["USD"].Equal(decimal.NewFromFloat(796.99)) || !balances["BTC"].Equal(decimal.NewFromInt(2)) {
+		t.Fatalf("unexpected balances after buy: %v\n", balances)
+	}
+
+	// limit price stops the walk after the first level
+	sell := order(types.Sell, 99, 2)
+	orderIds = exchange.ExecuteOrders([]types.Order{sell})
+	status = exchange.GetOrderStatuses([]types.OrderId{orderIds[sell]})[orderIds[sell]]
+	if status.Status != types.Canceled || !status.FilledQuantity.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("expected partial fill of 1, got %v\n", status)
+	}
+
+	// selling more BTC than held is rejected without touching balances
+	large := order(types.Sell, 98, 2)
+	orderIds = exchange.ExecuteOrders([]types.Order{large})
+	status = exchange.GetOrderStatuses([]types.OrderId{orderIds[large]})[orderIds[large]]
+	if status.Status != types.Canceled || status.FilledQuantity != nil {
+		t.Fatalf("expected rejection, got %v\n", status)
+	}
+	if !exchange.GetBalances()["BTC"].Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("rejected order changed balances: %v\n", exchange.GetBalances())
+	}
+}
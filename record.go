@@ -0,0 +1,134 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "time"
+
+    "github.com/denali-capital/grizzly/config"
+    "github.com/denali-capital/grizzly/recording"
+    "github.com/denali-capital/grizzly/types"
+)
+
+const recordingDirectory string = "data"
+
+func truncate(side []types.OrderBookEntry, depth uint) []types.OrderBookEntry {
+    if depth == 0 || uint(len(side)) <= depth {
+        return side
+    }
+    return side[:depth]
+}
+
+func snapshot(cfg *config.Config, exchanges []types.Exchange, depth uint, writer *recording.Writer) error {
+    for _, exchange := range exchanges {
+        assetPairs := cfg.AssetPairTranslator(exchange.String()).GetAssetPairs()
+        orderBooks := exchange.GetOrderBooks(assetPairs)
+        timestamp := time.Now()
+        for _, assetPair := range assetPairs {
+            spread := exchange.GetCurrentSpread(assetPair)
+            orderBook := orderBooks[assetPair]
+            err := writer.Write(recording.Snapshot{
+                Exchange: exchange.String(),
+                AssetPair: assetPair,
+                Timestamp: timestamp,
+                Spread: &spread,
+                OrderBook: &types.OrderBook{
+                    Bids: truncate(orderBook.Bids, depth),
+                    Asks: truncate(orderBook.Asks, depth),
+                },
+            })
+            if err != nil {
+                return err
+            }
+        }
+    }
+    return writer.Flush()
+}
+
+// labels need each exchange's latency as it was when the market was recorded
+func recordLatencies(exchanges []types.Exchange, writer *recording.Writer) error {
+    for _, exchange := range exchanges {
+        latency := exchange.GetLatency()
+        err := writer.Write(recording.Snapshot{
+            Exchange: exchange.String(),
+            Timestamp: time.Now(),
+            Latency: &latency,
+        })
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func recordCommand(ctx context.Context, cfg *config.Config, args []string) error {
+    flags := flag.NewFlagSet("record", flag.ExitOnError)
+    out := flags.String("out", "", "recording to write, compressed when it ends in .gz (default " + recordingDirectory + "/<unix time>.jsonl.gz)")
+    interval := flags.Duration("interval", time.Second, "time between snapshots")
+    depth := flags.Uint("depth", 20, "order book levels written per side, 0 writes whole books")
+    latencyInterval := flags.Duration("latency-interval", 5 * time.Second, "time between latency measurements, 0 records none")
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: grizzly record [flags] [exchange...]\n")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+
+    path := *out
+    if path == "" {
+        if err := os.MkdirAll(recordingDirectory, 0755); err != nil {
+            return err
+        }
+        path = filepath.Join(recordingDirectory, fmt.Sprintf("%v.jsonl.gz", time.Now().Unix()))
+    }
+
+    exchanges, err := newExchanges(cfg, flags.Args())
+    if err != nil {
+        return err
+    }
+    writer, err := recording.NewWriter(path)
+    if err != nil {
+        return err
+    }
+    logger.Info("recording", "exchanges", exchanges, "path", path)
+    metricsServer := serveMetrics(cfg)
+    defer func() {
+        closeRecorders(exchanges)
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout())
+        defer cancel()
+        shutdownServer(shutdownCtx, metricsServer)
+    }()
+
+    ticker := time.NewTicker(*interval)
+    defer ticker.Stop()
+    // GetLatency makes a REST request, so it is measured far less often than books are written
+    var latencies <-chan time.Time
+    if *latencyInterval > 0 {
+        latencyTicker := time.NewTicker(*latencyInterval)
+        defer latencyTicker.Stop()
+        latencies = latencyTicker.C
+        if err := recordLatencies(exchanges, writer); err != nil {
+            writer.Close()
+            return err
+        }
+    }
+    for {
+        select {
+        case <-ticker.C:
+            if err := snapshot(cfg, exchanges, *depth, writer); err != nil {
+                writer.Close()
+                return err
+            }
+        case <-latencies:
+            if err := recordLatencies(exchanges, writer); err != nil {
+                writer.Close()
+                return err
+            }
+        case <-ctx.Done():
+            // flushes the journal and ends the gzip stream
+            return writer.Close()
+        }
+    }
+}
@@ -0,0 +1,129 @@
+package recording
+
+import (
+    "bufio"
+    "compress/gzip"
+    "encoding/json"
+    "io"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/denali-capital/grizzly/types"
+)
+
+// one observation of an exchange's market for an asset pair
+// Spread, OrderBook and Latency are each optional; latency snapshots have no asset pair
+type Snapshot struct {
+    Exchange  string           `json:"exchange"`
+    AssetPair types.AssetPair  `json:"asset_pair"`
+    Timestamp time.Time        `json:"timestamp"`
+    Spread    *types.Spread    `json:"spread,omitempty"`
+    OrderBook *types.OrderBook `json:"order_book,omitempty"`
+    // the exchange's round trip latency estimate
+    Latency   *time.Duration   `json:"latency,omitempty"`
+}
+
+// JSON lines, gzipped when the path ends in .gz
+type Writer struct {
+    mutex   sync.Mutex
+    file    *os.File
+    gzip    *gzip.Writer
+    buffer  *bufio.Writer
+    encoder *json.Encoder
+}
+
+func NewWriter(path string) (*Writer, error) {
+    file, err := os.OpenFile(path, os.O_CREATE | os.O_EXCL | os.O_WRONLY, 0644)
+    if err != nil {
+        return nil, err
+    }
+    w := &Writer{
+        file: file,
+    }
+    var writer io.Writer = file
+    if strings.HasSuffix(path, ".gz") {
+        w.gzip = gzip.NewWriter(file)
+        writer = w.gzip
+    }
+    w.buffer = bufio.NewWriter(writer)
+    w.encoder = json.NewEncoder(w.buffer)
+    return w, nil
+}
+
+func (w *Writer) Write(snapshot Snapshot) error {
+    w.mutex.Lock()
+    defer w.mutex.Unlock()
+    return w.encoder.Encode(snapshot)
+}
+
+// flushes everything written so far; a recording is only readable up to its last flush
+func (w *Writer) Flush() error {
+    w.mutex.Lock()
+    defer w.mutex.Unlock()
+    if err := w.buffer.Flush(); err != nil {
+        return err
+    }
+    if w.gzip != nil {
+        return w.gzip.Flush()
+    }
+    return nil
+}
+
+func (w *Writer) Close() error {
+    if err := w.Flush(); err != nil {
+        return err
+    }
+    if w.gzip != nil {
+        if err := w.gzip.Close(); err != nil {
+            return err
+        }
+    }
+    return w.file.Close()
+}
+
+type Reader struct {
+    file    *os.File
+    gzip    *gzip.Reader
+    decoder *json.Decoder
+}
+
+func NewReader(path string) (*Reader, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    r := &Reader{
+        file: file,
+    }
+    var reader io.Reader = file
+    if strings.HasSuffix(path, ".gz") {
+        r.gzip, err = gzip.NewReader(file)
+        if err != nil {
+            file.Close()
+            return nil, err
+        }
+        reader = r.gzip
+    }
+    r.decoder = json.NewDecoder(bufio.NewReader(reader))
+    return r, nil
+}
+
+// returns io.EOF once every snapshot has been read
+// a recording cut off mid-line (e.g. by a crash) also ends with io.EOF
+func (r *Reader) Next() (Snapshot, error) {
+    snapshot := Snapshot{}
+    err := r.decoder.Decode(&snapshot)
+    if err == io.ErrUnexpectedEOF {
+        return Snapshot{}, io.EOF
+    }
+    return snapshot, err
+}
+
+func (r *Reader) Close() error {
+    if r.gzip != nil {
+        r.gzip.Close()
+    }
+    return r.file.Close()
+}
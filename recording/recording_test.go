@@ -0,0 +1,115 @@
+package recording
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+var BTCUSD types.AssetPair = types.NewAssetPair("BTC", "USD")
+
+func snapshot(timestamp time.Time, bid, ask int64) Snapshot {
+	return Snapshot{
+		Exchange:  "Kraken",
+		AssetPair: BTCUSD,
+		Timestamp: timestamp,
+		Spread: &types.Spread{
+			Bid:       decimal.NewFromInt(bid),
+			Ask:       decimal.NewFromInt(ask),
+			Timestamp: timestamp,
+		},
+		OrderBook: &types.OrderBook{
+			Bids: []types.OrderBookEntry{{Price: decimal.NewFromInt(bid), Quantity: decimal.NewFromInt(1)}},
+			Asks: []types.OrderBookEntry{{Price: decimal.NewFromInt(ask), Quantity: decimal.NewFromInt(1)}},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	directory, err := ioutil.TempDir("", "recording")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(directory)
+
+	for _, name := range []string{"plain.jsonl", "compressed.jsonl.gz"} {
+		path := filepath.Join(directory, name)
+		writer, err := NewWriter(path)
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		start := time.Unix(1640995200, 0).UTC()
+		for i := int64(0); i < 3; i++ {
+			if err := writer.Write(snapshot(start.Add(time.Duration(i) * time.Second), 100 + i, 101 + i)); err != nil {
+				t.Fatalf("%v\n", err)
+			}
+		}
+		latency := 80 * time.Millisecond
+		if err := writer.Write(Snapshot{Exchange: "Kraken", Timestamp: start, Latency: &latency}); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+
+		reader, err := NewReader(path)
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		replay := NewReplayExchange("Kraken", 10)
+		count := 0
+		for {
+			s, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%v: %v\n", name, err)
+			}
+			replay.Apply(s)
+			count++
+		}
+		reader.Close()
+
+		if count != 4 {
+			t.Fatalf("%v: expected 4 snapshots, got %v\n", name, count)
+		}
+		if replay.GetLatency() != 80 * time.Millisecond {
+			t.Fatalf("%v: expected the recorded latency of 80ms, got %v\n", name, replay.GetLatency())
+		}
+		if spread := replay.GetCurrentSpread(BTCUSD); !spread.Bid.Equal(decimal.NewFromInt(102)) {
+			t.Fatalf("%v: expected latest bid 102, got %v\n", name, spread.Bid)
+		}
+		if orderBook := replay.GetOrderBooks([]types.AssetPair{BTCUSD})[BTCUSD]; !orderBook.Asks[0].Price.Equal(decimal.NewFromInt(103)) {
+			t.Fatalf("%v: expected latest ask 103, got %v\n", name, orderBook.Asks[0].Price)
+		}
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	file, err := ioutil.TempFile("", "truncated*.jsonl")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`{"exchange":"Kraken","asset_pair":{"Base":"BTC","Quote":"USD"},"timestamp":"2022-01-01T00:00:00Z"}` + "\n" + `{"exchange":"Kra`)
+	file.Close()
+
+	reader, err := NewReader(file.Name())
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer reader.Close()
+	if _, err := reader.Next(); err != nil {
+		t.Fatalf("expected the complete snapshot to be read, got %v\n", err)
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Fatalf("expected a cut off snapshot to end the recording, got %v\n", err)
+	}
+}
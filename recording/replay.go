@@ -0,0 +1,143 @@
+package recording
+
+import (
+    "sync"
+    "time"
+
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/denali-capital/grizzly/util"
+    "github.com/shopspring/decimal"
+)
+
+var logger *logging.Logger = logging.New("recording")
+
+// serves recorded market data as if it were live, one snapshot at a time
+// trading is not supported, wrap in a paper.Exchange
+type ReplayExchange struct {
+    name       string
+    capacity   uint
+    mutex      sync.RWMutex
+    spreads    map[types.AssetPair]*util.FixedSizeSpreadQueue
+    orderBooks map[types.AssetPair]*types.OrderBook
+    latency    time.Duration
+}
+
+func NewReplayExchange(name string, capacity uint) *ReplayExchange {
+    return &ReplayExchange{
+        name: name,
+        capacity: capacity,
+        spreads: make(map[types.AssetPair]*util.FixedSizeSpreadQueue),
+        orderBooks: make(map[types.AssetPair]*types.OrderBook),
+    }
+}
+
+func (r *ReplayExchange) Apply(snapshot Snapshot) {
+    r.mutex.Lock()
+    defer r.mutex.Unlock()
+
+    if snapshot.Spread != nil {
+        queue, ok := r.spreads[snapshot.AssetPair]
+        if !ok {
+            queue = util.NewFixedSizeSpreadQueue(r.capacity)
+            r.spreads[snapshot.AssetPair] = queue
+        }
+        queue.Push(*snapshot.Spread)
+    }
+    if snapshot.OrderBook != nil {
+        r.orderBooks[snapshot.AssetPair] = snapshot.OrderBook
+    }
+    if snapshot.Latency != nil {
+        r.latency = *snapshot.Latency
+    }
+}
+
+func (r *ReplayExchange) AssetPairs() []types.AssetPair {
+    r.mutex.RLock()
+    defer r.mutex.RUnlock()
+
+    assetPairs := make([]types.AssetPair, 0, len(r.orderBooks))
+    for assetPair := range r.orderBooks {
+        assetPairs = append(assetPairs, assetPair)
+    }
+    return assetPairs
+}
+
+func (r *ReplayExchange) String() string {
+    return r.name
+}
+
+func (r *ReplayExchange) GetHistoricalSpreads(assetPairs []types.AssetPair, duration time.Duration, samples uint) map[types.AssetPair][]types.Spread {
+    r.mutex.RLock()
+    defer r.mutex.RUnlock()
+
+    historicalSpreads := make(map[types.AssetPair][]types.Spread)
+    for _, assetPair := range assetPairs {
+        queue, ok := r.spreads[assetPair]
+        if !ok || samples == 0 || duration <= 0 {
+            historicalSpreads[assetPair] = []types.Spread{}
+            continue
+        }
+        historicalSpreads[assetPair] = util.GetSpreadSamples(queue.Data(), duration, samples)
+    }
+    return historicalSpreads
+}
+
+func (r *ReplayExchange) GetCurrentSpread(assetPair types.AssetPair) types.Spread {
+    r.mutex.RLock()
+    defer r.mutex.RUnlock()
+
+    queue, ok := r.spreads[assetPair]
+    if !ok {
+        return types.Spread{}
+    }
+    spreads := queue.Data()
+    if len(spreads) == 0 {
+        return types.Spread{}
+    }
+    return spreads[len(spreads) - 1]
+}
+
+func (r *ReplayExchange) GetOrderBooks(assetPairs []types.AssetPair) map[types.AssetPair]*types.OrderBook {
+    r.mutex.RLock()
+    defer r.mutex.RUnlock()
+
+    orderBooks := make(map[types.AssetPair]*types.OrderBook)
+    for _, assetPair := range assetPairs {
+        if orderBook, ok := r.orderBooks[assetPair]; ok {
+            orderBooks[assetPair] = orderBook
+        } else {
+            orderBooks[assetPair] = &types.OrderBook{}
+        }
+    }
+    return orderBooks
+}
+
+// the latest latency recorded, 0 for recordings without any
+func (r *ReplayExchange) GetLatency() time.Duration {
+    r.mutex.RLock()
+    defer r.mutex.RUnlock()
+    return r.latency
+}
+
+func (r *ReplayExchange) ExecuteOrders(orders []types.Order) map[types.Order]types.OrderId {
+    logger.Fatal("replayed exchanges cannot trade", logging.Exchange(r.name))
+    return nil
+}
+
+func (r *ReplayExchange) GetOrderStatuses(orderIds []types.OrderId) map[types.OrderId]types.OrderStatus {
+    logger.Fatal("replayed exchanges cannot trade", logging.Exchange(r.name))
+    return nil
+}
+
+func (r *ReplayExchange) CancelOrders(orderIds []types.OrderId) {
+    logger.Fatal("replayed exchanges cannot trade", logging.Exchange(r.name))
+}
+
+func (r *ReplayExchange) GetOpenOrders() map[types.OrderId]types.OrderStatus {
+    return make(map[types.OrderId]types.OrderStatus)
+}
+
+func (r *ReplayExchange) GetBalances() map[types.Asset]decimal.Decimal {
+    return make(map[types.Asset]decimal.Decimal)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+
+    "github.com/denali-capital/grizzly/config"
+    "github.com/denali-capital/grizzly/secrets"
+)
+
+const secretsUsage string = `usage: grizzly secrets <command> [exchange...]
+
+commands:
+    add <exchange>        store credentials for an exchange
+    rotate <exchange>     replace stored credentials, blank input keeps the current value
+    verify [exchange...]  check credentials load for the given or every enabled exchange
+
+credentials are read from stdin one per line: api key, secret key and, where required, passphrase
+`
+
+func prompt(reader *bufio.Reader, label string) (string, error) {
+    fmt.Fprintf(os.Stderr, "%v: ", label)
+    line, err := reader.ReadString('\n')
+    if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+        return "", err
+    }
+    return strings.TrimSpace(line), nil
+}
+
+// blank answers fall back to current
+func readCredentials(exchange string, current secrets.Credentials) (secrets.Credentials, error) {
+    reader := bufio.NewReader(os.Stdin)
+    credentials := current
+    fields := []struct {
+        label string
+        value *string
+    }{
+        {"API key", &credentials.ApiKey},
+        {"Secret key", &credentials.SecretKey},
+    }
+    if secrets.RequiresPassphrase(exchange) {
+        fields = append(fields, struct {
+            label string
+            value *string
+        }{"Passphrase", &credentials.Passphrase})
+    }
+    for _, field := range fields {
+        value, err := prompt(reader, exchange + " " + field.label)
+        if err != nil {
+            return secrets.Credentials{}, err
+        }
+        if value != "" {
+            *field.value = value
+        }
+    }
+    return credentials, credentials.Validate(secrets.RequiresPassphrase(exchange))
+}
+
+func store(provider secrets.Provider, exchange string, credentials secrets.Credentials) error {
+    if err := provider.Put(exchange, credentials); err != nil {
+        if errors.Is(err, secrets.ErrReadOnly) {
+            return fmt.Errorf("the %v provider is read-only, set the variables in .env instead", provider)
+        }
+        return err
+    }
+    // read back so a bad write is noticed now rather than at startup
+    if _, err := secrets.Load(provider, exchange); err != nil {
+        return err
+    }
+    fmt.Printf("%v: stored in %v\n", exchange, provider)
+    return nil
+}
+
+func secretsCommand(ctx context.Context, cfg *config.Config, args []string) error {
+    if len(args) == 0 {
+        return errors.New(secretsUsage)
+    }
+    provider, err := secrets.NewProvider(cfg.Secrets.Provider, cfg.Secrets.Path, cfg.Secrets.VaultAddress)
+    if err != nil {
+        return err
+    }
+
+    switch args[0] {
+    case "add":
+        if len(args) != 2 {
+            return errors.New(secretsUsage)
+        }
+        exchange := args[1]
+        if _, ok := cfg.Exchanges[exchange]; !ok {
+            return fmt.Errorf("%v is not configured", exchange)
+        }
+        if _, err := provider.Get(exchange); err == nil {
+            return fmt.Errorf("%v already has credentials, use rotate", exchange)
+        } else if !errors.Is(err, secrets.ErrNotFound) {
+            return err
+        }
+        credentials, err := readCredentials(exchange, secrets.Credentials{})
+        if err != nil {
+            return err
+        }
+        return store(provider, exchange, credentials)
+    case "rotate":
+        if len(args) != 2 {
+            return errors.New(secretsUsage)
+        }
+        exchange := args[1]
+        current, err := provider.Get(exchange)
+        if err != nil {
+            return fmt.Errorf("%v: %w", exchange, err)
+        }
+        credentials, err := readCredentials(exchange, current)
+        if err != nil {
+            return err
+        }
+        return store(provider, exchange, credentials)
+    case "verify":
+        exchanges := args[1:]
+        if len(exchanges) == 0 {
+            exchanges = cfg.EnabledExchanges()
+        }
+        failed := false
+        for _, exchange := range exchanges {
+            if _, err := secrets.Load(provider, exchange); err != nil {
+                fmt.Printf("%v: %v\n", exchange, err)
+                failed = true
+                continue
+            }
+            fmt.Printf("%v: ok (%v)\n", exchange, provider)
+        }
+        if failed {
+            return errors.New("some credentials could not be loaded")
+        }
+        return nil
+    }
+    return errors.New(secretsUsage)
+}
@@ -0,0 +1,38 @@
+package secrets
+
+import (
+    "os"
+    "strings"
+)
+
+const ApiKeySuffix string = "_API_KEY"
+const SecretKeySuffix string = "_SECRET_KEY"
+const PassphraseSuffix string = "_API_PASSPHRASE"
+
+// reads <EXCHANGE>_API_KEY, <EXCHANGE>_SECRET_KEY and <EXCHANGE>_API_PASSPHRASE, e.g. from .env
+type EnvProvider struct{}
+
+func NewEnvProvider() *EnvProvider {
+    return &EnvProvider{}
+}
+
+func (e *EnvProvider) String() string {
+    return "env"
+}
+
+func (e *EnvProvider) Get(exchange string) (Credentials, error) {
+    prefix := strings.ToUpper(exchange)
+    credentials := Credentials{
+        ApiKey: os.Getenv(prefix + ApiKeySuffix),
+        SecretKey: os.Getenv(prefix + SecretKeySuffix),
+        Passphrase: os.Getenv(prefix + PassphraseSuffix),
+    }
+    if credentials == (Credentials{}) {
+        return Credentials{}, ErrNotFound
+    }
+    return credentials, nil
+}
+
+func (e *EnvProvider) Put(exchange string, credentials Credentials) error {
+    return ErrReadOnly
+}
@@ -0,0 +1,309 @@
+package secrets
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "hash"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "sync"
+)
+
+const fileVersion int = 1
+const kdfAlgorithm string = "pbkdf2-sha256"
+const defaultIterations int = 600000
+const saltSize int = 16
+const keySize int = 32
+
+const apiKeyField string = "api_key"
+const secretKeyField string = "secret_key"
+const passphraseField string = "passphrase"
+
+var encryptedValue *regexp.Regexp = regexp.MustCompile(`^ENC\[AES256_GCM,data:([A-Za-z0-9+/=]+),iv:([A-Za-z0-9+/=]+)\]$`)
+
+// sops-style encrypted file: exchange names and field names stay readable so the file can be
+// reviewed and diffed, every value is sealed with AES-256-GCM under a passphrase-derived key
+// and the whole tree is authenticated with a MAC
+type FileProvider struct {
+    path       string
+    passphrase []byte
+    iterations int
+    mutex      sync.Mutex
+}
+
+type kdf struct {
+    Algorithm  string `json:"algorithm"`
+    Iterations int    `json:"iterations"`
+    Salt       string `json:"salt"`
+}
+
+type encryptedFile struct {
+    Version   int                          `json:"version"`
+    Kdf       kdf                          `json:"kdf"`
+    Mac       string                       `json:"mac"`
+    Exchanges map[string]map[string]string `json:"exchanges"`
+}
+
+func NewFileProvider(path, passphrase string) *FileProvider {
+    return &FileProvider{
+        path: path,
+        passphrase: []byte(passphrase),
+        iterations: defaultIterations,
+    }
+}
+
+func (f *FileProvider) String() string {
+    return "file " + f.path
+}
+
+// RFC 8018
+func pbkdf2(password, salt []byte, iterations, size int, h func() hash.Hash) []byte {
+    prf := hmac.New(h, password)
+    blocks := (size + prf.Size() - 1) / prf.Size()
+    key := make([]byte, 0, blocks * prf.Size())
+    counter := make([]byte, 4)
+    for block := 1; block <= blocks; block++ {
+        prf.Reset()
+        prf.Write(salt)
+        binary.BigEndian.PutUint32(counter, uint32(block))
+        prf.Write(counter)
+        u := prf.Sum(nil)
+        t := make([]byte, len(u))
+        copy(t, u)
+        for i := 1; i < iterations; i++ {
+            prf.Reset()
+            prf.Write(u)
+            u = prf.Sum(u[:0])
+            for j := range t {
+                t[j] ^= u[j]
+            }
+        }
+        key = append(key, t...)
+    }
+    return key[:size]
+}
+
+type keys struct {
+    encryption []byte
+    mac        []byte
+}
+
+func (f *FileProvider) deriveKeys(k kdf) (keys, error) {
+    if k.Algorithm != kdfAlgorithm {
+        return keys{}, fmt.Errorf("unsupported kdf %v", k.Algorithm)
+    }
+    salt, err := base64.StdEncoding.DecodeString(k.Salt)
+    if err != nil {
+        return keys{}, fmt.Errorf("invalid kdf salt: %w", err)
+    }
+    derived := pbkdf2(f.passphrase, salt, k.Iterations, 2 * keySize, sha256.New)
+    return keys{
+        encryption: derived[:keySize],
+        mac: derived[keySize:],
+    }, nil
+}
+
+// covers exchange names, field names and ciphertexts so entries cannot be removed or swapped
+func computeMac(key []byte, exchanges map[string]map[string]string) string {
+    mac := hmac.New(sha256.New, key)
+    names := make([]string, 0, len(exchanges))
+    for name := range exchanges {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        fields := make([]string, 0, len(exchanges[name]))
+        for field := range exchanges[name] {
+            fields = append(fields, field)
+        }
+        sort.Strings(fields)
+        for _, field := range fields {
+            fmt.Fprintf(mac, "%q.%q=%q\n", name, field, exchanges[name][field])
+        }
+    }
+    return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newGcm(key []byte) (cipher.AEAD, error) {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    return cipher.NewGCM(block)
+}
+
+// the exchange and field are authenticated as additional data so a value only decrypts in place
+func encrypt(key []byte, exchange, field, plaintext string) (string, error) {
+    gcm, err := newGcm(key)
+    if err != nil {
+        return "", err
+    }
+    nonce := make([]byte, gcm.NonceSize())
+    if _, err := rand.Read(nonce); err != nil {
+        return "", err
+    }
+    ciphertext := gcm.Seal(nil, nonce, []byte(plaintext), []byte(exchange + "." + field))
+    return fmt.Sprintf("ENC[AES256_GCM,data:%v,iv:%v]", base64.StdEncoding.EncodeToString(ciphertext), base64.StdEncoding.EncodeToString(nonce)), nil
+}
+
+func decrypt(key []byte, exchange, field, value string) (string, error) {
+    matches := encryptedValue.FindStringSubmatch(value)
+    if matches == nil {
+        return "", fmt.Errorf("%v.%v is not an encrypted value", exchange, field)
+    }
+    ciphertext, err := base64.StdEncoding.DecodeString(matches[1])
+    if err != nil {
+        return "", err
+    }
+    nonce, err := base64.StdEncoding.DecodeString(matches[2])
+    if err != nil {
+        return "", err
+    }
+    gcm, err := newGcm(key)
+    if err != nil {
+        return "", err
+    }
+    if len(nonce) != gcm.NonceSize() {
+        return "", fmt.Errorf("%v.%v has an invalid iv", exchange, field)
+    }
+    plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(exchange + "." + field))
+    if err != nil {
+        return "", fmt.Errorf("%v.%v could not be decrypted", exchange, field)
+    }
+    return string(plaintext), nil
+}
+
+// returns a fresh file when none exists yet
+func (f *FileProvider) read() (*encryptedFile, keys, error) {
+    data, err := ioutil.ReadFile(f.path)
+    if errors.Is(err, os.ErrNotExist) {
+        salt := make([]byte, saltSize)
+        if _, err := rand.Read(salt); err != nil {
+            return nil, keys{}, err
+        }
+        file := &encryptedFile{
+            Version: fileVersion,
+            Kdf: kdf{
+                Algorithm: kdfAlgorithm,
+                Iterations: f.iterations,
+                Salt: base64.StdEncoding.EncodeToString(salt),
+            },
+            Exchanges: make(map[string]map[string]string),
+        }
+        k, err := f.deriveKeys(file.Kdf)
+        return file, k, err
+    }
+    if err != nil {
+        return nil, keys{}, err
+    }
+
+    file := &encryptedFile{}
+    if err := json.Unmarshal(data, file); err != nil {
+        return nil, keys{}, fmt.Errorf("%v: %w", f.path, err)
+    }
+    if file.Version != fileVersion {
+        return nil, keys{}, fmt.Errorf("%v: unsupported version %v", f.path, file.Version)
+    }
+    if file.Exchanges == nil {
+        file.Exchanges = make(map[string]map[string]string)
+    }
+    k, err := f.deriveKeys(file.Kdf)
+    if err != nil {
+        return nil, keys{}, fmt.Errorf("%v: %w", f.path, err)
+    }
+    if !hmac.Equal([]byte(computeMac(k.mac, file.Exchanges)), []byte(file.Mac)) {
+        return nil, keys{}, fmt.Errorf("%v: MAC mismatch, wrong passphrase or the file was modified", f.path)
+    }
+    return file, k, nil
+}
+
+// written to a temporary file and renamed so a crash never leaves a partial file behind
+func (f *FileProvider) write(file *encryptedFile, k keys) error {
+    file.Mac = computeMac(k.mac, file.Exchanges)
+    data, err := json.MarshalIndent(file, "", "  ")
+    if err != nil {
+        return err
+    }
+    temporary, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path) + ".tmp")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(temporary.Name())
+    if _, err := temporary.Write(append(data, '\n')); err != nil {
+        temporary.Close()
+        return err
+    }
+    if err := temporary.Chmod(0600); err != nil {
+        temporary.Close()
+        return err
+    }
+    if err := temporary.Close(); err != nil {
+        return err
+    }
+    return os.Rename(temporary.Name(), f.path)
+}
+
+func (f *FileProvider) Get(exchange string) (Credentials, error) {
+    f.mutex.Lock()
+    defer f.mutex.Unlock()
+
+    file, k, err := f.read()
+    if err != nil {
+        return Credentials{}, err
+    }
+    fields, ok := file.Exchanges[exchange]
+    if !ok {
+        return Credentials{}, ErrNotFound
+    }
+    plaintexts := make(map[string]string)
+    for field, value := range fields {
+        plaintext, err := decrypt(k.encryption, exchange, field, value)
+        if err != nil {
+            return Credentials{}, fmt.Errorf("%v: %w", f.path, err)
+        }
+        plaintexts[field] = plaintext
+    }
+    return Credentials{
+        ApiKey: plaintexts[apiKeyField],
+        SecretKey: plaintexts[secretKeyField],
+        Passphrase: plaintexts[passphraseField],
+    }, nil
+}
+
+func (f *FileProvider) Put(exchange string, credentials Credentials) error {
+    f.mutex.Lock()
+    defer f.mutex.Unlock()
+
+    file, k, err := f.read()
+    if err != nil {
+        return err
+    }
+    fields := make(map[string]string)
+    for field, plaintext := range map[string]string{
+        apiKeyField: credentials.ApiKey,
+        secretKeyField: credentials.SecretKey,
+        passphraseField: credentials.Passphrase,
+    } {
+        if plaintext == "" {
+            continue
+        }
+        value, err := encrypt(k.encryption, exchange, field, plaintext)
+        if err != nil {
+            return err
+        }
+        fields[field] = value
+    }
+    file.Exchanges[exchange] = fields
+    return f.write(file, k)
+}
@@ -0,0 +1,95 @@
+package secrets
+
+import (
+    "errors"
+    "fmt"
+    "os"
+)
+
+const PassphraseEnvVar string = "GRIZZLY_SECRETS_PASSPHRASE"
+const VaultTokenEnvVar string = "GRIZZLY_VAULT_TOKEN"
+
+var ErrNotFound error = errors.New("credentials not found")
+var ErrReadOnly error = errors.New("provider is read-only")
+
+type Credentials struct {
+    ApiKey     string
+    SecretKey  string
+    // only required by some exchanges (KuCoin)
+    Passphrase string
+}
+
+func redact(secret string) string {
+    if secret == "" {
+        return ""
+    }
+    return "[redacted]"
+}
+
+// keeps key material out of logs, however the credentials are formatted
+func (c Credentials) String() string {
+    return fmt.Sprintf("Credentials{ApiKey: %v, SecretKey: %v, Passphrase: %v}", redact(c.ApiKey), redact(c.SecretKey), redact(c.Passphrase))
+}
+
+func (c Credentials) GoString() string {
+    return c.String()
+}
+
+func (c Credentials) Validate(requirePassphrase bool) error {
+    if c.ApiKey == "" {
+        return errors.New("api key is empty")
+    }
+    if c.SecretKey == "" {
+        return errors.New("secret key is empty")
+    }
+    if requirePassphrase && c.Passphrase == "" {
+        return errors.New("passphrase is empty")
+    }
+    return nil
+}
+
+// exchanges whose API requires a passphrase next to the key pair
+func RequiresPassphrase(exchange string) bool {
+    return exchange == "KuCoin"
+}
+
+type Provider interface {
+    String() string
+    Get(exchange string) (Credentials, error)
+    // returns ErrReadOnly for backends grizzly cannot write to
+    Put(exchange string, credentials Credentials) error
+}
+
+// kind is one of env, file or vault
+// the file passphrase and vault token are read from the environment so they never live in config
+func NewProvider(kind, path, vaultAddress string) (Provider, error) {
+    switch kind {
+    case "env":
+        return NewEnvProvider(), nil
+    case "file":
+        passphrase := os.Getenv(PassphraseEnvVar)
+        if passphrase == "" {
+            return nil, fmt.Errorf("%v must be set to use the file secrets provider", PassphraseEnvVar)
+        }
+        return NewFileProvider(path, passphrase), nil
+    case "vault":
+        token := os.Getenv(VaultTokenEnvVar)
+        if token == "" {
+            return nil, fmt.Errorf("%v must be set to use the vault secrets provider", VaultTokenEnvVar)
+        }
+        return NewVaultProvider(vaultAddress, token), nil
+    }
+    return nil, fmt.Errorf("unknown secrets provider %v", kind)
+}
+
+// loads credentials for exchange and checks they are complete
+func Load(provider Provider, exchange string) (Credentials, error) {
+    credentials, err := provider.Get(exchange)
+    if err != nil {
+        return Credentials{}, fmt.Errorf("%v (%v): %w", exchange, provider, err)
+    }
+    if err := credentials.Validate(RequiresPassphrase(exchange)); err != nil {
+        return Credentials{}, fmt.Errorf("%v (%v): %w", exchange, provider, err)
+    }
+    return credentials, nil
+}
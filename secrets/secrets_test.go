@@ -0,0 +1,183 @@
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var kuCoinCredentials Credentials = Credentials{
+	ApiKey:     "61cff0fbd5e581000154c4a5",
+	SecretKey:  "not-a-real-secret",
+	Passphrase: "not-a-real-passphrase",
+}
+
+func TestPbkdf2(t *testing.T) {
+	// RFC 7914 section 11
+	expected := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if key := hex.EncodeToString(pbkdf2([]byte("passwd"), []byte("salt"), 1, 64, sha256.New)); key != expected {
+		t.Fatalf("expected %v, got %v\n", expected, key)
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		formatted := fmt.Sprintf(format, kuCoinCredentials)
+		if strings.Contains(formatted, kuCoinCredentials.ApiKey) || strings.Contains(formatted, kuCoinCredentials.SecretKey) || strings.Contains(formatted, kuCoinCredentials.Passphrase) {
+			t.Fatalf("%v leaks key material: %v\n", format, formatted)
+		}
+	}
+}
+
+func newTestFileProvider(path, passphrase string) *FileProvider {
+	provider := NewFileProvider(path, passphrase)
+	// keeps the tests fast, production files use defaultIterations
+	provider.iterations = 1000
+	return provider
+}
+
+func TestFileProvider(t *testing.T) {
+	directory, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(directory)
+	path := filepath.Join(directory, "secrets.json")
+
+	provider := newTestFileProvider(path, "correct horse battery staple")
+	if _, err := provider.Get("KuCoin"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before anything is stored, got %v\n", err)
+	}
+	if err := provider.Put("KuCoin", kuCoinCredentials); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	credentials, err := Load(provider, "KuCoin")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if credentials != kuCoinCredentials {
+		t.Fatalf("credentials did not round trip\n")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if strings.Contains(string(data), kuCoinCredentials.ApiKey) || strings.Contains(string(data), kuCoinCredentials.SecretKey) {
+		t.Fatalf("secrets file contains plaintext key material\n")
+	}
+
+	t.Run("WrongPassphrase", func(t *testing.T) {
+		if _, err := newTestFileProvider(path, "wrong").Get("KuCoin"); err == nil || !strings.Contains(err.Error(), "MAC mismatch") {
+			t.Fatalf("expected a MAC mismatch, got %v\n", err)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		file := encryptedFile{}
+		if err := json.Unmarshal(data, &file); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		// moving a ciphertext to another exchange must not decrypt
+		file.Exchanges["Kraken"] = file.Exchanges["KuCoin"]
+		tampered, _ := json.Marshal(file)
+		tamperedPath := filepath.Join(directory, "tampered.json")
+		if err := ioutil.WriteFile(tamperedPath, tampered, 0600); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if _, err := newTestFileProvider(tamperedPath, "correct horse battery staple").Get("Kraken"); err == nil {
+			t.Fatalf("expected tampering to be detected\n")
+		}
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		rotated := kuCoinCredentials
+		rotated.SecretKey = "rotated"
+		if err := provider.Put("KuCoin", rotated); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if credentials, err := provider.Get("KuCoin"); err != nil || credentials.SecretKey != "rotated" {
+			t.Fatalf("expected rotated secret key, got %v %v\n", credentials, err)
+		}
+	})
+}
+
+// stand-in for a local vault speaking the KV v1 API
+type vault struct {
+	token   string
+	mutex   sync.Mutex
+	secrets map[string]map[string]string
+}
+
+func (v *vault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Token") != v.token {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		data, ok := v.secrets[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(vaultSecret{Data: data})
+	case http.MethodPut:
+		data := make(map[string]string)
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		v.secrets[r.URL.Path] = data
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(&vault{
+		token:   "token",
+		secrets: make(map[string]map[string]string),
+	})
+	defer server.Close()
+
+	provider := NewVaultProvider(server.URL, "token")
+	if _, err := provider.Get("KuCoin"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v\n", err)
+	}
+	if err := provider.Put("KuCoin", kuCoinCredentials); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if credentials, err := Load(provider, "KuCoin"); err != nil || credentials != kuCoinCredentials {
+		t.Fatalf("credentials did not round trip: %v\n", err)
+	}
+	if _, err := NewVaultProvider(server.URL, "wrong").Get("KuCoin"); err == nil {
+		t.Fatalf("expected a bad token to be rejected\n")
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	os.Setenv("TESTEXCHANGE" + ApiKeySuffix, "key")
+	defer os.Unsetenv("TESTEXCHANGE" + ApiKeySuffix)
+
+	provider := NewEnvProvider()
+	if _, err := Load(provider, "TestExchange"); err == nil {
+		t.Fatalf("expected a missing secret key to be reported\n")
+	}
+	if err := provider.Put("TestExchange", kuCoinCredentials); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v\n", err)
+	}
+}
@@ -0,0 +1,99 @@
+package secrets
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+)
+
+const vaultMount string = "/v1/secret/grizzly/"
+const vaultTimeout time.Duration = 5 * time.Second
+
+// talks to a local HTTP vault using the Vault KV v1 API, credentials live at secret/grizzly/<exchange>
+type VaultProvider struct {
+    address    string
+    token      string
+    httpClient *http.Client
+}
+
+type vaultSecret struct {
+    Data map[string]string `json:"data"`
+}
+
+func NewVaultProvider(address, token string) *VaultProvider {
+    return &VaultProvider{
+        address: strings.TrimSuffix(address, "/"),
+        token: token,
+        httpClient: &http.Client{Timeout: vaultTimeout},
+    }
+}
+
+func (v *VaultProvider) String() string {
+    return "vault " + v.address
+}
+
+func (v *VaultProvider) do(method, exchange string, body []byte) (*http.Response, error) {
+    request, err := http.NewRequest(method, v.address + vaultMount + exchange, bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    request.Header.Set("X-Vault-Token", v.token)
+    if body != nil {
+        request.Header.Set("Content-Type", "application/json")
+    }
+    return v.httpClient.Do(request)
+}
+
+func (v *VaultProvider) Get(exchange string) (Credentials, error) {
+    response, err := v.do(http.MethodGet, exchange, nil)
+    if err != nil {
+        return Credentials{}, err
+    }
+    defer response.Body.Close()
+
+    switch response.StatusCode {
+    case http.StatusOK:
+    case http.StatusNotFound:
+        return Credentials{}, ErrNotFound
+    default:
+        // the body may echo the request, so only the status is reported
+        return Credentials{}, fmt.Errorf("vault returned %v", response.Status)
+    }
+
+    secret := vaultSecret{}
+    if err := json.NewDecoder(response.Body).Decode(&secret); err != nil {
+        return Credentials{}, fmt.Errorf("invalid vault response: %w", err)
+    }
+    return Credentials{
+        ApiKey: secret.Data[apiKeyField],
+        SecretKey: secret.Data[secretKeyField],
+        Passphrase: secret.Data[passphraseField],
+    }, nil
+}
+
+func (v *VaultProvider) Put(exchange string, credentials Credentials) error {
+    data := map[string]string{
+        apiKeyField: credentials.ApiKey,
+        secretKeyField: credentials.SecretKey,
+    }
+    if credentials.Passphrase != "" {
+        data[passphraseField] = credentials.Passphrase
+    }
+    body, err := json.Marshal(data)
+    if err != nil {
+        return err
+    }
+    response, err := v.do(http.MethodPut, exchange, body)
+    if err != nil {
+        return err
+    }
+    defer response.Body.Close()
+
+    if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNoContent {
+        return fmt.Errorf("vault returned %v", response.Status)
+    }
+    return nil
+}
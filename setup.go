@@ -0,0 +1,282 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "os"
+    "time"
+
+    "github.com/denali-capital/grizzly/arbitrage"
+    "github.com/denali-capital/grizzly/candles"
+    "github.com/denali-capital/grizzly/config"
+    "github.com/denali-capital/grizzly/control"
+    "github.com/denali-capital/grizzly/conversion"
+    "github.com/denali-capital/grizzly/decision"
+    "github.com/denali-capital/grizzly/execution"
+    "github.com/denali-capital/grizzly/exchanges/binanceus"
+    "github.com/denali-capital/grizzly/exchanges/kraken"
+    "github.com/denali-capital/grizzly/exchanges/kucoin"
+    "github.com/denali-capital/grizzly/features"
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
+    "github.com/denali-capital/grizzly/secrets"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/denali-capital/grizzly/util"
+    "github.com/shopspring/decimal"
+)
+
+type constructor func(cfg *config.Config, provider secrets.Provider) types.Exchange
+
+var implementedExchanges map[string]constructor = map[string]constructor{
+    "BinanceUS": func(cfg *config.Config, provider secrets.Provider) types.Exchange {
+        exchangeConfig := cfg.Exchanges["BinanceUS"]
+        return binanceus.NewBinanceUS(provider, cfg.AssetPairTranslator("BinanceUS"), exchangeConfig.SpreadCapacity, cfg.TradeCapacity("BinanceUS"), exchangeConfig.OrderBookDepth)
+    },
+    "Kraken": func(cfg *config.Config, provider secrets.Provider) types.Exchange {
+        exchangeConfig := cfg.Exchanges["Kraken"]
+        return kraken.NewKraken(provider, cfg.AssetPairTranslator("Kraken"), cfg.WebSocketAssetPairTranslator("Kraken"), exchangeConfig.SpreadCapacity, cfg.TradeCapacity("Kraken"), exchangeConfig.OrderBookDepth)
+    },
+    "KuCoin": func(cfg *config.Config, provider secrets.Provider) types.Exchange {
+        exchangeConfig := cfg.Exchanges["KuCoin"]
+        return kucoin.NewKuCoin(provider, cfg.AssetPairTranslator("KuCoin"), exchangeConfig.SpreadCapacity, cfg.TradeCapacity("KuCoin"), exchangeConfig.OrderBookDepth)
+    },
+}
+
+// names defaults to every enabled exchange
+func newExchanges(cfg *config.Config, names []string) ([]types.Exchange, error) {
+    if len(names) == 0 {
+        names = cfg.EnabledExchanges()
+    }
+    for _, name := range names {
+        if _, ok := implementedExchanges[name]; !ok {
+            return nil, fmt.Errorf("exchange implementation not found for %v", name)
+        }
+    }
+
+    provider, err := secrets.NewProvider(cfg.Secrets.Provider, cfg.Secrets.Path, cfg.Secrets.VaultAddress)
+    if err != nil {
+        return nil, err
+    }
+
+    exchanges := make([]types.Exchange, len(names))
+    for i, name := range names {
+        exchanges[i] = implementedExchanges[name](cfg, provider)
+    }
+    return exchanges, nil
+}
+
+func newVenues(cfg *config.Config, exchanges []types.Exchange) []arbitrage.Venue {
+    venues := make([]arbitrage.Venue, len(exchanges))
+    for i, exchange := range exchanges {
+        venues[i] = arbitrage.Venue{
+            Exchange: exchange,
+            AssetPairs: cfg.AssetPairTranslator(exchange.String()).GetAssetPairs(),
+            Fee: cfg.Fee(exchange.String()),
+        }
+    }
+    return venues
+}
+
+func newConverter(cfg *config.Config, exchanges []types.Exchange) *conversion.Converter {
+    exchangesByName := make(map[string]types.Exchange)
+    for _, exchange := range exchanges {
+        exchangesByName[exchange.String()] = exchange
+    }
+    converter := conversion.NewConverter(types.Asset(cfg.Conversion.Numeraire))
+    for _, equivalent := range cfg.Conversion.Equivalents {
+        exchange, ok := exchangesByName[equivalent.Exchange]
+        if !ok {
+            logger.Warn("exchange is not enabled, not converting", logging.Exchange(equivalent.Exchange), "asset", equivalent.Asset)
+            continue
+        }
+        assetPair, _ := cfg.AssetPair(equivalent.AssetPair)
+        converter.AddEquivalent(types.Asset(equivalent.Asset), exchange, assetPair, equivalent.CostFraction())
+    }
+    return converter
+}
+
+// GetOrderStatuses makes a REST request, so open orders are polled far less often than placed
+const settleInterval time.Duration = 10 * time.Second
+
+// the day's loss is checked as often as strategies look for opportunities
+func riskLimits(cfg *config.Config) execution.Limits {
+    return execution.Limits{
+        MaxOrderNotional: decimal.NewFromFloat(cfg.Risk.MaxOrderNotional),
+        MaxOpenOrders: cfg.Risk.MaxOpenOrders,
+        MaxDailyLoss: decimal.NewFromFloat(cfg.Risk.MaxDailyLoss),
+        LossInterval: cfg.Trading.SleepDuration.Duration,
+        SettleInterval: settleInterval,
+    }
+}
+
+// config has been validated, so parse errors cannot happen here
+func configureLogging(cfg *config.Config) {
+    format := logging.Text
+    if cfg.Logging.Format != "" {
+        format, _ = logging.ParseFormat(cfg.Logging.Format)
+    }
+    logging.Configure(format, os.Stderr)
+    if cfg.Logging.Level != "" {
+        level, _ := logging.ParseLevel(cfg.Logging.Level)
+        logging.SetLevel("", level)
+    }
+    for component, levelString := range cfg.Logging.Levels {
+        level, _ := logging.ParseLevel(levelString)
+        logging.SetLevel(component, level)
+    }
+    // dependencies still log through the standard library
+    logging.RedirectStandardLog("main")
+}
+
+// ErrServerClosed is expected once shutdownServer has been called
+func listenAndServe(server *http.Server, name string) {
+    if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+        logger.Fatal(name + " server failed", logging.Err(err))
+    }
+}
+
+// lets in-flight requests such as a final scrape finish, nil servers are skipped
+func shutdownServer(ctx context.Context, server *http.Server) {
+    if server == nil {
+        return
+    }
+    if err := server.Shutdown(ctx); err != nil {
+        logger.Warn("server did not shut down cleanly", "address", server.Addr, logging.Err(err))
+    }
+}
+
+// serves /metrics and /debug/log in the background when configured, nil otherwise
+func serveMetrics(cfg *config.Config) *http.Server {
+    if cfg.Metrics.Address == "" {
+        return nil
+    }
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", metrics.DefaultRegistry)
+    mux.Handle("/debug/log", logging.Handler())
+    server := &http.Server{Addr: cfg.Metrics.Address, Handler: mux}
+    go listenAndServe(server, "metrics")
+    logger.Info("serving metrics", "url", "http://" + cfg.Metrics.Address + "/metrics")
+    return server
+}
+
+// exchange -> every configured asset pair
+func configuredAssetPairs(cfg *config.Config, exchanges []types.Exchange) map[string][]types.AssetPair {
+    assetPairs := make(map[string][]types.AssetPair, len(exchanges))
+    for _, exchange := range exchanges {
+        assetPairs[exchange.String()] = cfg.AssetPairTranslator(exchange.String()).GetAssetPairs()
+    }
+    return assetPairs
+}
+
+// backfills when configured, then aggregates candles in the background until ctx is canceled
+func aggregateCandles(ctx context.Context, cfg *config.Config, exchanges []types.Exchange, assetPairs map[string][]types.AssetPair, state *control.State) *candles.Aggregator {
+    aggregator := candles.NewAggregator(exchanges, assetPairs, cfg.CandleCapacity(), cfg.Candles.Mid == "microprice")
+    if state != nil {
+        aggregator.Filter(state.Registered)
+    }
+    if cfg.Candles.Backfill {
+        aggregator.Backfill()
+    }
+    go aggregator.Run(ctx, cfg.CandlePollInterval())
+    return aggregator
+}
+
+// config has been validated, so the estimator always parses
+func volatilityOptions(cfg *config.Config) util.VolatilityOptions {
+    estimator, _ := util.ParseVolatilityEstimator(cfg.VolatilityEstimator())
+    return util.VolatilityOptions{
+        Estimator: estimator,
+        Interval: cfg.VolatilityInterval(),
+        Window: cfg.VolatilityWindow(),
+        Horizon: cfg.VolatilityHorizon(),
+        Lambda: cfg.VolatilityLambda(),
+    }
+}
+
+// GetLatency makes a REST request, so latency features are refreshed far less often than observed
+const latencyInterval time.Duration = 5 * time.Second
+
+func fees(cfg *config.Config, exchanges []types.Exchange) map[string]decimal.Decimal {
+    fees := make(map[string]decimal.Decimal)
+    for _, exchange := range exchanges {
+        fees[exchange.String()] = cfg.Fee(exchange.String())
+    }
+    return fees
+}
+
+func decisionOptions(cfg *config.Config, exchanges []types.Exchange) decision.Options {
+    return decision.Options{
+        Fees: fees(cfg, exchanges),
+        Notional: cfg.FeatureNotional(),
+        Margin: cfg.DecisionMargin(),
+    }
+}
+
+// config and the schema have been validated, so the extractor always builds
+func featureExtractor(cfg *config.Config, exchanges []types.Exchange, aggregator *candles.Aggregator) *features.Extractor {
+    schema, _ := features.LookupSchema(cfg.FeatureSchema())
+    extractor, _ := features.NewExtractor(schema, features.Options{
+        Aggregator: aggregator,
+        Volatility: volatilityOptions(cfg),
+        Fees: fees(cfg, exchanges),
+        Notional: cfg.FeatureNotional(),
+    })
+    return extractor
+}
+
+// an extractor whose latencies are measured in the background until ctx is canceled
+func newExtractor(ctx context.Context, cfg *config.Config, exchanges []types.Exchange, aggregator *candles.Aggregator) *features.Extractor {
+    extractor := featureExtractor(cfg, exchanges, aggregator)
+    go extractor.MeasureLatencies(ctx, exchanges, latencyInterval)
+    return extractor
+}
+
+// serves the status and control API in the background when configured, nil otherwise
+func serveControl(cfg *config.Config, state *control.State, ledger *control.Ledger, exchanges []types.Exchange, candleSource control.CandleSource, models control.ModelStore) *http.Server {
+    if cfg.Control.Address == "" {
+        return nil
+    }
+    server := control.NewServer(state, ledger, control.RiskLimits{
+        MaxOrderNotional: cfg.Risk.MaxOrderNotional,
+        MaxOpenOrders: cfg.Risk.MaxOpenOrders,
+        MaxDailyLoss: cfg.Risk.MaxDailyLoss,
+    }, exchanges, configuredAssetPairs(cfg, exchanges), candleSource, models)
+    httpServer := &http.Server{Addr: cfg.Control.Address, Handler: server}
+    go listenAndServe(httpServer, "control")
+    logger.Info("serving control API", "url", "http://" + cfg.Control.Address + "/status")
+    return httpServer
+}
+
+// feeds the ledger's balances and the balance and PnL gauges until ctx is canceled, PnL is the
+// change since the first poll
+func pollBalances(ctx context.Context, exchanges []types.Exchange, ledger *control.Ledger, interval time.Duration) {
+    for {
+        for _, exchange := range exchanges {
+            balances := exchange.GetBalances()
+            pnl := ledger.Observe(exchange.String(), balances)
+            for asset, balance := range balances {
+                value, _ := balance.Float64()
+                change, _ := pnl[asset].Float64()
+                metrics.Balance.Set(value, exchange.String(), string(asset))
+                metrics.Pnl.Set(change, exchange.String(), string(asset))
+            }
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-time.After(interval):
+        }
+    }
+}
+
+// unsubscribes and closes the WebSocket connections of every exchange that records
+func closeRecorders(exchanges []types.Exchange) {
+    for _, exchange := range exchanges {
+        if recorder, ok := exchange.(types.AssetPairRecorder); ok {
+            recorder.Close()
+        }
+    }
+}
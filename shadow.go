@@ -0,0 +1,90 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "os"
+    "time"
+
+    "github.com/denali-capital/grizzly/config"
+    "github.com/denali-capital/grizzly/control"
+    "github.com/denali-capital/grizzly/decision"
+    "github.com/denali-capital/grizzly/features"
+    "github.com/denali-capital/grizzly/labeling"
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/model"
+    "github.com/denali-capital/grizzly/shadow"
+    "github.com/denali-capital/grizzly/types"
+)
+
+// batches of observations waiting for the challengers, each exchange pair submits one per sleep
+const shadowQueueCapacity uint = 64
+
+// loads every [shadow] challenger and labels the opportunities they and the live model see until
+// ctx is canceled, but for asset pairs unregistered in state; nil without challengers, the log is
+// closed once ctx is canceled
+func newShadowEvaluator(ctx context.Context, cfg *config.Config, exchanges []types.Exchange, extractor *features.Extractor, options decision.Options, state *control.State) *shadow.Evaluator {
+    challengers := make([]shadow.Challenger, 0, len(cfg.Shadow.Challengers))
+    for _, directory := range cfg.Shadow.Challengers {
+        challenger, err := model.LoadCalibrated(directory, extractor.Schema().String(), loadModel)
+        if err != nil {
+            logger.Error("refused challenger model", "directory", directory, logging.Err(err))
+            continue
+        }
+        challengers = append(challengers, shadow.Challenger{Name: directory, Model: challenger})
+    }
+    if len(challengers) == 0 {
+        return nil
+    }
+
+    log, err := os.OpenFile(cfg.ShadowLog(), os.O_CREATE | os.O_APPEND | os.O_WRONLY, 0644)
+    if err != nil {
+        logger.Error("opening shadow log failed", "path", cfg.ShadowLog(), logging.Err(err))
+        return nil
+    }
+    evaluator := shadow.NewEvaluator(challengers, labeling.NewLabeler(extractor, labelingOptions(cfg, exchanges)), log, shadow.Options{
+        Decision: options,
+        Threshold: state.Threshold,
+        // the labeler drops opportunities whose legs found no book within the horizon
+        Expiry: cfg.LabelHorizon() + time.Minute,
+        Capacity: shadowQueueCapacity,
+    })
+    go evaluator.Run(ctx)
+    assetPairs := configuredAssetPairs(cfg, exchanges)
+    go func() {
+        defer log.Close()
+        for {
+            for _, snapshot := range liveSnapshots(exchanges, state.RegisteredAssetPairs(assetPairs)) {
+                evaluator.Apply(snapshot)
+            }
+            if !sleep(ctx, cfg.Trading.SleepDuration.Duration) {
+                return
+            }
+        }
+    }()
+    logger.Info("evaluating challenger models", "challengers", len(challengers), "log", cfg.ShadowLog())
+    return evaluator
+}
+
+func shadowCommand(ctx context.Context, cfg *config.Config, args []string) error {
+    flags := flag.NewFlagSet("shadow", flag.ExitOnError)
+    in := flags.String("in", cfg.ShadowLog(), "shadow log written by paper or live")
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: grizzly shadow [flags]\n")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+
+    file, err := os.Open(*in)
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+    report, err := shadow.ReadReport(file)
+    if err != nil {
+        return fmt.Errorf("%v: %w", *in, err)
+    }
+    report.Write(os.Stdout)
+    return nil
+}
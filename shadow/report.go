@@ -0,0 +1,105 @@
+package shadow
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+)
+
+// how one model's decisions fared on labeled opportunities
+type Tally struct {
+    Model     string
+    Outcomes  int
+    // opportunities labeled 1
+    Positives int
+    Trades    int
+    // trades labeled 1
+    Hits      int
+    // profit of every trade, had it been placed
+    Pnl       float64
+}
+
+// of trades, the fraction labeled 1; 0 without trades
+func (t Tally) Precision() float64 {
+    if t.Trades == 0 {
+        return 0
+    }
+    return float64(t.Hits) / float64(t.Trades)
+}
+
+// of opportunities labeled 1, the fraction traded; 0 without any
+func (t Tally) Recall() float64 {
+    if t.Positives == 0 {
+        return 0
+    }
+    return float64(t.Hits) / float64(t.Positives)
+}
+
+// tallies by model, in the order models first appear, so live comes first
+type Report struct {
+    order   []string
+    tallies map[string]*Tally
+}
+
+func NewReport() *Report {
+    return &Report{tallies: make(map[string]*Tally)}
+}
+
+func (r *Report) Add(record Record) {
+    for _, prediction := range record.Predictions {
+        tally, ok := r.tallies[prediction.Model]
+        if !ok {
+            tally = &Tally{Model: prediction.Model}
+            r.tallies[prediction.Model] = tally
+            r.order = append(r.order, prediction.Model)
+        }
+        tally.Outcomes++
+        tally.Positives += int(record.Label)
+        if prediction.Trade {
+            tally.Trades++
+            tally.Hits += int(record.Label)
+            tally.Pnl += record.Profit
+        }
+    }
+}
+
+func (r *Report) Clone() *Report {
+    clone := NewReport()
+    for _, model := range r.order {
+        tally := *r.tallies[model]
+        clone.order = append(clone.order, model)
+        clone.tallies[model] = &tally
+    }
+    return clone
+}
+
+func (r *Report) Tallies() []Tally {
+    tallies := make([]Tally, len(r.order))
+    for i, model := range r.order {
+        tallies[i] = *r.tallies[model]
+    }
+    return tallies
+}
+
+// a report of every record in a log the Evaluator wrote
+func ReadReport(reader io.Reader) (*Report, error) {
+    report := NewReport()
+    scanner := bufio.NewScanner(reader)
+    scanner.Buffer(make([]byte, 64 * 1024), 16 * 1024 * 1024)
+    for line := 1; scanner.Scan(); line++ {
+        var record Record
+        if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+            return nil, fmt.Errorf("line %v: %w", line, err)
+        }
+        report.Add(record)
+    }
+    return report, scanner.Err()
+}
+
+func (r *Report) Write(w io.Writer) {
+    fmt.Fprintf(w, "%-20v %10v %10v %10v %10v %14v\n", "model", "outcomes", "trades", "precision", "recall", "simulated pnl")
+    for _, tally := range r.Tallies() {
+        fmt.Fprintf(w, "%-20v %10v %10v %10.4f %10.4f %14.4f\n", tally.Model, tally.Outcomes, tally.Trades, tally.Precision(), tally.Recall(), tally.Pnl)
+    }
+}
@@ -0,0 +1,231 @@
+package shadow
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+    "math"
+    "sync"
+    "time"
+
+    "github.com/denali-capital/grizzly/decision"
+    "github.com/denali-capital/grizzly/labeling"
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/model"
+    "github.com/denali-capital/grizzly/recording"
+    "github.com/denali-capital/grizzly/types"
+)
+
+var logger *logging.Logger = logging.New("shadow")
+
+// the name the live model's predictions are recorded under
+const Live string = "live"
+
+// a model that sees every observation the live model does, but never trades
+type Challenger struct {
+    Name  string
+    Model model.Model
+}
+
+// one model's view of an opportunity
+type Prediction struct {
+    Model         string  `json:"model"`
+    // calibrated
+    Probability   float64 `json:"probability"`
+    // whether the model trades it: at or above the threshold, with an expected value beating the margin
+    Trade         bool    `json:"trade"`
+    ExpectedValue float64 `json:"expected_value"`
+}
+
+// every model's prediction of an opportunity the labeler detected, live first, and its outcome
+type Record struct {
+    Timestamp   time.Time       `json:"timestamp"`
+    Exchange1   string          `json:"exchange1"`
+    Exchange2   string          `json:"exchange2"`
+    AssetPair   types.AssetPair `json:"asset_pair"`
+    Predictions []Prediction    `json:"predictions"`
+    Label       int32           `json:"label"`
+    // quote profit of the opportunity at execution, after fees and slippage
+    Profit      float64         `json:"profit"`
+}
+
+type Options struct {
+    Decision  decision.Options
+    // probability under which no model trades, the control threshold
+    Threshold func() float64
+    // how long an opportunity waits for its label before it is forgotten
+    Expiry    time.Duration
+    // batches waiting for the challengers, more are dropped
+    Capacity  uint
+}
+
+// opportunities are matched to their labels by where and when they were observed
+type key struct {
+    exchange1 string
+    exchange2 string
+    assetPair types.AssetPair
+    timestamp int64
+}
+
+func keyOf(observation types.Observation) key {
+    return key{observation.Exchange1, observation.Exchange2, observation.AssetPair, observation.Timestamp.UnixNano()}
+}
+
+// the live model's predictions and decisions of one batch of observations between two exchanges
+type batch struct {
+    exchange1    types.Exchange
+    exchange2    types.Exchange
+    observations []types.Observation
+    live         []Prediction
+}
+
+// predicts the live model's observations with every challenger, decides as the live model does
+// at each challenger's probability, and once the labeler has labeled an opportunity writes every
+// model's decision next to its outcome
+type Evaluator struct {
+    mutex       sync.Mutex
+    batches     chan batch
+    challengers []Challenger
+    labeler     *labeling.Labeler
+    options     Options
+    pending     map[key]Record
+    // JSON lines of Records, nil to keep only the report
+    log         *json.Encoder
+    report      *Report
+}
+
+// the labeler should label the way training does, so that outcomes are the labels models learn
+func NewEvaluator(challengers []Challenger, labeler *labeling.Labeler, log io.Writer, options Options) *Evaluator {
+    e := &Evaluator{
+        batches: make(chan batch, options.Capacity),
+        challengers: challengers,
+        labeler: labeler,
+        options: options,
+        pending: make(map[key]Record),
+        report: NewReport(),
+    }
+    if log != nil {
+        e.log = json.NewEncoder(log)
+    }
+    return e
+}
+
+func (e *Evaluator) predict(exchange1, exchange2 types.Exchange, assetPair types.AssetPair, name string, probability float32, threshold float64) Prediction {
+    prediction := Prediction{Model: name, Probability: float64(probability)}
+    if prediction.Probability < threshold {
+        return prediction
+    }
+    d, ok := decision.Decide(exchange1, exchange2, assetPair, prediction.Probability, e.options.Decision)
+    if !ok {
+        return prediction
+    }
+    prediction.Trade, prediction.ExpectedValue = d.Trade, d.ExpectedValue.InexactFloat64()
+    return prediction
+}
+
+// non-blocking, so that trading is never held up by the challengers; live is the live model's
+// prediction and decision of each observation; returns false if the queue is full and the batch
+// was dropped
+func (e *Evaluator) Submit(exchange1, exchange2 types.Exchange, observations []types.Observation, live []Prediction) bool {
+    select {
+    case e.batches <- batch{exchange1, exchange2, observations, live}:
+        return true
+    default:
+        logger.Warn("shadow queue full, observations dropped", "exchange1", exchange1, "exchange2", exchange2, "observations", len(observations))
+        return false
+    }
+}
+
+// observes submitted batches until ctx is canceled; challengers decide on the books as they are
+// when a batch is dequeued, moments after the live model did
+func (e *Evaluator) Run(ctx context.Context) {
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case b := <-e.batches:
+            e.Observe(b.exchange1, b.exchange2, b.observations, b.live)
+        }
+    }
+}
+
+// records the live model's predictions and decisions of observations between exchange1 and
+// exchange2 and every challenger's, for the opportunities among them the labeler will label;
+// observations any model could not predict are left out, so that models are compared on the same
+func (e *Evaluator) Observe(exchange1, exchange2 types.Exchange, observations []types.Observation, live []Prediction) {
+    if len(observations) == 0 || len(observations) != len(live) {
+        return
+    }
+    predictions := make([][]float32, len(e.challengers))
+    for i, challenger := range e.challengers {
+        predictions[i] = challenger.Model.Predict(observations)
+    }
+    threshold := e.options.Threshold()
+    records := make([]*Record, len(observations))
+    for i, observation := range observations {
+        record := &Record{
+            Timestamp: observation.Timestamp,
+            Exchange1: observation.Exchange1,
+            Exchange2: observation.Exchange2,
+            AssetPair: observation.AssetPair,
+            Predictions: []Prediction{live[i]},
+        }
+        record.Predictions[0].Model = Live
+        predicted := !math.IsNaN(live[i].Probability)
+        for j, challenger := range e.challengers {
+            predicted = predicted && !math.IsNaN(float64(predictions[j][i]))
+            if predicted {
+                record.Predictions = append(record.Predictions, e.predict(exchange1, exchange2, observation.AssetPair, challenger.Name, predictions[j][i], threshold))
+            }
+        }
+        if predicted {
+            records[i] = record
+        }
+    }
+
+    // detected and recorded together, so that no label arrives before its record
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+    for i, observation := range observations {
+        if records[i] != nil && e.labeler.Detect(exchange1, exchange2, observation.AssetPair, observation.Timestamp) {
+            e.pending[keyOf(observation)] = *records[i]
+        }
+    }
+}
+
+func (e *Evaluator) resolve(labeled types.Observation) {
+    k := keyOf(labeled)
+    record, ok := e.pending[k]
+    if !ok {
+        return
+    }
+    delete(e.pending, k)
+    record.Label, record.Profit = labeled.Label, labeled.Profit
+    e.report.Add(record)
+    if e.log != nil {
+        if err := e.log.Encode(record); err != nil {
+            logger.Error("writing shadow record failed", logging.Err(err))
+        }
+    }
+}
+
+// labels the opportunities snapshot executes, and forgets those older than Expiry
+func (e *Evaluator) Apply(snapshot recording.Snapshot) {
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+    for _, labeled := range e.labeler.Apply(snapshot) {
+        e.resolve(labeled)
+    }
+    for k, record := range e.pending {
+        if snapshot.Timestamp.Sub(record.Timestamp) > e.options.Expiry {
+            delete(e.pending, k)
+        }
+    }
+}
+
+// of every labeled opportunity so far
+func (e *Evaluator) Report() *Report {
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+    return e.report.Clone()
+}
@@ -0,0 +1,170 @@
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denali-capital/grizzly/decision"
+	"github.com/denali-capital/grizzly/features"
+	"github.com/denali-capital/grizzly/labeling"
+	"github.com/denali-capital/grizzly/recording"
+	"github.com/denali-capital/grizzly/types"
+	"github.com/shopspring/decimal"
+)
+
+var BTCUSD types.AssetPair = types.NewAssetPair("BTC", "USD")
+
+var start time.Time = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func snapshot(exchange string, offset time.Duration, bid, ask int64) recording.Snapshot {
+	timestamp := start.Add(offset)
+	return recording.Snapshot{
+		Exchange: exchange,
+		AssetPair: BTCUSD,
+		Timestamp: timestamp,
+		Spread: &types.Spread{Bid: decimal.NewFromInt(bid), Ask: decimal.NewFromInt(ask), Timestamp: timestamp},
+		OrderBook: &types.OrderBook{
+			Bids: []types.OrderBookEntry{{Price: decimal.NewFromInt(bid), Quantity: decimal.NewFromInt(5)}},
+			Asks: []types.OrderBookEntry{{Price: decimal.NewFromInt(ask), Quantity: decimal.NewFromInt(5)}},
+		},
+	}
+}
+
+type constant float32
+
+func (c constant) Predict(observations []types.Observation) []float32 {
+	predictions := make([]float32, len(observations))
+	for i := range predictions {
+		predictions[i] = float32(c)
+	}
+	return predictions
+}
+
+func TestEvaluator(t *testing.T) {
+	fees := map[string]decimal.Decimal{"Exchange1": decimal.NewFromFloat(0.001), "Exchange2": decimal.NewFromFloat(0.001)}
+	extractor, err := features.NewExtractor(features.Default, features.Options{Fees: fees, Notional: decimal.NewFromInt(100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exchange1, exchange2 := recording.NewReplayExchange("Exchange1", 10), recording.NewReplayExchange("Exchange2", 10)
+	labeler := labeling.NewLabeler(extractor, labeling.Options{Horizon: time.Second, Threshold: decimal.NewFromFloat(0.001), Fees: fees, Notional: decimal.NewFromInt(100)})
+	log := &bytes.Buffer{}
+	evaluator := NewEvaluator([]Challenger{{Name: "timid", Model: constant(0.1)}}, labeler, log, Options{
+		Decision: decision.Options{Fees: fees, Notional: decimal.NewFromInt(100)},
+		Threshold: func() float64 { return 0.5 },
+		Expiry: time.Minute,
+	})
+
+	// buying on Exchange1 at 100 and selling on Exchange2 at 105 pays
+	exchange1.Apply(snapshot("Exchange1", 0, 99, 100))
+	exchange2.Apply(snapshot("Exchange2", 0, 105, 106))
+	observation := extractor.ExtractAt(exchange1, exchange2, BTCUSD, start)
+	// the live model's own decision is recorded, not one the evaluator makes for it
+	evaluator.Observe(exchange1, exchange2, []types.Observation{observation}, []Prediction{{Probability: 0.9, Trade: true, ExpectedValue: 1}})
+	if len(evaluator.pending) != 1 {
+		t.Fatalf("expected one opportunity pending, got %v", len(evaluator.pending))
+	}
+
+	evaluator.Apply(snapshot("Exchange1", 100 * time.Millisecond, 99, 100))
+	evaluator.Apply(snapshot("Exchange2", 100 * time.Millisecond, 105, 106))
+	tallies := evaluator.Report().Tallies()
+	if len(tallies) != 2 || tallies[0].Model != Live || tallies[0].Trades != 1 || tallies[0].Hits != 1 || tallies[0].Pnl <= 0 || tallies[0].Recall() != 1 {
+		t.Fatalf("expected live to have traded the profitable opportunity, got %+v", tallies)
+	}
+	if tallies[1].Model != "timid" || tallies[1].Trades != 0 || tallies[1].Positives != 1 || tallies[1].Recall() != 0 || tallies[1].Precision() != 0 {
+		t.Fatalf("expected the challenger to have passed on it, got %+v", tallies[1])
+	}
+
+	// the log reads back into the same report
+	report, err := ReadReport(strings.NewReader(log.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read := report.Tallies(); len(read) != 2 || read[0] != tallies[0] || read[1] != tallies[1] {
+		t.Fatalf("expected %+v from the log, got %+v", tallies, read)
+	}
+
+	// opportunities that are never labeled are forgotten
+	evaluator.Observe(exchange1, exchange2, []types.Observation{extractor.ExtractAt(exchange1, exchange2, BTCUSD, start.Add(time.Second))}, []Prediction{{Probability: 0.9}})
+	evaluator.Apply(snapshot("Exchange3", 2 * time.Minute, 99, 100))
+	if len(evaluator.pending) != 0 {
+		t.Fatalf("expected expired opportunities to be forgotten, got %v", len(evaluator.pending))
+	}
+
+	// nor are those the live model could not predict
+	evaluator.Observe(exchange1, exchange2, []types.Observation{extractor.ExtractAt(exchange1, exchange2, BTCUSD, start.Add(2 * time.Minute))}, []Prediction{{Probability: math.NaN()}})
+	if len(evaluator.pending) != 0 {
+		t.Fatalf("expected an observation without a prediction to be left out, got %v", len(evaluator.pending))
+	}
+}
+
+func TestRun(t *testing.T) {
+	fees := map[string]decimal.Decimal{"Exchange1": decimal.NewFromFloat(0.001), "Exchange2": decimal.NewFromFloat(0.001)}
+	extractor, err := features.NewExtractor(features.Default, features.Options{Fees: fees, Notional: decimal.NewFromInt(100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exchange1, exchange2 := recording.NewReplayExchange("Exchange1", 10), recording.NewReplayExchange("Exchange2", 10)
+	labeler := labeling.NewLabeler(extractor, labeling.Options{Horizon: time.Second, Threshold: decimal.NewFromFloat(0.001), Fees: fees, Notional: decimal.NewFromInt(100)})
+	evaluator := NewEvaluator([]Challenger{{Name: "timid", Model: constant(0.1)}}, labeler, nil, Options{
+		Decision: decision.Options{Fees: fees, Notional: decimal.NewFromInt(100)},
+		Threshold: func() float64 { return 0.5 },
+		Expiry: time.Minute,
+		Capacity: 1,
+	})
+	exchange1.Apply(snapshot("Exchange1", 0, 99, 100))
+	exchange2.Apply(snapshot("Exchange2", 0, 105, 106))
+	observations := []types.Observation{extractor.ExtractAt(exchange1, exchange2, BTCUSD, start)}
+
+	// nothing is dequeued before Run, so the second batch does not fit
+	if !evaluator.Submit(exchange1, exchange2, observations, []Prediction{{Probability: 0.9}}) || evaluator.Submit(exchange1, exchange2, observations, []Prediction{{Probability: 0.9}}) {
+		t.Fatal("expected only one batch to be queued")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		evaluator.Run(ctx)
+		close(done)
+	}()
+	pending := func() int {
+		evaluator.mutex.Lock()
+		defer evaluator.mutex.Unlock()
+		return len(evaluator.pending)
+	}
+	for deadline := time.Now().Add(5 * time.Second); pending() == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if pending() != 1 {
+		t.Fatalf("expected the queued batch to be observed, got %v pending", pending())
+	}
+}
+
+func TestReport(t *testing.T) {
+	report := NewReport()
+	predictions := func(live, challenger bool) []Prediction {
+		return []Prediction{{Model: Live, Trade: live}, {Model: "challenger", Trade: challenger}}
+	}
+	report.Add(Record{Predictions: predictions(true, true), Label: 1, Profit: 2})
+	report.Add(Record{Predictions: predictions(true, false), Label: 0, Profit: -1})
+	report.Add(Record{Predictions: predictions(false, true), Label: 1, Profit: 3})
+
+	tallies := report.Tallies()
+	if live := tallies[0]; live.Precision() != 0.5 || live.Recall() != 0.5 || live.Pnl != 1 {
+		t.Fatalf("expected live to have half its trades right, half the positives and a profit of 1, got %+v", live)
+	}
+	if challenger := tallies[1]; challenger.Precision() != 1 || challenger.Recall() != 1 || challenger.Pnl != 5 {
+		t.Fatalf("expected the challenger to have traded both positives for 5, got %+v", challenger)
+	}
+
+	var table bytes.Buffer
+	report.Write(&table)
+	if lines := strings.Split(strings.TrimSpace(table.String()), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[1], "live") {
+		t.Fatalf("expected a header and a line per model, live first, got\n%v", table.String())
+	}
+}
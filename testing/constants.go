@@ -6,13 +6,13 @@ import (
 	"github.com/denali-capital/grizzly/types"
 )
 
-const (
-	BTCUSD types.AssetPair = iota
-	ETHUSDT
-	ADAUSDT
-	BTCUSDC
-	LTCUSDC
-	DOGEUSD
+var (
+	BTCUSD  types.AssetPair = types.NewAssetPair("BTC", "USD")
+	ETHUSDT types.AssetPair = types.NewAssetPair("ETH", "USDT")
+	ADAUSDT types.AssetPair = types.NewAssetPair("ADA", "USDT")
+	BTCUSDC types.AssetPair = types.NewAssetPair("BTC", "USDC")
+	LTCUSDC types.AssetPair = types.NewAssetPair("LTC", "USDC")
+	DOGEUSD types.AssetPair = types.NewAssetPair("DOGE", "USD")
 )
 
 var AssetPairs []types.AssetPair = []types.AssetPair{BTCUSD, ADAUSDT, BTCUSDC}
@@ -48,5 +48,3 @@ const SampleDuration time.Duration = 2 * time.Second
 const LatencyDuration time.Duration = time.Second
 
 const Samples uint = 10
-
-const SecretKeySuffix string = "_SECRET_KEY"
@@ -0,0 +1,268 @@
+package main
+
+import (
+    "context"
+    "bytes"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/denali-capital/grizzly/candles"
+    "github.com/denali-capital/grizzly/config"
+    "github.com/denali-capital/grizzly/dashboard"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/shopspring/decimal"
+)
+
+type snapshotSource interface {
+    Snapshot() (dashboard.Snapshot, error)
+}
+
+// reads the recorders of exchanges started in this process
+type recorderSource struct {
+    exchanges  []types.Exchange
+    // exchange -> asset pairs shared with at least one other exchange
+    assetPairs map[string][]types.AssetPair
+    aggregator *candles.Aggregator
+    mutex      sync.Mutex
+    latencies  map[string]time.Duration
+}
+
+func newRecorderSource(ctx context.Context, cfg *config.Config, exchanges []types.Exchange, latencyInterval time.Duration) *recorderSource {
+    counts := make(map[types.AssetPair]int)
+    for _, exchange := range exchanges {
+        for _, assetPair := range cfg.AssetPairTranslator(exchange.String()).GetAssetPairs() {
+            counts[assetPair]++
+        }
+    }
+    assetPairs := make(map[string][]types.AssetPair)
+    for _, exchange := range exchanges {
+        for _, assetPair := range cfg.AssetPairTranslator(exchange.String()).GetAssetPairs() {
+            if counts[assetPair] > 1 {
+                assetPairs[exchange.String()] = append(assetPairs[exchange.String()], assetPair)
+            }
+        }
+    }
+    source := &recorderSource{
+        exchanges: exchanges,
+        assetPairs: assetPairs,
+        aggregator: aggregateCandles(ctx, cfg, exchanges, assetPairs, nil),
+        latencies: make(map[string]time.Duration),
+    }
+    go source.measureLatencies(ctx, latencyInterval)
+    return source
+}
+
+// GetLatency makes a REST request, so it runs far less often than frames are drawn
+func (r *recorderSource) measureLatencies(ctx context.Context, interval time.Duration) {
+    for {
+        for _, exchange := range r.exchanges {
+            latency := exchange.GetLatency()
+            r.mutex.Lock()
+            r.latencies[exchange.String()] = latency
+            r.mutex.Unlock()
+        }
+
+        if !sleep(ctx, interval) {
+            return
+        }
+    }
+}
+
+func (r *recorderSource) Snapshot() (dashboard.Snapshot, error) {
+    snapshot := dashboard.Snapshot{
+        Spreads: make(map[string]map[types.AssetPair]types.Spread),
+        OrderBooks: make(map[string]map[types.AssetPair]*types.OrderBook),
+        Candles: make(map[string]map[types.AssetPair][]types.Candle),
+        Latencies: make(map[string]time.Duration),
+    }
+    for _, exchange := range r.exchanges {
+        name := exchange.String()
+        snapshot.Spreads[name] = make(map[types.AssetPair]types.Spread)
+        snapshot.Candles[name] = make(map[types.AssetPair][]types.Candle)
+        for _, assetPair := range r.assetPairs[name] {
+            snapshot.Spreads[name][assetPair] = exchange.GetCurrentSpread(assetPair)
+            snapshot.Candles[name][assetPair], _ = r.aggregator.GetCandles(name, assetPair, time.Minute)
+        }
+        snapshot.OrderBooks[name] = exchange.GetOrderBooks(r.assetPairs[name])
+    }
+    r.mutex.Lock()
+    for name, latency := range r.latencies {
+        snapshot.Latencies[name] = latency
+    }
+    r.mutex.Unlock()
+    return snapshot, nil
+}
+
+// polls the control API of a running paper or live process
+type apiSource struct {
+    cfg             *config.Config
+    url             string
+    httpClient      *http.Client
+    // /latency makes REST requests in the daemon, so it is polled less often than frames are drawn
+    latencyInterval time.Duration
+    latenciesAt     time.Time
+    latencies       map[string]time.Duration
+}
+
+func (a *apiSource) get(path string, value interface{}) error {
+    response, err := a.httpClient.Get(a.url + path)
+    if err != nil {
+        return err
+    }
+    defer response.Body.Close()
+    if response.StatusCode != http.StatusOK {
+        return fmt.Errorf("GET %v: %v", a.url + path, response.Status)
+    }
+    return json.NewDecoder(response.Body).Decode(value)
+}
+
+// asset pairs missing from the local config are skipped
+func (a *apiSource) Snapshot() (dashboard.Snapshot, error) {
+    snapshot := dashboard.Snapshot{
+        Spreads: make(map[string]map[types.AssetPair]types.Spread),
+        OrderBooks: make(map[string]map[types.AssetPair]*types.OrderBook),
+        Candles: make(map[string]map[types.AssetPair][]types.Candle),
+        Latencies: make(map[string]time.Duration),
+    }
+
+    spreads := make(map[string]map[string]types.Spread)
+    if err := a.get("/spreads", &spreads); err != nil {
+        return snapshot, err
+    }
+    for name, byCanonical := range spreads {
+        snapshot.Spreads[name] = make(map[types.AssetPair]types.Spread)
+        for canonical, spread := range byCanonical {
+            if assetPair, ok := a.cfg.AssetPair(canonical); ok {
+                snapshot.Spreads[name][assetPair] = spread
+            }
+        }
+    }
+
+    orderBooks := make(map[string]map[string]*types.OrderBook)
+    if err := a.get("/order_books", &orderBooks); err != nil {
+        return snapshot, err
+    }
+    for name, byCanonical := range orderBooks {
+        snapshot.OrderBooks[name] = make(map[types.AssetPair]*types.OrderBook)
+        for canonical, orderBook := range byCanonical {
+            if assetPair, ok := a.cfg.AssetPair(canonical); ok {
+                snapshot.OrderBooks[name][assetPair] = orderBook
+            }
+        }
+    }
+
+    candles := make(map[string]map[string][]types.Candle)
+    if err := a.get(fmt.Sprintf("/candles?resolution=1m&limit=%v", dashboard.HourCandles), &candles); err != nil {
+        return snapshot, err
+    }
+    for name, byCanonical := range candles {
+        snapshot.Candles[name] = make(map[types.AssetPair][]types.Candle)
+        for canonical, history := range byCanonical {
+            if assetPair, ok := a.cfg.AssetPair(canonical); ok {
+                snapshot.Candles[name][assetPair] = history
+            }
+        }
+    }
+
+    if time.Since(a.latenciesAt) >= a.latencyInterval {
+        latencies := make(map[string]float64)
+        if err := a.get("/latency", &latencies); err != nil {
+            return snapshot, err
+        }
+        a.latencies = make(map[string]time.Duration, len(latencies))
+        for name, seconds := range latencies {
+            a.latencies[name] = time.Duration(seconds * float64(time.Second))
+        }
+        a.latenciesAt = time.Now()
+    }
+    snapshot.Latencies = a.latencies
+    return snapshot, nil
+}
+
+func parseSizes(s string) ([]decimal.Decimal, error) {
+    sizes := make([]decimal.Decimal, 0)
+    for _, field := range strings.Split(s, ",") {
+        if field == "" {
+            continue
+        }
+        size, err := decimal.NewFromString(field)
+        if err != nil || !size.IsPositive() {
+            return nil, fmt.Errorf("invalid size %q, expected a positive base quantity", field)
+        }
+        sizes = append(sizes, size)
+    }
+    return sizes, nil
+}
+
+// live cross-exchange spreads, edges and depth, redrawn until interrupted
+func topCommand(ctx context.Context, cfg *config.Config, args []string) error {
+    flags := flag.NewFlagSet("top", flag.ExitOnError)
+    url := flags.String("url", "", "control API of a running process, e.g. http://" + cfg.Control.Address + "; recorders are started in this process when empty")
+    refresh := flags.Duration("refresh", 250 * time.Millisecond, "time between frames")
+    latencyInterval := flags.Duration("latency-interval", 5 * time.Second, "time between latency measurements")
+    sizesFlag := flags.String("sizes", "0.1,1,10", "comma separated base quantities to show slippage at")
+    flags.Usage = func() {
+        fmt.Fprintf(flags.Output(), "usage: grizzly top [flags] [exchange...]\n\nexchanges default to every enabled exchange and are ignored with -url\n\n")
+        flags.PrintDefaults()
+    }
+    flags.Parse(args)
+
+    sizes, err := parseSizes(*sizesFlag)
+    if err != nil {
+        return err
+    }
+
+    options := dashboard.Options{
+        Fees: make(map[string]decimal.Decimal),
+        Sizes: sizes,
+    }
+    for name := range cfg.Exchanges {
+        options.Fees[name] = cfg.Fee(name)
+    }
+
+    var source snapshotSource
+    if *url != "" {
+        source = &apiSource{
+            cfg: cfg,
+            url: strings.TrimRight(*url, "/"),
+            // latency requests wait on the exchanges' REST APIs
+            httpClient: &http.Client{Timeout: 10 * time.Second},
+            latencyInterval: *latencyInterval,
+        }
+    } else {
+        exchanges, err := newExchanges(cfg, flags.Args())
+        if err != nil {
+            return err
+        }
+        source = newRecorderSource(ctx, cfg, exchanges, *latencyInterval)
+        defer closeRecorders(exchanges)
+    }
+
+    ticker := time.NewTicker(*refresh)
+    defer ticker.Stop()
+    frame := &bytes.Buffer{}
+    for {
+        frame.Reset()
+        frame.WriteString(dashboard.ClearScreen)
+        snapshot, err := source.Snapshot()
+        if err != nil {
+            fmt.Fprintf(frame, "grizzly top  %v\n\n%v\n", time.Now().Format("15:04:05.000"), err)
+        } else {
+            dashboard.Render(frame, snapshot, options, time.Now())
+        }
+        // written at once so the terminal never shows half a frame
+        os.Stdout.Write(frame.Bytes())
+
+        select {
+        case <-ctx.Done():
+            return nil
+        case <-ticker.C:
+        }
+    }
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "sort"
+    "time"
+
+    "github.com/denali-capital/grizzly/arbitrage"
+    "github.com/denali-capital/grizzly/config"
+    "github.com/denali-capital/grizzly/control"
+    "github.com/denali-capital/grizzly/conversion"
+    "github.com/denali-capital/grizzly/decision"
+    "github.com/denali-capital/grizzly/drift"
+    "github.com/denali-capital/grizzly/execution"
+    "github.com/denali-capital/grizzly/features"
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
+    "github.com/denali-capital/grizzly/model"
+    "github.com/denali-capital/grizzly/paper"
+    "github.com/denali-capital/grizzly/shadow"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/denali-capital/grizzly/util"
+    "github.com/shopspring/decimal"
+)
+
+// false once ctx is canceled
+func sleep(ctx context.Context, duration time.Duration) bool {
+    select {
+    case <-ctx.Done():
+        return false
+    case <-time.After(duration):
+        return true
+    }
+}
+
+// cycles starting from numeraire-equivalent assets start with at most maxOrderNotional, the
+// coordinator shrinks the rest
+func maxSizes(converter *conversion.Converter, maxOrderNotional decimal.Decimal) map[types.Asset]decimal.Decimal {
+    sizes := make(map[types.Asset]decimal.Decimal)
+    if !maxOrderNotional.IsPositive() {
+        return sizes
+    }
+    for _, asset := range converter.Assets() {
+        if rate, ok := converter.Rate(converter.Numeraire(), asset); ok && rate.IsPositive() {
+            sizes[asset] = maxOrderNotional.Mul(rate)
+        }
+    }
+    return sizes
+}
+
+// opportunities in graph that the balances, exchange -> asset, of every node an order spends from
+// can fund, sized by maxSizes
+func fundedOpportunities(graph *arbitrage.Graph, balances map[string]map[types.Asset]decimal.Decimal, converter *conversion.Converter, maxOrderNotional decimal.Decimal) []types.Opportunity {
+    nodes := make(map[arbitrage.Node]decimal.Decimal)
+    for exchange, assets := range balances {
+        for asset, balance := range assets {
+            nodes[arbitrage.Node{Exchange: exchange, Asset: asset}] = balance
+        }
+    }
+    return graph.Opportunities(nodes, maxSizes(converter, maxOrderNotional))
+}
+
+// multi-hop, multi-venue loops over every recorded order book but those of asset pairs
+// unregistered in state, until ctx is canceled; cycles are funded from the balances ledger last
+// polled, so that routing makes no REST request
+func route(ctx context.Context, venues []arbitrage.Venue, ledger *control.Ledger, state *control.State, converter *conversion.Converter, coordinator *execution.Coordinator, transferCost decimal.Decimal, maxOrderNotional decimal.Decimal, sleepDuration time.Duration) {
+    for {
+        registered := make([]arbitrage.Venue, len(venues))
+        for i, venue := range venues {
+            venue.AssetPairs = state.Registered(venue.Exchange.String(), venue.AssetPairs)
+            registered[i] = venue
+        }
+        graph := arbitrage.BuildGraph(registered, converter, map[types.AssetPair]decimal.Decimal{}, transferCost)
+        for _, opportunity := range fundedOpportunities(graph, ledger.Balances(), converter, maxOrderNotional) {
+            coordinator.Submit(opportunity)
+        }
+
+        if !sleep(ctx, sleepDuration) {
+            return
+        }
+    }
+}
+
+// trades opportunities between exchange1 and exchange2 whose expected value, at predictor's
+// calibrated probability, beats the decision margin; probabilities under the control threshold
+// and asset pairs unregistered on either exchange are never traded; every observation and prediction is handed to monitor, and with the live
+// decisions to evaluator unless it is nil
+func grizzly(ctx context.Context, exchange1 types.Exchange, exchange2 types.Exchange, allowedAssetPairs []types.AssetPair, extractor *features.Extractor, predictor model.Model, monitor *drift.Monitor, evaluator *shadow.Evaluator, options decision.Options, coordinator *execution.Coordinator, state *control.State, sleepDuration time.Duration) {
+    pair := control.PairKey(exchange1.String(), exchange2.String())
+    for {
+        assetPairs := state.Registered(exchange2.String(), state.Registered(exchange1.String(), allowedAssetPairs))
+        if state.Killed() || state.Paused(exchange1.String(), exchange2.String()) || len(assetPairs) == 0 {
+            if !sleep(ctx, sleepDuration) {
+                return
+            }
+            continue
+        }
+
+        observations := make([]types.Observation, len(assetPairs))
+        for i, assetPair := range assetPairs {
+            observations[i] = extractor.Extract(exchange1, exchange2, assetPair)
+        }
+        predictions := predictor.Predict(observations)
+        monitor.Observe(observations, predictions)
+        live := make([]shadow.Prediction, len(predictions))
+        for i, probability := range predictions {
+            live[i].Probability = float64(probability)
+            if float64(probability) < state.Threshold() {
+                continue
+            }
+            d, ok := decision.Decide(exchange1, exchange2, assetPairs[i], float64(probability), options)
+            if ok {
+                live[i].ExpectedValue = d.ExpectedValue.InexactFloat64()
+            }
+            if !ok || !d.Trade {
+                metrics.Decisions.Inc(pair, "pass")
+                continue
+            }
+            metrics.Decisions.Inc(pair, "trade")
+            live[i].Trade = true
+            coordinator.Submit(d.Opportunity(time.Now()))
+        }
+        // after the live orders are on their way, so that the challengers never delay them
+        if evaluator != nil {
+            evaluator.Submit(exchange1, exchange2, observations, live)
+        }
+
+        if !sleep(ctx, sleepDuration) {
+            return
+        }
+    }
+}
+
+func printSummary(exchanges []types.Exchange, ledger *control.Ledger, runtime time.Duration, executed int, reports map[string]execution.CloseOutReport) {
+    fmt.Printf("ran for %v, %v opportunities executed\n\n", runtime.Round(time.Second), executed)
+    fmt.Printf("%-10v %10v %10v %10v %18v %20v\n", "exchange", "submitted", "filled", "canceled", "shutdown canceled", "shutdown flattened")
+    for _, exchange := range exchanges {
+        name := exchange.String()
+        fmt.Printf("%-10v %10v %10v %10v %18v %20v\n", name, metrics.Orders.Value(name, "submitted"), metrics.Orders.Value(name, "filled"), metrics.Orders.Value(name, "canceled"), len(reports[name].Canceled), len(reports[name].Flattened))
+    }
+    fmt.Printf("\n%-10v %-6v %20v\n", "exchange", "asset", "pnl")
+    for _, exchange := range exchanges {
+        pnl := ledger.Observe(exchange.String(), exchange.GetBalances())
+        for _, asset := range sortedAssets(pnl) {
+            fmt.Printf("%-10v %-6v %20v\n", exchange, asset, pnl[asset])
+        }
+    }
+}
+
+// runs every strategy against exchanges until ctx is canceled, then closes out orders per
+// the shutdown config, tears down the recorders and prints a summary
+func trade(ctx context.Context, cfg *config.Config, exchanges []types.Exchange) {
+    start := time.Now()
+    // config has been validated, so parse errors cannot happen here
+    policy, _ := execution.ParseClosePolicy(cfg.ShutdownOpenOrders())
+    state := control.NewState(cfg.Trading.Threshold)
+    ledger := control.NewLedger()
+    // so that the summary's PnL covers this run and routing has balances before the first poll
+    for _, exchange := range exchanges {
+        ledger.Observe(exchange.String(), exchange.GetBalances())
+    }
+    metricsServer := serveMetrics(cfg)
+    aggregator := aggregateCandles(ctx, cfg, exchanges, configuredAssetPairs(cfg, exchanges), state)
+    go pollBalances(ctx, exchanges, ledger, cfg.BalanceInterval())
+
+    extractor := newExtractor(ctx, cfg, exchanges, aggregator)
+    // versions are swapped into the model the process started with, and predicted calibrated
+    store := newModelStore(ctx, cfg, extractor, newModel())
+    train(ctx, cfg, exchanges, extractor, store, state)
+    controlServer := serveControl(cfg, state, ledger, exchanges, aggregator, store)
+    // every exchange pair's observations are predicted together
+    batcher := model.NewBatcher(store, model.BatcherOptions{Window: cfg.InferenceBatchWindow(), MaxBatch: cfg.InferenceMaxBatch()})
+    go batcher.Run(ctx)
+    monitor := monitorDrift(ctx, cfg, store)
+    options := decisionOptions(cfg, exchanges)
+    if cfg.Drift.Conservative {
+        options.Conservative, options.ConservativeMargin = monitor.Drifted, cfg.DriftConservativeMargin()
+    }
+    // challengers decide with the same options, so they are held to the same margins
+    evaluator := newShadowEvaluator(ctx, cfg, exchanges, extractor, options, state)
+
+    converter := newConverter(cfg, exchanges)
+    limits := riskLimits(cfg)
+    coordinator := execution.NewCoordinator(exchanges, state, converter, limits, cfg.Trading.OpportunityQueueCapacity, cfg.Trading.OpportunityMaxAge.Duration)
+    coordinatorDone := make(chan struct{})
+    go func() {
+        coordinator.Run(ctx)
+        close(coordinatorDone)
+    }()
+
+    go route(ctx, newVenues(cfg, exchanges), ledger, state, converter, coordinator, cfg.TransferCost(), limits.MaxOrderNotional, cfg.Trading.SleepDuration.Duration)
+
+    for exchangePair := range util.ExchangeCombinations(exchanges, 2) {
+        commonAssetPairs := util.AssetPairIntersection(
+            cfg.AssetPairTranslator(exchangePair[0].String()).GetAssetPairs(),
+            cfg.AssetPairTranslator(exchangePair[1].String()).GetAssetPairs(),
+        )
+
+        // start go routines and predictions here
+        go grizzly(ctx, exchangePair[0], exchangePair[1], commonAssetPairs, extractor, batcher, monitor, evaluator, options, coordinator, state, cfg.Trading.SleepDuration.Duration)
+    }
+
+    <-ctx.Done()
+    logger.Info("shutting down", "open_orders", policy, "timeout", cfg.ShutdownTimeout())
+    // no opportunity is half placed once Run returns, so every order is tracked
+    <-coordinatorDone
+
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout())
+    defer cancel()
+    reports := coordinator.CloseOut(shutdownCtx, policy)
+    for exchange, report := range reports {
+        logger.Info("closed out orders", logging.Exchange(exchange), "canceled", len(report.Canceled), "flattened", len(report.Flattened))
+    }
+    // flattening needs live quotes, so recorders are closed last
+    closeRecorders(exchanges)
+    shutdownServer(shutdownCtx, controlServer)
+    shutdownServer(shutdownCtx, metricsServer)
+
+    printSummary(exchanges, ledger, time.Since(start), coordinator.Executed(), reports)
+    if evaluator != nil {
+        fmt.Println()
+        evaluator.Report().Write(os.Stdout)
+    }
+}
+
+func sortedAssets(balances map[types.Asset]decimal.Decimal) []types.Asset {
+    assets := make([]types.Asset, 0, len(balances))
+    for asset := range balances {
+        assets = append(assets, asset)
+    }
+    sort.Slice(assets, func(i, j int) bool {
+        return assets[i] < assets[j]
+    })
+    return assets
+}
+
+func printBalances(exchange string, balances map[types.Asset]decimal.Decimal) {
+    for _, asset := range sortedAssets(balances) {
+        fmt.Printf("%-10v %-6v %v\n", exchange, asset, balances[asset])
+    }
+}
+
+func wrapPaper(cfg *config.Config, exchanges []types.Exchange) []types.Exchange {
+    paperExchanges := make([]types.Exchange, len(exchanges))
+    for i, exchange := range exchanges {
+        paperExchanges[i] = paper.NewExchange(exchange, cfg.Fee(exchange.String()), cfg.PaperBalances(exchange.String()))
+    }
+    return paperExchanges
+}
+
+func paperCommand(ctx context.Context, cfg *config.Config, args []string) error {
+    exchanges, err := newExchanges(cfg, args)
+    if err != nil {
+        return err
+    }
+    paperExchanges := wrapPaper(cfg, exchanges)
+
+    trade(ctx, cfg, paperExchanges)
+
+    fmt.Println()
+    for _, exchange := range paperExchanges {
+        printBalances(exchange.String(), exchange.GetBalances())
+    }
+    return nil
+}
+
+func liveCommand(ctx context.Context, cfg *config.Config, args []string) error {
+    exchanges, err := newExchanges(cfg, args)
+    if err != nil {
+        return err
+    }
+    logger.Info("trading live", "exchanges", exchanges)
+
+    trade(ctx, cfg, exchanges)
+    return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+    "context"
+    "time"
+
+    "github.com/denali-capital/grizzly/config"
+    "github.com/denali-capital/grizzly/control"
+    "github.com/denali-capital/grizzly/drift"
+    "github.com/denali-capital/grizzly/features"
+    "github.com/denali-capital/grizzly/labeling"
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/model"
+    "github.com/denali-capital/grizzly/recording"
+    "github.com/denali-capital/grizzly/types"
+    "github.com/denali-capital/grizzly/util"
+)
+
+// every exchange's current spread and order book of each of its asset pairs, for labelers
+func liveSnapshots(exchanges []types.Exchange, assetPairs map[string][]types.AssetPair) []recording.Snapshot {
+    snapshots := make([]recording.Snapshot, 0)
+    for _, exchange := range exchanges {
+        orderBooks := exchange.GetOrderBooks(assetPairs[exchange.String()])
+        timestamp := time.Now()
+        for _, assetPair := range assetPairs[exchange.String()] {
+            spread := exchange.GetCurrentSpread(assetPair)
+            snapshots = append(snapshots, recording.Snapshot{
+                Exchange: exchange.String(),
+                AssetPair: assetPair,
+                Timestamp: timestamp,
+                Spread: &spread,
+                OrderBook: orderBooks[assetPair],
+            })
+        }
+    }
+    return snapshots
+}
+
+// labels opportunities between every pair of exchanges as they trade, the way grizzly label
+// does for recordings, and hands the labeled observations to trainer until ctx is canceled;
+// asset pairs unregistered in state are left out
+func labelLive(ctx context.Context, cfg *config.Config, exchanges []types.Exchange, extractor *features.Extractor, trainer *model.Trainer, state *control.State) {
+    labeler := labeling.NewLabeler(extractor, labelingOptions(cfg, exchanges))
+    configured := configuredAssetPairs(cfg, exchanges)
+    exchangePairs := make([][]types.Exchange, 0)
+    for exchangePair := range util.ExchangeCombinations(exchanges, 2) {
+        exchangePairs = append(exchangePairs, exchangePair)
+    }
+
+    for {
+        assetPairs := state.RegisteredAssetPairs(configured)
+        for _, snapshot := range liveSnapshots(exchanges, assetPairs) {
+            trainer.Add(labeler.Apply(snapshot)...)
+        }
+
+        now := time.Now()
+        for _, exchangePair := range exchangePairs {
+            for _, assetPair := range util.AssetPairIntersection(assetPairs[exchangePair[0].String()], assetPairs[exchangePair[1].String()]) {
+                labeler.Detect(exchangePair[0], exchangePair[1], assetPair, now)
+            }
+        }
+
+        if !sleep(ctx, cfg.Trading.SleepDuration.Duration) {
+            return
+        }
+    }
+}
+
+// loads the newest stored version of the model into live, then keeps loading newer ones in the
+// background until ctx is canceled; versions for another feature schema than extractor's are refused
+func newModelStore(ctx context.Context, cfg *config.Config, extractor *features.Extractor, live model.Learner) *model.Store {
+    store := model.NewStore(cfg.ModelDirectory(), extractor.Schema().String(), live, loadModel)
+    if _, err := store.Reload(); err != nil {
+        logger.Error("refused model version", logging.Err(err))
+    }
+    // a version of the configured schema may have replaced the model the process started with
+    if err := store.CheckSchema(); err != nil {
+        logger.Fatal("model cannot read the configured [features] schema, train or promote a version of it", "version", store.Current(), "directory", cfg.ModelDirectory(), logging.Err(err))
+    }
+    logger.Info("using model version", "version", store.Current(), "directory", cfg.ModelDirectory())
+    go store.Watch(ctx, cfg.ModelWatchInterval())
+    return store
+}
+
+// checks the live observations for drift from the training window of the store's current version
+// until ctx is canceled
+func monitorDrift(ctx context.Context, cfg *config.Config, store *model.Store) *drift.Monitor {
+    monitor := drift.NewMonitor(store.Reference, drift.Options{
+        Window: cfg.DriftWindow(),
+        MinObservations: cfg.DriftMinObservations(),
+        PSIThreshold: cfg.DriftPSIThreshold(),
+        KSThreshold: cfg.DriftKSThreshold(),
+    })
+    go monitor.Run(ctx, cfg.DriftInterval())
+    return monitor
+}
+
+// labels and retrains the store's live model in the background when training is enabled
+func train(ctx context.Context, cfg *config.Config, exchanges []types.Exchange, extractor *features.Extractor, store *model.Store, state *control.State) {
+    if !cfg.Training.Enabled {
+        return
+    }
+    trainer := model.NewTrainer(store, model.TrainerOptions{
+        Interval: cfg.TrainingInterval(),
+        Window: cfg.TrainingWindow(),
+        MinObservations: int(cfg.TrainingMinObservations()),
+        Capacity: int(cfg.TrainingCapacity()),
+        BatchSize: int(cfg.TrainingBatchSize()),
+        Epochs: int(cfg.TrainingEpochs()),
+        Threshold: func() float32 {
+            return float32(state.Threshold())
+        },
+        Calibration: cfg.DecisionCalibration(),
+    })
+    go labelLive(ctx, cfg, exchanges, extractor, trainer, state)
+    go trainer.Run(ctx)
+    logger.Info("training online", "interval", cfg.TrainingInterval(), "window", cfg.TrainingWindow())
+}
@@ -24,3 +24,8 @@ type PredictionResponse struct {
     Index       uint
     Prediction  float32
 }
+
+type ExecutionResponse struct {
+    Exchange string
+    OrderIds map[Order]OrderId
+}
@@ -19,9 +19,44 @@ const (
     Sell
 )
 
+// a public print, Side is the aggressor's
+type Trade struct {
+    Price     decimal.Decimal
+    Quantity  decimal.Decimal
+    Side      OrderType
+    Timestamp time.Time
+}
+
+// open, high, low and close over [Start, Start + resolution)
+type Candle struct {
+    Start  time.Time
+    Open   decimal.Decimal
+    High   decimal.Decimal
+    Low    decimal.Decimal
+    Close  decimal.Decimal
+    // base quantity traded, zero for candles built from quotes alone
+    Volume decimal.Decimal
+    Trades uint
+}
+
 type Asset string
 
-type AssetPair uint
+type AssetPair struct {
+    Base  Asset
+    Quote Asset
+}
+
+func NewAssetPair(base, quote Asset) AssetPair {
+    return AssetPair{
+        Base: base,
+        Quote: quote,
+    }
+}
+
+// canonical name, e.g. BTCUSD
+func (a AssetPair) String() string {
+    return string(a.Base) + string(a.Quote)
+}
 
 type AssetPairTranslator map[AssetPair]string
 
@@ -75,15 +110,33 @@ type OrderBookEntry struct {
     UpdateId uint
 }
 
+// features of trading AssetPair between Exchange1 and Exchange2 at Timestamp, laid out
+// as named by Schema; see the features package
 type Observation struct {
-    PriceDelta  float32
-    Liquidity1  float32
-    Liquidity2  float32
-    Latency1    float32
-    Latency2    float32
-    Volatility1 float32
-    Volatility2 float32
+    Exchange1 string
+    Exchange2 string
+    AssetPair AssetPair
+    Timestamp time.Time
+    // name/version, e.g. grizzly/1
+    Schema    string
+    Features  []float32
 
     // optional
-    Label       int32
+    Label     int32
+    // quote profit of the labeled trade at execution, after fees and slippage
+    Profit    float64
+}
+
+type Leg struct {
+    Exchange string
+    Order    Order
+}
+
+type Opportunity struct {
+    Legs           []Leg
+    // Size and ExpectedProfit are denominated in Asset
+    Asset          Asset
+    Size           decimal.Decimal
+    ExpectedProfit decimal.Decimal
+    Timestamp      time.Time
 }
@@ -20,14 +20,19 @@ type Exchange interface {
     ExecuteOrders(orders []Order) map[Order]OrderId
     GetOrderStatuses(orderIds []OrderId) map[OrderId]OrderStatus
     CancelOrders(orderIds []OrderId)
+    // open orders on the exchange's asset pairs, including ones placed outside this process
+    GetOpenOrders() map[OrderId]OrderStatus
 
     // * getting account info
     GetBalances() map[Asset]decimal.Decimal
 }
 
-// add closing?
 type AssetPairRecorder interface {
     RegisterAssetPair(assetPair AssetPair)
+    // unsubscribes from the asset pair and stops processing its updates, no-op if it is not registered
+    UnregisterAssetPair(assetPair AssetPair)
+    // unsubscribes from every asset pair, stops recording and closes the connection
+    Close()
 }
 
 type SpreadRecorder interface {
@@ -36,6 +41,20 @@ type SpreadRecorder interface {
     GetHistoricalSpreads(assetPair AssetPair) ([]Spread, bool)
 }
 
+type TradeRecorder interface {
+    AssetPairRecorder
+    // oldest first, trades at or after since still in the buffer
+    GetTradesSince(assetPair AssetPair, since time.Time) ([]Trade, bool)
+    // volume weighted average price over the trailing window, false without trades in it
+    GetVWAP(assetPair AssetPair, window time.Duration) (decimal.Decimal, bool)
+}
+
+type CandleProvider interface {
+    // candles from the exchange's REST API starting at or after since, oldest first;
+    // false when the exchange does not offer resolution
+    GetHistoricalCandles(assetPair AssetPair, resolution time.Duration, since time.Time) ([]Candle, bool)
+}
+
 type OrderBookRecorder interface {
     AssetPairRecorder
     GetOrderBook(assetPair AssetPair) (OrderBook, bool)
@@ -75,27 +75,30 @@ func Contains(s []string, e string) bool {
     return false
 }
 
-func addCombinations(c chan []*types.Exchange, exchanges []*types.Exchange, k uint, init []*types.Exchange) {
+func addCombinations(c chan []types.Exchange, exchanges []types.Exchange, k uint, init []types.Exchange) {
     if k == 0 {
         c <- init
         return
     }
 
-    otherExchanges := make([]*types.Exchange, len(exchanges))
+    otherExchanges := make([]types.Exchange, len(exchanges))
     copy(otherExchanges, exchanges)
     for _, exchange := range exchanges {
         otherExchanges = otherExchanges[1:]
-        addCombinations(c, otherExchanges, k - 1, append(init, exchange))
+        // copy so sibling combinations never share a backing array
+        combination := make([]types.Exchange, len(init), len(init) + 1)
+        copy(combination, init)
+        addCombinations(c, otherExchanges, k - 1, append(combination, exchange))
     }
 }
 
-func ExchangeCombinations(exchanges []*types.Exchange, k uint) <-chan []*types.Exchange {
-    c := make(chan []*types.Exchange)
+func ExchangeCombinations(exchanges []types.Exchange, k uint) <-chan []types.Exchange {
+    c := make(chan []types.Exchange)
 
-    go func(c chan []*types.Exchange){
+    go func(c chan []types.Exchange){
         defer close(c)
 
-        addCombinations(c, exchanges, k, []*types.Exchange{})
+        addCombinations(c, exchanges, k, []types.Exchange{})
     }(c)
 
     return c
@@ -1,7 +1,6 @@
 package util
 
 import (
-	"log"
 	"time"
 
 	"github.com/denali-capital/grizzly/types"
@@ -16,7 +15,7 @@ func GetSpreadSamples(historicalSpreads []types.Spread, duration time.Duration,
 
     // check enough samples exist
     if leastRecentTimestamp := historicalSpreads[0].Timestamp; mostRecentTimestamp.Add(time.Duration(-(samples - 1)) * period).Before(leastRecentTimestamp) {
-        log.Println("warning: duration is too long, using longest possible duration instead")
+        logger.Warn("duration is too long, using longest possible duration instead", "duration", duration, "samples", samples, "available", mostRecentTimestamp.Sub(leastRecentTimestamp))
         period = time.Duration(mostRecentTimestamp.Sub(leastRecentTimestamp).Nanoseconds() / int64(samples))
     }
 
@@ -3,18 +3,24 @@ package util
 import (
     "encoding/json"
     "io/ioutil"
-    "log"
-
     "net/http"
     "net/url"
+    "strconv"
+    "sync/atomic"
+    "time"
+
+    "github.com/denali-capital/grizzly/logging"
+    "github.com/denali-capital/grizzly/metrics"
 )
 
+var logger *logging.Logger = logging.New("util")
+
 // return errors with these functions?
 // pointerize these functions?
 func ParseUrlWithQuery(urlString string, values url.Values) string {
     url, err := url.Parse(urlString)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("parsing url failed", "url", urlString, logging.Err(err))
     }
 
     queryParams := url.Query()
@@ -28,51 +34,73 @@ func ParseUrlWithQuery(urlString string, values url.Values) string {
     return url.String()
 }
 
-func HttpGetAndGetBody(httpClient *http.Client, urlString string) map[string]interface{} {
-    var resp *http.Response
-    var err error
-    if httpClient != nil {
-        resp, err = httpClient.Get(urlString)
-        if err != nil {
-            log.Fatalln(err)
-        }
-    } else {
-        resp, err = http.Get(urlString)
-        if err != nil {
-            log.Fatalln(err)
-        }
-    }
-    defer resp.Body.Close()
-    body, err := ioutil.ReadAll(resp.Body)
+// host and path of urlString, the query carries nonces and signatures
+func Endpoint(urlString string) string {
+    url, err := url.Parse(urlString)
     if err != nil {
-        log.Fatalln(err)
+        return urlString
     }
+    return url.Host + url.Path
+}
 
-    var bodyJson map[string]interface{}
-    err = json.Unmarshal(body, &bodyJson)
-    if err != nil {
-        log.Fatalln(err)
-    }
+var requestIds uint64
+var requestIdPrefix string = strconv.FormatInt(time.Now().Unix(), 36)
 
-    return bodyJson
+// unique within and across runs, ties together the log lines of one REST call
+func NewRequestId() string {
+    return requestIdPrefix + "-" + strconv.FormatUint(atomic.AddUint64(&requestIds, 1), 10)
 }
 
-func DoHttpAndGetBody(httpClient *http.Client, request *http.Request) map[string]interface{} {
+func fatalRestError(request *http.Request, requestId string, msg string, err error) {
+    metrics.RestErrors.Inc(Endpoint(request.URL.String()))
+    logger.Fatal(msg, logging.RequestId(requestId), "endpoint", Endpoint(request.URL.String()), logging.Err(err))
+}
+
+// returns the raw body and the id the request was logged under
+func doHttp(httpClient *http.Client, request *http.Request) ([]byte, string) {
+    if httpClient == nil {
+        httpClient = http.DefaultClient
+    }
+    requestId := NewRequestId()
+    start := time.Now()
     resp, err := httpClient.Do(request)
     if err != nil {
-        log.Fatalln(err)
+        fatalRestError(request, requestId, "request failed", err)
     }
     defer resp.Body.Close()
     body, err := ioutil.ReadAll(resp.Body)
     if err != nil {
-        log.Fatalln(err)
+        fatalRestError(request, requestId, "reading response failed", err)
     }
+    logger.Debug("request", logging.RequestId(requestId), "method", request.Method, "endpoint", Endpoint(request.URL.String()), "status", resp.StatusCode, "duration", time.Since(start))
+    return body, requestId
+}
 
-    var bodyJson map[string]interface{}
-    err = json.Unmarshal(body, &bodyJson)
+func decodeBody(request *http.Request, requestId string, body []byte, v interface{}) {
+    if err := json.Unmarshal(body, v); err != nil {
+        fatalRestError(request, requestId, "decoding response failed", err)
+    }
+}
+
+func HttpGetAndGetBody(httpClient *http.Client, urlString string) (map[string]interface{}, string) {
+    request, err := http.NewRequest("GET", urlString, nil)
     if err != nil {
-        log.Fatalln(err)
+        logger.Fatal("building request failed", "endpoint", Endpoint(urlString), logging.Err(err))
     }
+    return DoHttpAndGetBody(httpClient, request)
+}
 
-    return bodyJson
+// for endpoints that respond with a top-level JSON array
+func DoHttpAndGetArrayBody(httpClient *http.Client, request *http.Request) ([]interface{}, string) {
+    body, requestId := doHttp(httpClient, request)
+    var bodyJson []interface{}
+    decodeBody(request, requestId, body, &bodyJson)
+    return bodyJson, requestId
+}
+
+func DoHttpAndGetBody(httpClient *http.Client, request *http.Request) (map[string]interface{}, string) {
+    body, requestId := doHttp(httpClient, request)
+    var bodyJson map[string]interface{}
+    decodeBody(request, requestId, body, &bodyJson)
+    return bodyJson, requestId
 }
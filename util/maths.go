@@ -1,34 +1,16 @@
 package util
 
 import (
-    "log"
+    "fmt"
     "math"
+    "time"
 
-    "github.com/montanaflynn/stats"
     "github.com/denali-capital/grizzly/types"
     "github.com/shopspring/decimal"
 )
 
-// TODO: can do better than midpoint prices based on the paper
 var two decimal.Decimal = decimal.NewFromInt(2)
 
-func ComputePriceVolatility(spreads []types.Spread) float64 {
-    midpoints := make([]float64, len(spreads))
-
-    for i, spread := range spreads {
-        midpoints[i] = spread.Bid.Add(spread.Ask).Div(two).InexactFloat64()
-    }
-
-    sdev, err := stats.StdDevS(midpoints)
-
-    if err != nil {
-        // this only fails if the len of slice is zero
-        log.Fatalln(err)
-    }
-
-    return sdev
-}
-
 func ComputeSlippage(orderbook *types.OrderBook, quantity decimal.Decimal) decimal.Decimal {
     midpoint := orderbook.Bids[0].Price.Add(orderbook.Asks[0].Price).Div(two)
     idealCost := quantity.Mul(midpoint)
@@ -42,6 +24,181 @@ func ComputeSlippage(orderbook *types.OrderBook, quantity decimal.Decimal) decim
     return buyCost.Sub(sellCost).Div(idealCost.Mul(two))
 }
 
+func Midpoint(spread types.Spread) decimal.Decimal {
+    return spread.Bid.Add(spread.Ask).Div(two)
+}
+
+// top of book mid weighted towards the side with less quantity, where the price is more
+// likely to move; false when either side is empty
+func ComputeMicroprice(orderBook *types.OrderBook) (decimal.Decimal, bool) {
+    if orderBook == nil || len(orderBook.Bids) == 0 || len(orderBook.Asks) == 0 {
+        return decimal.Zero, false
+    }
+    bid, ask := orderBook.Bids[0], orderBook.Asks[0]
+    quantity := bid.Quantity.Add(ask.Quantity)
+    if !quantity.IsPositive() {
+        return decimal.Zero, false
+    }
+    return bid.Price.Mul(ask.Quantity).Add(ask.Price.Mul(bid.Quantity)).Div(quantity), true
+}
+
+// log returns between consecutive prices, pairs with a non-positive price are skipped
+func LogReturns(prices []float64) []float64 {
+    returns := make([]float64, 0, len(prices))
+    for i := 1; i < len(prices); i++ {
+        if prices[i - 1] > 0 && prices[i] > 0 {
+            returns = append(returns, math.Log(prices[i] / prices[i - 1]))
+        }
+    }
+    return returns
+}
+
+// per interval variance to a standard deviation over horizon
+func scaleVariance(variance float64, interval, horizon time.Duration) float64 {
+    if variance <= 0 || interval <= 0 {
+        return 0
+    }
+    return math.Sqrt(variance * float64(horizon) / float64(interval))
+}
+
+// root mean square of log returns of prices sampled interval apart, scaled to horizon;
+// returns are assumed to have zero mean, as they do at these frequencies
+func ComputeRealizedVolatility(prices []float64, interval, horizon time.Duration) float64 {
+    returns := LogReturns(prices)
+    if len(returns) == 0 {
+        return 0
+    }
+    variance := 0.0
+    for _, r := range returns {
+        variance += r * r
+    }
+    return scaleVariance(variance / float64(len(returns)), interval, horizon)
+}
+
+// RiskMetrics exponentially weighted variance of log returns of prices sampled interval
+// apart, scaled to horizon; lambda is the decay, 0.94 in RiskMetrics
+func ComputeEwmaVolatility(prices []float64, interval, horizon time.Duration, lambda float64) float64 {
+    returns := LogReturns(prices)
+    if len(returns) == 0 {
+        return 0
+    }
+    variance := returns[0] * returns[0]
+    for _, r := range returns[1:] {
+        variance = lambda * variance + (1 - lambda) * r * r
+    }
+    return scaleVariance(variance, interval, horizon)
+}
+
+func logRatio(numerator, denominator decimal.Decimal) (float64, bool) {
+    if !numerator.IsPositive() || !denominator.IsPositive() {
+        return 0, false
+    }
+    return math.Log(numerator.InexactFloat64() / denominator.InexactFloat64()), true
+}
+
+// high-low range estimator over candles of resolution interval, scaled to horizon
+func ComputeParkinsonVolatility(candles []types.Candle, interval, horizon time.Duration) float64 {
+    sum := 0.0
+    n := 0
+    for _, candle := range candles {
+        if hl, ok := logRatio(candle.High, candle.Low); ok {
+            sum += hl * hl
+            n++
+        }
+    }
+    if n == 0 {
+        return 0
+    }
+    return scaleVariance(sum / (4 * math.Ln2 * float64(n)), interval, horizon)
+}
+
+// open-high-low-close estimator over candles of resolution interval, scaled to horizon
+func ComputeGarmanKlassVolatility(candles []types.Candle, interval, horizon time.Duration) float64 {
+    sum := 0.0
+    n := 0
+    for _, candle := range candles {
+        hl, okRange := logRatio(candle.High, candle.Low)
+        co, okBody := logRatio(candle.Close, candle.Open)
+        if okRange && okBody {
+            sum += 0.5 * hl * hl - (2 * math.Ln2 - 1) * co * co
+            n++
+        }
+    }
+    if n == 0 {
+        return 0
+    }
+    return scaleVariance(sum / float64(n), interval, horizon)
+}
+
+// how the volatility_1 and volatility_2 features are estimated from candles
+type VolatilityEstimator string
+
+const (
+    // log returns of closes
+    RealizedVolatility VolatilityEstimator = "realized"
+    // RiskMetrics EWMA of log returns of closes
+    EwmaVolatility VolatilityEstimator = "ewma"
+    // high-low range
+    ParkinsonVolatility VolatilityEstimator = "parkinson"
+    // open, high, low and close
+    GarmanKlassVolatility VolatilityEstimator = "garman_klass"
+)
+
+func ParseVolatilityEstimator(s string) (VolatilityEstimator, error) {
+    switch estimator := VolatilityEstimator(s); estimator {
+    case RealizedVolatility, EwmaVolatility, ParkinsonVolatility, GarmanKlassVolatility:
+        return estimator, nil
+    }
+    return "", fmt.Errorf("unknown volatility estimator %q, expected realized, ewma, parkinson or garman_klass", s)
+}
+
+type VolatilityOptions struct {
+    Estimator VolatilityEstimator
+    // resolution of the candles, i.e. the spacing of returns
+    Interval  time.Duration
+    // trailing candles used, 0 uses every candle
+    Window    uint
+    // estimates are scaled to this horizon
+    Horizon   time.Duration
+    // decay for EwmaVolatility
+    Lambda    float64
+}
+
+// candles are closed candles at options.Interval, oldest first; zero when there are too few
+func ComputeVolatility(candles []types.Candle, options VolatilityOptions) float64 {
+    if options.Window > 0 && uint(len(candles)) > options.Window {
+        candles = candles[uint(len(candles)) - options.Window:]
+    }
+    switch options.Estimator {
+    case ParkinsonVolatility:
+        return ComputeParkinsonVolatility(candles, options.Interval, options.Horizon)
+    case GarmanKlassVolatility:
+        return ComputeGarmanKlassVolatility(candles, options.Interval, options.Horizon)
+    }
+    closes := make([]float64, len(candles))
+    for i, candle := range candles {
+        closes[i] = candle.Close.InexactFloat64()
+    }
+    if options.Estimator == EwmaVolatility {
+        return ComputeEwmaVolatility(closes, options.Interval, options.Horizon, options.Lambda)
+    }
+    return ComputeRealizedVolatility(closes, options.Interval, options.Horizon)
+}
+
+// volume weighted average price, false when there is no volume
+func ComputeVWAP(trades []types.Trade) (decimal.Decimal, bool) {
+    notional := decimal.Zero
+    volume := decimal.Zero
+    for _, trade := range trades {
+        notional = notional.Add(trade.Price.Mul(trade.Quantity))
+        volume = volume.Add(trade.Quantity)
+    }
+    if volume.IsZero() {
+        return decimal.Zero, false
+    }
+    return notional.Div(volume), true
+}
+
 func computeOrderCost(side []types.OrderBookEntry, amountToFill decimal.Decimal) decimal.Decimal {
     cumulativeCost := decimal.Zero
 
@@ -1,9 +1,9 @@
 package util
 
 import (
-	"log"
 	"sort"
 
+	"github.com/denali-capital/grizzly/logging"
 	"github.com/denali-capital/grizzly/types"
 	"github.com/shopspring/decimal"
 )
@@ -16,11 +16,11 @@ func remove(slice []types.OrderBookEntry, s int) []types.OrderBookEntry {
 func GetPriceAndQuantity(rawOrderBookEntry []interface{}) (decimal.Decimal, decimal.Decimal) {
 	price, err := decimal.NewFromString(rawOrderBookEntry[0].(string))
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal("parsing order book price failed", logging.Err(err))
 	}
 	quantity, err := decimal.NewFromString(rawOrderBookEntry[1].(string))
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal("parsing order book quantity failed", logging.Err(err))
 	}
 	return price, quantity
 }
@@ -2,6 +2,7 @@ package util
 
 import (
     "sync"
+    "time"
 
     "github.com/denali-capital/grizzly/types"
 )
@@ -78,6 +79,62 @@ func (c *ConcurrentFixedSizeSpreadQueue) Back() types.Spread {
     return c.internal[len(c.internal) - 1]
 }
 
+// bounded ring of trades in arrival order, the oldest is overwritten once full
+type ConcurrentTradeRing struct {
+    sync.RWMutex
+    internal []types.Trade
+    // index of the oldest trade
+    start    int
+    size     int
+}
+
+func NewConcurrentTradeRing(capacity uint) *ConcurrentTradeRing {
+    return &ConcurrentTradeRing{
+        internal: make([]types.Trade, capacity),
+    }
+}
+
+func (c *ConcurrentTradeRing) Push(trade types.Trade) {
+    c.Lock()
+    defer c.Unlock()
+    if len(c.internal) == 0 {
+        return
+    }
+    if c.size < len(c.internal) {
+        c.internal[(c.start + c.size) % len(c.internal)] = trade
+        c.size++
+        return
+    }
+    c.internal[c.start] = trade
+    c.start = (c.start + 1) % len(c.internal)
+}
+
+// oldest first
+func (c *ConcurrentTradeRing) Data() []types.Trade {
+    return c.Since(time.Time{})
+}
+
+// oldest first, trades are assumed to arrive in timestamp order
+func (c *ConcurrentTradeRing) Since(since time.Time) []types.Trade {
+    c.RLock()
+    defer c.RUnlock()
+    // binary search over the ring for the first trade at or after since
+    low, high := 0, c.size
+    for low < high {
+        middle := (low + high) / 2
+        if c.internal[(c.start + middle) % len(c.internal)].Timestamp.Before(since) {
+            low = middle + 1
+        } else {
+            high = middle
+        }
+    }
+    trades := make([]types.Trade, c.size - low)
+    for i := range trades {
+        trades[i] = c.internal[(c.start + low + i) % len(c.internal)]
+    }
+    return trades
+}
+
 type ConcurrentOrderBook struct {
     sync.RWMutex
     internal     types.OrderBook